@@ -1,6 +1,9 @@
 package analytics
 
-import "context"
+import (
+	"context"
+	"sync"
+)
 
 func GetTracker(_ context.Context) Tracker {
 	return amplitudeMixpanelTracker{}
@@ -39,3 +42,48 @@ func SaveEventProps(ctx context.Context, name EventName, props map[string]interf
 		ec[k] = v
 	}
 }
+
+type warningsCollectorKeyType string
+
+const warningsCollectorKey warningsCollectorKeyType = "warnings collector"
+
+// maxCollectedWarnings limits count of collected warnings: only the last ones are kept
+const maxCollectedWarnings = 20
+
+type warningsCollector struct {
+	lock     sync.Mutex
+	warnings []string
+}
+
+// ContextWithWarningsCollector makes loggers of the returned context collect logged warnings
+func ContextWithWarningsCollector(ctx context.Context) context.Context {
+	return context.WithValue(ctx, warningsCollectorKey, &warningsCollector{})
+}
+
+func collectWarning(ctx context.Context, warning string) {
+	wc, ok := ctx.Value(warningsCollectorKey).(*warningsCollector)
+	if !ok {
+		return
+	}
+
+	wc.lock.Lock()
+	defer wc.lock.Unlock()
+
+	wc.warnings = append(wc.warnings, warning)
+	if len(wc.warnings) > maxCollectedWarnings {
+		wc.warnings = wc.warnings[len(wc.warnings)-maxCollectedWarnings:]
+	}
+}
+
+// GetCollectedWarnings returns the last warnings logged with the context
+func GetCollectedWarnings(ctx context.Context) []string {
+	wc, ok := ctx.Value(warningsCollectorKey).(*warningsCollector)
+	if !ok {
+		return nil
+	}
+
+	wc.lock.Lock()
+	defer wc.lock.Unlock()
+
+	return append([]string{}, wc.warnings...)
+}
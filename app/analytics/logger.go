@@ -38,6 +38,7 @@ func (log logger) le() *logrus.Entry {
 func (log logger) Warnf(format string, args ...interface{}) {
 	err := fmt.Errorf(format, args...)
 	log.le().Warn(err.Error())
+	collectWarning(log.ctx, err.Error())
 	trackError(log.ctx, err, apperrors.LevelWarn)
 }
 
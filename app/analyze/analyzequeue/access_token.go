@@ -0,0 +1,56 @@
+package analyzequeue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/task"
+	"github.com/golangci/golangci-worker/app/lib/httputils"
+)
+
+// AccessTokenFetcher fetches GitHub access token of pr analysis: dead tasks are saved without tokens
+type AccessTokenFetcher interface {
+	FetchPRAccessToken(ctx context.Context, t *task.PRAnalysis) (string, error)
+}
+
+// APIAccessTokenFetcher fetches the token of the user who enabled analysis of the repo from golangci-api
+type APIAccessTokenFetcher struct {
+	host   string
+	client httputils.Client
+}
+
+func NewAPIAccessTokenFetcher(client httputils.Client) *APIAccessTokenFetcher {
+	return &APIAccessTokenFetcher{
+		client: client,
+		host:   os.Getenv("API_URL"),
+	}
+}
+
+func (f APIAccessTokenFetcher) getAccessTokenURL(t *task.PRAnalysis) string {
+	return fmt.Sprintf("%s/v1/repos/github.com/%s/%s/access_token?user_id=%d",
+		f.host, t.Repo.Owner, t.Repo.Name, t.UserID)
+}
+
+func (f APIAccessTokenFetcher) FetchPRAccessToken(ctx context.Context, t *task.PRAnalysis) (string, error) {
+	bodyReader, err := f.client.Get(ctx, f.getAccessTokenURL(t))
+	if err != nil {
+		return "", err
+	}
+
+	defer bodyReader.Close()
+
+	var resp struct {
+		AccessToken string
+	}
+	if err = json.NewDecoder(bodyReader).Decode(&resp); err != nil {
+		return "", fmt.Errorf("can't read json body: %s", err)
+	}
+
+	if resp.AccessToken == "" {
+		return "", fmt.Errorf("no access token of repo %s", t.Repo.FullName())
+	}
+
+	return resp.AccessToken, nil
+}
@@ -1,14 +1,28 @@
 package analyzequeue
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/golangci/golangci-shared/pkg/apperrors"
 	"github.com/golangci/golangci-shared/pkg/config"
 	"github.com/golangci/golangci-shared/pkg/logutil"
 	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/consumers"
+	"github.com/golangci/golangci-worker/app/analyze/deadletter"
+	"github.com/golangci/golangci-worker/app/analyze/localstate"
 	"github.com/golangci/golangci-worker/app/analyze/processors"
+	"github.com/golangci/golangci-worker/app/analyze/prstate"
+	"github.com/golangci/golangci-worker/app/analyze/repostate"
+	"github.com/golangci/golangci-worker/app/analyze/templates"
+	"github.com/golangci/golangci-worker/app/lib/boltutils"
 	"github.com/golangci/golangci-worker/app/lib/experiments"
+	"github.com/golangci/golangci-worker/app/lib/goutils/buildcache"
+	"github.com/golangci/golangci-worker/app/lib/goutils/goproxy"
+	"github.com/golangci/golangci-worker/app/lib/httputils"
 	"github.com/golangci/golangci-worker/app/lib/queue"
 )
 
@@ -21,25 +35,163 @@ func RegisterTasks() {
 	trackedLog := apperrors.WrapLogWithTracker(log, nil, et)
 	ec := experiments.NewChecker(cfg, trackedLog)
 
+	concurrency, err := getWorkerConcurrency()
+	if err != nil {
+		log.Fatalf("Can't get worker concurrency: %s", err)
+	}
+
+	limits, err := consumers.NewLimitsFromEnv(concurrency)
+	if err != nil {
+		log.Fatalf("Can't make analyses limits: %s", err)
+	}
+
+	queues, err := getSubscribedQueues()
+	if err != nil {
+		log.Fatalf("Can't get subscribed queues: %s", err)
+	}
+
+	deduper, err := consumers.NewDeduperFromEnv()
+	if err != nil {
+		log.Fatalf("Can't make analyses deduper: %s", err)
+	}
+
+	deadLetterStore, err := deadletter.NewStoreFromEnv()
+	if err != nil {
+		log.Fatalf("Can't make dead-letter store: %s", err)
+	}
+
+	deadLetters := deadletter.NewQueue(deadLetterStore, taskRetryCount+1)
 	rpf := processors.NewRepoProcessorFactory(&processors.StaticRepoConfig{}, trackedLog)
-	repoAnalyzer := consumers.NewAnalyzeRepo(ec, rpf)
+	for _, q := range queues {
+		cq := consumers.Queue{
+			Name:        q.Name,
+			Priority:    q.Priority,
+			Requeuer:    requeuer{queue: q},
+			DeadLetters: deadLetters,
+			Dedupe:      deduper,
+		}
 
-	server := queue.GetServer()
-	err := server.RegisterTasks(map[string]interface{}{
-		"analyzeV2":   consumers.NewAnalyzePR().Consume,
-		"analyzeRepo": repoAnalyzer.Consume,
-	})
+		// register all tasks in every queue: the legacy queue contains all of them
+		prAnalyzer := consumers.NewAnalyzePR(limits, cq)
+		repoAnalyzer := consumers.NewAnalyzeRepo(ec, rpf, limits, cq)
+		err = queue.GetBackend().RegisterTasks(q.Name, map[string]interface{}{
+			consumers.PRAnalysisTaskName:         prAnalyzer.Consume,
+			consumers.RepoAnalysisTaskName:       repoAnalyzer.Consume,
+			consumers.LegacyPRAnalysisTaskName:   prAnalyzer.ConsumeLegacy,
+			consumers.LegacyRepoAnalysisTaskName: repoAnalyzer.ConsumeLegacy,
+		})
+		if err != nil {
+			log.Fatalf("Can't register tasks of queue %s: %s", q.Name, err)
+		}
+	}
+}
+
+// getWorkerConcurrency returns count of analyses running at the same time in this worker
+func getWorkerConcurrency() (int, error) {
+	v := os.Getenv("WORKER_CONCURRENCY")
+	if v == "" {
+		return 1, nil
+	}
+
+	concurrency, err := strconv.Atoi(v)
+	if err != nil || concurrency <= 0 {
+		return 0, fmt.Errorf("invalid WORKER_CONCURRENCY value %q: must be a positive integer", v)
+	}
+
+	return concurrency, nil
+}
+
+const stateOutboxInterval = time.Minute
+
+// runStateOutbox delivers to API states saved locally while API was unavailable
+func runStateOutbox() error {
+	if !boltutils.IsConfigured() {
+		return nil
+	}
+
+	outbox, err := localstate.GetOutbox()
+	if err != nil {
+		return err
+	}
+
+	client := httputils.GrequestsClient{}
+	prstate.RegisterAPISender(outbox, prstate.NewAPIStorage(client))
+	repostate.RegisterAPISender(outbox, repostate.NewAPIStorage(client))
+	go outbox.Run(context.Background(), stateOutboxInterval)
+	return nil
+}
+
+const buildCacheTrimInterval = time.Hour
+
+// runBuildCacheTrimmer trims the build cache shared between analyses
+func runBuildCacheTrimmer() error {
+	if !buildcache.IsConfigured() {
+		return nil
+	}
+
+	cache, err := buildcache.Get()
 	if err != nil {
-		log.Fatalf("Can't register queue tasks: %s", err)
+		return err
 	}
+
+	go cache.RunTrimmer(context.Background(), buildCacheTrimInterval)
+	return nil
 }
 
+// runGoProxy starts the go proxy of executors before the first analysis: invalid config must fail the worker
+func runGoProxy() error {
+	if !goproxy.IsConfigured() {
+		return nil
+	}
+
+	_, err := goproxy.Get()
+	return err
+}
+
+const workerPrefetchFactor = 2
+
 func RunWorker() error {
-	server := queue.GetServer()
-	worker := server.NewWorker("worker_name", 1)
-	err := worker.Launch()
+	if err := runStateOutbox(); err != nil {
+		return fmt.Errorf("can't run state outbox: %s", err)
+	}
+
+	if err := runBuildCacheTrimmer(); err != nil {
+		return fmt.Errorf("can't run build cache trimmer: %s", err)
+	}
+
+	if err := runGoProxy(); err != nil {
+		return fmt.Errorf("can't run go proxy: %s", err)
+	}
+
+	// invalid templates of the deployment must fail the worker, not analyses
+	if _, err := templates.GetDeploymentConfig(); err != nil {
+		return err
+	}
+
+	concurrency, err := getWorkerConcurrency()
 	if err != nil {
-		return fmt.Errorf("can't launch worker: %s", err)
+		return err
+	}
+
+	queues, err := getSubscribedQueues()
+	if err != nil {
+		return err
+	}
+
+	// Take more tasks than can run: waiting tasks are run by priorities of their queues
+	// and in fair order between repo owners, tasks over the limits of their repo or owner
+	// are requeued instead of waiting. The budget is shared by all queues.
+	queues = append([]Queue{}, queues...)
+	sort.SliceStable(queues, func(i, j int) bool {
+		return queues[i].Priority > queues[j].Priority
+	})
+	var queueNames []string
+	for _, q := range queues {
+		queueNames = append(queueNames, q.Name)
+	}
+
+	if err = queue.GetBackend().RunWorker(queueNames, concurrency*workerPrefetchFactor); err != nil {
+		return fmt.Errorf("can't run worker: %s", err)
 	}
 
 	return nil
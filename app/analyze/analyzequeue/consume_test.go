@@ -2,6 +2,7 @@ package analyzequeue
 
 import (
 	"context"
+	"os"
 	"testing"
 	"time"
 
@@ -55,6 +56,7 @@ func TestSendReceiveProcessing(t *testing.T) {
 	task := &task.PRAnalysis{
 		Context:      github.FakeContext,
 		APIRequestID: "req_id",
+		HeadSHA:      "sha",
 	}
 
 	notifyCh := make(chan bool)
@@ -65,6 +67,9 @@ func TestSendReceiveProcessing(t *testing.T) {
 	}).restore()
 
 	test.Init()
+	// run without redis
+	defer overrideEnv("QUEUE_BACKEND", "inprocess")()
+	defer overrideEnv("REDIS_URL", "")()
 	queue.Init()
 	RegisterTasks()
 	go func() {
@@ -81,3 +86,21 @@ func TestSendReceiveProcessing(t *testing.T) {
 		t.Fatalf("Timeouted waiting of processing")
 	}
 }
+
+// overrideEnv sets the env var or unsets it if the value is empty, it returns the func restoring the var
+func overrideEnv(k, v string) func() {
+	prev, wasSet := os.LookupEnv(k)
+	if v == "" {
+		os.Unsetenv(k)
+	} else {
+		os.Setenv(k, v)
+	}
+
+	return func() {
+		if wasSet {
+			os.Setenv(k, prev)
+		} else {
+			os.Unsetenv(k)
+		}
+	}
+}
@@ -14,20 +14,41 @@ import (
 
 var ProcessorFactory = processors.NewGithubFactory()
 
+// PRAnalysisTaskName is a machinery task name of pull request analysis with JSON payload
+const PRAnalysisTaskName = "analyzePRPayload"
+
+// LegacyPRAnalysisTaskName is a machinery task name of pull request analysis with positional args:
+// such tasks are consumed until all of them sent before the payload was introduced are processed
+const LegacyPRAnalysisTaskName = "analyzeV2"
+
 type AnalyzePR struct {
 	baseConsumer
 }
 
-func NewAnalyzePR() *AnalyzePR {
+// NewAnalyzePR makes pr analysis consumer of the queue: limits can be nil to run analyses without limits
+func NewAnalyzePR(limits *Limits, q Queue) *AnalyzePR {
 	return &AnalyzePR{
 		baseConsumer: baseConsumer{
 			eventName:           analytics.EventPRChecked,
 			needSendToAnalytics: true,
+			limits:              limits,
+			queue:               q,
 		},
 	}
 }
 
-func (c AnalyzePR) Consume(ctx context.Context, repoOwner, repoName, githubAccessToken string,
+// Consume consumes task with the payload encoded by task.EncodePRAnalysis
+func (c AnalyzePR) Consume(ctx context.Context, payload string) error {
+	t, err := task.DecodePRAnalysis(payload)
+	if err != nil {
+		return fmt.Errorf("invalid pr analysis task: %s", err)
+	}
+
+	return c.consume(ctx, t)
+}
+
+// ConsumeLegacy consumes task with positional args
+func (c AnalyzePR) ConsumeLegacy(ctx context.Context, repoOwner, repoName, githubAccessToken string,
 	pullRequestNumber int, APIRequestID string, userID uint, analysisGUID string) error {
 
 	t := &task.PRAnalysis{
@@ -44,15 +65,32 @@ func (c AnalyzePR) Consume(ctx context.Context, repoOwner, repoName, githubAcces
 		AnalysisGUID: analysisGUID,
 	}
 
+	return c.consume(ctx, t)
+}
+
+func (c AnalyzePR) consume(ctx context.Context, t *task.PRAnalysis) error {
 	ctx = c.prepareContext(ctx, map[string]interface{}{
-		"repoName":     fmt.Sprintf("%s/%s", repoOwner, repoName),
+		"repoName":     t.Repo.FullName(),
 		"provider":     "github",
-		"prNumber":     pullRequestNumber,
-		"userIDString": strconv.Itoa(int(userID)),
-		"analysisGUID": analysisGUID,
+		"prNumber":     t.PullRequestNumber,
+		"userIDString": strconv.Itoa(int(t.UserID)),
+		"analysisGUID": t.AnalysisGUID,
+	})
+
+	requeue := func(delay time.Duration) error {
+		return c.queue.Requeuer.RequeuePRAnalysis(t, delay)
+	}
+
+	return c.queue.Dedupe.runPR(ctx, t, requeue, func() error {
+		return c.limits.run(ctx, &t.Repo, t.AnalysisGUID, c.queue.Priority, requeue, func() error {
+			return c.analyzePR(ctx, t)
+		})
 	})
+}
 
-	return c.wrapConsuming(ctx, func() error {
+func (c AnalyzePR) analyzePR(ctx context.Context, t *task.PRAnalysis) error {
+	dt := c.newDeadLetterTask(PRAnalysisTaskName, t.AnalysisGUID, t.WithoutSecrets(), t.GithubAccessToken)
+	return c.wrapConsuming(ctx, dt, func() error {
 		var cancel context.CancelFunc
 		// If you change timeout value don't forget to change it
 		// in golangci-api stale analyzes checker
@@ -61,11 +99,11 @@ func (c AnalyzePR) Consume(ctx context.Context, repoOwner, repoName, githubAcces
 
 		p, err := ProcessorFactory.BuildProcessor(ctx, t)
 		if err != nil {
-			return fmt.Errorf("can't build processor for task %+v: %s", t, err)
+			return fmt.Errorf("can't build processor for task %+v: %s", t.WithoutSecrets(), err)
 		}
 
 		if err = p.Process(ctx); err != nil {
-			return fmt.Errorf("can't process pr analysis of %+v: %s", t, err)
+			return fmt.Errorf("can't process pr analysis of %+v: %s", t.WithoutSecrets(), err)
 		}
 
 		return nil
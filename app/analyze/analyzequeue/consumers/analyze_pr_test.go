@@ -7,6 +7,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/task"
+	"github.com/golangci/golangci-worker/app/lib/github"
 	"github.com/golangci/golangci-worker/app/test"
 	"github.com/stretchr/testify/assert"
 )
@@ -30,7 +32,20 @@ func TestAnalyzeRepo(t *testing.T) {
 		repoOwner, repoName = parts[0], parts[1]
 	}
 
-	err := NewAnalyzePR().Consume(context.Background(), repoOwner, repoName,
-		os.Getenv("TEST_GITHUB_TOKEN"), prNumber, "", userID, "test-guid")
+	payload, err := task.EncodePRAnalysis(&task.PRAnalysis{
+		Context: github.Context{
+			Repo: github.Repo{
+				Owner: repoOwner,
+				Name:  repoName,
+			},
+			GithubAccessToken: os.Getenv("TEST_GITHUB_TOKEN"),
+			PullRequestNumber: prNumber,
+		},
+		UserID:       userID,
+		AnalysisGUID: "test-guid",
+	})
+	assert.NoError(t, err)
+
+	err = NewAnalyzePR(nil, Queue{}).Consume(context.Background(), payload)
 	assert.NoError(t, err)
 }
@@ -8,12 +8,20 @@ import (
 	"time"
 
 	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/task"
 	"github.com/golangci/golangci-worker/app/analyze/processors"
 	"github.com/golangci/golangci-worker/app/lib/experiments"
 	"github.com/golangci/golangci-worker/app/lib/github"
 	"github.com/pkg/errors"
 )
 
+// RepoAnalysisTaskName is a machinery task name of repo analysis with JSON payload
+const RepoAnalysisTaskName = "analyzeRepoPayload"
+
+// LegacyRepoAnalysisTaskName is a machinery task name of repo analysis with positional args:
+// such tasks are consumed until all of them sent before the payload was introduced are processed
+const LegacyRepoAnalysisTaskName = "analyzeRepo"
+
 type AnalyzeRepo struct {
 	baseConsumer
 
@@ -21,22 +29,45 @@ type AnalyzeRepo struct {
 	rpf *processors.RepoProcessorFactory
 }
 
-func NewAnalyzeRepo(ec *experiments.Checker, rpf *processors.RepoProcessorFactory) *AnalyzeRepo {
+func NewAnalyzeRepo(ec *experiments.Checker, rpf *processors.RepoProcessorFactory,
+	limits *Limits, q Queue) *AnalyzeRepo {
+
 	return &AnalyzeRepo{
 		baseConsumer: baseConsumer{
 			eventName: analytics.EventRepoAnalyzed,
+			limits:    limits,
+			queue:     q,
 		},
 		ec:  ec,
 		rpf: rpf,
 	}
 }
 
-func (c AnalyzeRepo) Consume(ctx context.Context, repoName, analysisGUID, branch string) error {
+// Consume consumes task with the payload encoded by task.EncodeRepoAnalysis
+func (c AnalyzeRepo) Consume(ctx context.Context, payload string) error {
+	t, err := task.DecodeRepoAnalysis(payload)
+	if err != nil {
+		return fmt.Errorf("invalid repo analysis task: %s", err)
+	}
+
+	return c.consume(ctx, t)
+}
+
+// ConsumeLegacy consumes task with positional args
+func (c AnalyzeRepo) ConsumeLegacy(ctx context.Context, repoName, analysisGUID, branch string) error {
+	return c.consume(ctx, &task.RepoAnalysis{
+		Name:         repoName,
+		AnalysisGUID: analysisGUID,
+		Branch:       branch,
+	})
+}
+
+func (c AnalyzeRepo) consume(ctx context.Context, t *task.RepoAnalysis) error {
 	ctx = c.prepareContext(ctx, map[string]interface{}{
-		"repoName":     repoName,
+		"repoName":     t.Name,
 		"provider":     "github",
-		"analysisGUID": analysisGUID,
-		"branch":       branch,
+		"analysisGUID": t.AnalysisGUID,
+		"branch":       t.Branch,
 	})
 
 	if os.Getenv("DISABLE_REPO_ANALYSIS") == "1" {
@@ -44,32 +75,57 @@ func (c AnalyzeRepo) Consume(ctx context.Context, repoName, analysisGUID, branch
 		return errors.New("repo analysis is disabled")
 	}
 
-	return c.wrapConsuming(ctx, func() error {
+	repo, err := ParseRepoName(t.Name)
+	if err != nil {
+		return err
+	}
+
+	requeue := func(delay time.Duration) error {
+		return c.queue.Requeuer.RequeueRepoAnalysis(t, delay)
+	}
+
+	return c.queue.Dedupe.runRepo(ctx, t, requeue, func() error {
+		return c.limits.run(ctx, repo, t.AnalysisGUID, c.queue.Priority, requeue, func() error {
+			return c.runAnalyzeRepo(ctx, repo, t)
+		})
+	})
+}
+
+func (c AnalyzeRepo) runAnalyzeRepo(ctx context.Context, repo *github.Repo, t *task.RepoAnalysis) error {
+	dt := c.newDeadLetterTask(RepoAnalysisTaskName, t.AnalysisGUID, t)
+	return c.wrapConsuming(ctx, dt, func() error {
 		var cancel context.CancelFunc
 		// If you change timeout value don't forget to change it
 		// in golangci-api stale analyzes checker
 		ctx, cancel = context.WithTimeout(ctx, 10*time.Minute)
 		defer cancel()
 
-		return c.analyzeRepo(ctx, repoName, analysisGUID, branch)
+		return c.analyzeRepo(ctx, repo, t.AnalysisGUID, t.Branch, t.CommitSHA)
 	})
 }
 
-func (c AnalyzeRepo) analyzeRepo(ctx context.Context, repoName, analysisGUID, branch string) error {
+// ParseRepoName parses the repo name owner/name of repo analysis tasks
+func ParseRepoName(repoName string) (*github.Repo, error) {
 	parts := strings.Split(repoName, "/")
-	repo := &github.Repo{
-		Owner: parts[0],
-		Name:  parts[1],
-	}
 	if len(parts) != 2 {
-		return fmt.Errorf("invalid repo name %s", repoName)
+		return nil, fmt.Errorf("invalid repo name %s", repoName)
 	}
 
+	return &github.Repo{
+		Owner: parts[0],
+		Name:  parts[1],
+	}, nil
+}
+
+func (c AnalyzeRepo) analyzeRepo(ctx context.Context, repo *github.Repo, analysisGUID, branch, commitSHA string) error {
+	repoName := repo.FullName()
+
 	if c.ec.IsActiveForAnalysis("use_new_repo_analysis", repo, false) {
 		repoCtx := &processors.RepoContext{
 			Ctx:          ctx,
 			AnalysisGUID: analysisGUID,
 			Branch:       branch,
+			CommitSHA:    commitSHA,
 			Repo:         repo,
 		}
 		p, cleanup, err := c.rpf.BuildProcessor(repoCtx)
@@ -2,16 +2,20 @@ package consumers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"runtime/debug"
 	"time"
 
 	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/analyze/deadletter"
 )
 
 type baseConsumer struct {
 	eventName           analytics.EventName
 	needSendToAnalytics bool
+	limits              *Limits
+	queue               Queue
 }
 
 const statusOk = "ok"
@@ -20,20 +24,24 @@ const statusFail = "fail"
 func (c baseConsumer) prepareContext(ctx context.Context, trackingProps map[string]interface{}) context.Context {
 	ctx = analytics.ContextWithEventPropsCollector(ctx, c.eventName)
 	ctx = analytics.ContextWithTrackingProps(ctx, trackingProps)
+	ctx = analytics.ContextWithWarningsCollector(ctx)
 	return ctx
 }
 
-func (c baseConsumer) wrapConsuming(ctx context.Context, f func() error) (err error) {
+// wrapConsuming runs f and moves the task to the dead-letter queue if it was the last failed attempt
+func (c baseConsumer) wrapConsuming(ctx context.Context, dt *deadletter.Task, f func() error) (err error) {
+	startedAt := time.Now()
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("panic recovered: %v, %s, source is %s", r, debug.Stack(), err)
 			analytics.Log(ctx).Errorf("processing of %q task failed: %s", c.eventName, err)
 		}
+
+		c.recordAttempt(ctx, dt, startedAt, err)
 	}()
 
 	analytics.Log(ctx).Infof("Starting consuming of %s...", c.eventName)
 
-	startedAt := time.Now()
 	err = f()
 	duration := time.Since(startedAt)
 	analytics.Log(ctx).Infof("Finished consuming of %s for %s", c.eventName, duration)
@@ -49,6 +57,51 @@ func (c baseConsumer) wrapConsuming(ctx context.Context, f func() error) (err er
 	return err
 }
 
+func (c baseConsumer) recordAttempt(ctx context.Context, dt *deadletter.Task, startedAt time.Time, err error) {
+	if c.queue.DeadLetters == nil {
+		return
+	}
+
+	if err == nil {
+		if recordErr := c.queue.DeadLetters.RecordSuccess(ctx, dt.ID); recordErr != nil {
+			analytics.Log(ctx).Warnf("Can't clear failed attempts of task %s: %s", dt.ID, recordErr)
+		}
+		return
+	}
+
+	dt.Warnings = analytics.GetCollectedWarnings(ctx)
+	attempt := deadletter.Attempt{
+		StartedAt: startedAt,
+		Duration:  time.Since(startedAt),
+		Error:     err.Error(),
+	}
+	dead, recordErr := c.queue.DeadLetters.RecordFailure(ctx, dt, attempt)
+	if recordErr != nil {
+		analytics.Log(ctx).Warnf("Can't record failed attempt of task %s: %s", dt.ID, recordErr)
+		return
+	}
+
+	if dead {
+		analytics.Log(ctx).Errorf("Task %s failed all attempts, moved it to the dead-letter queue", dt.ID)
+	}
+}
+
+// newDeadLetterTask makes dead-letter task for the task payload: the payload must not contain secrets,
+// secrets are hidden in errors and warnings of the task
+func (c baseConsumer) newDeadLetterTask(name, analysisGUID string, payload interface{},
+	secrets ...string) *deadletter.Task {
+
+	payloadJSON, _ := json.Marshal(payload) // task structs are always marshaled
+
+	return &deadletter.Task{
+		ID:      fmt.Sprintf("%s:%s", name, analysisGUID),
+		Name:    name,
+		Queue:   c.queue.Name,
+		Payload: payloadJSON,
+		Secrets: secrets,
+	}
+}
+
 func (c baseConsumer) sendAnalytics(ctx context.Context, duration time.Duration, err error) {
 	props := map[string]interface{}{
 		"durationSeconds": int(duration / time.Second),
@@ -0,0 +1,250 @@
+package consumers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/task"
+	"github.com/golangci/golangci-worker/app/analyze/dedupe"
+	"github.com/golangci/golangci-worker/app/analyze/prstate"
+	"github.com/golangci/golangci-worker/app/analyze/repostate"
+	"github.com/golangci/golangci-worker/app/analyze/resultjson"
+	"github.com/golangci/golangci-worker/app/lib/httputils"
+	"github.com/pkg/errors"
+)
+
+const defaultDedupeTTL = time.Hour
+
+const defaultDuplicateRequeueDelay = 30 * time.Second
+
+const (
+	// dedupeLeaseTTL is how long the key of a running analysis is kept without heartbeats:
+	// duplicates of an analysis of a crashed worker are run after the lease expires
+	dedupeLeaseTTL = time.Minute
+
+	dedupeHeartbeatInterval = 20 * time.Second
+)
+
+// Deduper runs only one of identical analyses (with the same idempotency key) sent during the TTL:
+// e.g. sent by webhook redeliveries or API retries. A duplicate of a finished analysis reuses its result,
+// a duplicate of a running analysis is requeued until the analysis finishes.
+//
+// It's the only mechanism skipping identical analyses: unlike the cancellation registry it works for
+// repo analyses too, runs before the task takes a slot of limits and gives the duplicate the result
+// of the first analysis instead of skipping it. The cancellation registry only orders analyses of
+// different commits of a pull request.
+type Deduper struct {
+	store        dedupe.Store
+	ttl          time.Duration
+	prStates     prstate.Storage
+	repoStates   repostate.Storage
+	requeueDelay time.Duration
+
+	leaseTTL          time.Duration
+	heartbeatInterval time.Duration
+}
+
+func NewDeduper(store dedupe.Store, ttl time.Duration, prStates prstate.Storage,
+	repoStates repostate.Storage, requeueDelay time.Duration) *Deduper {
+
+	return &Deduper{
+		store:        store,
+		ttl:          ttl,
+		prStates:     prStates,
+		repoStates:   repoStates,
+		requeueDelay: requeueDelay,
+
+		leaseTTL:          dedupeLeaseTTL,
+		heartbeatInterval: dedupeHeartbeatInterval,
+	}
+}
+
+// NewDeduperFromEnv makes deduper with keys shared through redis if it's configured, otherwise keys are per process
+func NewDeduperFromEnv() (*Deduper, error) {
+	ttlSec, err := getIntEnv("DEDUPE_TTL_SEC", int(defaultDedupeTTL/time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	client := httputils.GrequestsClient{}
+	prStates, err := prstate.NewStorageFromEnv(client)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make pr state storage")
+	}
+
+	repoStates, err := repostate.NewStorageFromEnv(client)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make repo state storage")
+	}
+
+	return NewDeduper(dedupe.NewStoreFromEnv(), time.Duration(ttlSec)*time.Second,
+		prStates, repoStates, defaultDuplicateRequeueDelay), nil
+}
+
+// duplicate describes how to reuse the state of the analysis which owns the idempotency key
+type duplicate struct {
+	key          string
+	analysisGUID string
+
+	// reuse copies the state of the analysis ownerGUID to this analysis if it's finished,
+	// it returns the status of the analysis ownerGUID
+	reuse func(ownerGUID string) (string, error)
+}
+
+func (d *Deduper) runPR(ctx context.Context, t *task.PRAnalysis, requeue requeueFunc, f func() error) error {
+	owner, name := t.Repo.Owner, t.Repo.Name
+	return d.run(ctx, duplicate{
+		key:          t.GetIdempotencyKey(),
+		analysisGUID: t.AnalysisGUID,
+		reuse: func(ownerGUID string) (string, error) {
+			state, err := d.prStates.GetState(ctx, owner, name, ownerGUID)
+			if err != nil {
+				return "", err
+			}
+			if !isFinishedStatus(state.Status) {
+				return state.Status, nil
+			}
+
+			return state.Status, d.prStates.UpdateState(ctx, owner, name, t.AnalysisGUID, &prstate.State{
+				Status:              state.Status,
+				ReportedIssuesCount: state.ReportedIssuesCount,
+				ResultJSON:          linkResult(state.ResultJSON, ownerGUID),
+			})
+		},
+	}, requeue, f)
+}
+
+func (d *Deduper) runRepo(ctx context.Context, t *task.RepoAnalysis, requeue requeueFunc, f func() error) error {
+	repo, parseErr := ParseRepoName(t.Name)
+	if parseErr != nil {
+		return parseErr
+	}
+
+	owner, name := repo.Owner, repo.Name
+	return d.run(ctx, duplicate{
+		key:          t.GetIdempotencyKey(),
+		analysisGUID: t.AnalysisGUID,
+		reuse: func(ownerGUID string) (string, error) {
+			state, err := d.repoStates.GetState(ctx, owner, name, ownerGUID)
+			if err != nil {
+				return "", err
+			}
+			if !isFinishedStatus(state.Status) {
+				return state.Status, nil
+			}
+
+			return state.Status, d.repoStates.UpdateState(ctx, owner, name, t.AnalysisGUID, &repostate.State{
+				Status:     state.Status,
+				ResultJSON: linkResult(state.ResultJSON, ownerGUID),
+			})
+		},
+	}, requeue, f)
+}
+
+// run runs f if the analysis is the first one with its idempotency key. Otherwise it reuses the result
+// of the first analysis or requeues the task if the first analysis isn't finished yet.
+// Problems with the store or states don't block analyses: f is run then.
+func (d *Deduper) run(ctx context.Context, dup duplicate, requeue requeueFunc, f func() error) error {
+	if d == nil || dup.key == "" {
+		return f()
+	}
+
+	ownerGUID, err := d.store.Claim(ctx, dup.key, dup.analysisGUID, d.leaseTTL)
+	if err != nil {
+		analytics.Log(ctx).Warnf("Can't claim idempotency key %s, run without deduplication: %s", dup.key, err)
+		return f()
+	}
+
+	if ownerGUID == dup.analysisGUID {
+		return d.runOwner(ctx, dup, f)
+	}
+
+	status, err := dup.reuse(ownerGUID)
+	if err != nil {
+		analytics.Log(ctx).Warnf("Can't reuse result of identical analysis %s, run without deduplication: %s",
+			ownerGUID, err)
+		return f()
+	}
+
+	if !isFinishedStatus(status) {
+		analytics.Log(ctx).Infof("Identical analysis %s is in status %q, requeue the task in %s",
+			ownerGUID, status, d.requeueDelay)
+		if err = requeue(d.requeueDelay); err != nil {
+			return errors.Wrap(err, "failed to requeue the task")
+		}
+		return nil
+	}
+
+	analytics.Log(ctx).Infof("Reused result of identical analysis %s", ownerGUID)
+	return nil
+}
+
+// runOwner runs f refreshing the lease of the key. The key is kept for the TTL only if f succeeded:
+// the key of a failed analysis expires with its lease and a duplicate can run the analysis.
+func (d *Deduper) runOwner(ctx context.Context, dup duplicate, f func() error) error {
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		d.heartbeat(ctx, dup, stopCh)
+	}()
+
+	err := f()
+	close(stopCh)
+	<-doneCh
+
+	if err == nil {
+		d.refresh(ctx, dup, d.ttl)
+	}
+
+	return err
+}
+
+// heartbeat refreshes the lease until stopCh is closed: a retry of the analysis
+// takes back the short lease from the TTL set by the previous attempt
+func (d *Deduper) heartbeat(ctx context.Context, dup duplicate, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(d.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		d.refresh(ctx, dup, d.leaseTTL)
+
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Deduper) refresh(ctx context.Context, dup duplicate, ttl time.Duration) {
+	if err := d.store.Refresh(ctx, dup.key, dup.analysisGUID, ttl); err != nil {
+		analytics.Log(ctx).Warnf("Can't refresh idempotency key %s: %s", dup.key, err)
+	}
+}
+
+func isFinishedStatus(status string) bool {
+	switch status {
+	case "", "sent_to_queue", "processing":
+		return false
+	}
+
+	return true
+}
+
+// linkResult returns a copy of the result with a warning linking to the analysis the result was made by
+func linkResult(res *resultjson.Document, analysisGUID string) *resultjson.Document {
+	if res == nil {
+		return nil
+	}
+
+	ret := *res
+	ret.WorkerRes.Warnings = append([]resultjson.Warning{}, res.WorkerRes.Warnings...)
+	ret.WorkerRes.Warnings = append(ret.WorkerRes.Warnings, resultjson.Warning{
+		Tag:  "dedupe",
+		Text: fmt.Sprintf("Result is reused from identical analysis %s", analysisGUID),
+	})
+	return &ret
+}
@@ -0,0 +1,139 @@
+package consumers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/task"
+	"github.com/golangci/golangci-worker/app/analyze/dedupe"
+	"github.com/golangci/golangci-worker/app/analyze/prstate"
+	"github.com/golangci/golangci-worker/app/analyze/resultjson"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPRAnalysis(analysisGUID string) *task.PRAnalysis {
+	return &task.PRAnalysis{
+		Context: github.Context{
+			Repo:              github.Repo{Owner: "owner", Name: "name"},
+			PullRequestNumber: 1,
+		},
+		AnalysisGUID: analysisGUID,
+		HeadSHA:      "sha",
+	}
+}
+
+type dedupeRun struct {
+	analyzed bool
+	requeued bool
+}
+
+func runDedupedPR(t *testing.T, d *Deduper, pt *task.PRAnalysis) dedupeRun {
+	var r dedupeRun
+	err := d.runPR(context.Background(), pt, func(delay time.Duration) error {
+		r.requeued = true
+		return nil
+	}, func() error {
+		r.analyzed = true
+		return nil
+	})
+	assert.NoError(t, err)
+	return r
+}
+
+func TestDedupePR(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	states := prstate.NewMockStorage(ctrl)
+	d := NewDeduper(dedupe.NewMemoryStore(), time.Hour, states, nil, time.Second)
+
+	assert.Equal(t, dedupeRun{analyzed: true}, runDedupedPR(t, d, newTestPRAnalysis("first")))
+
+	// the first analysis is running: the duplicate waits for it
+	states.EXPECT().GetState(gomock.Any(), "owner", "name", "first").Return(&prstate.State{
+		Status: "processing",
+	}, nil)
+	assert.Equal(t, dedupeRun{requeued: true}, runDedupedPR(t, d, newTestPRAnalysis("second")))
+
+	// retries and requeues of the first analysis aren't duplicates
+	assert.Equal(t, dedupeRun{analyzed: true}, runDedupedPR(t, d, newTestPRAnalysis("first")))
+
+	// the first analysis is finished: the duplicate reuses its result
+	res := &resultjson.Document{
+		WorkerRes: resultjson.WorkerResult{
+			Warnings: []resultjson.Warning{{Tag: "tag", Text: "text"}},
+		},
+	}
+	states.EXPECT().GetState(gomock.Any(), "owner", "name", "first").Return(&prstate.State{
+		Status:              "processed/failure",
+		ReportedIssuesCount: 2,
+		ResultJSON:          res,
+	}, nil)
+	states.EXPECT().UpdateState(gomock.Any(), "owner", "name", "second", &prstate.State{
+		Status:              "processed/failure",
+		ReportedIssuesCount: 2,
+		ResultJSON: &resultjson.Document{
+			WorkerRes: resultjson.WorkerResult{
+				Warnings: []resultjson.Warning{
+					{Tag: "tag", Text: "text"},
+					{Tag: "dedupe", Text: "Result is reused from identical analysis first"},
+				},
+			},
+		},
+	}).Return(nil)
+	assert.Equal(t, dedupeRun{}, runDedupedPR(t, d, newTestPRAnalysis("second")))
+	assert.Len(t, res.WorkerRes.Warnings, 1, "result of the first analysis must not be changed")
+
+	// analyses without idempotency key aren't deduplicated
+	pt := newTestPRAnalysis("third")
+	pt.HeadSHA = ""
+	assert.Equal(t, dedupeRun{analyzed: true}, runDedupedPR(t, d, pt))
+}
+
+func TestNilDeduper(t *testing.T) {
+	var d *Deduper
+	assert.Equal(t, dedupeRun{analyzed: true}, runDedupedPR(t, d, newTestPRAnalysis("first")))
+}
+
+func TestDedupeRunsDuplicateOfCrashedAnalysis(t *testing.T) {
+	store := dedupe.NewMemoryStore()
+	d := NewDeduper(store, time.Hour, nil, nil, time.Second)
+	d.leaseTTL = 50 * time.Millisecond
+
+	// the worker of the first analysis crashed after claiming the key
+	_, err := store.Claim(context.Background(), newTestPRAnalysis("first").GetIdempotencyKey(), "first", d.leaseTTL)
+	assert.NoError(t, err)
+
+	time.Sleep(2 * d.leaseTTL)
+	assert.Equal(t, dedupeRun{analyzed: true}, runDedupedPR(t, d, newTestPRAnalysis("second")))
+}
+
+func TestDedupeKeepsKeyOfRunningAnalysis(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	states := prstate.NewMockStorage(ctrl)
+	states.EXPECT().GetState(gomock.Any(), "owner", "name", "first").Return(&prstate.State{
+		Status: "processing",
+	}, nil)
+
+	d := NewDeduper(dedupe.NewMemoryStore(), time.Hour, states, nil, time.Second)
+	d.leaseTTL = 50 * time.Millisecond
+	d.heartbeatInterval = 10 * time.Millisecond
+
+	err := d.runPR(context.Background(), newTestPRAnalysis("first"), nil, func() error {
+		// the lease is refreshed by heartbeats while the analysis runs
+		time.Sleep(2 * d.leaseTTL)
+		assert.Equal(t, dedupeRun{requeued: true}, runDedupedPR(t, d, newTestPRAnalysis("second")))
+		return errors.New("failed")
+	})
+	assert.Error(t, err)
+
+	// the key of the failed analysis expires with its lease
+	time.Sleep(2 * d.leaseTTL)
+	assert.Equal(t, dedupeRun{analyzed: true}, runDedupedPR(t, d, newTestPRAnalysis("second")))
+}
@@ -0,0 +1,180 @@
+package consumers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/task"
+	"github.com/golangci/golangci-worker/app/analyze/deadletter"
+	"github.com/golangci/golangci-worker/app/lib/concurrency"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/golangci/golangci-worker/app/lib/redisutils"
+	"github.com/pkg/errors"
+)
+
+// slotTTL must be greater than the analysis timeout
+const slotTTL = 15 * time.Minute
+
+const defaultRequeueDelay = 30 * time.Second
+
+type requeueFunc func(delay time.Duration) error
+
+// Requeuer sends tasks back to the queue to run them later
+type Requeuer interface {
+	RequeuePRAnalysis(t *task.PRAnalysis, delay time.Duration) error
+	RequeueRepoAnalysis(t *task.RepoAnalysis, delay time.Duration) error
+}
+
+// Queue is a queue which consumer takes tasks from
+type Queue struct {
+	Name        string
+	Priority    int               // tasks from queues with greater priority run first
+	Requeuer    Requeuer          // sends tasks back to this queue
+	DeadLetters *deadletter.Queue // keeps tasks which failed all attempts, can be nil
+	Dedupe      *Deduper          // skips identical analyses, can be nil
+}
+
+// Limits caps count of concurrently running analyses per repo owner and per repo
+// across all workers and runs analyses of this worker in weighted fair order between repo owners.
+type Limits struct {
+	limiter      concurrency.Limiter
+	scheduler    *concurrency.FairScheduler
+	ownerLimit   int
+	repoLimit    int
+	requeueDelay time.Duration
+}
+
+func NewLimits(limiter concurrency.Limiter, scheduler *concurrency.FairScheduler,
+	ownerLimit, repoLimit int, requeueDelay time.Duration) *Limits {
+
+	return &Limits{
+		limiter:      limiter,
+		scheduler:    scheduler,
+		ownerLimit:   ownerLimit,
+		repoLimit:    repoLimit,
+		requeueDelay: requeueDelay,
+	}
+}
+
+// NewLimitsFromEnv makes limits shared through redis if it's configured, otherwise limits are per process
+func NewLimitsFromEnv(workerConcurrency int) (*Limits, error) {
+	ownerLimit, err := getIntEnv("MAX_ANALYSES_PER_OWNER", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	repoLimit, err := getIntEnv("MAX_ANALYSES_PER_REPO", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	requeueDelaySec, err := getIntEnv("LIMITED_ANALYSIS_REQUEUE_DELAY_SEC", int(defaultRequeueDelay/time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	weights, err := parseOwnerWeights(os.Getenv("OWNER_WEIGHTS"))
+	if err != nil {
+		return nil, err
+	}
+
+	var limiter concurrency.Limiter
+	if redisutils.IsConfigured() {
+		limiter = concurrency.NewRedisLimiter(redisutils.GetPool())
+	} else {
+		limiter = concurrency.NewMemoryLimiter()
+	}
+
+	scheduler := concurrency.NewFairScheduler(workerConcurrency, weights)
+	requeueDelay := time.Duration(requeueDelaySec) * time.Second
+	return NewLimits(limiter, scheduler, ownerLimit, repoLimit, requeueDelay), nil
+}
+
+func getIntEnv(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+
+	ret, err := strconv.Atoi(v)
+	if err != nil || ret < 0 {
+		return 0, fmt.Errorf("invalid %s value %q: must be a non-negative integer", key, v)
+	}
+
+	return ret, nil
+}
+
+// parseOwnerWeights parses weights in the format "owner1=3,owner2=2"
+func parseOwnerWeights(s string) (map[string]float64, error) {
+	weights := map[string]float64{}
+	if s == "" {
+		return weights, nil
+	}
+
+	for _, kv := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(kv), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid owner weight %q: must be owner=weight", kv)
+		}
+
+		w, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || w <= 0 {
+			return nil, fmt.Errorf("invalid weight of owner %s: %q", parts[0], parts[1])
+		}
+
+		weights[strings.ToLower(parts[0])] = w
+	}
+
+	return weights, nil
+}
+
+// run waits for a turn of the task in this worker by its priority and fairly between repo owners
+// and runs f if the repo and its owner have free slots. Otherwise it calls requeue to run the task later:
+// then the task doesn't occupy the worker while analyses of other owners can run.
+func (l *Limits) run(ctx context.Context, repo *github.Repo, taskID string, priority int,
+	requeue requeueFunc, f func() error) error {
+
+	if l == nil {
+		return f()
+	}
+
+	owner := strings.ToLower(repo.Owner)
+	releaseTurn, err := l.scheduler.Acquire(ctx, owner, priority)
+	if err != nil {
+		return errors.Wrap(err, "failed to wait for a free worker slot")
+	}
+	defer releaseTurn()
+
+	slots := []concurrency.Slot{
+		{Key: "owner:" + owner, Limit: l.ownerLimit},
+		{Key: "repo:" + strings.ToLower(repo.FullName()), Limit: l.repoLimit},
+	}
+	acquired, err := l.limiter.TryAcquire(ctx, taskID, slots, slotTTL)
+	if err != nil {
+		// don't block analyses because of limiter problems
+		analytics.Log(ctx).Warnf("Can't acquire concurrency slots, run without limits: %s", err)
+		return f()
+	}
+
+	if !acquired {
+		analytics.Log(ctx).Infof("Too many running analyses of %s, requeue the task in %s",
+			repo.FullName(), l.requeueDelay)
+		if err = requeue(l.requeueDelay); err != nil {
+			return errors.Wrap(err, "failed to requeue the task")
+		}
+		return nil
+	}
+
+	defer func() {
+		if releaseErr := l.limiter.Release(ctx, taskID, slots); releaseErr != nil {
+			analytics.Log(ctx).Warnf("Can't release concurrency slots: %s", releaseErr)
+		}
+	}()
+
+	return f()
+}
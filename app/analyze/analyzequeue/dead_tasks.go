@@ -0,0 +1,59 @@
+package analyzequeue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/consumers"
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/task"
+	"github.com/golangci/golangci-worker/app/analyze/deadletter"
+)
+
+// RequeueDeadTask sends the dead task back to its queue and removes it from the dead-letter queue.
+// Dead tasks are saved without access tokens: they are fetched again by tokens.
+func RequeueDeadTask(ctx context.Context, store deadletter.Store, tokens AccessTokenFetcher, taskID string) error {
+	t, err := store.Get(ctx, taskID)
+	if err != nil {
+		return fmt.Errorf("can't get dead task %s: %s", taskID, err)
+	}
+	if t == nil {
+		return fmt.Errorf("no dead task %s", taskID)
+	}
+
+	switch t.Name {
+	case consumers.PRAnalysisTaskName, consumers.LegacyPRAnalysisTaskName:
+		var pt task.PRAnalysis
+		if err = json.Unmarshal(t.Payload, &pt); err != nil {
+			return fmt.Errorf("can't unmarshal pr analysis task: %s", err)
+		}
+		if pt.GithubAccessToken == "" {
+			if pt.GithubAccessToken, err = tokens.FetchPRAccessToken(ctx, &pt); err != nil {
+				return fmt.Errorf("can't fetch access token of pr analysis task: %s", err)
+			}
+		}
+		err = schedulePRAnalysis(&pt, deadTaskQueue(t, prAnalysisQueue(&pt)), nil)
+	case consumers.RepoAnalysisTaskName, consumers.LegacyRepoAnalysisTaskName:
+		var rt task.RepoAnalysis
+		if err = json.Unmarshal(t.Payload, &rt); err != nil {
+			return fmt.Errorf("can't unmarshal repo analysis task: %s", err)
+		}
+		err = scheduleRepoAnalysis(&rt, deadTaskQueue(t, repoAnalysisQueue(&rt)), nil)
+	default:
+		return fmt.Errorf("unknown task name %q", t.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	return store.Remove(ctx, taskID)
+}
+
+// deadTaskQueue returns the queue the task was consumed from: if there is no such queue now it returns def
+func deadTaskQueue(t *deadletter.Task, def Queue) Queue {
+	if q := findQueue(t.Queue); q != nil {
+		return *q
+	}
+
+	return def
+}
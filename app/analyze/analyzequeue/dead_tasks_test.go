@@ -0,0 +1,158 @@
+package analyzequeue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/consumers"
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/task"
+	"github.com/golangci/golangci-worker/app/analyze/deadletter"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/golangci/golangci-worker/app/lib/httputils"
+	"github.com/golangci/golangci-worker/app/lib/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingBackend records sent tasks
+type recordingBackend struct {
+	queue.Backend
+	tasks []*queue.Task
+}
+
+func (b *recordingBackend) Send(t *queue.Task) error {
+	b.tasks = append(b.tasks, t)
+	return nil
+}
+
+func mockQueueBackend(b queue.Backend) (restore func()) {
+	prev := queueBackend
+	queueBackend = func() queue.Backend {
+		return b
+	}
+	return func() {
+		queueBackend = prev
+	}
+}
+
+type testTokenFetcher struct {
+	token string
+	err   error
+}
+
+func (f testTokenFetcher) FetchPRAccessToken(_ context.Context, _ *task.PRAnalysis) (string, error) {
+	return f.token, f.err
+}
+
+func addDeadTask(t *testing.T, store deadletter.Store, name string, payload interface{}) *deadletter.Task {
+	payloadJSON, err := json.Marshal(payload)
+	assert.NoError(t, err)
+
+	dt := &deadletter.Task{
+		ID:      name + ":guid",
+		Name:    name,
+		Queue:   PRPaidQueue.Name,
+		Payload: payloadJSON,
+		DeadAt:  time.Now(),
+	}
+	assert.NoError(t, store.Add(context.Background(), dt))
+	return dt
+}
+
+func TestRequeueDeadPRTaskFetchesAccessToken(t *testing.T) {
+	ctx := context.Background()
+	store := deadletter.NewMemoryStore(deadletter.DefaultRetention)
+	pt := &task.PRAnalysis{
+		Context:      github.FakeContext,
+		UserID:       1,
+		AnalysisGUID: "guid",
+	}
+	dt := addDeadTask(t, store, consumers.PRAnalysisTaskName, pt.WithoutSecrets())
+
+	backend := &recordingBackend{}
+	defer mockQueueBackend(backend)()
+
+	assert.NoError(t, RequeueDeadTask(ctx, store, testTokenFetcher{token: "new_token"}, dt.ID))
+	if assert.Len(t, backend.tasks, 1) {
+		sent := backend.tasks[0]
+		assert.Equal(t, consumers.PRAnalysisTaskName, sent.Name)
+		assert.Equal(t, PRPaidQueue.Name, sent.Queue, "task is sent to the queue it was consumed from")
+
+		sentTask, err := task.DecodePRAnalysis(sent.Args[0].(string))
+		assert.NoError(t, err)
+		assert.Equal(t, "new_token", sentTask.GithubAccessToken)
+		assert.Equal(t, pt.AnalysisGUID, sentTask.AnalysisGUID)
+	}
+
+	removed, err := store.Get(ctx, dt.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, removed)
+}
+
+func TestRequeueDeadPRTaskKeepsTaskIfNoAccessToken(t *testing.T) {
+	ctx := context.Background()
+	store := deadletter.NewMemoryStore(deadletter.DefaultRetention)
+	pt := &task.PRAnalysis{
+		Context:      github.FakeContext,
+		AnalysisGUID: "guid",
+	}
+	dt := addDeadTask(t, store, consumers.PRAnalysisTaskName, pt.WithoutSecrets())
+
+	backend := &recordingBackend{}
+	defer mockQueueBackend(backend)()
+
+	err := RequeueDeadTask(ctx, store, testTokenFetcher{err: errors.New("no access")}, dt.ID)
+	assert.Error(t, err)
+	assert.Empty(t, backend.tasks)
+
+	kept, err := store.Get(ctx, dt.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, kept)
+}
+
+func TestRequeueDeadRepoTask(t *testing.T) {
+	ctx := context.Background()
+	store := deadletter.NewMemoryStore(deadletter.DefaultRetention)
+	rt := &task.RepoAnalysis{
+		Name:         "owner/name",
+		AnalysisGUID: "guid",
+		Branch:       "master",
+	}
+	dt := addDeadTask(t, store, consumers.RepoAnalysisTaskName, rt)
+
+	backend := &recordingBackend{}
+	defer mockQueueBackend(backend)()
+
+	tokens := testTokenFetcher{err: errors.New("repo analysis doesn't need tokens")}
+	assert.NoError(t, RequeueDeadTask(ctx, store, tokens, dt.ID))
+	if assert.Len(t, backend.tasks, 1) {
+		assert.Equal(t, consumers.RepoAnalysisTaskName, backend.tasks[0].Name)
+	}
+
+	assert.Error(t, RequeueDeadTask(ctx, store, tokens, dt.ID), "no such task after requeue")
+}
+
+func TestAPIAccessTokenFetcher(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := httputils.NewMockClient(ctrl)
+	client.EXPECT().Get(gomock.Any(), "https://api/v1/repos/github.com/owner/name/access_token?user_id=1").
+		Return(ioutil.NopCloser(strings.NewReader(`{"AccessToken":"token"}`)), nil)
+
+	f := APIAccessTokenFetcher{
+		host:   "https://api",
+		client: client,
+	}
+	token, err := f.FetchPRAccessToken(context.Background(), &task.PRAnalysis{
+		Context: github.FakeContext,
+		UserID:  1,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "token", token)
+}
@@ -0,0 +1,84 @@
+package analyzequeue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/golangci/golangci-worker/app/lib/httputils"
+)
+
+// PlanFetcher fetches whether the repo is analyzed by a paid plan: its tasks go to paid queues
+type PlanFetcher interface {
+	IsPaidRepo(ctx context.Context, repo *github.Repo) (bool, error)
+}
+
+// APIPlanFetcher fetches the plan of the repo owner from golangci-api
+type APIPlanFetcher struct {
+	host   string
+	client httputils.Client
+}
+
+func NewAPIPlanFetcher(client httputils.Client) *APIPlanFetcher {
+	return &APIPlanFetcher{
+		client: client,
+		host:   os.Getenv("API_URL"),
+	}
+}
+
+func (f APIPlanFetcher) getPlanURL(repo *github.Repo) string {
+	return fmt.Sprintf("%s/v1/repos/github.com/%s/%s/plan", f.host, repo.Owner, repo.Name)
+}
+
+func (f APIPlanFetcher) IsPaidRepo(ctx context.Context, repo *github.Repo) (bool, error) {
+	bodyReader, err := f.client.Get(ctx, f.getPlanURL(repo))
+	if err != nil {
+		return false, err
+	}
+
+	defer bodyReader.Close()
+
+	var resp struct {
+		IsPaid bool
+	}
+	if err = json.NewDecoder(bodyReader).Decode(&resp); err != nil {
+		return false, fmt.Errorf("can't read json body: %s", err)
+	}
+
+	return resp.IsPaid, nil
+}
+
+// planFetcher returns nil if API isn't configured: then all tasks go to free queues. It's overridden in tests.
+var planFetcher = func() PlanFetcher {
+	if os.Getenv("API_URL") == "" {
+		return nil
+	}
+
+	return NewAPIPlanFetcher(httputils.GrequestsClient{})
+}
+
+const planFetchTimeout = 10 * time.Second
+
+// isPaidRepo returns whether tasks of the repo go to paid queues: a task is sent to a free queue
+// if the plan can't be fetched, it mustn't be lost
+func isPaidRepo(repo *github.Repo) bool {
+	f := planFetcher()
+	if f == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), planFetchTimeout)
+	defer cancel()
+
+	isPaid, err := f.IsPaidRepo(ctx, repo)
+	if err != nil {
+		analytics.Log(ctx).Warnf("Can't fetch plan of repo %s, send its task to free queue: %s", repo.FullName(), err)
+		return false
+	}
+
+	return isPaid
+}
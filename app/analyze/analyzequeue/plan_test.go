@@ -0,0 +1,80 @@
+package analyzequeue
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/task"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/golangci/golangci-worker/app/lib/httputils"
+	"github.com/stretchr/testify/assert"
+)
+
+type testPlanFetcher struct {
+	paidRepos map[string]bool
+	err       error
+}
+
+func (f testPlanFetcher) IsPaidRepo(_ context.Context, repo *github.Repo) (bool, error) {
+	return f.paidRepos[repo.FullName()], f.err
+}
+
+func mockPlanFetcher(f PlanFetcher) (restore func()) {
+	prev := planFetcher
+	planFetcher = func() PlanFetcher {
+		return f
+	}
+	return func() {
+		planFetcher = prev
+	}
+}
+
+func TestScheduleToQueueOfPlan(t *testing.T) {
+	backend := &recordingBackend{}
+	defer mockQueueBackend(backend)()
+	defer mockPlanFetcher(testPlanFetcher{paidRepos: map[string]bool{"paid/repo": true}})()
+
+	paidRepo := github.Context{Repo: github.Repo{Owner: "paid", Name: "repo"}}
+	assert.NoError(t, SchedulePRAnalysis(&task.PRAnalysis{Context: paidRepo, HeadSHA: "sha"}))
+	assert.NoError(t, SchedulePRAnalysis(&task.PRAnalysis{Context: github.FakeContext, HeadSHA: "sha"}))
+	assert.NoError(t, ScheduleRepoAnalysis(&task.RepoAnalysis{Name: "paid/repo", CommitSHA: "sha"}))
+	assert.NoError(t, ScheduleRepoAnalysis(&task.RepoAnalysis{Name: "free/repo", CommitSHA: "sha"}))
+
+	var queues []string
+	for _, bt := range backend.tasks {
+		queues = append(queues, bt.Queue)
+	}
+	assert.Equal(t, []string{PRPaidQueue.Name, PRQueue.Name, RepoPaidQueue.Name, RepoQueue.Name}, queues)
+}
+
+func TestScheduleToFreeQueueIfPlanIsUnknown(t *testing.T) {
+	backend := &recordingBackend{}
+	defer mockQueueBackend(backend)()
+	defer mockPlanFetcher(testPlanFetcher{err: errors.New("api is down")})()
+
+	assert.NoError(t, SchedulePRAnalysis(&task.PRAnalysis{Context: github.FakeContext, HeadSHA: "sha"}))
+	if assert.Len(t, backend.tasks, 1) {
+		assert.Equal(t, PRQueue.Name, backend.tasks[0].Queue)
+	}
+}
+
+func TestAPIPlanFetcher(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := httputils.NewMockClient(ctrl)
+	client.EXPECT().Get(gomock.Any(), "https://api/v1/repos/github.com/owner/name/plan").
+		Return(ioutil.NopCloser(strings.NewReader(`{"IsPaid":true}`)), nil)
+
+	f := APIPlanFetcher{
+		host:   "https://api",
+		client: client,
+	}
+	isPaid, err := f.IsPaidRepo(context.Background(), &github.Repo{Owner: "owner", Name: "name"})
+	assert.NoError(t, err)
+	assert.True(t, isPaid)
+}
@@ -1,85 +1,140 @@
 package analyzequeue
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"time"
 
-	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/consumers"
 	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/task"
+	"github.com/golangci/golangci-worker/app/lib/github"
 	"github.com/golangci/golangci-worker/app/lib/queue"
 )
 
+// queueBackend is overridden in tests
+var queueBackend = queue.GetBackend
+
+// taskRetryCount is count of retries of a failed task: after the last retry the task is moved to the dead-letter queue
+const taskRetryCount = 3
+
+// githubClient is overridden in tests
+var githubClient github.Client = github.NewMyClient()
+
+const commitFetchTimeout = 30 * time.Second
+
+// SchedulePRAnalysis sends the task to the queue of the plan of the repo. It sets the head commit
+// and the idempotency key of the task: identical tasks are run only once.
 func SchedulePRAnalysis(t *task.PRAnalysis) error {
-	args := []tasks.Arg{
-		{
-			Type:  "string",
-			Value: t.Repo.Owner,
-		},
-		{
-			Type:  "string",
-			Value: t.Repo.Name,
-		},
-		{
-			Type:  "string",
-			Value: t.GithubAccessToken,
-		},
-		{
-			Type:  "int",
-			Value: t.PullRequestNumber,
-		},
-		{
-			Type:  "string",
-			Value: t.APIRequestID,
-		},
-		{
-			Type:  "uint",
-			Value: t.UserID,
-		},
-		{
-			Type:  "string",
-			Value: t.AnalysisGUID,
-		},
+	if !t.IsPaid {
+		t.IsPaid = isPaidRepo(&t.Repo)
 	}
-	signature := &tasks.Signature{
-		Name:         "analyzeV2",
-		Args:         args,
-		RetryCount:   3,
-		RetryTimeout: 600, // 600 sec
+
+	if t.HeadSHA == "" {
+		t.HeadSHA = fetchCommitSHA(&t.Repo, func(ctx context.Context) (string, error) {
+			pr, err := githubClient.GetPullRequest(ctx, &t.Context)
+			if err != nil {
+				return "", err
+			}
+			return pr.GetHead().GetSHA(), nil
+		})
 	}
+	t.IdempotencyKey = t.GetIdempotencyKey()
 
-	_, err := queue.GetServer().SendTask(signature)
-	if err != nil {
-		return fmt.Errorf("failed to send the pr analysis task %v to analyze queue: %s", t, err)
+	return schedulePRAnalysis(t, prAnalysisQueue(t), nil)
+}
+
+// schedulePRAnalysis sends the task to the queue to run it not earlier than eta: nil eta means now
+func schedulePRAnalysis(t *task.PRAnalysis, q Queue, eta *time.Time) error {
+	name, args := consumers.LegacyPRAnalysisTaskName, []interface{}{t.Repo.Owner, t.Repo.Name,
+		t.GithubAccessToken, t.PullRequestNumber, t.APIRequestID, t.UserID, t.AnalysisGUID}
+	if !sendLegacyTasks() {
+		payload, err := task.EncodePRAnalysis(t)
+		if err != nil {
+			return err
+		}
+		name, args = consumers.PRAnalysisTaskName, []interface{}{payload}
+	}
+
+	if err := sendTask(name, args, q, eta); err != nil {
+		return fmt.Errorf("failed to send the pr analysis task %s to analyze queue %s: %s",
+			t.AnalysisGUID, q.Name, err)
 	}
 
 	return nil
 }
 
+// ScheduleRepoAnalysis sends the task to the queue of the plan of the repo. It sets the head commit
+// of the branch and the idempotency key of the task: identical tasks are run only once.
 func ScheduleRepoAnalysis(t *task.RepoAnalysis) error {
-	args := []tasks.Arg{
-		{
-			Type:  "string",
-			Value: t.Name,
-		},
-		{
-			Type:  "string",
-			Value: t.AnalysisGUID,
-		},
-		{
-			Type:  "string",
-			Value: t.Branch,
-		},
-	}
-	signature := &tasks.Signature{
-		Name:         "analyzeRepo",
-		Args:         args,
-		RetryCount:   3,
-		RetryTimeout: 600, // 600 sec
+	if repo, err := consumers.ParseRepoName(t.Name); err == nil {
+		if !t.IsPaid {
+			t.IsPaid = isPaidRepo(repo)
+		}
+
+		if t.CommitSHA == "" {
+			// repo analyses are of public repos: no access token is needed
+			t.CommitSHA = fetchCommitSHA(repo, func(ctx context.Context) (string, error) {
+				return githubClient.GetBranchSHA(ctx, &github.Context{Repo: *repo}, t.Branch)
+			})
+		}
 	}
+	t.IdempotencyKey = t.GetIdempotencyKey()
 
-	_, err := queue.GetServer().SendTask(signature)
+	return scheduleRepoAnalysis(t, repoAnalysisQueue(t), nil)
+}
+
+// fetchCommitSHA returns the commit fetched by fetch or an empty string if it can't be fetched:
+// then the task is sent without the idempotency key and isn't deduplicated
+func fetchCommitSHA(repo *github.Repo, fetch func(ctx context.Context) (string, error)) string {
+	ctx, cancel := context.WithTimeout(context.Background(), commitFetchTimeout)
+	defer cancel()
+
+	sha, err := fetch(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to send the repo analysis task %v to analyze queue: %s", t, err)
+		analytics.Log(ctx).Warnf("Can't fetch commit of repo %s, send task without idempotency key: %s",
+			repo.FullName(), err)
+		return ""
+	}
+
+	return sha
+}
+
+func scheduleRepoAnalysis(t *task.RepoAnalysis, q Queue, eta *time.Time) error {
+	name, args := consumers.LegacyRepoAnalysisTaskName, []interface{}{t.Name, t.AnalysisGUID, t.Branch}
+	if !sendLegacyTasks() {
+		payload, err := task.EncodeRepoAnalysis(t)
+		if err != nil {
+			return err
+		}
+		name, args = consumers.RepoAnalysisTaskName, []interface{}{payload}
+	}
+
+	if err := sendTask(name, args, q, eta); err != nil {
+		return fmt.Errorf("failed to send the repo analysis task %s to analyze queue %s: %s",
+			t.AnalysisGUID, q.Name, err)
 	}
 
 	return nil
 }
+
+// sendLegacyTasks returns true if tasks must be sent with positional args: set SEND_LEGACY_TASKS=1
+// during a rolling deploy while workers which don't consume payload tasks are running.
+// Legacy tasks lose fields which aren't positional args, e.g. commits and idempotency keys.
+func sendLegacyTasks() bool {
+	return os.Getenv("SEND_LEGACY_TASKS") == "1"
+}
+
+// sendTask sends the task with the only payload arg (new fields of tasks don't break compatibility)
+// or with positional args of legacy tasks
+func sendTask(name string, args []interface{}, q Queue, eta *time.Time) error {
+	return queueBackend().Send(&queue.Task{
+		Name:         name,
+		Args:         args,
+		Queue:        q.Name,
+		RetryCount:   taskRetryCount,
+		RetryTimeout: 600, // 600 sec
+		ETA:          eta,
+	})
+}
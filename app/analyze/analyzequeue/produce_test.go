@@ -0,0 +1,94 @@
+package analyzequeue
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/consumers"
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/task"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	gh "github.com/google/go-github/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockGithubClient(c github.Client) (restore func()) {
+	prev := githubClient
+	githubClient = c
+	return func() {
+		githubClient = prev
+	}
+}
+
+func TestScheduleSetsIdempotencyKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	backend := &recordingBackend{}
+	defer mockQueueBackend(backend)()
+	defer mockPlanFetcher(nil)()
+
+	client := github.NewMockClient(ctrl)
+	client.EXPECT().GetPullRequest(gomock.Any(), &github.FakeContext).Return(&gh.PullRequest{
+		Head: &gh.PullRequestBranch{SHA: gh.String("headSHA")},
+	}, nil)
+	client.EXPECT().GetBranchSHA(gomock.Any(), &github.Context{Repo: github.FakeContext.Repo}, "master").
+		Return("branchSHA", nil)
+	defer mockGithubClient(client)()
+
+	pt := &task.PRAnalysis{Context: github.FakeContext}
+	assert.NoError(t, SchedulePRAnalysis(pt))
+	assert.Equal(t, "headSHA", pt.HeadSHA)
+	assert.Equal(t, "pr:owner/name#1@headSHA", pt.IdempotencyKey)
+
+	rt := &task.RepoAnalysis{Name: "owner/name", Branch: "master"}
+	assert.NoError(t, ScheduleRepoAnalysis(rt))
+	assert.Equal(t, "branchSHA", rt.CommitSHA)
+	assert.Equal(t, "repo:owner/name@master:branchSHA", rt.IdempotencyKey)
+
+	assert.Len(t, backend.tasks, 2)
+}
+
+func TestScheduleWithoutCommit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	backend := &recordingBackend{}
+	defer mockQueueBackend(backend)()
+	defer mockPlanFetcher(nil)()
+
+	client := github.NewMockClient(ctrl)
+	client.EXPECT().GetBranchSHA(gomock.Any(), gomock.Any(), "master").Return("", errors.New("private repo"))
+	defer mockGithubClient(client)()
+
+	// the task isn't lost if its commit can't be fetched: it just isn't deduplicated
+	rt := &task.RepoAnalysis{Name: "owner/name", Branch: "master"}
+	assert.NoError(t, ScheduleRepoAnalysis(rt))
+	assert.Empty(t, rt.IdempotencyKey)
+	assert.Len(t, backend.tasks, 1)
+}
+
+func TestScheduleLegacyTasks(t *testing.T) {
+	backend := &recordingBackend{}
+	defer mockQueueBackend(backend)()
+	defer overrideEnv("SEND_LEGACY_TASKS", "1")()
+
+	pt := &task.PRAnalysis{
+		Context:      github.FakeContext,
+		APIRequestID: "req_id",
+		UserID:       1,
+		AnalysisGUID: "pr_guid",
+	}
+	assert.NoError(t, schedulePRAnalysis(pt, PRQueue, nil))
+
+	rt := &task.RepoAnalysis{Name: "owner/name", AnalysisGUID: "repo_guid", Branch: "master"}
+	assert.NoError(t, scheduleRepoAnalysis(rt, RepoQueue, nil))
+
+	// workers which don't consume payload tasks consume tasks with positional args
+	assert.Len(t, backend.tasks, 2)
+	assert.Equal(t, consumers.LegacyPRAnalysisTaskName, backend.tasks[0].Name)
+	assert.Equal(t, []interface{}{"owner", "name", github.FakeContext.GithubAccessToken, 1, "req_id", uint(1), "pr_guid"},
+		backend.tasks[0].Args)
+	assert.Equal(t, consumers.LegacyRepoAnalysisTaskName, backend.tasks[1].Name)
+	assert.Equal(t, []interface{}{"owner/name", "repo_guid", "master"}, backend.tasks[1].Args)
+}
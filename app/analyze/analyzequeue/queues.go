@@ -0,0 +1,95 @@
+package analyzequeue
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/task"
+	"github.com/golangci/golangci-worker/app/lib/queue"
+)
+
+// Queue is a named queue of analysis tasks
+type Queue struct {
+	Name     string
+	Priority int // worker runs tasks from queues with greater priority first
+}
+
+// Interactive pull request analyses go ahead of background repo analyses, paid plans go ahead of free
+var (
+	PRPaidQueue   = Queue{Name: "analyze_pr_paid", Priority: 40}
+	PRQueue       = Queue{Name: "analyze_pr", Priority: 30}
+	RepoPaidQueue = Queue{Name: "analyze_repo_paid", Priority: 20}
+	RepoQueue     = Queue{Name: "analyze_repo", Priority: 10}
+
+	// legacyQueue contains tasks sent before named queues were introduced: they are drained
+	// after all tasks of named queues
+	legacyQueue = Queue{Name: queue.DefaultQueue, Priority: 0}
+)
+
+var allQueues = []Queue{PRPaidQueue, PRQueue, RepoPaidQueue, RepoQueue, legacyQueue}
+
+func prAnalysisQueue(t *task.PRAnalysis) Queue {
+	if t.IsPaid {
+		return PRPaidQueue
+	}
+
+	return PRQueue
+}
+
+func repoAnalysisQueue(t *task.RepoAnalysis) Queue {
+	if t.IsPaid {
+		return RepoPaidQueue
+	}
+
+	return RepoQueue
+}
+
+// getSubscribedQueues returns queues this worker consumes: all queues by default
+// or comma-separated queue names from WORKER_QUEUES env var.
+func getSubscribedQueues() ([]Queue, error) {
+	v := os.Getenv("WORKER_QUEUES")
+	if v == "" {
+		return allQueues, nil
+	}
+
+	var ret []Queue
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		q := findQueue(name)
+		if q == nil {
+			return nil, fmt.Errorf("invalid WORKER_QUEUES value %q: no queue %q", v, name)
+		}
+
+		ret = append(ret, *q)
+	}
+
+	return ret, nil
+}
+
+func findQueue(name string) *Queue {
+	for _, q := range allQueues {
+		if q.Name == name {
+			q := q
+			return &q
+		}
+	}
+
+	return nil
+}
+
+// requeuer sends back to its queue tasks of repos which have too many running analyses
+type requeuer struct {
+	queue Queue
+}
+
+func (r requeuer) RequeuePRAnalysis(t *task.PRAnalysis, delay time.Duration) error {
+	eta := time.Now().Add(delay)
+	return schedulePRAnalysis(t, r.queue, &eta)
+}
+
+func (r requeuer) RequeueRepoAnalysis(t *task.RepoAnalysis, delay time.Duration) error {
+	eta := time.Now().Add(delay)
+	return scheduleRepoAnalysis(t, r.queue, &eta)
+}
@@ -0,0 +1,46 @@
+package analyzequeue
+
+import (
+	"os"
+	"testing"
+
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue/task"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskQueues(t *testing.T) {
+	assert.Equal(t, PRQueue, prAnalysisQueue(&task.PRAnalysis{}))
+	assert.Equal(t, PRPaidQueue, prAnalysisQueue(&task.PRAnalysis{IsPaid: true}))
+	assert.Equal(t, RepoQueue, repoAnalysisQueue(&task.RepoAnalysis{}))
+	assert.Equal(t, RepoPaidQueue, repoAnalysisQueue(&task.RepoAnalysis{IsPaid: true}))
+
+	assert.True(t, PRQueue.Priority > RepoPaidQueue.Priority, "pull requests must go ahead of repos")
+	assert.True(t, RepoQueue.Priority > legacyQueue.Priority, "named queues must go ahead of the legacy queue")
+}
+
+func TestGetSubscribedQueues(t *testing.T) {
+	defer os.Unsetenv("WORKER_QUEUES")
+
+	cases := []struct {
+		env       string
+		expQueues []Queue
+		expErr    bool
+	}{
+		{"", allQueues, false},
+		{"analyze_pr_paid, analyze_pr", []Queue{PRPaidQueue, PRQueue}, false},
+		{"analyze_pr,unknown", nil, true},
+	}
+
+	defer overrideEnv("WORKER_QUEUES", "")()
+	for _, c := range cases {
+		os.Setenv("WORKER_QUEUES", c.env)
+		queues, err := getSubscribedQueues()
+		if c.expErr {
+			assert.Error(t, err, c.env)
+			continue
+		}
+
+		assert.NoError(t, err, c.env)
+		assert.Equal(t, c.expQueues, queues, c.env)
+	}
+}
@@ -0,0 +1,82 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PayloadVersion is a version of task payloads: increase it on incompatible changes of tasks.
+// New fields can be added to tasks without changing the version: old workers ignore them.
+const PayloadVersion = 1
+
+type prAnalysisPayload struct {
+	Version    int
+	PRAnalysis *PRAnalysis
+}
+
+type repoAnalysisPayload struct {
+	Version      int
+	RepoAnalysis *RepoAnalysis
+}
+
+func EncodePRAnalysis(t *PRAnalysis) (string, error) {
+	return encodePayload(prAnalysisPayload{
+		Version:    PayloadVersion,
+		PRAnalysis: t,
+	})
+}
+
+func DecodePRAnalysis(payload string) (*PRAnalysis, error) {
+	var p prAnalysisPayload
+	if err := decodePayload(payload, &p, &p.Version); err != nil {
+		return nil, err
+	}
+
+	if p.PRAnalysis == nil {
+		return nil, fmt.Errorf("no pr analysis in payload %s", payload)
+	}
+
+	return p.PRAnalysis, nil
+}
+
+func EncodeRepoAnalysis(t *RepoAnalysis) (string, error) {
+	return encodePayload(repoAnalysisPayload{
+		Version:      PayloadVersion,
+		RepoAnalysis: t,
+	})
+}
+
+func DecodeRepoAnalysis(payload string) (*RepoAnalysis, error) {
+	var p repoAnalysisPayload
+	if err := decodePayload(payload, &p, &p.Version); err != nil {
+		return nil, err
+	}
+
+	if p.RepoAnalysis == nil {
+		return nil, fmt.Errorf("no repo analysis in payload %s", payload)
+	}
+
+	return p.RepoAnalysis, nil
+}
+
+func encodePayload(p interface{}) (string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", fmt.Errorf("can't marshal task payload: %s", err)
+	}
+
+	return string(data), nil
+}
+
+func decodePayload(payload string, p interface{}, version *int) error {
+	if err := json.Unmarshal([]byte(payload), p); err != nil {
+		return fmt.Errorf("can't unmarshal task payload: %s", err)
+	}
+
+	if *version < 1 || *version > PayloadVersion {
+		return fmt.Errorf("unsupported task payload version %d, max supported version is %d",
+			*version, PayloadVersion)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPRAnalysisPayload(t *testing.T) {
+	pt := &PRAnalysis{
+		Context:      github.FakeContext,
+		APIRequestID: "req_id",
+		UserID:       1,
+		AnalysisGUID: "guid",
+		IsPaid:       true,
+	}
+
+	payload, err := EncodePRAnalysis(pt)
+	assert.NoError(t, err)
+
+	decoded, err := DecodePRAnalysis(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, pt, decoded)
+}
+
+func TestRepoAnalysisPayload(t *testing.T) {
+	rt := &RepoAnalysis{
+		Name:         "owner/name",
+		AnalysisGUID: "guid",
+		Branch:       "master",
+	}
+
+	payload, err := EncodeRepoAnalysis(rt)
+	assert.NoError(t, err)
+
+	decoded, err := DecodeRepoAnalysis(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, rt, decoded)
+}
+
+func TestDecodePayload(t *testing.T) {
+	cases := []struct {
+		name    string
+		payload string
+		exp     *RepoAnalysis
+		expErr  bool
+	}{
+		{
+			name:    "unknown fields of newer workers are ignored",
+			payload: `{"Version":1,"RepoAnalysis":{"Name":"owner/name","Branch":"master","HeadSHA":"sha"}}`,
+			exp:     &RepoAnalysis{Name: "owner/name", Branch: "master"},
+		},
+		{
+			name:    "newer version",
+			payload: `{"Version":2,"RepoAnalysis":{"Name":"owner/name"}}`,
+			expErr:  true,
+		},
+		{
+			name:    "no version",
+			payload: `{"RepoAnalysis":{"Name":"owner/name"}}`,
+			expErr:  true,
+		},
+		{
+			name:    "no task",
+			payload: `{"Version":1}`,
+			expErr:  true,
+		},
+		{
+			name:    "invalid json",
+			payload: `owner/name`,
+			expErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		rt, err := DecodeRepoAnalysis(c.payload)
+		if c.expErr {
+			assert.Error(t, err, c.name)
+			continue
+		}
+
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, c.exp, rt, c.name)
+	}
+}
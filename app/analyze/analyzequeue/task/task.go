@@ -1,16 +1,66 @@
 package task
 
-import "github.com/golangci/golangci-worker/app/lib/github"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golangci/golangci-worker/app/lib/github"
+)
 
 type PRAnalysis struct {
 	github.Context
 	APIRequestID string
 	UserID       uint
 	AnalysisGUID string
+
+	IsPaid bool // tasks of paid plans go to paid queues
+
+	HeadSHA        string `json:",omitempty"` // head commit of the pull request when the task was sent
+	IdempotencyKey string `json:",omitempty"` // overrides the key made by GetIdempotencyKey
+}
+
+// WithoutSecrets returns a copy of the task without the access token: it's saved to the dead-letter queue
+// and the token is fetched again when the task is requeued
+func (t PRAnalysis) WithoutSecrets() *PRAnalysis {
+	t.GithubAccessToken = ""
+	return &t
+}
+
+// GetIdempotencyKey returns the key of identical analyses: of the same pull request and head commit.
+// It returns empty string if the key can't be made: then the analysis isn't deduplicated.
+func (t PRAnalysis) GetIdempotencyKey() string {
+	if t.IdempotencyKey != "" {
+		return t.IdempotencyKey
+	}
+
+	if t.HeadSHA == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("pr:%s#%d@%s", strings.ToLower(t.Repo.FullName()), t.PullRequestNumber, t.HeadSHA)
 }
 
 type RepoAnalysis struct {
 	Name         string
 	AnalysisGUID string
 	Branch       string
+
+	IsPaid bool // tasks of paid plans go to paid queues
+
+	CommitSHA      string `json:",omitempty"` // head commit of the branch when the task was sent
+	IdempotencyKey string `json:",omitempty"` // overrides the key made by GetIdempotencyKey
+}
+
+// GetIdempotencyKey returns the key of identical analyses: of the same repo, branch and commit.
+// It returns empty string if the key can't be made: then the analysis isn't deduplicated.
+func (t RepoAnalysis) GetIdempotencyKey() string {
+	if t.IdempotencyKey != "" {
+		return t.IdempotencyKey
+	}
+
+	if t.CommitSHA == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("repo:%s@%s:%s", strings.ToLower(t.Name), t.Branch, t.CommitSHA)
 }
@@ -0,0 +1,50 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPRAnalysisIdempotencyKey(t *testing.T) {
+	pt := PRAnalysis{
+		Context: github.Context{
+			Repo:              github.Repo{Owner: "Owner", Name: "Name"},
+			PullRequestNumber: 1,
+		},
+	}
+	assert.Empty(t, pt.GetIdempotencyKey(), "no key without head commit")
+
+	pt.HeadSHA = "sha"
+	assert.Equal(t, "pr:owner/name#1@sha", pt.GetIdempotencyKey())
+
+	pt.IdempotencyKey = "delivery"
+	assert.Equal(t, "delivery", pt.GetIdempotencyKey())
+}
+
+func TestRepoAnalysisIdempotencyKey(t *testing.T) {
+	rt := RepoAnalysis{
+		Name:   "Owner/Name",
+		Branch: "master",
+	}
+	assert.Empty(t, rt.GetIdempotencyKey(), "no key without commit")
+
+	rt.CommitSHA = "sha"
+	assert.Equal(t, "repo:owner/name@master:sha", rt.GetIdempotencyKey())
+
+	rt.IdempotencyKey = "delivery"
+	assert.Equal(t, "delivery", rt.GetIdempotencyKey())
+}
+
+func TestPRAnalysisWithoutSecrets(t *testing.T) {
+	pt := PRAnalysis{
+		Context:      github.FakeContext,
+		AnalysisGUID: "guid",
+	}
+
+	stripped := pt.WithoutSecrets()
+	assert.Empty(t, stripped.GithubAccessToken)
+	assert.Equal(t, pt.AnalysisGUID, stripped.AnalysisGUID)
+	assert.Equal(t, github.FakeContext.GithubAccessToken, pt.GithubAccessToken, "task isn't changed")
+}
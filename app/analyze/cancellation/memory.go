@@ -0,0 +1,98 @@
+package cancellation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryRun struct {
+	run       Run
+	expiresAt time.Time
+}
+
+// MemoryRegistry keeps runs in memory: it's used in tests and when redis isn't configured
+type MemoryRegistry struct {
+	lock sync.Mutex
+	runs map[string]memoryRun
+}
+
+var _ Registry = &MemoryRegistry{}
+
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{
+		runs: map[string]memoryRun{},
+	}
+}
+
+// latest returns the latest not expired run: lock must be held
+func (m *MemoryRegistry) latest(key string) *Run {
+	mr, ok := m.runs[key]
+	if !ok {
+		return nil
+	}
+
+	if time.Now().After(mr.expiresAt) {
+		delete(m.runs, key)
+		return nil
+	}
+
+	return &mr.run
+}
+
+func (m *MemoryRegistry) Start(_ context.Context, key string, r *Run) (*Run, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if by := blockingRun(m.latest(key), r); by != nil {
+		return by, nil
+	}
+
+	m.runs[key] = memoryRun{
+		run:       *r,
+		expiresAt: time.Now().Add(runLeaseTTL),
+	}
+	return nil, nil
+}
+
+func (m *MemoryRegistry) Latest(_ context.Context, key string) (*Run, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.latest(key), nil
+}
+
+func (m *MemoryRegistry) Refresh(_ context.Context, key string, r *Run) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if latest := m.latest(key); latest != nil && latest.AnalysisGUID == r.AnalysisGUID {
+		m.runs[key] = memoryRun{
+			run:       *latest,
+			expiresAt: time.Now().Add(runLeaseTTL),
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryRegistry) Finish(_ context.Context, key string, r *Run, succeeded bool) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	latest := m.latest(key)
+	if latest == nil || latest.AnalysisGUID != r.AnalysisGUID {
+		return nil
+	}
+
+	if !succeeded {
+		delete(m.runs, key)
+		return nil
+	}
+
+	m.runs[key] = memoryRun{
+		run:       *latest,
+		expiresAt: time.Now().Add(succeededRunTTL),
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package cancellation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// maxStartAttempts is max count of attempts to start a run when other workers change the run concurrently
+const maxStartAttempts = 3
+
+// RedisRegistry keeps runs in redis: runs are shared between all workers
+type RedisRegistry struct {
+	pool *redis.Pool
+}
+
+var _ Registry = &RedisRegistry{}
+
+func NewRedisRegistry(pool *redis.Pool) *RedisRegistry {
+	return &RedisRegistry{
+		pool: pool,
+	}
+}
+
+func (r RedisRegistry) redisKey(key string) string {
+	return "pr_analysis_run:" + key
+}
+
+// Start checks the registered run and saves the new one in a transaction: the transaction is aborted
+// and retried if another worker changes the run after the check
+func (r RedisRegistry) Start(_ context.Context, key string, run *Run) (*Run, error) {
+	runJSON, err := json.Marshal(run)
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal run: %s", err)
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	rkey := r.redisKey(key)
+	for i := 0; i < maxStartAttempts; i++ {
+		prev, watchErr := watchRun(conn, rkey)
+		if watchErr != nil {
+			return nil, watchErr
+		}
+
+		if by := blockingRun(prev, run); by != nil {
+			return by, nil
+		}
+
+		_ = conn.Send("MULTI")
+		_ = conn.Send("SET", rkey, runJSON, "EX", int(runLeaseTTL/time.Second))
+		reply, execErr := conn.Do("EXEC")
+		if execErr != nil {
+			return nil, fmt.Errorf("can't start run in redis: %s", execErr)
+		}
+		if reply != nil {
+			return nil, nil // registered
+		}
+	}
+
+	return nil, errors.New("can't start run in redis: the run is changed concurrently")
+}
+
+func (r RedisRegistry) Latest(_ context.Context, key string) (*Run, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return getRun(conn, r.redisKey(key))
+}
+
+func (r RedisRegistry) Refresh(_ context.Context, key string, run *Run) error {
+	return r.updateIfLatest(key, run, func(conn redis.Conn, rkey string) {
+		_ = conn.Send("EXPIRE", rkey, int(runLeaseTTL/time.Second))
+	})
+}
+
+func (r RedisRegistry) Finish(_ context.Context, key string, run *Run, succeeded bool) error {
+	return r.updateIfLatest(key, run, func(conn redis.Conn, rkey string) {
+		if succeeded {
+			_ = conn.Send("EXPIRE", rkey, int(succeededRunTTL/time.Second))
+		} else {
+			_ = conn.Send("DEL", rkey)
+		}
+	})
+}
+
+// updateIfLatest sends commands of update in a transaction if the run is the latest one.
+// If another run is started concurrently the transaction is aborted: the run isn't the latest one anymore.
+func (r RedisRegistry) updateIfLatest(key string, run *Run, update func(conn redis.Conn, rkey string)) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	rkey := r.redisKey(key)
+	latest, err := watchRun(conn, rkey)
+	if err != nil {
+		return err
+	}
+
+	if latest == nil || latest.AnalysisGUID != run.AnalysisGUID {
+		return nil
+	}
+
+	_ = conn.Send("MULTI")
+	update(conn, rkey)
+	if _, err = conn.Do("EXEC"); err != nil {
+		return fmt.Errorf("can't update run in redis: %s", err)
+	}
+
+	return nil
+}
+
+// watchRun watches the key for changes and returns its run
+func watchRun(conn redis.Conn, rkey string) (*Run, error) {
+	if _, err := conn.Do("WATCH", rkey); err != nil {
+		return nil, fmt.Errorf("can't watch run in redis: %s", err)
+	}
+
+	return getRun(conn, rkey)
+}
+
+func getRun(conn redis.Conn, rkey string) (*Run, error) {
+	reply, err := redis.Bytes(conn.Do("GET", rkey))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't get run from redis: %s", err)
+	}
+
+	return parseRun(reply)
+}
+
+func parseRun(data []byte) (*Run, error) {
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("can't unmarshal run: %s", err)
+	}
+
+	return &run, nil
+}
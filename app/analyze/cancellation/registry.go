@@ -0,0 +1,117 @@
+package cancellation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/golangci/golangci-worker/app/lib/redisutils"
+)
+
+// Run is an analysis of a head commit of a pull request
+type Run struct {
+	AnalysisGUID string
+	HeadSHA      string
+	QueuedAt     time.Time // when the analysis task was queued, zero if it's unknown
+}
+
+const (
+	// runLeaseTTL is how long a running run is kept without refreshing: runs of crashed workers expire
+	runLeaseTTL = time.Minute
+
+	// succeededRunTTL is how long a succeeded run blocks runs of tasks queued before it
+	succeededRunTTL = 24 * time.Hour
+)
+
+// Registry keeps the latest started run for every pull request
+type Registry interface {
+	// Start registers the run as the latest one. If a run of a later queued task is registered, it returns
+	// that run and doesn't register the new one. Identical analyses of the same head commit are skipped
+	// by the deduper of consumers before they are started.
+	Start(ctx context.Context, key string, r *Run) (*Run, error)
+
+	// Latest returns the latest started run or nil if there are no runs
+	Latest(ctx context.Context, key string) (*Run, error)
+
+	// Refresh extends the lease of the running run if it's still the latest one
+	Refresh(ctx context.Context, key string, r *Run) error
+
+	// Finish ends the run if it's still the latest one: a succeeded run blocks runs of tasks queued
+	// before it for a day, a failed run is removed
+	Finish(ctx context.Context, key string, r *Run, succeeded bool) error
+}
+
+// blockingRun returns the registered run prev if it blocks starting of the run r
+func blockingRun(prev, r *Run) *Run {
+	if prev == nil || prev.AnalysisGUID == r.AnalysisGUID {
+		return nil // retry of the same analysis isn't stale
+	}
+
+	// a redelivered task of an older head commit mustn't supersede the newer analysis
+	if !prev.QueuedAt.IsZero() && !r.QueuedAt.IsZero() && r.QueuedAt.Before(prev.QueuedAt) {
+		return prev
+	}
+
+	return nil
+}
+
+func PullRequestKey(repo *github.Repo, pullRequestNumber int) string {
+	return fmt.Sprintf("%s#%d", repo.FullName(), pullRequestNumber)
+}
+
+var defaultMemoryRegistry = NewMemoryRegistry()
+
+// NewRegistryFromEnv returns redis registry if redis is configured: it's shared by all workers.
+// Otherwise it returns in-memory registry shared by all analyses of this worker.
+func NewRegistryFromEnv() Registry {
+	if redisutils.IsConfigured() {
+		return NewRedisRegistry(redisutils.GetPool())
+	}
+
+	return defaultMemoryRegistry
+}
+
+// Watch returns a copy of ctx which is canceled when a newer run for the key is started.
+// It refreshes the lease of the run until ctx is canceled: interval must be less than runLeaseTTL.
+func Watch(ctx context.Context, reg Registry, key string, r *Run, interval time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			latest, err := reg.Latest(ctx, key)
+			if err != nil {
+				analytics.Log(ctx).Warnf("Can't get the latest run of %s: %s", key, err)
+				continue
+			}
+
+			if IsSuperseded(r, latest) {
+				analytics.Log(ctx).Infof("Analysis %s of %s is superseded by analysis %s of %s, cancel it",
+					r.AnalysisGUID, r.HeadSHA, latest.AnalysisGUID, latest.HeadSHA)
+				cancel()
+				return
+			}
+
+			if err = reg.Refresh(ctx, key, r); err != nil {
+				analytics.Log(ctx).Warnf("Can't refresh the run of %s: %s", key, err)
+			}
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// IsSuperseded returns true if the latest run isn't the run r
+func IsSuperseded(r, latest *Run) bool {
+	return latest != nil && latest.AnalysisGUID != r.AnalysisGUID
+}
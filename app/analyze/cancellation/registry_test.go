@@ -0,0 +1,178 @@
+package cancellation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/lib/redisutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// forEachRegistry runs the test for the memory and the redis registries
+func forEachRegistry(t *testing.T, test func(t *testing.T, reg Registry)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryRegistry())
+	})
+	t.Run("redis", func(t *testing.T) {
+		test(t, NewRedisRegistry(redisutils.NewFakeDB().Pool()))
+	})
+}
+
+func TestRegistryStart(t *testing.T) {
+	forEachRegistry(t, testRegistryStart)
+}
+
+func testRegistryStart(t *testing.T, reg Registry) {
+	ctx := context.Background()
+
+	latest, err := reg.Latest(ctx, "a/b#1")
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+
+	first := &Run{AnalysisGUID: "1", HeadSHA: "sha1"}
+	by, err := reg.Start(ctx, "a/b#1", first)
+	assert.NoError(t, err)
+	assert.Nil(t, by)
+
+	// retry of the same analysis isn't stale
+	by, err = reg.Start(ctx, "a/b#1", first)
+	assert.NoError(t, err)
+	assert.Nil(t, by)
+
+	second := &Run{AnalysisGUID: "3", HeadSHA: "sha2"}
+	by, err = reg.Start(ctx, "a/b#1", second)
+	assert.NoError(t, err)
+	assert.Nil(t, by)
+
+	latest, err = reg.Latest(ctx, "a/b#1")
+	assert.NoError(t, err)
+	assert.Equal(t, second, latest)
+	assert.True(t, IsSuperseded(first, latest))
+	assert.False(t, IsSuperseded(second, latest))
+}
+
+func TestWatchCancelsSupersededRun(t *testing.T) {
+	ctx := context.Background()
+	reg := NewMemoryRegistry()
+
+	first := &Run{AnalysisGUID: "1", HeadSHA: "sha1"}
+	_, err := reg.Start(ctx, "a/b#1", first)
+	assert.NoError(t, err)
+
+	watchCtx, cancel := Watch(ctx, reg, "a/b#1", first, time.Millisecond)
+	defer cancel()
+
+	select {
+	case <-watchCtx.Done():
+		t.Fatalf("Latest run was canceled")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	_, err = reg.Start(ctx, "a/b#1", &Run{AnalysisGUID: "2", HeadSHA: "sha2"})
+	assert.NoError(t, err)
+
+	select {
+	case <-watchCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("Superseded run wasn't canceled")
+	}
+}
+
+func TestRegistryDoesntSupersedeByStaleTask(t *testing.T) {
+	forEachRegistry(t, testRegistryDoesntSupersedeByStaleTask)
+}
+
+func testRegistryDoesntSupersedeByStaleTask(t *testing.T, reg Registry) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	newer := &Run{AnalysisGUID: "2", HeadSHA: "sha2", QueuedAt: now}
+	by, err := reg.Start(ctx, "a/b#1", newer)
+	assert.NoError(t, err)
+	assert.Nil(t, by)
+
+	// redelivered task of the older head commit
+	by, err = reg.Start(ctx, "a/b#1", &Run{AnalysisGUID: "1", HeadSHA: "sha1", QueuedAt: now.Add(-time.Minute)})
+	assert.NoError(t, err)
+	if assert.NotNil(t, by) {
+		assert.Equal(t, newer.AnalysisGUID, by.AnalysisGUID)
+	}
+
+	latest, err := reg.Latest(ctx, "a/b#1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, latest) {
+		assert.Equal(t, newer.AnalysisGUID, latest.AnalysisGUID)
+	}
+}
+
+func TestRegistryFinish(t *testing.T) {
+	forEachRegistry(t, testRegistryFinish)
+}
+
+func testRegistryFinish(t *testing.T, reg Registry) {
+	ctx := context.Background()
+
+	now := time.Now()
+	failed := &Run{AnalysisGUID: "1", HeadSHA: "sha1", QueuedAt: now}
+	_, err := reg.Start(ctx, "a/b#1", failed)
+	assert.NoError(t, err)
+	assert.NoError(t, reg.Finish(ctx, "a/b#1", failed, false))
+
+	// the failed run doesn't block tasks queued before it
+	succeeded := &Run{AnalysisGUID: "2", HeadSHA: "sha1", QueuedAt: now.Add(-time.Minute)}
+	by, err := reg.Start(ctx, "a/b#1", succeeded)
+	assert.NoError(t, err)
+	assert.Nil(t, by)
+	assert.NoError(t, reg.Refresh(ctx, "a/b#1", succeeded))
+	assert.NoError(t, reg.Finish(ctx, "a/b#1", succeeded, true))
+
+	// finish of not the latest run doesn't remove the latest run
+	assert.NoError(t, reg.Finish(ctx, "a/b#1", failed, false))
+
+	by, err = reg.Start(ctx, "a/b#1", &Run{AnalysisGUID: "3", HeadSHA: "sha0", QueuedAt: now.Add(-2 * time.Minute)})
+	assert.NoError(t, err)
+	if assert.NotNil(t, by) {
+		assert.Equal(t, succeeded.AnalysisGUID, by.AnalysisGUID, "succeeded run blocks stale tasks")
+	}
+}
+
+func TestMemoryRegistryRunLeaseExpires(t *testing.T) {
+	ctx := context.Background()
+	reg := NewMemoryRegistry()
+
+	queuedAt := time.Now()
+	crashed := &Run{AnalysisGUID: "1", HeadSHA: "sha2", QueuedAt: queuedAt}
+	_, err := reg.Start(ctx, "a/b#1", crashed)
+	assert.NoError(t, err)
+
+	reg.runs["a/b#1"] = memoryRun{
+		run:       *crashed,
+		expiresAt: time.Now().Add(-time.Second),
+	}
+
+	// the crashed run doesn't block tasks queued before it
+	by, err := reg.Start(ctx, "a/b#1", &Run{AnalysisGUID: "2", HeadSHA: "sha1", QueuedAt: queuedAt.Add(-time.Minute)})
+	assert.NoError(t, err)
+	assert.Nil(t, by)
+}
+
+func TestRedisRegistryRunLeaseExpires(t *testing.T) {
+	ctx := context.Background()
+	db := redisutils.NewFakeDB()
+	reg := NewRedisRegistry(db.Pool())
+
+	queuedAt := time.Now()
+	crashed := &Run{AnalysisGUID: "1", HeadSHA: "sha2", QueuedAt: queuedAt}
+	_, err := reg.Start(ctx, "a/b#1", crashed)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	db.Now = func() time.Time {
+		return now.Add(runLeaseTTL)
+	}
+
+	by, err := reg.Start(ctx, "a/b#1", &Run{AnalysisGUID: "2", HeadSHA: "sha1", QueuedAt: queuedAt.Add(-time.Minute)})
+	assert.NoError(t, err)
+	assert.Nil(t, by)
+}
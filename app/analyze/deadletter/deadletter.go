@@ -0,0 +1,172 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/lib/redisutils"
+)
+
+// Attempt is a failed attempt to process a task
+type Attempt struct {
+	StartedAt time.Time
+	Duration  time.Duration
+	Error     string
+}
+
+// Task is a task which failed all attempts
+type Task struct {
+	ID       string          // unique id of the task, e.g. task name and analysis guid
+	Name     string          // machinery task name
+	Queue    string          // queue the task was consumed from
+	Payload  json.RawMessage // task struct without secrets: they are fetched again on requeue
+	Attempts []Attempt
+	Warnings []string // warnings logged during the last attempt
+	DeadAt   time.Time
+
+	Secrets []string `json:"-"` // secrets of the task are hidden in errors and warnings
+}
+
+// Store keeps failed attempts of tasks and dead tasks
+type Store interface {
+	// AddAttempt saves the failed attempt of the task and returns all failed attempts of the task
+	AddAttempt(ctx context.Context, taskID string, a Attempt) ([]Attempt, error)
+	// ClearAttempts removes failed attempts of the task
+	ClearAttempts(ctx context.Context, taskID string) error
+
+	// Add saves the dead task and removes its attempts
+	Add(ctx context.Context, t *Task) error
+	// List returns dead tasks ordered from the newest to the oldest
+	List(ctx context.Context) ([]Task, error)
+	// Get returns the dead task or nil if there is no such task
+	Get(ctx context.Context, taskID string) (*Task, error)
+	// Remove removes the dead task: it's not an error if there is no such task
+	Remove(ctx context.Context, taskID string) error
+	// Purge removes all dead tasks
+	Purge(ctx context.Context) error
+}
+
+// Retention limits dead tasks: the oldest ones are removed when a new task is added
+type Retention struct {
+	MaxAge   time.Duration
+	MaxCount int
+}
+
+var DefaultRetention = Retention{
+	MaxAge:   14 * 24 * time.Hour,
+	MaxCount: 1000,
+}
+
+func (r Retention) expired(t *Task, now time.Time) bool {
+	return t.DeadAt.Before(now.Add(-r.MaxAge))
+}
+
+// retentionFromEnv returns retention from DEAD_LETTER_RETENTION_DAYS and DEAD_LETTER_MAX_TASKS env vars
+func retentionFromEnv() (*Retention, error) {
+	ret := DefaultRetention
+	if v := os.Getenv("DEAD_LETTER_RETENTION_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 {
+			return nil, fmt.Errorf("invalid DEAD_LETTER_RETENTION_DAYS value %q: must be a positive integer", v)
+		}
+		ret.MaxAge = time.Duration(days) * 24 * time.Hour
+	}
+
+	if v := os.Getenv("DEAD_LETTER_MAX_TASKS"); v != "" {
+		count, err := strconv.Atoi(v)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid DEAD_LETTER_MAX_TASKS value %q: must be a positive integer", v)
+		}
+		ret.MaxCount = count
+	}
+
+	return &ret, nil
+}
+
+var defaultMemoryStore = NewMemoryStore(DefaultRetention)
+
+// NewStoreFromEnv returns redis store if redis is configured: it's shared by all workers and the CLI.
+// Otherwise it returns in-memory store of this process.
+func NewStoreFromEnv() (Store, error) {
+	if !redisutils.IsConfigured() {
+		return defaultMemoryStore, nil
+	}
+
+	retention, err := retentionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRedisStore(redisutils.GetPool(), *retention), nil
+}
+
+// Queue moves tasks which failed all attempts to the dead-letter store
+type Queue struct {
+	store       Store
+	maxAttempts int
+}
+
+func NewQueue(store Store, maxAttempts int) *Queue {
+	return &Queue{
+		store:       store,
+		maxAttempts: maxAttempts,
+	}
+}
+
+func (q Queue) Store() Store {
+	return q.store
+}
+
+func hideSecrets(texts, secrets []string) []string {
+	var ret []string
+	for _, text := range texts {
+		ret = append(ret, hideSecretsInText(text, secrets))
+	}
+
+	return ret
+}
+
+func hideSecretsInText(text string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			text = strings.Replace(text, secret, "{hidden}", -1)
+		}
+	}
+
+	return text
+}
+
+// RecordFailure saves the failed attempt of the task. If it was the last attempt
+// the task is moved to the dead-letter store and RecordFailure returns true.
+func (q Queue) RecordFailure(ctx context.Context, t *Task, a Attempt) (bool, error) {
+	a.Error = hideSecretsInText(a.Error, t.Secrets)
+	attempts, err := q.store.AddAttempt(ctx, t.ID, a)
+	if err != nil {
+		return false, fmt.Errorf("can't save attempt: %s", err)
+	}
+
+	if len(attempts) < q.maxAttempts {
+		return false, nil
+	}
+
+	dead := *t
+	dead.Attempts = attempts
+	dead.Warnings = hideSecrets(t.Warnings, t.Secrets)
+	dead.Secrets = nil
+	dead.DeadAt = time.Now()
+	if err = q.store.Add(ctx, &dead); err != nil {
+		return false, fmt.Errorf("can't save dead task: %s", err)
+	}
+
+	return true, nil
+}
+
+// RecordSuccess forgets failed attempts of the successfully processed task
+func (q Queue) RecordSuccess(ctx context.Context, taskID string) error {
+	return q.store.ClearAttempts(ctx, taskID)
+}
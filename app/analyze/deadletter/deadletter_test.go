@@ -0,0 +1,153 @@
+package deadletter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/lib/redisutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// forEachStore runs the test for the memory and the redis stores
+func forEachStore(t *testing.T, retention Retention, test func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStore(retention))
+	})
+	t.Run("redis", func(t *testing.T) {
+		test(t, NewRedisStore(redisutils.NewFakeDB().Pool(), retention))
+	})
+}
+
+func failAttempt(t *testing.T, q *Queue, task *Task, errText string) bool {
+	dead, err := q.RecordFailure(context.Background(), task, Attempt{
+		StartedAt: time.Now(),
+		Duration:  time.Second,
+		Error:     errText,
+	})
+	assert.NoError(t, err)
+	return dead
+}
+
+func TestMoveToDeadLetterAfterLastAttempt(t *testing.T) {
+	forEachStore(t, DefaultRetention, testMoveToDeadLetterAfterLastAttempt)
+}
+
+func testMoveToDeadLetterAfterLastAttempt(t *testing.T, store Store) {
+	ctx := context.Background()
+	q := NewQueue(store, 3)
+	task := &Task{
+		ID:       "analyzeV2:guid",
+		Name:     "analyzeV2",
+		Queue:    "analyze_pr",
+		Payload:  []byte(`{"AnalysisGUID":"guid"}`),
+		Warnings: []string{"can't clone"},
+	}
+
+	assert.False(t, failAttempt(t, q, task, "error 1"))
+	assert.False(t, failAttempt(t, q, task, "error 2"))
+	assert.True(t, failAttempt(t, q, task, "error 3"))
+
+	dead, err := store.Get(ctx, task.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, dead) {
+		assert.Len(t, dead.Attempts, 3)
+		assert.Equal(t, "error 3", dead.Attempts[2].Error)
+		assert.Equal(t, task.Warnings, dead.Warnings)
+		assert.False(t, dead.DeadAt.IsZero())
+	}
+
+	// attempts of a requeued task start from scratch
+	assert.False(t, failAttempt(t, q, task, "error 4"))
+}
+
+func TestSuccessClearsAttempts(t *testing.T) {
+	forEachStore(t, DefaultRetention, testSuccessClearsAttempts)
+}
+
+func testSuccessClearsAttempts(t *testing.T, store Store) {
+	ctx := context.Background()
+	q := NewQueue(store, 2)
+	task := &Task{ID: "analyzeRepo:guid"}
+
+	assert.False(t, failAttempt(t, q, task, "error 1"))
+	assert.NoError(t, q.RecordSuccess(ctx, task.ID))
+	assert.False(t, failAttempt(t, q, task, "error 2"))
+
+	tasks, err := store.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func TestListRemovePurge(t *testing.T) {
+	forEachStore(t, DefaultRetention, testListRemovePurge)
+}
+
+func testListRemovePurge(t *testing.T, store Store) {
+	ctx := context.Background()
+	now := time.Now()
+	assert.NoError(t, store.Add(ctx, &Task{ID: "old", DeadAt: now.Add(-time.Hour)}))
+	assert.NoError(t, store.Add(ctx, &Task{ID: "new", DeadAt: now}))
+	assert.NoError(t, store.Add(ctx, &Task{ID: "removed", DeadAt: now}))
+	assert.NoError(t, store.Remove(ctx, "removed"))
+
+	tasks, err := store.List(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, "new", tasks[0].ID)
+		assert.Equal(t, "old", tasks[1].ID)
+	}
+
+	assert.NoError(t, store.Purge(ctx))
+	tasks, err = store.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func TestRetention(t *testing.T) {
+	forEachStore(t, Retention{MaxAge: 24 * time.Hour, MaxCount: 2}, testRetention)
+}
+
+func testRetention(t *testing.T, store Store) {
+	ctx := context.Background()
+	now := time.Now()
+	assert.NoError(t, store.Add(ctx, &Task{ID: "expired", DeadAt: now.Add(-25 * time.Hour)}))
+	assert.NoError(t, store.Add(ctx, &Task{ID: "oldest", DeadAt: now.Add(-3 * time.Hour)}))
+	assert.NoError(t, store.Add(ctx, &Task{ID: "old", DeadAt: now.Add(-2 * time.Hour)}))
+	assert.NoError(t, store.Add(ctx, &Task{ID: "new", DeadAt: now}))
+
+	tasks, err := store.List(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, "new", tasks[0].ID)
+		assert.Equal(t, "old", tasks[1].ID)
+	}
+
+	for _, id := range []string{"expired", "oldest"} {
+		task, getErr := store.Get(ctx, id)
+		assert.NoError(t, getErr)
+		assert.Nil(t, task, id)
+	}
+}
+
+func TestSecretsAreHidden(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(DefaultRetention)
+	q := NewQueue(store, 1)
+	task := &Task{
+		ID:       "analyzeV2:guid",
+		Payload:  []byte(`{"AnalysisGUID":"guid"}`),
+		Warnings: []string{"can't clone https://secret-token@github.com/owner/repo"},
+		Secrets:  []string{"secret-token"},
+	}
+
+	assert.True(t, failAttempt(t, q, task, "can't get pr with token secret-token"))
+
+	dead, err := store.Get(ctx, task.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, dead) {
+		assert.Equal(t, "can't get pr with token {hidden}", dead.Attempts[0].Error)
+		assert.Equal(t, []string{"can't clone https://{hidden}@github.com/owner/repo"}, dead.Warnings)
+		assert.Empty(t, dead.Secrets)
+	}
+}
@@ -0,0 +1,118 @@
+package deadletter
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps tasks in memory of this process: it's used in tests and when redis isn't configured
+type MemoryStore struct {
+	lock      sync.Mutex
+	attempts  map[string][]Attempt
+	tasks     map[string]Task
+	retention Retention
+}
+
+var _ Store = &MemoryStore{}
+
+func NewMemoryStore(retention Retention) *MemoryStore {
+	return &MemoryStore{
+		attempts:  map[string][]Attempt{},
+		tasks:     map[string]Task{},
+		retention: retention,
+	}
+}
+
+func (s *MemoryStore) AddAttempt(_ context.Context, taskID string, a Attempt) ([]Attempt, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.attempts[taskID] = append(s.attempts[taskID], a)
+	return append([]Attempt{}, s.attempts[taskID]...), nil
+}
+
+func (s *MemoryStore) ClearAttempts(_ context.Context, taskID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.attempts, taskID)
+	return nil
+}
+
+func (s *MemoryStore) Add(_ context.Context, t *Task) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.tasks[t.ID] = *t
+	delete(s.attempts, t.ID)
+	s.prune()
+	return nil
+}
+
+// prune removes expired tasks and the oldest tasks over the limit
+func (s *MemoryStore) prune() {
+	var tasks []Task
+	for _, t := range s.tasks {
+		tasks = append(tasks, t)
+	}
+	sortTasks(tasks)
+
+	now := time.Now()
+	for i, t := range tasks {
+		if i >= s.retention.MaxCount || s.retention.expired(&t, now) {
+			delete(s.tasks, t.ID)
+		}
+	}
+}
+
+func (s *MemoryStore) List(_ context.Context) ([]Task, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ret := []Task{}
+	now := time.Now()
+	for _, t := range s.tasks {
+		if !s.retention.expired(&t, now) {
+			ret = append(ret, t)
+		}
+	}
+
+	sortTasks(ret)
+	return ret, nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, taskID string) (*Task, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	t, ok := s.tasks[taskID]
+	if !ok || s.retention.expired(&t, time.Now()) {
+		return nil, nil
+	}
+
+	return &t, nil
+}
+
+func (s *MemoryStore) Remove(_ context.Context, taskID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.tasks, taskID)
+	return nil
+}
+
+func (s *MemoryStore) Purge(_ context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.tasks = map[string]Task{}
+	return nil
+}
+
+func sortTasks(tasks []Task) {
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].DeadAt.After(tasks[j].DeadAt)
+	})
+}
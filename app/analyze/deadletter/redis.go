@@ -0,0 +1,225 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// attemptsTTL must be greater than the time of all retries of a task
+const attemptsTTL = 24 * time.Hour
+
+const tasksKey = "deadletter_tasks"
+
+// tasksByTimeKey is a sorted set of task ids scored by DeadAt: it's used for retention
+const tasksByTimeKey = "deadletter_tasks_by_time"
+
+// RedisStore keeps tasks in redis: they are shared between all workers and the CLI
+type RedisStore struct {
+	pool      *redis.Pool
+	retention Retention
+}
+
+var _ Store = &RedisStore{}
+
+func NewRedisStore(pool *redis.Pool, retention Retention) *RedisStore {
+	return &RedisStore{
+		pool:      pool,
+		retention: retention,
+	}
+}
+
+func (s RedisStore) attemptsKey(taskID string) string {
+	return "deadletter_attempts:" + taskID
+}
+
+func (s RedisStore) AddAttempt(_ context.Context, taskID string, a Attempt) ([]Attempt, error) {
+	attemptJSON, err := json.Marshal(a)
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal attempt: %s", err)
+	}
+
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	key := s.attemptsKey(taskID)
+	if err = conn.Send("MULTI"); err != nil {
+		return nil, fmt.Errorf("can't start redis transaction: %s", err)
+	}
+	_ = conn.Send("RPUSH", key, attemptJSON)
+	_ = conn.Send("EXPIRE", key, int(attemptsTTL/time.Second))
+	_ = conn.Send("LRANGE", key, 0, -1)
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, fmt.Errorf("can't save attempt in redis: %s", err)
+	}
+
+	attemptsJSON, err := redis.ByteSlices(replies[2], nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attempts reply from redis: %s", err)
+	}
+
+	var attempts []Attempt
+	for _, data := range attemptsJSON {
+		var prev Attempt
+		if err = json.Unmarshal(data, &prev); err != nil {
+			return nil, fmt.Errorf("can't unmarshal attempt: %s", err)
+		}
+		attempts = append(attempts, prev)
+	}
+
+	return attempts, nil
+}
+
+func (s RedisStore) ClearAttempts(_ context.Context, taskID string) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("DEL", s.attemptsKey(taskID)); err != nil {
+		return fmt.Errorf("can't remove attempts from redis: %s", err)
+	}
+
+	return nil
+}
+
+func (s RedisStore) Add(_ context.Context, t *Task) error {
+	taskJSON, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("can't marshal task: %s", err)
+	}
+
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	if err = conn.Send("MULTI"); err != nil {
+		return fmt.Errorf("can't start redis transaction: %s", err)
+	}
+	_ = conn.Send("HSET", tasksKey, t.ID, taskJSON)
+	_ = conn.Send("ZADD", tasksByTimeKey, t.DeadAt.Unix(), t.ID)
+	_ = conn.Send("DEL", s.attemptsKey(t.ID))
+	if _, err = conn.Do("EXEC"); err != nil {
+		return fmt.Errorf("can't save task in redis: %s", err)
+	}
+
+	return s.prune(conn)
+}
+
+// prune removes expired tasks and the oldest tasks over the limit
+func (s RedisStore) prune(conn redis.Conn) error {
+	maxDeadAt := time.Now().Add(-s.retention.MaxAge).Unix()
+	expiredIDs, err := redis.Strings(conn.Do("ZRANGEBYSCORE", tasksByTimeKey, "-inf", fmt.Sprintf("(%d", maxDeadAt)))
+	if err != nil {
+		return fmt.Errorf("can't get expired tasks from redis: %s", err)
+	}
+
+	// ids are ordered from the newest to the oldest: all ids after MaxCount are over the limit
+	overLimitIDs, err := redis.Strings(conn.Do("ZREVRANGE", tasksByTimeKey, s.retention.MaxCount, -1))
+	if err != nil {
+		return fmt.Errorf("can't get tasks over the limit from redis: %s", err)
+	}
+
+	return s.remove(conn, append(expiredIDs, overLimitIDs...))
+}
+
+func (s RedisStore) remove(conn redis.Conn, taskIDs []string) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+
+	if err := conn.Send("MULTI"); err != nil {
+		return fmt.Errorf("can't start redis transaction: %s", err)
+	}
+	_ = conn.Send("HDEL", redis.Args{}.Add(tasksKey).AddFlat(taskIDs)...)
+	_ = conn.Send("ZREM", redis.Args{}.Add(tasksByTimeKey).AddFlat(taskIDs)...)
+	if _, err := conn.Do("EXEC"); err != nil {
+		return fmt.Errorf("can't remove tasks from redis: %s", err)
+	}
+
+	return nil
+}
+
+func (s RedisStore) List(_ context.Context) ([]Task, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	tasksJSON, err := redis.ByteSlices(conn.Do("HVALS", tasksKey))
+	if err != nil {
+		return nil, fmt.Errorf("can't get tasks from redis: %s", err)
+	}
+
+	ret := []Task{}
+	var expiredIDs []string
+	now := time.Now()
+	for _, data := range tasksJSON {
+		t, parseErr := parseTask(data)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		if s.retention.expired(t, now) {
+			// tasks saved before retention was added aren't in the sorted set
+			expiredIDs = append(expiredIDs, t.ID)
+			continue
+		}
+		ret = append(ret, *t)
+	}
+
+	if err = s.remove(conn, expiredIDs); err != nil {
+		return nil, err
+	}
+
+	sortTasks(ret)
+	return ret, nil
+}
+
+func (s RedisStore) Get(_ context.Context, taskID string) (*Task, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	data, err := redis.Bytes(conn.Do("HGET", tasksKey, taskID))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't get task from redis: %s", err)
+	}
+
+	t, err := parseTask(data)
+	if err != nil {
+		return nil, err
+	}
+	if s.retention.expired(t, time.Now()) {
+		return nil, nil
+	}
+
+	return t, nil
+}
+
+func (s RedisStore) Remove(_ context.Context, taskID string) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	return s.remove(conn, []string{taskID})
+}
+
+func (s RedisStore) Purge(_ context.Context) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("DEL", tasksKey, tasksByTimeKey); err != nil {
+		return fmt.Errorf("can't remove tasks from redis: %s", err)
+	}
+
+	return nil
+}
+
+func parseTask(data []byte) (*Task, error) {
+	var t Task
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("can't unmarshal task: %s", err)
+	}
+
+	return &t, nil
+}
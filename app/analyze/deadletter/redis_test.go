@@ -0,0 +1,67 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/lib/redisutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisStoreAttemptsExpire(t *testing.T) {
+	ctx := context.Background()
+	db := redisutils.NewFakeDB()
+	store := NewRedisStore(db.Pool(), DefaultRetention)
+
+	_, err := store.AddAttempt(ctx, "task", Attempt{Error: "error 1"})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	db.Now = func() time.Time {
+		return now.Add(attemptsTTL)
+	}
+
+	attempts, err := store.AddAttempt(ctx, "task", Attempt{Error: "error 2"})
+	assert.NoError(t, err)
+	if assert.Len(t, attempts, 1) {
+		assert.Equal(t, "error 2", attempts[0].Error)
+	}
+}
+
+func TestRedisStoreDoesntSaveSecrets(t *testing.T) {
+	ctx := context.Background()
+	db := redisutils.NewFakeDB()
+	store := NewRedisStore(db.Pool(), DefaultRetention)
+
+	assert.NoError(t, store.Add(ctx, &Task{ID: "task", DeadAt: time.Now(), Secrets: []string{"token"}}))
+
+	conn := db.Pool().Get()
+	defer conn.Close()
+	data, err := conn.Do("HGET", tasksKey, "task")
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data.([]byte)), "token")
+}
+
+func TestRedisStoreRemovesExpiredLegacyTasks(t *testing.T) {
+	ctx := context.Background()
+	db := redisutils.NewFakeDB()
+	store := NewRedisStore(db.Pool(), Retention{MaxAge: time.Hour, MaxCount: 10})
+
+	// tasks saved before retention was added aren't in the sorted set
+	legacyJSON, err := json.Marshal(&Task{ID: "legacy", DeadAt: time.Now().Add(-2 * time.Hour)})
+	assert.NoError(t, err)
+	conn := db.Pool().Get()
+	defer conn.Close()
+	_, err = conn.Do("HSET", tasksKey, "legacy", legacyJSON)
+	assert.NoError(t, err)
+
+	tasks, err := store.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+
+	n, err := conn.Do("HLEN", tasksKey)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
@@ -0,0 +1,65 @@
+package dedupe
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryClaim struct {
+	analysisGUID string
+	expiresAt    time.Time
+}
+
+// MemoryStore keeps claims in memory: it's used in tests and when redis isn't configured
+type MemoryStore struct {
+	lock   sync.Mutex
+	claims map[string]memoryClaim
+	now    func() time.Time
+}
+
+var _ Store = &MemoryStore{}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		claims: map[string]memoryClaim{},
+		now:    time.Now,
+	}
+}
+
+func (s *MemoryStore) Claim(_ context.Context, key, analysisGUID string, ttl time.Duration) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	for k, c := range s.claims {
+		if !c.expiresAt.After(now) {
+			delete(s.claims, k)
+		}
+	}
+
+	if c, ok := s.claims[key]; ok {
+		return c.analysisGUID, nil
+	}
+
+	s.claims[key] = memoryClaim{
+		analysisGUID: analysisGUID,
+		expiresAt:    now.Add(ttl),
+	}
+	return analysisGUID, nil
+}
+
+func (s *MemoryStore) Refresh(_ context.Context, key, analysisGUID string, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	if c, ok := s.claims[key]; ok && c.analysisGUID == analysisGUID && c.expiresAt.After(now) {
+		s.claims[key] = memoryClaim{
+			analysisGUID: analysisGUID,
+			expiresAt:    now.Add(ttl),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package dedupe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreClaim(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	owner, err := s.Claim(ctx, "key", "first", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", owner)
+
+	owner, err = s.Claim(ctx, "key", "second", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", owner, "duplicate must be linked to the first analysis")
+
+	owner, err = s.Claim(ctx, "key", "first", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", owner, "retry of the first analysis must own the key")
+
+	owner, err = s.Claim(ctx, "other key", "second", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", owner)
+
+	now = now.Add(time.Minute)
+	owner, err = s.Claim(ctx, "key", "third", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "third", owner, "claim must expire")
+}
+
+func TestMemoryStoreRefresh(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	_, err := s.Claim(ctx, "key", "first", time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Refresh(ctx, "key", "second", time.Hour), "only the owner refreshes the claim")
+	assert.NoError(t, s.Refresh(ctx, "key", "first", 2*time.Minute))
+
+	now = now.Add(time.Minute)
+	owner, err := s.Claim(ctx, "key", "second", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", owner, "claim must be refreshed")
+
+	now = now.Add(time.Minute)
+	owner, err = s.Claim(ctx, "key", "second", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", owner, "not refreshed claim must expire")
+}
@@ -0,0 +1,67 @@
+package dedupe
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// claimScript sets the key if it's not set and returns the value of the key.
+// KEYS[1] - claim key, ARGV[1] - analysis guid, ARGV[2] - ttl in seconds
+var claimScript = redis.NewScript(1, `
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'EX', ARGV[2]) then
+	return ARGV[1]
+end
+return redis.call('GET', KEYS[1])
+`)
+
+// refreshScript sets ttl of the key if it's claimed by the analysis.
+// KEYS[1] - claim key, ARGV[1] - analysis guid, ARGV[2] - ttl in seconds
+var refreshScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('EXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// RedisStore keeps claims in redis: they are shared between all workers
+type RedisStore struct {
+	pool *redis.Pool
+}
+
+var _ Store = &RedisStore{}
+
+func NewRedisStore(pool *redis.Pool) *RedisStore {
+	return &RedisStore{
+		pool: pool,
+	}
+}
+
+func (s RedisStore) Claim(_ context.Context, key, analysisGUID string, ttl time.Duration) (string, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	owner, err := redis.String(claimScript.Do(conn, redisKey(key), analysisGUID, int(ttl/time.Second)))
+	if err != nil {
+		return "", fmt.Errorf("can't claim idempotency key in redis: %s", err)
+	}
+
+	return owner, nil
+}
+
+func (s RedisStore) Refresh(_ context.Context, key, analysisGUID string, ttl time.Duration) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	if _, err := refreshScript.Do(conn, redisKey(key), analysisGUID, int(ttl/time.Second)); err != nil {
+		return fmt.Errorf("can't refresh idempotency key in redis: %s", err)
+	}
+
+	return nil
+}
+
+func redisKey(key string) string {
+	return "analysis_idempotency_key:" + key
+}
@@ -0,0 +1,32 @@
+package dedupe
+
+import (
+	"context"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/lib/redisutils"
+)
+
+// Store keeps for a short time which analysis claimed an idempotency key first
+type Store interface {
+	// Claim claims the key for the analysis if the key isn't claimed yet. It returns guid
+	// of the analysis owning the key: it's analysisGUID if the key was claimed by this call
+	// or by the same analysis before.
+	Claim(ctx context.Context, key, analysisGUID string, ttl time.Duration) (string, error)
+
+	// Refresh sets ttl of the key if it's claimed by the analysis: the claim of a crashed analysis
+	// isn't refreshed and expires
+	Refresh(ctx context.Context, key, analysisGUID string, ttl time.Duration) error
+}
+
+var defaultMemoryStore = NewMemoryStore()
+
+// NewStoreFromEnv returns redis store if redis is configured: it's shared by all workers.
+// Otherwise it returns in-memory store shared by all analyses of this worker.
+func NewStoreFromEnv() Store {
+	if redisutils.IsConfigured() {
+		return NewRedisStore(redisutils.GetPool())
+	}
+
+	return defaultMemoryStore
+}
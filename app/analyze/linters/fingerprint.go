@@ -0,0 +1,18 @@
+package linters
+
+// Fingerprinter is a linter whose results depend not only on its name and the analyzed code:
+// e.g. on its command line arguments and env
+type Fingerprinter interface {
+	Linter
+	// Fingerprint returns the same string for runs with the same results of the same code
+	Fingerprint() string
+}
+
+// Fingerprint returns the fingerprint of the linter: its name if it isn't Fingerprinter
+func Fingerprint(l Linter) string {
+	if f, ok := l.(Fingerprinter); ok {
+		return f.Fingerprint()
+	}
+
+	return l.Name()
+}
@@ -0,0 +1,31 @@
+package linters
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golangci/golangci-worker/app/lib/executors"
+)
+
+// Fixer is implemented by linters able to fix found issues in the working tree
+type Fixer interface {
+	Fix(ctx context.Context, exec executors.Executor) error
+}
+
+// FixRunner runs fixers of linters in the working tree
+type FixRunner interface {
+	Fix(ctx context.Context, lnts []Linter, exec executors.Executor) error
+}
+
+// RunFixers runs fixers of linters in the working dir of exec: linters not implementing Fixer are skipped
+func RunFixers(ctx context.Context, lnts []Linter, exec executors.Executor) error {
+	for _, l := range lnts {
+		if f, ok := l.(Fixer); ok {
+			if err := f.Fix(ctx, exec); err != nil {
+				return fmt.Errorf("can't fix issues of %s: %s", l.Name(), err)
+			}
+		}
+	}
+
+	return nil
+}
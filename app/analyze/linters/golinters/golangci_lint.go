@@ -7,27 +7,51 @@ import (
 	"strings"
 
 	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/analyze/linters"
 	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/resultjson"
+	"github.com/golangci/golangci-worker/app/analyze/severity"
 	"github.com/golangci/golangci-worker/app/lib/errorutils"
 	"github.com/golangci/golangci-worker/app/lib/executors"
 
 	"github.com/golangci/golangci-lint/pkg/printers"
+	lintresult "github.com/golangci/golangci-lint/pkg/result"
+)
+
+// runEnvKey makes golangci-lint use defaults of golangci.com runs, e.g. the set of enabled linters
+const (
+	runEnvKey   = "GOLANGCI_COM_RUN"
+	runEnvValue = "1"
 )
 
 type GolangciLint struct {
 	PatchPath string
 }
 
+var _ linters.PatchLinter = GolangciLint{}
+var _ linters.Fingerprinter = GolangciLint{}
+
 func (g GolangciLint) Name() string {
 	return "golangci-lint"
 }
 
-func (g GolangciLint) Run(ctx context.Context, exec executors.Executor) (*result.Result, error) {
-	exec = exec.WithEnv("GOLANGCI_COM_RUN", "1")
+func (g GolangciLint) WithPatchPath(patchPath string) linters.Linter {
+	g.PatchPath = patchPath
+	return g
+}
+
+// Fingerprint returns arguments and env of golangci-lint runs: they change results as much as the config does.
+// The patch path isn't included: results of the whole repo are reused for pull requests.
+func (g GolangciLint) Fingerprint() string {
+	g.PatchPath = ""
+	parts := append([]string{g.Name()}, g.baseArgs()...)
+	parts = append(parts, runEnvKey+"="+runEnvValue)
+	return strings.Join(parts, " ")
+}
 
-	args := []string{
+func (g GolangciLint) baseArgs() []string {
+	return []string{
 		"run",
-		"--out-format=json",
 		"--issues-exit-code=0",
 		"--print-welcome=false",
 		"--timeout=5m",
@@ -35,7 +59,25 @@ func (g GolangciLint) Run(ctx context.Context, exec executors.Executor) (*result
 		"--new-from-rev=",
 		"--new-from-patch=" + g.PatchPath,
 	}
+}
+
+// Fix runs golangci-lint with --fix in the working dir of exec: it fixes in the module of the working dir
+// issues it knows how to fix, e.g. gofmt, goimports and misspell ones
+func (g GolangciLint) Fix(ctx context.Context, exec executors.Executor) error {
+	exec = exec.WithEnv(runEnvKey, runEnvValue)
+
+	args := append(g.baseArgs(), "--fix")
+	if out, err := exec.Run(ctx, g.Name(), args...); err != nil {
+		return fmt.Errorf("can't run golangci-lint with --fix: %s, %s", err, out)
+	}
+
+	return nil
+}
 
+func (g GolangciLint) Run(ctx context.Context, exec executors.Executor) (*result.Result, error) {
+	exec = exec.WithEnv(runEnvKey, runEnvValue)
+
+	args := append(g.baseArgs(), "--out-format=json")
 	out, runErr := exec.Run(ctx, g.Name(), args...)
 	rawJSON := []byte(out)
 
@@ -76,17 +118,111 @@ func (g GolangciLint) Run(ctx context.Context, exec executors.Executor) (*result
 	}
 
 	var retIssues []result.Issue
+	fileLines := map[string][]string{}
 	for _, i := range res.Issues {
-		retIssues = append(retIssues, result.Issue{
+		issue := result.Issue{
 			File:       i.FilePath(),
 			LineNumber: i.Line(),
 			Text:       i.Text,
 			FromLinter: i.FromLinter,
 			HunkPos:    i.HunkPos,
-		})
+			Column:     i.Column(),
+			EndLine:    i.Line(),
+		}
+		validRange := isValidLineRange(&i)
+		if validRange && i.LineRange != nil {
+			issue.EndLine = i.LineRange.To
+		}
+		if i.Replacement != nil && validRange {
+			issue.Replacement = &result.Replacement{
+				NeedOnlyDelete: i.Replacement.NeedOnlyDelete,
+				NewLines:       i.Replacement.NewLines,
+			}
+		}
+		issue.Fingerprint = result.Fingerprint(&issue, g.getSourceContext(ctx, exec, fileLines, &i))
+		retIssues = append(retIssues, issue)
 	}
+
+	severityMapper, err := g.buildSeverityMapper(ctx, exec)
+	if err != nil {
+		return nil, &errorutils.BadInputError{
+			PublicDesc: fmt.Sprintf("invalid severity config: %s", err),
+		}
+	}
+	severityMapper.Apply(retIssues)
+
+	resultJSON, err := buildResultJSON(rawJSON, retIssues)
+	if err != nil {
+		return nil, &errorutils.InternalError{
+			PublicDesc:  "can't run golangci-lint: invalid output json",
+			PrivateDesc: fmt.Sprintf("can't build result json: %s", err),
+		}
+	}
+
 	return &result.Result{
-		Issues:     retIssues,
-		ResultJSON: json.RawMessage(rawJSON),
+		Issues:       retIssues,
+		ResultJSON:   resultJSON,
+		FailSeverity: severityMapper.FailSeverity(),
 	}, nil
 }
+
+func (g GolangciLint) buildSeverityMapper(ctx context.Context, exec executors.Executor) (*severity.Mapper, error) {
+	cfg, err := severity.LoadRepoConfig(ctx, exec)
+	if err != nil {
+		analytics.Log(ctx).Warnf("Can't load severity config, use the default one: %s", err)
+		cfg = &severity.Config{}
+	}
+
+	return severity.NewMapper(cfg, severity.DeploymentFailSeverity())
+}
+
+func (g GolangciLint) getSourceContext(ctx context.Context, exec executors.Executor,
+	fileLines map[string][]string, i *lintresult.Issue) []string {
+
+	lines, ok := fileLines[i.FilePath()]
+	if !ok {
+		out, err := exec.Run(ctx, "cat", i.FilePath())
+		if err != nil {
+			analytics.Log(ctx).Warnf("Can't read file %s for issue fingerprint: %s", i.FilePath(), err)
+		} else {
+			lines = strings.Split(out, "\n")
+		}
+		fileLines[i.FilePath()] = lines
+	}
+
+	if sc := result.SourceContext(lines, i.Line()); sc != nil {
+		return sc
+	}
+
+	return i.SourceLines
+}
+
+// isValidLineRange checks that the line range of the issue starts at its line: the replacement
+// of the issue replaces lines of the range, they are commented by the suggestion
+func isValidLineRange(i *lintresult.Issue) bool {
+	r := i.LineRange
+	return r == nil || (r.From == i.Line() && r.To >= r.From)
+}
+
+// buildResultJSON adds computed fields of issues to golangci-lint json output
+func buildResultJSON(rawJSON []byte, issues []result.Issue) (*resultjson.LintResult, error) {
+	var ret resultjson.LintResult
+	if err := json.Unmarshal(rawJSON, &ret); err != nil {
+		return nil, err
+	}
+
+	if len(ret.Issues) != len(issues) {
+		return nil, fmt.Errorf("issues count mismatch: %d != %d", len(ret.Issues), len(issues))
+	}
+
+	for ind := range ret.Issues {
+		ret.Issues[ind].Fingerprint = issues[ind].Fingerprint
+		ret.Issues[ind].Severity = string(issues[ind].GetSeverity())
+		ret.Issues[ind].EndLine = issues[ind].EndLine
+	}
+	if ret.Issues == nil {
+		ret.Issues = []resultjson.Issue{}
+	}
+
+	return &ret, nil
+}
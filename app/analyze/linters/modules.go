@@ -0,0 +1,327 @@
+package linters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/resultjson"
+	"github.com/golangci/golangci-worker/app/lib/errorutils"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+)
+
+// PatchLinter is a linter reporting only new issues of the patch in PatchPath
+type PatchLinter interface {
+	Linter
+	// WithPatchPath returns the linter with the patch path relative to the working dir
+	WithPatchPath(patchPath string) Linter
+}
+
+// ModulesRunner runs linters by the wrapped runner in every module of a multi-module repo:
+// go tools and linters analyze only the module of the working dir.
+type ModulesRunner struct {
+	runner    Runner
+	modules   []string // dirs of modules relative to the repo root, the repo root is "."
+	patch     string   // empty for analyses of repos
+	patchPath string   // path of the patch of the repo root relative to the repo root
+}
+
+var _ Runner = &ModulesRunner{}
+var _ FixRunner = &ModulesRunner{}
+
+func NewModulesRunner(runner Runner, modules []string, patch, patchPath string) *ModulesRunner {
+	return &ModulesRunner{
+		runner:    runner,
+		modules:   modules,
+		patch:     patch,
+		patchPath: patchPath,
+	}
+}
+
+// Run returns merged issues of all modules with file paths relative to the repo root.
+// The analysis fails if linters failed in all modules or in a module changed by the patch:
+// issues of the patch can't be checked. Errors of other modules are in Result.Modules.
+func (r ModulesRunner) Run(ctx context.Context, lnts []Linter, exec executors.Executor) (*result.Result, error) {
+	if len(r.modules) == 0 || (len(r.modules) == 1 && r.modules[0] == ".") {
+		return r.runner.Run(ctx, lnts, exec)
+	}
+
+	changedModules := r.changedModules()
+	var ret *result.Result
+	var moduleResults []result.ModuleResult
+	var firstErr error
+	for ind, dir := range r.modules {
+		res, mr, err := r.runModule(ctx, lnts, exec, ind, dir)
+		moduleResults = append(moduleResults, *mr)
+		if err != nil {
+			analytics.Log(ctx).Warnf("Can't run linters in module %s: %s", dir, err)
+			if changedModules[dir] {
+				return nil, changedModuleError(dir, err)
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if ret == nil {
+			// fail severity and report of linters are taken from the first module: the repo root if it's a module
+			ret = &result.Result{
+				MaxIssuesPerFile: res.MaxIssuesPerFile,
+				FailSeverity:     res.FailSeverity,
+			}
+		}
+		mergeModuleResult(ret, res, dir)
+	}
+
+	if ret == nil {
+		return nil, firstErr // don't wrap error, need to save it's type
+	}
+
+	ret.Modules = moduleResults
+	return ret, nil
+}
+
+// Fix runs fixers of linters in every module with the patch of the module.
+// It fails in the same cases as Run does: fixes of the changed module can't be skipped.
+func (r ModulesRunner) Fix(ctx context.Context, lnts []Linter, exec executors.Executor) error {
+	if len(r.modules) == 0 || (len(r.modules) == 1 && r.modules[0] == ".") {
+		return RunFixers(ctx, lnts, exec)
+	}
+
+	changedModules := r.changedModules()
+	fixed := false
+	var firstErr error
+	for ind, dir := range r.modules {
+		if err := r.fixModule(ctx, lnts, exec, ind, dir); err != nil {
+			analytics.Log(ctx).Warnf("Can't fix issues in module %s: %s", dir, err)
+			if changedModules[dir] {
+				return changedModuleError(dir, err)
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		fixed = true
+	}
+
+	if !fixed {
+		return firstErr
+	}
+
+	return nil
+}
+
+func (r ModulesRunner) fixModule(ctx context.Context, lnts []Linter, exec executors.Executor,
+	ind int, dir string) error {
+
+	moduleExec := moduleExecutor(exec, dir)
+	lnts, cleanup, err := r.setupPatch(ctx, lnts, moduleExec, ind, dir)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	return RunFixers(ctx, lnts, moduleExec)
+}
+
+// changedModules returns dirs of modules with files changed by the patch
+func (r ModulesRunner) changedModules() map[string]bool {
+	ret := map[string]bool{}
+	for _, file := range patchFiles(r.patch) {
+		if dir := moduleOfFile(r.modules, file); dir != "" {
+			ret[dir] = true
+		}
+	}
+
+	return ret
+}
+
+// moduleOfFile returns the dir of the deepest module containing the file, it returns empty string
+// if the file isn't in any module
+func moduleOfFile(modules []string, file string) string {
+	ret := ""
+	for _, dir := range modules {
+		if dir != "." && !strings.HasPrefix(file, dir+"/") {
+			continue
+		}
+
+		if ret == "" || ret == "." || len(dir) > len(ret) {
+			ret = dir
+		}
+	}
+
+	return ret
+}
+
+// changedModuleError adds the module dir to the public error: the type of the error is kept
+func changedModuleError(dir string, err error) error {
+	switch e := err.(type) {
+	case *errorutils.BadInputError:
+		return &errorutils.BadInputError{
+			PublicDesc: fmt.Sprintf("module %s: %s", dir, e.PublicDesc),
+		}
+	case *errorutils.InternalError:
+		return &errorutils.InternalError{
+			PublicDesc:  fmt.Sprintf("module %s: %s", dir, e.PublicDesc),
+			PrivateDesc: fmt.Sprintf("module %s: %s", dir, e.PrivateDesc),
+		}
+	}
+
+	return fmt.Errorf("module %s: %s", dir, err)
+}
+
+func (r ModulesRunner) runModule(ctx context.Context, lnts []Linter, exec executors.Executor,
+	ind int, dir string) (*result.Result, *result.ModuleResult, error) {
+
+	startedAt := time.Now()
+	mr := &result.ModuleResult{
+		Dir: dir,
+	}
+
+	moduleExec := moduleExecutor(exec, dir)
+	lnts, cleanup, err := r.setupPatch(ctx, lnts, moduleExec, ind, dir)
+	if err != nil {
+		mr.Error = "can't store patch of module"
+		return nil, mr, err
+	}
+	defer cleanup()
+
+	res, err := r.runner.Run(ctx, lnts, moduleExec)
+	mr.Duration = time.Since(startedAt)
+	if err != nil {
+		mr.Error = publicModuleError(err)
+		return nil, mr, err
+	}
+
+	mr.IssuesCount = len(res.Issues)
+	return res, mr, nil
+}
+
+func moduleExecutor(exec executors.Executor, dir string) executors.Executor {
+	if dir == "." {
+		return exec
+	}
+
+	return exec.WithWorkDir(path.Join(exec.WorkDir(), dir))
+}
+
+// setupPatch stores the patch with paths relative to the module next to the patch of the repo root
+// and makes linters use it
+func (r ModulesRunner) setupPatch(ctx context.Context, lnts []Linter, moduleExec executors.Executor,
+	ind int, dir string) ([]Linter, func(), error) {
+
+	nop := func() {}
+	if r.patch == "" || dir == "." {
+		return lnts, nop, nil
+	}
+
+	// the path is relative to the module dir
+	modulePatchPath := path.Join(strings.Repeat("../", strings.Count(dir, "/")+1),
+		path.Dir(r.patchPath), fmt.Sprintf("module%d.%s", ind, path.Base(r.patchPath)))
+
+	f, err := ioutil.TempFile("", "golangci.module.diff")
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't create temp file for module patch: %s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err = f.WriteString(modulePatch(r.patch, dir)); err != nil {
+		return nil, nil, fmt.Errorf("can't write module patch to temp file %s: %s", f.Name(), err)
+	}
+
+	if err = moduleExec.CopyFile(ctx, modulePatchPath, f.Name()); err != nil {
+		return nil, nil, fmt.Errorf("can't copy module patch file: %s", err)
+	}
+
+	var ret []Linter
+	for _, lnt := range lnts {
+		if pl, ok := lnt.(PatchLinter); ok {
+			lnt = pl.WithPatchPath(modulePatchPath)
+		}
+		ret = append(ret, lnt)
+	}
+
+	return ret, func() {
+		if out, rmErr := moduleExec.Run(ctx, "rm", "-f", modulePatchPath); rmErr != nil {
+			analytics.Log(ctx).Warnf("Can't remove module patch: %s, %s", rmErr, out)
+		}
+	}, nil
+}
+
+func publicModuleError(err error) string {
+	switch e := err.(type) {
+	case *errorutils.BadInputError:
+		return e.PublicDesc
+	case *errorutils.InternalError:
+		return e.PublicDesc
+	}
+
+	return "internal error"
+}
+
+func mergeModuleResult(ret, res *result.Result, dir string) {
+	for _, i := range res.Issues {
+		i.File = moduleFilePath(dir, i.File)
+		i.Fingerprint = moduleFingerprint(dir, i.Fingerprint)
+		ret.Issues = append(ret.Issues, i)
+	}
+
+	if res.ResultJSON == nil {
+		return
+	}
+
+	if ret.ResultJSON == nil {
+		ret.ResultJSON = &resultjson.LintResult{
+			Issues: []resultjson.Issue{},
+		}
+		if res.ResultJSON.Report != nil {
+			report := *res.ResultJSON.Report
+			report.Warnings = nil
+			ret.ResultJSON.Report = &report
+		}
+	}
+
+	for _, i := range res.ResultJSON.Issues {
+		i.Pos.Filename = moduleFilePath(dir, i.Pos.Filename)
+		i.Fingerprint = moduleFingerprint(dir, i.Fingerprint)
+		ret.ResultJSON.Issues = append(ret.ResultJSON.Issues, i)
+	}
+
+	if res.ResultJSON.Report != nil && ret.ResultJSON.Report != nil {
+		for _, w := range res.ResultJSON.Report.Warnings {
+			w.Text = fmt.Sprintf("module %s: %s", dir, w.Text)
+			ret.ResultJSON.Report.Warnings = append(ret.ResultJSON.Report.Warnings, w)
+		}
+	}
+}
+
+func moduleFilePath(dir, file string) string {
+	if dir == "." || file == "" || path.IsAbs(file) {
+		return file
+	}
+
+	return path.Join(dir, file)
+}
+
+// moduleFingerprint makes fingerprints of issues of different modules different:
+// linters build them from paths relative to the module
+func moduleFingerprint(dir, fingerprint string) string {
+	if dir == "." || fingerprint == "" {
+		return fingerprint
+	}
+
+	h := sha256.Sum256([]byte(dir + "\n" + fingerprint))
+	return hex.EncodeToString(h[:])[:len(fingerprint)]
+}
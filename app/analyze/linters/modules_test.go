@@ -0,0 +1,246 @@
+package linters
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/resultjson"
+	"github.com/golangci/golangci-worker/app/lib/errorutils"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPatch = `diff --git a/main.go b/main.go
+index 1..2 100644
+--- a/main.go
++++ b/main.go
+@@ -1 +1,2 @@
+ package main
++var x = 1
+diff --git a/tools/gen/gen.go b/tools/gen/gen.go
+new file mode 100644
+--- /dev/null
++++ b/tools/gen/gen.go
+@@ -0,0 +1 @@
++package gen
+diff --git a/toolsx/a.go b/toolsx/a.go
+--- a/toolsx/a.go
++++ b/toolsx/a.go
+@@ -1 +1 @@
+-package a
++package b
+`
+
+func TestModulePatch(t *testing.T) {
+	assert.Equal(t, `diff --git a/gen/gen.go b/gen/gen.go
+new file mode 100644
+--- /dev/null
++++ b/gen/gen.go
+@@ -0,0 +1 @@
++package gen
+`, modulePatch(testPatch, "tools"))
+	assert.Equal(t, "", modulePatch(testPatch, "api"))
+}
+
+type patchLinter struct {
+	patchPath string
+	fixed     map[string]string // patch paths of fixes by working dirs
+}
+
+func (l patchLinter) Run(ctx context.Context, exec executors.Executor) (*result.Result, error) {
+	return nil, nil
+}
+
+func (l patchLinter) Name() string {
+	return "patch"
+}
+
+func (l patchLinter) WithPatchPath(patchPath string) Linter {
+	l.patchPath = patchPath
+	return l
+}
+
+func (l patchLinter) Fix(ctx context.Context, exec executors.Executor) error {
+	l.fixed[exec.WorkDir()] = l.patchPath
+	return nil
+}
+
+// fakeRunner returns results by the working dir
+type fakeRunner struct {
+	results    map[string]*result.Result
+	errors     map[string]error
+	patchPaths map[string]string
+}
+
+func (r fakeRunner) Run(ctx context.Context, lnts []Linter, exec executors.Executor) (*result.Result, error) {
+	r.patchPaths[exec.WorkDir()] = lnts[0].(patchLinter).patchPath
+	if err := r.errors[exec.WorkDir()]; err != nil {
+		return nil, err
+	}
+	return r.results[exec.WorkDir()], nil
+}
+
+func newTestModuleResult(file string) *result.Result {
+	return &result.Result{
+		Issues: []result.Issue{{File: file, LineNumber: 1, Fingerprint: "0123456789abcdef"}},
+		ResultJSON: &resultjson.LintResult{
+			Issues: []resultjson.Issue{{Pos: resultjson.Position{Filename: file, Line: 1}, Fingerprint: "0123456789abcdef"}},
+			Report: &resultjson.Report{Warnings: []resultjson.ReportWarning{{Text: "warning"}}},
+		},
+		FailSeverity: result.SeverityWarning,
+	}
+}
+
+func TestModulesRunner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exec := executors.NewMockExecutor(ctrl)
+	exec.EXPECT().WorkDir().Return("/repo").AnyTimes()
+	for _, dir := range []string{"/repo/tools", "/repo/api/v2"} {
+		moduleExec := executors.NewMockExecutor(ctrl)
+		moduleExec.EXPECT().WorkDir().Return(dir).AnyTimes()
+		exec.EXPECT().WithWorkDir(dir).Return(moduleExec)
+		if dir == "/repo/tools" {
+			moduleExec.EXPECT().CopyFile(gomock.Any(), "../../module2.changes.patch", gomock.Any()).
+				DoAndReturn(func(ctx context.Context, dst, src string) error {
+					patch, err := ioutil.ReadFile(src)
+					assert.NoError(t, err)
+					assert.Equal(t, modulePatch(testPatch, "tools"), string(patch))
+					return nil
+				})
+			moduleExec.EXPECT().Run(gomock.Any(), "rm", "-f", "../../module2.changes.patch").Return("", nil)
+		} else {
+			moduleExec.EXPECT().CopyFile(gomock.Any(), "../../../module1.changes.patch", gomock.Any()).Return(nil)
+			moduleExec.EXPECT().Run(gomock.Any(), "rm", "-f", "../../../module1.changes.patch").Return("", nil)
+		}
+	}
+
+	runner := fakeRunner{
+		results: map[string]*result.Result{
+			"/repo":       newTestModuleResult("main.go"),
+			"/repo/tools": newTestModuleResult("gen/gen.go"),
+		},
+		errors: map[string]error{
+			"/repo/api/v2": &errorutils.BadInputError{PublicDesc: "can't load packages"},
+		},
+		patchPaths: map[string]string{},
+	}
+	mr := NewModulesRunner(runner, []string{".", "api/v2", "tools"}, testPatch, "../changes.patch")
+	res, err := mr.Run(context.Background(), []Linter{patchLinter{patchPath: "../changes.patch"}}, exec)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"/repo":        "../changes.patch",
+		"/repo/api/v2": "../../../module1.changes.patch",
+		"/repo/tools":  "../../module2.changes.patch",
+	}, runner.patchPaths)
+
+	assert.Len(t, res.Issues, 2)
+	assert.Equal(t, "main.go", res.Issues[0].File)
+	assert.Equal(t, "0123456789abcdef", res.Issues[0].Fingerprint)
+	assert.Equal(t, "tools/gen/gen.go", res.Issues[1].File)
+	assert.Len(t, res.Issues[1].Fingerprint, 16)
+	assert.NotEqual(t, res.Issues[0].Fingerprint, res.Issues[1].Fingerprint)
+
+	assert.Len(t, res.ResultJSON.Issues, 2)
+	assert.Equal(t, "tools/gen/gen.go", res.ResultJSON.Issues[1].Pos.Filename)
+	assert.Equal(t, res.Issues[1].Fingerprint, res.ResultJSON.Issues[1].Fingerprint)
+	assert.Equal(t, []resultjson.ReportWarning{{Text: "module .: warning"}, {Text: "module tools: warning"}},
+		res.ResultJSON.Report.Warnings)
+	assert.Equal(t, result.SeverityWarning, res.FailSeverity)
+
+	assert.Len(t, res.Modules, 3)
+	for i, exp := range []result.ModuleResult{
+		{Dir: ".", IssuesCount: 1},
+		{Dir: "api/v2", Error: "can't load packages"},
+		{Dir: "tools", IssuesCount: 1},
+	} {
+		res.Modules[i].Duration = 0
+		assert.Equal(t, exp, res.Modules[i])
+	}
+}
+
+func TestModulesRunnerFailsIfAllModulesFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exec := executors.NewMockExecutor(ctrl)
+	exec.EXPECT().WorkDir().Return("/repo").AnyTimes()
+	moduleExec := executors.NewMockExecutor(ctrl)
+	moduleExec.EXPECT().WorkDir().Return("/repo/tools").AnyTimes()
+	exec.EXPECT().WithWorkDir("/repo/tools").Return(moduleExec)
+
+	rootErr := &errorutils.InternalError{PublicDesc: "can't run golangci-lint"}
+	runner := fakeRunner{
+		errors: map[string]error{
+			"/repo":       rootErr,
+			"/repo/tools": &errorutils.BadInputError{PublicDesc: "invalid config"},
+		},
+		patchPaths: map[string]string{},
+	}
+	mr := NewModulesRunner(runner, []string{".", "tools"}, "", "")
+	_, err := mr.Run(context.Background(), []Linter{patchLinter{}}, exec)
+	assert.Equal(t, rootErr, err)
+}
+
+func TestModulesRunnerFailsIfChangedModuleFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exec := executors.NewMockExecutor(ctrl)
+	exec.EXPECT().WorkDir().Return("/repo").AnyTimes()
+	moduleExec := executors.NewMockExecutor(ctrl)
+	moduleExec.EXPECT().WorkDir().Return("/repo/tools").AnyTimes()
+	moduleExec.EXPECT().CopyFile(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	moduleExec.EXPECT().Run(gomock.Any(), "rm", "-f", gomock.Any()).Return("", nil)
+	exec.EXPECT().WithWorkDir("/repo/tools").Return(moduleExec)
+
+	runner := fakeRunner{
+		results: map[string]*result.Result{
+			"/repo": newTestModuleResult("main.go"),
+		},
+		errors: map[string]error{
+			"/repo/tools": &errorutils.BadInputError{PublicDesc: "can't load packages"},
+		},
+		patchPaths: map[string]string{},
+	}
+	mr := NewModulesRunner(runner, []string{".", "tools"}, testPatch, "../changes.patch")
+	_, err := mr.Run(context.Background(), []Linter{patchLinter{}}, exec)
+	assert.Equal(t, &errorutils.BadInputError{PublicDesc: "module tools: can't load packages"}, err,
+		"issues of the changed module can't be checked")
+}
+
+func TestModulesRunnerFix(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exec := executors.NewMockExecutor(ctrl)
+	exec.EXPECT().WorkDir().Return("/repo").AnyTimes()
+	moduleExec := executors.NewMockExecutor(ctrl)
+	moduleExec.EXPECT().WorkDir().Return("/repo/tools").AnyTimes()
+	moduleExec.EXPECT().CopyFile(gomock.Any(), "../../module1.changes.patch", gomock.Any()).Return(nil)
+	moduleExec.EXPECT().Run(gomock.Any(), "rm", "-f", "../../module1.changes.patch").Return("", nil)
+	exec.EXPECT().WithWorkDir("/repo/tools").Return(moduleExec)
+
+	fixer := patchLinter{patchPath: "../changes.patch", fixed: map[string]string{}}
+	mr := NewModulesRunner(nil, []string{".", "tools"}, testPatch, "../changes.patch")
+	assert.NoError(t, mr.Fix(context.Background(), []Linter{fixer}, exec))
+	assert.Equal(t, map[string]string{
+		"/repo":       "../changes.patch",
+		"/repo/tools": "../../module1.changes.patch",
+	}, fixer.fixed)
+}
+
+func TestModuleOfFile(t *testing.T) {
+	modules := []string{".", "api", "api/v2", "tools"}
+	assert.Equal(t, "api/v2", moduleOfFile(modules, "api/v2/main.go"))
+	assert.Equal(t, "api", moduleOfFile(modules, "api/main.go"))
+	assert.Equal(t, ".", moduleOfFile(modules, "toolsx/a.go"))
+	assert.Equal(t, "", moduleOfFile([]string{"tools"}, "main.go"))
+
+	assert.Equal(t, []string{"main.go", "tools/gen/gen.go", "toolsx/a.go"}, patchFiles(testPatch))
+}
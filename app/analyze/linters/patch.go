@@ -0,0 +1,96 @@
+package linters
+
+import (
+	"strings"
+)
+
+// modulePatch returns diffs of files of the module from the git patch of the repo
+// with file paths relative to the module dir
+func modulePatch(patch, dir string) string {
+	var ret []string
+	for _, fileDiff := range splitPatchByFiles(patch) {
+		if rewritten, ok := rewriteFileDiff(fileDiff, dir+"/"); ok {
+			ret = append(ret, rewritten...)
+		}
+	}
+
+	if len(ret) == 0 {
+		return ""
+	}
+
+	return strings.Join(ret, "\n") + "\n"
+}
+
+// patchFiles returns paths of files changed by the git patch: both old and new paths of renamed files
+func patchFiles(patch string) []string {
+	var ret []string
+	for _, fileDiff := range splitPatchByFiles(patch) {
+		header := strings.TrimPrefix(fileDiff[0], "diff --git ")
+		if header == fileDiff[0] {
+			continue
+		}
+
+		ind := strings.LastIndex(header, " b/")
+		if ind == -1 {
+			continue
+		}
+
+		oldPath, newPath := strings.TrimPrefix(header[:ind], "a/"), header[ind+len(" b/"):]
+		ret = append(ret, newPath)
+		if oldPath != newPath {
+			ret = append(ret, oldPath)
+		}
+	}
+
+	return ret
+}
+
+func splitPatchByFiles(patch string) [][]string {
+	var ret [][]string
+	var cur []string
+	for _, line := range strings.Split(strings.TrimSuffix(patch, "\n"), "\n") {
+		if strings.HasPrefix(line, "diff --git ") && cur != nil {
+			ret = append(ret, cur)
+			cur = nil
+		}
+		cur = append(cur, line)
+	}
+	if cur != nil {
+		ret = append(ret, cur)
+	}
+
+	return ret
+}
+
+var patchHeaderPathPrefixes = []string{"--- a/", "+++ b/", "rename from ", "rename to ", "copy from ", "copy to "}
+
+// rewriteFileDiff strips the prefix from paths in the header of the file diff,
+// it returns false if the file isn't in the dir with the prefix
+func rewriteFileDiff(lines []string, prefix string) ([]string, bool) {
+	ret := make([]string, 0, len(lines))
+	inModule := false
+	for ind, line := range lines {
+		if strings.HasPrefix(line, "@@ ") {
+			return append(ret, lines[ind:]...), inModule
+		}
+
+		if strings.HasPrefix(line, "diff --git ") {
+			rewritten := strings.Replace(line, " a/"+prefix, " a/", 1)
+			rewritten = strings.Replace(rewritten, " b/"+prefix, " b/", 1)
+			inModule = inModule || rewritten != line
+			ret = append(ret, rewritten)
+			continue
+		}
+
+		for _, p := range patchHeaderPathPrefixes {
+			if strings.HasPrefix(line, p+prefix) {
+				line = p + strings.TrimPrefix(line, p+prefix)
+				inModule = true
+				break
+			}
+		}
+		ret = append(ret, line)
+	}
+
+	return ret, inModule
+}
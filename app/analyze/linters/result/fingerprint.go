@@ -0,0 +1,63 @@
+package result
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FingerprintContextLines is a count of lines before and after the issue line
+// included into the fingerprint
+const FingerprintContextLines = 2
+
+var (
+	numberRe = regexp.MustCompile(`\d+`)
+	spacesRe = regexp.MustCompile(`\s+`)
+)
+
+// normalizeText removes from the issue text parts changing without
+// changes of the issue itself: line numbers, complexity values, whitespace
+func normalizeText(text string) string {
+	text = numberRe.ReplaceAllString(text, "N")
+	text = spacesRe.ReplaceAllString(text, " ")
+	return strings.TrimSpace(text)
+}
+
+// SourceContext returns the issue line with nearby lines from the file lines
+func SourceContext(fileLines []string, lineNumber int) []string {
+	if lineNumber <= 0 || lineNumber > len(fileLines) {
+		return nil
+	}
+
+	from := lineNumber - 1 - FingerprintContextLines
+	if from < 0 {
+		from = 0
+	}
+
+	to := lineNumber + FingerprintContextLines
+	if to > len(fileLines) {
+		to = len(fileLines)
+	}
+
+	return fileLines[from:to]
+}
+
+func hashSourceContext(sourceContext []string) string {
+	h := sha256.New()
+	for _, line := range sourceContext {
+		// indentation changes shouldn't change the fingerprint
+		fmt.Fprintf(h, "%s\n", strings.TrimSpace(line))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Fingerprint builds a fingerprint of the issue from its linter, file, text and source context.
+// Line numbers aren't used: the fingerprint is the same after shifting of the code.
+func Fingerprint(i *Issue, sourceContext []string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s", i.FromLinter, i.File, normalizeText(i.Text), hashSourceContext(sourceContext))
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
@@ -0,0 +1,56 @@
+package result
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testFileLines = []string{
+	"package p",
+	"",
+	"func f() {",
+	"	x := 1",
+	"	_ = x",
+	"}",
+}
+
+func TestSourceContext(t *testing.T) {
+	assert.Equal(t, testFileLines[1:6], SourceContext(testFileLines, 4))
+	assert.Equal(t, testFileLines[0:3], SourceContext(testFileLines, 1))
+	assert.Equal(t, testFileLines[3:6], SourceContext(testFileLines, 6))
+	assert.Nil(t, SourceContext(testFileLines, 0))
+	assert.Nil(t, SourceContext(testFileLines, 7))
+}
+
+func TestFingerprintIsStableOnLineShift(t *testing.T) {
+	i := NewIssue("govet", "x declared at line 4 is unused", "p/f.go", 4, 4)
+	fp := Fingerprint(&i, SourceContext(testFileLines, 4))
+
+	shiftedLines := append([]string{"// comment", "// more comment"}, testFileLines...)
+	for j := range shiftedLines {
+		shiftedLines[j] = "  " + shiftedLines[j] // reindent
+	}
+	shifted := NewIssue("govet", "x declared at line 6 is unused", "p/f.go", 6, 8)
+	assert.Equal(t, fp, Fingerprint(&shifted, SourceContext(shiftedLines, 6)))
+}
+
+func TestFingerprintDiffers(t *testing.T) {
+	i := NewIssue("govet", "x is unused", "p/f.go", 4, 4)
+	sc := SourceContext(testFileLines, 4)
+	fp := Fingerprint(&i, sc)
+
+	otherLinter := i
+	otherLinter.FromLinter = "megacheck"
+	assert.NotEqual(t, fp, Fingerprint(&otherLinter, sc))
+
+	otherFile := i
+	otherFile.File = "p/g.go"
+	assert.NotEqual(t, fp, Fingerprint(&otherFile, sc))
+
+	otherText := i
+	otherText.Text = "y is unused"
+	assert.NotEqual(t, fp, Fingerprint(&otherText, sc))
+
+	assert.NotEqual(t, fp, Fingerprint(&i, SourceContext(testFileLines, 5)))
+}
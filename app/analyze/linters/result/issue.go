@@ -6,6 +6,37 @@ type Issue struct {
 	File       string
 	LineNumber int
 	HunkPos    int
+
+	// Column and EndLine are 1-based; zero value means unknown.
+	// golangci-lint doesn't report end columns of issues.
+	Column  int
+	EndLine int
+
+	Severity Severity
+
+	// Replacement is set if the linter knows how to fix the issue
+	Replacement *Replacement
+
+	// Fingerprint identifies the issue across commits and rebases:
+	// it doesn't depend on the line number of the issue
+	Fingerprint string
+}
+
+type Range struct {
+	From, To int
+}
+
+type Replacement struct {
+	NeedOnlyDelete bool     // need to delete all lines of the issue without replacement with new lines
+	NewLines       []string // if NeedOnlyDelete is false it's the replacement lines
+}
+
+func (i Issue) GetLineRange() Range {
+	if i.EndLine <= i.LineNumber {
+		return Range{From: i.LineNumber, To: i.LineNumber}
+	}
+
+	return Range{From: i.LineNumber, To: i.EndLine}
 }
 
 func NewIssue(fromLinter, text, file string, lineNumber, hunkPos int) Issue {
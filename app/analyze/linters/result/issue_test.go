@@ -0,0 +1,18 @@
+package result
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLineRange(t *testing.T) {
+	i := NewIssue("gofmt", "File is not gofmt-ed", "p/f.go", 10, 3)
+	assert.Equal(t, Range{From: 10, To: 10}, i.GetLineRange())
+
+	i.EndLine = 10
+	assert.Equal(t, Range{From: 10, To: 10}, i.GetLineRange())
+
+	i.EndLine = 12
+	assert.Equal(t, Range{From: 10, To: 12}, i.GetLineRange())
+}
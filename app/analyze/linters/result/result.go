@@ -1,7 +1,27 @@
 package result
 
+import (
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analyze/resultjson"
+)
+
 type Result struct {
 	Issues           []Issue
 	MaxIssuesPerFile int // Needed for gofmt and goimports where it is 1
-	ResultJSON       interface{}
+	ResultJSON       *resultjson.LintResult
+
+	// FailSeverity is the minimal severity of issues failing the analysis
+	FailSeverity Severity
+
+	// Modules are results of modules of a multi-module repo, it's empty if the repo was analyzed as a whole
+	Modules []ModuleResult
+}
+
+// ModuleResult is the result of linters in a module of a multi-module repo
+type ModuleResult struct {
+	Dir         string // relative to the repo root
+	Duration    time.Duration
+	IssuesCount int
+	Error       string // public description of the error, empty if linters succeeded
 }
@@ -0,0 +1,62 @@
+package result
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+
+	// DefaultSeverity is used for issues without severity
+	DefaultSeverity = SeverityError
+)
+
+// Severities are ordered from the most to the least important
+var Severities = []Severity{SeverityError, SeverityWarning, SeverityInfo}
+
+var severityLevels = map[Severity]int{
+	SeverityInfo:    1,
+	SeverityWarning: 2,
+	SeverityError:   3,
+}
+
+func ParseSeverity(s string) (Severity, error) {
+	sev := Severity(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := severityLevels[sev]; !ok {
+		return "", fmt.Errorf("invalid severity %q, valid are: %s", s, Severities)
+	}
+
+	return sev, nil
+}
+
+func (s Severity) IsAtLeast(other Severity) bool {
+	return severityLevels[s] >= severityLevels[other]
+}
+
+// HasBlockingIssues returns true if any issue has severity at least failSeverity
+func HasBlockingIssues(issues []Issue, failSeverity Severity) bool {
+	if failSeverity == "" {
+		failSeverity = SeverityInfo
+	}
+
+	for _, i := range issues {
+		if i.GetSeverity().IsAtLeast(failSeverity) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (i Issue) GetSeverity() Severity {
+	if i.Severity == "" {
+		return DefaultSeverity
+	}
+
+	return i.Severity
+}
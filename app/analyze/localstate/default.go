@@ -0,0 +1,26 @@
+package localstate
+
+import (
+	"sync"
+
+	"github.com/golangci/golangci-worker/app/lib/boltutils"
+)
+
+var outbox *Outbox
+var outboxErr error
+var initOnce sync.Once
+
+// GetOutbox returns the outbox in the bolt db from STATE_DB_PATH env var
+func GetOutbox() (*Outbox, error) {
+	initOnce.Do(func() {
+		db, err := boltutils.GetDB()
+		if err != nil {
+			outboxErr = err
+			return
+		}
+
+		outbox = NewOutbox(db)
+	})
+
+	return outbox, outboxErr
+}
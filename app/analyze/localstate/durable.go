@@ -0,0 +1,69 @@
+package localstate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+)
+
+// AnalysisStateSender delivers the JSON-encoded state of the analysis
+type AnalysisStateSender func(ctx context.Context, owner, name, analysisID string, data []byte) error
+
+// DurableStorage saves states of analyses locally and delivers them by the sender through the outbox:
+// states aren't lost if the API is down or the worker restarts
+type DurableStorage struct {
+	local  *Store
+	outbox *Outbox
+	bucket string
+}
+
+func NewDurableStorage(outbox *Outbox, bucket string, send AnalysisStateSender) *DurableStorage {
+	RegisterAnalysisStateSender(outbox, bucket, send)
+	return &DurableStorage{
+		local:  outbox.Store(bucket),
+		outbox: outbox,
+		bucket: bucket,
+	}
+}
+
+// RegisterAnalysisStateSender makes the outbox deliver states of analyses saved in the bucket
+func RegisterAnalysisStateSender(outbox *Outbox, bucket string, send AnalysisStateSender) {
+	outbox.Register(bucket, func(ctx context.Context, key string, data []byte) error {
+		parts := strings.SplitN(key, "/", 3)
+		if len(parts) != 3 {
+			return fmt.Errorf("invalid state key %q", key)
+		}
+
+		return send(ctx, parts[0], parts[1], parts[2], data)
+	})
+}
+
+func getAnalysisKey(owner, name, analysisID string) string {
+	return fmt.Sprintf("%s/%s/%s", owner, name, analysisID)
+}
+
+func (s DurableStorage) UpdateState(ctx context.Context, owner, name, analysisID string, state interface{}) error {
+	key := getAnalysisKey(owner, name, analysisID)
+	if err := s.local.Put(key, state); err != nil {
+		return fmt.Errorf("can't save state locally: %s", err)
+	}
+
+	if err := s.outbox.Deliver(ctx, s.bucket, key); err != nil {
+		analytics.Log(ctx).Warnf("State was saved locally and will be delivered to API later: %s", err)
+	}
+
+	return nil
+}
+
+// GetState decodes the local state to state and returns false if there is no local state:
+// states of analyses created by the API or trimmed from the outbox are only in the API
+func (s DurableStorage) GetState(owner, name, analysisID string, state interface{}) (bool, error) {
+	found, err := s.local.Get(getAnalysisKey(owner, name, analysisID), state)
+	if err != nil {
+		return false, fmt.Errorf("can't get local state: %s", err)
+	}
+
+	return found, nil
+}
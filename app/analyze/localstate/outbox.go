@@ -0,0 +1,259 @@
+package localstate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+const outboxBucket = "outbox"
+
+// Sender delivers the JSON-encoded state saved by the key
+type Sender func(ctx context.Context, key string, data []byte) error
+
+type entry struct {
+	Bucket    string
+	Key       string
+	Seq       uint64 // changed on every save: delivery of an older state must not ack a newer one
+	Attempts  int
+	LastError string
+	CreatedAt time.Time
+}
+
+func entryKey(bucket, key string) []byte {
+	return []byte(bucket + "\x00" + key)
+}
+
+func enqueue(tx *bolt.Tx, bucket, key string) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(outboxBucket))
+	if err != nil {
+		return errors.Wrap(err, "failed to create outbox bucket")
+	}
+
+	seq, err := b.NextSequence()
+	if err != nil {
+		return errors.Wrap(err, "failed to get outbox sequence")
+	}
+
+	return putEntry(b, &entry{
+		Bucket:    bucket,
+		Key:       key,
+		Seq:       seq,
+		CreatedAt: time.Now(),
+	})
+}
+
+func putEntry(b *bolt.Bucket, e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal outbox entry")
+	}
+
+	return b.Put(entryKey(e.Bucket, e.Key), data)
+}
+
+func getEntry(b *bolt.Bucket, k []byte) (*entry, error) {
+	data := b.Get(k)
+	if data == nil {
+		return nil, nil
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal outbox entry %q", k)
+	}
+
+	return &e, nil
+}
+
+// Outbox delivers saved states by senders registered for buckets until they are acknowledged:
+// an entry is removed from the outbox only after successful delivery or by Trim.
+// The bolt db is locked by one process: every worker has its own db and delivers only its states,
+// they are lost if the db file is removed before the delivery.
+type Outbox struct {
+	db *bolt.DB
+
+	sendersLock sync.RWMutex
+	senders     map[string]Sender
+
+	maxStates   int
+	maxStateAge time.Duration
+}
+
+func NewOutbox(db *bolt.DB) *Outbox {
+	return &Outbox{
+		db:          db,
+		senders:     map[string]Sender{},
+		maxStates:   defaultMaxStates,
+		maxStateAge: defaultMaxStateAge,
+	}
+}
+
+func (o *Outbox) Register(bucket string, s Sender) {
+	o.sendersLock.Lock()
+	defer o.sendersLock.Unlock()
+	o.senders[bucket] = s
+}
+
+func (o *Outbox) getSender(bucket string) Sender {
+	o.sendersLock.RLock()
+	defer o.sendersLock.RUnlock()
+	return o.senders[bucket]
+}
+
+// Store returns the store of states saving to the outbox
+func (o *Outbox) Store(bucket string) *Store {
+	return NewStore(o.db, bucket)
+}
+
+// Deliver sends the state saved by the key if it wasn't delivered yet
+func (o *Outbox) Deliver(ctx context.Context, bucket, key string) error {
+	var e *entry
+	err := o.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(outboxBucket))
+		if b == nil {
+			return nil
+		}
+
+		var err error
+		e, err = getEntry(b, entryKey(bucket, key))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if e == nil {
+		return nil // already delivered
+	}
+
+	return o.deliver(ctx, e)
+}
+
+func (o *Outbox) deliver(ctx context.Context, e *entry) error {
+	send := o.getSender(e.Bucket)
+	if send == nil {
+		return fmt.Errorf("no sender for bucket %s", e.Bucket)
+	}
+
+	data, err := getData(o.db, e.Bucket, e.Key)
+	if err != nil {
+		return err
+	}
+
+	var sendErr error
+	if data != nil {
+		sendErr = send(ctx, e.Key, data)
+	} // else the state was removed: nothing to deliver
+
+	err = o.db.Update(func(tx *bolt.Tx) error {
+		return ackEntry(tx, e, sendErr)
+	})
+	if sendErr != nil {
+		return errors.Wrapf(sendErr, "failed to deliver %s %s (attempt %d)", e.Bucket, e.Key, e.Attempts+1)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to update outbox")
+	}
+
+	return nil
+}
+
+func ackEntry(tx *bolt.Tx, e *entry, sendErr error) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(outboxBucket))
+	if err != nil {
+		return errors.Wrap(err, "failed to create outbox bucket")
+	}
+
+	k := entryKey(e.Bucket, e.Key)
+	cur, err := getEntry(b, k)
+	if err != nil {
+		return err
+	}
+
+	if cur == nil || cur.Seq != e.Seq {
+		return nil // a newer state was saved: it will be delivered separately
+	}
+
+	if sendErr == nil {
+		return b.Delete(k)
+	}
+
+	cur.Attempts++
+	cur.LastError = sendErr.Error()
+	return putEntry(b, cur)
+}
+
+func (o *Outbox) pendingEntries() ([]entry, error) {
+	var ret []entry
+	err := o.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(outboxBucket))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var e entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return errors.Wrapf(err, "failed to unmarshal outbox entry %q", k)
+			}
+
+			ret = append(ret, e)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read outbox")
+	}
+
+	return ret, nil
+}
+
+// DeliverPending tries to deliver all pending states and returns count of not delivered ones
+func (o *Outbox) DeliverPending(ctx context.Context) (int, error) {
+	entries, err := o.pendingEntries()
+	if err != nil {
+		return 0, err
+	}
+
+	failed := 0
+	for i := range entries {
+		if err = o.deliver(ctx, &entries[i]); err != nil {
+			analytics.Log(ctx).Warnf("Outbox: %s", err)
+			failed++
+		}
+	}
+
+	return failed, nil
+}
+
+// Run trims states and delivers pending ones every interval until the context is done
+func (o *Outbox) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := o.Trim(ctx, time.Now()); err != nil {
+			analytics.Log(ctx).Errorf("Outbox: can't trim states: %s", err)
+		}
+
+		failed, err := o.DeliverPending(ctx)
+		if err != nil {
+			analytics.Log(ctx).Errorf("Outbox: can't deliver pending states: %s", err)
+		} else if failed != 0 {
+			analytics.Log(ctx).Warnf("Outbox: %d states weren't delivered, will retry in %s", failed, interval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
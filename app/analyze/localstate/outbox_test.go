@@ -0,0 +1,181 @@
+package localstate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+type testState struct {
+	Status string
+}
+
+func newTestOutbox(t *testing.T) (*Outbox, func()) {
+	dir, err := ioutil.TempDir("", "localstate")
+	assert.NoError(t, err)
+
+	db, err := bolt.Open(filepath.Join(dir, "state.db"), 0600, nil)
+	assert.NoError(t, err)
+
+	return NewOutbox(db), func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestOutboxRetriesUntilDelivered(t *testing.T) {
+	o, cleanup := newTestOutbox(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var sent []string
+	var sendErr error
+	o.Register("states", func(ctx context.Context, key string, data []byte) error {
+		if sendErr != nil {
+			return sendErr
+		}
+		sent = append(sent, key+" "+string(data))
+		return nil
+	})
+
+	s := o.Store("states")
+	assert.NoError(t, s.Put("a/b/1", testState{Status: "processed"}))
+
+	sendErr = errors.New("api is down")
+	assert.Error(t, o.Deliver(ctx, "states", "a/b/1"))
+
+	failed, err := o.DeliverPending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, failed)
+
+	entries, err := o.pendingEntries()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, 2, entries[0].Attempts)
+	assert.Equal(t, "api is down", entries[0].LastError)
+
+	sendErr = nil
+	failed, err = o.DeliverPending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, failed)
+	assert.Equal(t, []string{`a/b/1 {"Status":"processed"}`}, sent)
+
+	entries, err = o.pendingEntries()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// delivered state is still available locally
+	var state testState
+	found, err := s.Get("a/b/1", &state)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "processed", state.Status)
+
+	found, err = s.Get("a/b/2", &state)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestOutboxKeepsStateSavedDuringDelivery(t *testing.T) {
+	o, cleanup := newTestOutbox(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	s := o.Store("states")
+
+	var sent []string
+	o.Register("states", func(ctx context.Context, key string, data []byte) error {
+		sent = append(sent, string(data))
+		if len(sent) == 1 {
+			// newer state is saved while the older one is being delivered
+			assert.NoError(t, s.Put(key, testState{Status: "processed"}))
+		}
+		return nil
+	})
+
+	assert.NoError(t, s.Put("a/b/1", testState{Status: "processing"}))
+	assert.NoError(t, o.Deliver(ctx, "states", "a/b/1"))
+
+	entries, err := o.pendingEntries()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	failed, err := o.DeliverPending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, failed)
+	assert.Equal(t, []string{`{"Status":"processing"}`, `{"Status":"processed"}`}, sent)
+}
+
+func TestOutboxTrim(t *testing.T) {
+	o, cleanup := newTestOutbox(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	o.maxStates = 2
+	o.Register("states", func(ctx context.Context, key string, data []byte) error {
+		return nil
+	})
+
+	s := o.Store("states")
+	for _, key := range []string{"a/b/1", "a/b/2", "a/b/3"} {
+		assert.NoError(t, s.Put(key, testState{Status: "processed"}))
+	}
+	assert.NoError(t, o.Deliver(ctx, "states", "a/b/3"))
+
+	// the oldest state over the max count is removed with its outbox entry
+	removed, err := o.Trim(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	var state testState
+	found, err := s.Get("a/b/1", &state)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	entries, err := o.pendingEntries()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "a/b/2", entries[0].Key)
+
+	// states older than the max age are removed
+	removed, err = o.Trim(ctx, time.Now().Add(o.maxStateAge))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	entries, err = o.pendingEntries()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestDurableStorage(t *testing.T) {
+	o, cleanup := newTestOutbox(t)
+	defer cleanup()
+
+	var sent []string
+	ds := NewDurableStorage(o, "states", func(ctx context.Context, owner, name, analysisID string, data []byte) error {
+		sent = append(sent, fmt.Sprintf("%s/%s/%s %s", owner, name, analysisID, data))
+		return nil
+	})
+
+	ctx := context.Background()
+	assert.NoError(t, ds.UpdateState(ctx, "owner", "name", "1", testState{Status: "processed"}))
+	assert.Equal(t, []string{`owner/name/1 {"Status":"processed"}`}, sent)
+
+	var state testState
+	found, err := ds.GetState("owner", "name", "1", &state)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "processed", state.Status)
+
+	found, err = ds.GetState("owner", "name", "2", &state)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
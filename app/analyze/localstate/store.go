@@ -0,0 +1,90 @@
+package localstate
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Store keeps JSON-encoded states in a bolt bucket. Every saved state is queued
+// to the outbox in the same transaction: it's delivered even after the worker restart.
+// States are removed by Outbox.Trim.
+type Store struct {
+	db     *bolt.DB
+	bucket string
+}
+
+func NewStore(db *bolt.DB, bucket string) *Store {
+	return &Store{
+		db:     db,
+		bucket: bucket,
+	}
+}
+
+func (s Store) Put(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal state")
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return s.put(tx, key, data)
+	})
+}
+
+func (s Store) put(tx *bolt.Tx, key string, data []byte) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(s.bucket))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create bucket %s", s.bucket)
+	}
+
+	if err = b.Put([]byte(key), data); err != nil {
+		return errors.Wrapf(err, "failed to save state %s", key)
+	}
+
+	if err = index(tx, s.bucket, key, time.Now()); err != nil {
+		return err
+	}
+
+	return enqueue(tx, s.bucket, key)
+}
+
+// Get decodes state to v and returns false if there is no state for the key
+func (s Store) Get(key string, v interface{}) (bool, error) {
+	data, err := getData(s.db, s.bucket, key)
+	if err != nil {
+		return false, err
+	}
+
+	if data == nil {
+		return false, nil
+	}
+
+	if err = json.Unmarshal(data, v); err != nil {
+		return false, errors.Wrapf(err, "failed to unmarshal state %s", key)
+	}
+
+	return true, nil
+}
+
+func getData(db *bolt.DB, bucket, key string) ([]byte, error) {
+	var data []byte
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+
+		if d := b.Get([]byte(key)); d != nil {
+			data = append([]byte{}, d...) // d is valid only inside the transaction
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read state %s", key)
+	}
+
+	return data, nil
+}
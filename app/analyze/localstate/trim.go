@@ -0,0 +1,132 @@
+package localstate
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+const savedAtBucket = "saved_at"
+
+// Default bounds of the db: states are needed locally only until they are delivered and analyses are done
+const (
+	defaultMaxStates   = 10000
+	defaultMaxStateAge = 7 * 24 * time.Hour
+)
+
+// savedState is an entry of the index of saved states by the time of the last save
+type savedState struct {
+	bucket  string
+	key     string
+	savedAt time.Time
+}
+
+func index(tx *bolt.Tx, bucket, key string, savedAt time.Time) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(savedAtBucket))
+	if err != nil {
+		return errors.Wrap(err, "failed to create saved states index bucket")
+	}
+
+	data, err := savedAt.MarshalText()
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal save time")
+	}
+
+	return b.Put(entryKey(bucket, key), data)
+}
+
+func listSavedStates(tx *bolt.Tx) ([]savedState, error) {
+	b := tx.Bucket([]byte(savedAtBucket))
+	if b == nil {
+		return nil, nil
+	}
+
+	var ret []savedState
+	err := b.ForEach(func(k, v []byte) error {
+		parts := bytes.SplitN(k, []byte{0}, 2)
+		if len(parts) != 2 {
+			return errors.Errorf("invalid saved state key %q", k)
+		}
+
+		s := savedState{
+			bucket: string(parts[0]),
+			key:    string(parts[1]),
+		}
+		if err := s.savedAt.UnmarshalText(v); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal save time of %q", k)
+		}
+
+		ret = append(ret, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// removeState removes the state with its outbox entry and returns true if it wasn't delivered
+func removeState(tx *bolt.Tx, s savedState) (bool, error) {
+	k := entryKey(s.bucket, s.key)
+	if err := tx.Bucket([]byte(savedAtBucket)).Delete(k); err != nil {
+		return false, err
+	}
+
+	if b := tx.Bucket([]byte(s.bucket)); b != nil {
+		if err := b.Delete([]byte(s.key)); err != nil {
+			return false, err
+		}
+	}
+
+	b := tx.Bucket([]byte(outboxBucket))
+	if b == nil || b.Get(k) == nil {
+		return false, nil
+	}
+
+	return true, b.Delete(k)
+}
+
+// Trim removes states saved more than max age ago and the oldest states over the max count:
+// the db doesn't grow if the API is down for a long time. Removed states which weren't delivered are lost.
+func (o *Outbox) Trim(ctx context.Context, now time.Time) (int, error) {
+	removed := 0
+	err := o.db.Update(func(tx *bolt.Tx) error {
+		states, err := listSavedStates(tx)
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(states, func(i, j int) bool {
+			return states[i].savedAt.After(states[j].savedAt)
+		})
+
+		for i, s := range states {
+			if i < o.maxStates && now.Sub(s.savedAt) < o.maxStateAge {
+				continue
+			}
+
+			undelivered, err := removeState(tx, s)
+			if err != nil {
+				return errors.Wrapf(err, "failed to remove state %s %s", s.bucket, s.key)
+			}
+			if undelivered {
+				analytics.Log(ctx).Errorf("Outbox: state %s %s saved at %s wasn't delivered, drop it",
+					s.bucket, s.key, s.savedAt)
+			}
+			removed++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to trim states")
+	}
+
+	return removed, nil
+}
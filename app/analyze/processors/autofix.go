@@ -0,0 +1,265 @@
+package processors
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/analyze/linters"
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+	"github.com/golangci/golangci-worker/app/lib/experiments"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	gh "github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+type autofixMode string
+
+const (
+	autofixModeNone        autofixMode = ""
+	autofixModePush        autofixMode = "push"         // push fixes to the pull request branch
+	autofixModePullRequest autofixMode = "pull_request" // open a separate pull request with fixes
+)
+
+const (
+	defaultAutofixAuthorName  = "golangci"
+	defaultAutofixAuthorEmail = "bot@golangci.com"
+)
+
+func getAutofixMode(ec *experiments.Checker, repo *github.Repo) autofixMode {
+	if !ec.IsActiveForAnalysis("autofix", repo, true) {
+		return autofixModeNone
+	}
+
+	if ec.IsActiveForAnalysis("autofix_push", repo, true) {
+		return autofixModePush
+	}
+
+	return autofixModePullRequest
+}
+
+func hasFixableIssues(issues []result.Issue) bool {
+	for _, i := range issues {
+		if i.Replacement != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+type autofixer struct {
+	mode    autofixMode
+	linters []linters.Linter
+	client  github.Client
+	context *github.Context
+
+	authorName, authorEmail string
+}
+
+func newAutofixer(mode autofixMode, lintersList []linters.Linter, client github.Client, c *github.Context) *autofixer {
+	ret := &autofixer{
+		mode:        mode,
+		linters:     lintersList,
+		client:      client,
+		context:     c,
+		authorName:  os.Getenv("AUTOFIX_AUTHOR_NAME"),
+		authorEmail: os.Getenv("AUTOFIX_AUTHOR_EMAIL"),
+	}
+	if ret.authorName == "" {
+		ret.authorName = defaultAutofixAuthorName
+	}
+	if ret.authorEmail == "" {
+		ret.authorEmail = defaultAutofixAuthorEmail
+	}
+
+	return ret
+}
+
+// fix runs fixers by fr in the working tree of exec and delivers the diff to GitHub.
+// It returns an url of the created commit or pull request or an empty string if there is nothing to fix.
+func (a autofixer) fix(ctx context.Context, pr *gh.PullRequest, fr linters.FixRunner,
+	exec executors.Executor) (string, error) {
+
+	headRepo := pr.GetHead().GetRepo().GetFullName()
+	if headRepo != "" && headRepo != a.context.Repo.FullName() {
+		return "", fmt.Errorf("can't push fixes to the fork %s", headRepo)
+	}
+
+	// stage changes made by the setup of the working tree (go.mod, go.sum, vendor, generated files):
+	// only changes made by fixers are delivered
+	if out, err := exec.Run(ctx, "git", "add", "--all"); err != nil {
+		return "", fmt.Errorf("can't stage changes of the working tree: %s, %s", err, out)
+	}
+
+	if err := fr.Fix(ctx, a.linters, exec); err != nil {
+		return "", errors.Wrap(err, "failed to fix issues")
+	}
+
+	files, err := a.readChangedFiles(ctx, exec)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read changed files")
+	}
+	if len(files) == 0 {
+		return "", nil
+	}
+
+	headSHA := pr.GetHead().GetSHA()
+	commit := &github.Commit{
+		ParentSHA:   headSHA,
+		Message:     "Fix issues found by golangci-lint",
+		AuthorName:  a.authorName,
+		AuthorEmail: a.authorEmail,
+		Files:       files,
+	}
+
+	if a.mode == autofixModePush {
+		var sha string
+		sha, err = a.client.PushCommit(ctx, a.context, pr.GetHead().GetRef(), commit)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to push fixes to pull request branch")
+		}
+
+		return fmt.Sprintf("https://github.com/%s/commit/%s", a.context.Repo.FullName(), sha), nil
+	}
+
+	// one branch per pull request: it's reset to the new head commit of the pull request
+	// to update the pull request with fixes instead of opening a new one for every commit
+	branch := fmt.Sprintf("golangci/fixes/%d", pr.GetNumber())
+	if err = a.client.ResetBranch(ctx, a.context, branch, headSHA); err != nil {
+		return "", errors.Wrap(err, "failed to reset branch for fixes")
+	}
+
+	if _, err = a.client.PushCommit(ctx, a.context, branch, commit); err != nil {
+		return "", errors.Wrap(err, "failed to push fixes")
+	}
+
+	base := pr.GetHead().GetRef()
+	fixesPR, err := a.client.GetOpenPullRequest(ctx, a.context, branch, base)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get pull request with fixes")
+	}
+	if fixesPR != nil {
+		return fixesPR.GetHTMLURL(), nil
+	}
+
+	fixesPR, err = a.client.CreatePullRequest(ctx, a.context, &gh.NewPullRequest{
+		Title: gh.String(fmt.Sprintf("golangci fixes for #%d", pr.GetNumber())),
+		Head:  gh.String(branch),
+		Base:  gh.String(base),
+		Body: gh.String(fmt.Sprintf("GolangCI fixed issues found in #%d. "+
+			"Merge this pull request to apply the fixes.", pr.GetNumber())),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create pull request with fixes")
+	}
+
+	return fixesPR.GetHTMLURL(), nil
+}
+
+// changedFile is a file changed in the working tree relatively to the index
+type changedFile struct {
+	path    string
+	mode    string // mode in the working tree
+	deleted bool
+}
+
+// readChangedFiles reads files changed by fixers: other changes were staged before running fixers
+func (a autofixer) readChangedFiles(ctx context.Context, exec executors.Executor) ([]github.FileChange, error) {
+	// make new files visible to git diff without staging their content
+	if out, err := exec.Run(ctx, "git", "add", "--intent-to-add", "--ignore-removal", "."); err != nil {
+		return nil, fmt.Errorf("can't add new files: %s, %s", err, out)
+	}
+
+	out, err := exec.Run(ctx, "git", "-c", "core.quotePath=false", "diff", "--raw", "--no-renames")
+	if err != nil {
+		return nil, fmt.Errorf("can't get changed files: %s, %s", err, out)
+	}
+
+	changedFiles, err := parseChangedFiles(out)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []github.FileChange
+	for _, f := range changedFiles {
+		if f.deleted {
+			ret = append(ret, github.FileChange{
+				Path:    f.path,
+				Deleted: true,
+			})
+			continue
+		}
+
+		if f.mode != github.RegularFileMode && f.mode != github.ExecutableFileMode {
+			analytics.Log(ctx).Warnf("Autofix: skip changed file %s with git mode %s", f.path, f.mode)
+			continue
+		}
+
+		content, readErr := readFile(ctx, exec, f.path)
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		ret = append(ret, github.FileChange{
+			Path:    f.path,
+			Content: content,
+			Mode:    f.mode,
+		})
+	}
+
+	return ret, nil
+}
+
+func readFile(ctx context.Context, exec executors.Executor, path string) (string, error) {
+	// read through base64 to preserve file content exactly: executors split output by lines
+	encoded, err := exec.Run(ctx, "base64", path)
+	if err != nil {
+		return "", fmt.Errorf("can't read file %s: %s, %s", path, err, encoded)
+	}
+
+	content, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to decode content of file %s", path)
+	}
+
+	return string(content), nil
+}
+
+// parseChangedFiles parses output of git diff --raw: ":100644 100755 1234567 0000000 M\tpath"
+func parseChangedFiles(out string) ([]changedFile, error) {
+	var ret []changedFile
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		tabParts := strings.SplitN(line, "\t", 2)
+		fields := strings.Fields(tabParts[0])
+		if len(tabParts) != 2 || len(fields) != 5 || !strings.HasPrefix(fields[0], ":") {
+			return nil, fmt.Errorf("invalid git diff line %q", line)
+		}
+
+		path := tabParts[1]
+		if strings.HasPrefix(path, `"`) { // paths with special chars are quoted
+			unquoted, err := strconv.Unquote(path)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted path in git diff line %q: %s", line, err)
+			}
+			path = unquoted
+		}
+
+		ret = append(ret, changedFile{
+			path:    path,
+			mode:    fields[1],
+			deleted: fields[4] == "D",
+		})
+	}
+
+	return ret, nil
+}
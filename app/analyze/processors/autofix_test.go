@@ -0,0 +1,137 @@
+package processors
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golangci/golangci-worker/app/analyze/linters"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	gh "github.com/google/go-github/github"
+	"github.com/stretchr/testify/assert"
+)
+
+type testFixer struct {
+	linters.Linter
+	fixed bool
+}
+
+func (f *testFixer) Name() string {
+	return "fixer"
+}
+
+func (f *testFixer) Fix(ctx context.Context, exec executors.Executor) error {
+	f.fixed = true
+	return nil
+}
+
+// rootFixRunner runs fixers only in the repo root
+var rootFixRunner = linters.NewModulesRunner(nil, nil, "", "")
+
+func TestParseChangedFiles(t *testing.T) {
+	out := ":100644 100644 7898192 0000000 M\ta.go\n" +
+		":100755 100755 6178079 0000000 M\tscripts/b.sh\n" +
+		":100644 000000 f2ad6c7 0000000 D\tc.go\n" +
+		":000000 100644 0000000 0000000 A\tnew file.go\n" +
+		":100644 100644 7898192 0000000 M\t\"tab\\tfile.go\"\n"
+
+	files, err := parseChangedFiles(out)
+	assert.NoError(t, err)
+	assert.Equal(t, []changedFile{
+		{path: "a.go", mode: "100644"},
+		{path: "scripts/b.sh", mode: "100755"},
+		{path: "c.go", mode: "000000", deleted: true},
+		{path: "new file.go", mode: "100644"},
+		{path: "tab\tfile.go", mode: "100644"},
+	}, files)
+
+	_, err = parseChangedFiles("M\ta.go")
+	assert.Error(t, err)
+}
+
+func TestAutofixPushesOnlyFilesChangedByFixers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fixer := &testFixer{}
+	exec := executors.NewMockExecutor(ctrl)
+	exec.EXPECT().Run(any, "git", "add", "--all").DoAndReturn(func(context.Context, string, ...string) (string, error) {
+		assert.False(t, fixer.fixed, "changes of setup are staged before fixing")
+		return "", nil
+	})
+	exec.EXPECT().Run(any, "git", "add", "--intent-to-add", "--ignore-removal", ".").Return("", nil)
+	exec.EXPECT().Run(any, "git", "-c", "core.quotePath=false", "diff", "--raw", "--no-renames").
+		Return(":100644 100644 7898192 0000000 M\tmain.go\n"+
+			":100755 100755 6178079 0000000 M\trun.sh\n"+
+			":100644 000000 f2ad6c7 0000000 D\tunused.go\n"+
+			":120000 120000 f2ad6c7 0000000 M\tlink\n", nil)
+	exec.EXPECT().Run(any, "base64", "main.go").
+		Return(base64.StdEncoding.EncodeToString([]byte("package main\n")), nil)
+	exec.EXPECT().Run(any, "base64", "run.sh").
+		Return(base64.StdEncoding.EncodeToString([]byte("#!/bin/sh\n")), nil)
+
+	client := github.NewMockClient(ctrl)
+	client.EXPECT().PushCommit(any, any, testBranch, any).
+		DoAndReturn(func(_ context.Context, _ *github.Context, _ string, commit *github.Commit) (string, error) {
+			assert.Equal(t, testSHA, commit.ParentSHA)
+			assert.Equal(t, []github.FileChange{
+				{Path: "main.go", Content: "package main\n", Mode: github.RegularFileMode},
+				{Path: "run.sh", Content: "#!/bin/sh\n", Mode: github.ExecutableFileMode},
+				{Path: "unused.go", Deleted: true},
+			}, commit.Files)
+			return "fixSHA", nil
+		})
+
+	a := newAutofixer(autofixModePush, []linters.Linter{fixer}, client, &github.FakeContext)
+	url, err := a.fix(testCtx, testPR, rootFixRunner, exec)
+	assert.NoError(t, err)
+	assert.True(t, fixer.fixed)
+	assert.Equal(t, "https://github.com/owner/name/commit/fixSHA", url)
+}
+
+func TestAutofixWithoutChanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exec := executors.NewMockExecutor(ctrl)
+	exec.EXPECT().Run(any, "git", any).Return("", nil).AnyTimes()
+	exec.EXPECT().Run(any, "git", any, any).Return("", nil).AnyTimes()
+	exec.EXPECT().Run(any, "git", any, any, any).Return("", nil).AnyTimes()
+	exec.EXPECT().Run(any, "git", any, any, any, any, any).Return("", nil).AnyTimes()
+
+	a := newAutofixer(autofixModePush, []linters.Linter{&testFixer{}}, github.NewMockClient(ctrl), &github.FakeContext)
+	url, err := a.fix(testCtx, testPR, rootFixRunner, exec)
+	assert.NoError(t, err)
+	assert.Empty(t, url, "nothing is pushed")
+}
+
+func TestAutofixUpdatesPullRequestWithFixes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exec := executors.NewMockExecutor(ctrl)
+	exec.EXPECT().Run(any, "git", "add", "--all").Return("", nil).AnyTimes()
+	exec.EXPECT().Run(any, "git", "add", "--intent-to-add", "--ignore-removal", ".").Return("", nil).AnyTimes()
+	exec.EXPECT().Run(any, "git", "-c", "core.quotePath=false", "diff", "--raw", "--no-renames").
+		Return(":100644 000000 f2ad6c7 0000000 D\tunused.go\n", nil).AnyTimes()
+
+	const fixesBranch = "golangci/fixes/7"
+	fixesPR := &gh.PullRequest{HTMLURL: gh.String("https://github.com/owner/name/pull/8")}
+	client := github.NewMockClient(ctrl)
+	for i := 0; i < 2; i++ { // the same head commit is analyzed again
+		client.EXPECT().ResetBranch(any, any, fixesBranch, testSHA).Return(nil)
+		client.EXPECT().PushCommit(any, any, fixesBranch, any).Return("fixSHA", nil)
+	}
+	client.EXPECT().GetOpenPullRequest(any, any, fixesBranch, testBranch).Return(nil, nil)
+	client.EXPECT().CreatePullRequest(any, any, any).Return(fixesPR, nil)
+	client.EXPECT().GetOpenPullRequest(any, any, fixesBranch, testBranch).Return(fixesPR, nil)
+
+	a := newAutofixer(autofixModePullRequest, []linters.Linter{&testFixer{}}, client, &github.FakeContext)
+	for i := 0; i < 2; i++ {
+		url, err := a.fix(testCtx, testPR, rootFixRunner, exec)
+		assert.NoError(t, err)
+		assert.Equal(t, fixesPR.GetHTMLURL(), url)
+	}
+}
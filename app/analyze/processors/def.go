@@ -7,6 +7,7 @@ const (
 	statusProcessing  = "processing"
 	statusProcessed   = "processed"
 	statusNotFound    = "not_found"
+	statusSuperseded  = "processed/superseded"
 
 	noGoFilesToAnalyzeMessage = "No Go files to analyze"
 	noGoFilesToAnalyzeErr     = "no go files to analyze"
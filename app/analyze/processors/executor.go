@@ -10,6 +10,7 @@ import (
 	"github.com/golangci/golangci-worker/app/lib/executors"
 	"github.com/golangci/golangci-worker/app/lib/experiments"
 	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/golangci/golangci-worker/app/lib/goutils/goproxy"
 	"github.com/pkg/errors"
 )
 
@@ -46,3 +47,23 @@ func makeExecutor(ctx context.Context, repo *github.Repo, forPull bool, log logu
 
 	return s, nil
 }
+
+// withGoProxy makes go commands of the analysis fetch modules through the go proxy of the worker:
+// private modules are fetched with credentials of the repo owner
+func withGoProxy(exec executors.Executor, analysisGUID, repoOwner string) (executors.Executor, *goproxy.Session, error) {
+	if !goproxy.IsConfigured() {
+		return exec, nil, nil
+	}
+
+	proxy, err := goproxy.Get()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "can't get go proxy")
+	}
+
+	sess, err := proxy.StartSession(analysisGUID, repoOwner)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "can't start go proxy session")
+	}
+
+	return sess.WithEnv(exec), sess, nil
+}
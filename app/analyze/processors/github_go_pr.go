@@ -12,16 +12,20 @@ import (
 	"github.com/golangci/golangci-api/pkg/goenv/ensuredeps"
 	goenvresult "github.com/golangci/golangci-api/pkg/goenv/result"
 	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/analyze/cancellation"
 	"github.com/golangci/golangci-worker/app/analyze/linters"
 	"github.com/golangci/golangci-worker/app/analyze/linters/golinters"
 	"github.com/golangci/golangci-worker/app/analyze/linters/result"
 	"github.com/golangci/golangci-worker/app/analyze/prstate"
 	"github.com/golangci/golangci-worker/app/analyze/repoinfo"
 	"github.com/golangci/golangci-worker/app/analyze/reporters"
+	"github.com/golangci/golangci-worker/app/analyze/resultcache"
+	"github.com/golangci/golangci-worker/app/analyze/templates"
 	"github.com/golangci/golangci-worker/app/lib/errorutils"
 	"github.com/golangci/golangci-worker/app/lib/executors"
 	"github.com/golangci/golangci-worker/app/lib/fetchers"
 	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/golangci/golangci-worker/app/lib/goutils/goproxy"
 	"github.com/golangci/golangci-worker/app/lib/goutils/workspaces"
 	"github.com/golangci/golangci-worker/app/lib/httputils"
 	gh "github.com/google/go-github/github"
@@ -33,6 +37,9 @@ import (
 
 const (
 	patchPath = "../changes.patch"
+
+	// supersedeCheckInterval is how often running analysis checks that it wasn't superseded by a newer one
+	supersedeCheckInterval = 10 * time.Second
 )
 
 type githubGoPRConfig struct {
@@ -44,6 +51,8 @@ type githubGoPRConfig struct {
 	exec        executors.Executor
 	client      github.Client
 	state       prstate.Storage
+	runs        cancellation.Registry
+	resultCache resultcache.Store // nil if results aren't cached
 }
 
 type githubGoPR struct {
@@ -52,14 +61,25 @@ type githubGoPR struct {
 
 	context *github.Context
 	gw      *workspaces.Go
+	patch   string
 
 	resLog *goenvresult.Log
 
+	run    *cancellation.Run // nil if the run wasn't registered
+	runKey string
+
+	proxySession *goproxy.Session // nil if the go proxy isn't configured
+
+	cache     *resultcache.AnalysisCache // nil if the result isn't cached
+	cachedRes *result.Result             // nil if there is no cached result
+
 	githubGoPRConfig
 	resultCollector
 
 	newWorkspaceInstaller workspaces.Installer
 	ec                    *experiments.Checker
+	autofixer             *autofixer
+	templates             *templates.Templates
 }
 
 //nolint:gocyclo
@@ -68,14 +88,6 @@ func newGithubGoPR(ctx context.Context, c *github.Context, cfg githubGoPRConfig,
 		cfg.client = github.NewMyClient()
 	}
 
-	if cfg.exec == nil {
-		var err error
-		cfg.exec, err = makeExecutor(ctx, &c.Repo, true, nil, nil)
-		if err != nil {
-			return nil, fmt.Errorf("can't make executor: %s", err)
-		}
-	}
-
 	if cfg.repoFetcher == nil {
 		cfg.repoFetcher = fetchers.NewGit()
 	}
@@ -99,7 +111,11 @@ func newGithubGoPR(ctx context.Context, c *github.Context, cfg githubGoPRConfig,
 
 	if cfg.reporter == nil {
 		includeLinterName := ec.IsActiveForAnalysis("include_linter_name_in_comment", &c.Repo, true)
-		cfg.reporter = reporters.NewGithubReviewer(c, cfg.client, includeLinterName)
+		reviewer := reporters.NewGithubReviewer(c, cfg.client, includeLinterName, getReviewEventsMode(ec, &c.Repo))
+		targets := []reporters.Target{
+			{Name: "github", Reporter: reviewer, Required: true, Retries: 1},
+		}
+		cfg.reporter = reporters.NewComposite(append(targets, reporters.NewNotifiersFromEnv()...)...)
 	}
 
 	if cfg.runner == nil {
@@ -107,21 +123,64 @@ func newGithubGoPR(ctx context.Context, c *github.Context, cfg githubGoPRConfig,
 	}
 
 	if cfg.state == nil {
-		cfg.state = prstate.NewAPIStorage(httputils.GrequestsClient{})
+		state, err := prstate.NewStorageFromEnv(httputils.GrequestsClient{})
+		if err != nil {
+			return nil, fmt.Errorf("can't make state storage: %s", err)
+		}
+		cfg.state = state
+	}
+
+	if cfg.runs == nil {
+		cfg.runs = cancellation.NewRegistryFromEnv()
+	}
+
+	if cfg.resultCache == nil {
+		cache, err := resultcache.NewStoreFromEnv(log)
+		if err != nil {
+			return nil, fmt.Errorf("can't make result cache: %s", err)
+		}
+		cfg.resultCache = cache
 	}
 
+	madeExec := cfg.exec == nil
+	if madeExec {
+		var err error
+		cfg.exec, err = makeExecutor(ctx, &c.Repo, true, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("can't make executor: %s", err)
+		}
+	}
+
+	exec, proxySession, err := withGoProxy(cfg.exec, analysisGUID, c.Repo.Owner)
+	if err != nil {
+		if madeExec {
+			cfg.exec.Clean()
+		}
+		return nil, err
+	}
+	cfg.exec = exec
+
 	var wi workspaces.Installer
 
-	if ec.IsActiveForAnalysis("new_pr_prepare", &c.Repo, true) {
+	if ec.IsActiveForAnalysis("go_modules_installer", &c.Repo, true) {
+		wi = workspaces.NewGoModules(cfg.exec, log, cfg.repoFetcher, workspaces.NewGo2(cfg.exec, log, cfg.repoFetcher))
+	} else if ec.IsActiveForAnalysis("new_pr_prepare", &c.Repo, true) {
 		wi = workspaces.NewGo2(cfg.exec, log, cfg.repoFetcher)
 	}
 
+	var af *autofixer
+	if mode := getAutofixMode(ec, &c.Repo); mode != autofixModeNone {
+		af = newAutofixer(mode, cfg.linters, cfg.client, c)
+	}
+
 	return &githubGoPR{
 		context:               c,
 		githubGoPRConfig:      cfg,
 		analysisGUID:          analysisGUID,
+		proxySession:          proxySession,
 		newWorkspaceInstaller: wi,
 		ec:                    ec,
+		autofixer:             af,
 	}, nil
 }
 
@@ -148,6 +207,7 @@ func (g githubGoPR) getRepo() *fetchers.Repo {
 		CloneURL: g.context.GetCloneURL(g.pr.GetHead().GetRepo()),
 		Ref:      g.pr.GetHead().GetRef(),
 		FullPath: fmt.Sprintf("github.com/%s/%s", g.context.Repo.Owner, g.context.Repo.Name),
+		Owner:    g.context.Repo.Owner,
 	}
 }
 
@@ -203,22 +263,17 @@ func (g *githubGoPR) prepareRepo(ctx context.Context) error {
 }
 
 func (g githubGoPR) updateAnalysisState(ctx context.Context, res *result.Result, status github.Status, publicError string) {
-	resJSON := &resultJSON{
-		Version: 1,
-		WorkerRes: workerRes{
-			Timings:  g.timings,
-			Warnings: g.warnings,
-			Error:    publicError,
-		},
-	}
+	g.saveAnalysisState(ctx, res, "processed/"+string(status), publicError)
+}
 
+func (g githubGoPR) saveAnalysisState(ctx context.Context, res *result.Result, stateStatus, publicError string) {
+	resJSON := g.buildResultDocument(res, publicError, buildPrepareLog(g.resLog, g.buildSecrets()))
 	issuesCount := 0
 	if res != nil {
-		resJSON.GolangciLintRes = res.ResultJSON
 		issuesCount = len(res.Issues)
 	}
 	s := &prstate.State{
-		Status:              "processed/" + string(status),
+		Status:              stateStatus,
 		ReportedIssuesCount: issuesCount,
 		ResultJSON:          resJSON,
 	}
@@ -228,15 +283,66 @@ func (g githubGoPR) updateAnalysisState(ctx context.Context, res *result.Result,
 	}
 }
 
-func getGithubStatusForIssues(issues []result.Issue) (github.Status, string) {
-	switch len(issues) {
-	case 0:
-		return github.StatusSuccess, "No issues found!"
-	case 1:
-		return github.StatusFailure, "1 issue found"
-	default:
-		return github.StatusFailure, fmt.Sprintf("%d issues found", len(issues))
+func getIssuesCountsBySeverity(issues []result.Issue) []templates.CountData {
+	counts := map[result.Severity]int{}
+	for _, i := range issues {
+		counts[i.GetSeverity()]++
+	}
+
+	var ret []templates.CountData
+	for _, sev := range result.Severities {
+		if counts[sev] != 0 {
+			ret = append(ret, templates.CountData{Name: string(sev), Count: counts[sev]})
+		}
+	}
+
+	return ret
+}
+
+func getReviewEventsMode(ec *experiments.Checker, repo *github.Repo) reporters.ReviewEventsMode {
+	if !ec.IsActiveForAnalysis("review_request_changes", repo, true) {
+		return reporters.ReviewEventsModeComment
 	}
+
+	if ec.IsActiveForAnalysis("review_approve", repo, true) {
+		return reporters.ReviewEventsModeApprove
+	}
+
+	return reporters.ReviewEventsModeDismiss
+}
+
+func getGithubStatusForIssues(t *templates.Templates, issues []result.Issue,
+	failSeverity result.Severity) (github.Status, string, error) {
+	data := &templates.StatusData{
+		Total:    len(issues),
+		Blocking: result.HasBlockingIssues(issues, failSeverity),
+		Counts:   getIssuesCountsBySeverity(issues),
+	}
+	desc, err := t.RenderStatus(data)
+	if err != nil {
+		return "", "", err
+	}
+
+	if data.Blocking {
+		return github.StatusFailure, desc, nil
+	}
+
+	return github.StatusSuccess, desc, nil
+}
+
+func (g githubGoPR) getGithubStatusForIssues(ctx context.Context, res *result.Result) (github.Status, string) {
+	t := g.templates
+	if t == nil {
+		t = templates.Default()
+	}
+
+	status, desc, err := getGithubStatusForIssues(t, res.Issues, res.FailSeverity)
+	if err != nil {
+		analytics.Log(ctx).Warnf("Can't render commit status, use the default template: %s", err)
+		status, desc, _ = getGithubStatusForIssues(templates.Default(), res.Issues, res.FailSeverity)
+	}
+
+	return status, desc
 }
 
 func (g githubGoPR) buildSecrets() map[string]string {
@@ -245,7 +351,7 @@ func (g githubGoPR) buildSecrets() map[string]string {
 		g.context.GithubAccessToken: hidden,
 		g.analysisGUID:              hidden,
 	}
-	if g.newWorkspaceInstaller == nil {
+	if g.gw != nil {
 		ret[g.gw.Gopath()] = "$GOPATH"
 	}
 
@@ -267,13 +373,17 @@ func (g githubGoPR) buildSecrets() map[string]string {
 func (g *githubGoPR) processWithGuaranteedGithubStatus(ctx context.Context) error {
 	res, err := g.work(ctx)
 	analytics.Log(ctx).Infof("timings: %s", g.timings)
+	g.addFetchedModules(g.proxySession.Finish(ctx))
 
+	reportCtx := ctx
 	ctx = context.Background() // no timeout for state and status saving: it must be durable
 
 	var status github.Status
 	var statusDesc, publicError string
+	ignored := false
 	if err != nil {
 		if serr, ok := err.(*IgnoredError); ok {
+			ignored = true
 			status, statusDesc = serr.Status, serr.StatusDesc
 			if !serr.IsRecoverable {
 				err = nil
@@ -298,7 +408,25 @@ func (g *githubGoPR) processWithGuaranteedGithubStatus(ctx context.Context) erro
 			publicError = statusDesc
 		}
 	} else {
-		status, statusDesc = getGithubStatusForIssues(res.Issues)
+		status, statusDesc = g.getGithubStatusForIssues(ctx, res)
+	}
+
+	if g.isSuperseded(ctx) {
+		// don't overwrite review and commit status of the newer head commit
+		g.publicWarn("process", "Analysis was superseded by a newer analysis of the pull request")
+		g.saveAnalysisState(ctx, res, statusSuperseded, publicError)
+		return nil
+	}
+
+	if !ignored {
+		if rerr := g.report(reportCtx, res, status, statusDesc, publicError); rerr != nil {
+			status, statusDesc = github.StatusError, "can't send pull request comments to github"
+			publicError = statusDesc
+			err = &errorutils.InternalError{
+				PublicDesc:  statusDesc,
+				PrivateDesc: fmt.Sprintf("can't send pull request comments to github: %s", rerr),
+			}
+		}
 	}
 
 	// update of state must be before commit status update: user can open details link before: race condition
@@ -308,6 +436,57 @@ func (g *githubGoPR) processWithGuaranteedGithubStatus(ctx context.Context) erro
 	return err
 }
 
+func (g *githubGoPR) report(ctx context.Context, res *result.Result, status github.Status,
+	statusDesc, publicError string) error {
+	a := &reporters.Analysis{
+		GUID:              g.analysisGUID,
+		Repo:              g.context.Repo,
+		PullRequestNumber: g.pr.GetNumber(),
+		CommitSHA:         g.pr.GetHead().GetSHA(),
+		Patch:             g.patch,
+		Status:            string(status),
+		StatusDesc:        statusDesc,
+		Error:             publicError,
+		DetailsURL:        g.getDetailsURL(),
+		Timings:           toReportersTimings(g.timings),
+		Result:            res,
+		Templates:         g.templates,
+	}
+	return reportAnalysis(ctx, g.reporter, a, &g.resultCollector)
+}
+
+// loadTemplates builds templates of the repo: the deployment config is checked at the worker startup,
+// an invalid repo config is a bad input
+func (g githubGoPR) loadTemplates(ctx context.Context) (*templates.Templates, error) {
+	deploymentCfg, err := templates.GetDeploymentConfig()
+	if err != nil {
+		return nil, &errorutils.InternalError{
+			PublicDesc:  "invalid templates config of the service",
+			PrivateDesc: fmt.Sprintf("invalid deployment templates config: %s", err),
+		}
+	}
+
+	repoCfg, err := g.loadRepoTemplatesConfig(ctx)
+	if err != nil {
+		analytics.Log(ctx).Warnf("Can't load repo templates config, use the default one: %s", err)
+	}
+
+	t, err := templates.New(deploymentCfg, repoCfg)
+	if err != nil {
+		return nil, &errorutils.BadInputError{
+			PublicDesc: fmt.Sprintf("invalid templates config: %s", err),
+		}
+	}
+
+	return t, nil
+}
+
+// loadRepoTemplatesConfig reads templates from the base commit of the pull request: templates of the head commit
+// are set by the author of the pull request, e.g. of a fork, and the text of the service is set by the repo
+func (g githubGoPR) loadRepoTemplatesConfig(ctx context.Context) (*templates.Config, error) {
+	return templates.FetchRepoConfig(ctx, g.client, g.context, g.pr.GetBase().GetSHA())
+}
+
 func (g *githubGoPR) work(ctx context.Context) (res *result.Result, err error) {
 	defer func() {
 		if rerr := recover(); rerr != nil {
@@ -330,17 +509,22 @@ func (g *githubGoPR) work(ctx context.Context) (res *result.Result, err error) {
 		}
 	}
 
-	if err = g.prepareRepo(ctx); err != nil {
+	if g.needsRepo() {
+		if err = g.prepareRepo(ctx); err != nil {
+			return nil, err // don't wrap error, need to save it's type
+		}
+	}
+
+	if g.templates, err = g.loadTemplates(ctx); err != nil {
 		return nil, err // don't wrap error, need to save it's type
 	}
 
-	g.trackTiming("Analysis", func() {
-		res, err = g.runner.Run(ctx, g.linters, g.exec)
-	})
-	if err != nil {
+	if res, err = g.runLinters(ctx); err != nil {
 		return nil, err // don't wrap error, need to save it's type
 	}
 
+	g.addModuleResults(res, g.buildSecrets())
+
 	issues := res.Issues
 	analytics.SaveEventProp(ctx, analytics.EventPRChecked, "reportedIssues", len(issues))
 
@@ -350,22 +534,164 @@ func (g *githubGoPR) work(ctx context.Context) (res *result.Result, err error) {
 		analytics.Log(ctx).Infof("Linters found %d issues: %+v", len(issues), issues)
 	}
 
-	if err = g.reporter.Report(ctx, g.pr.GetHead().GetSHA(), issues); err != nil {
-		return nil, &errorutils.InternalError{
-			PublicDesc:  "can't send pull request comments to github",
-			PrivateDesc: fmt.Sprintf("can't send pull request comments to github: %s", err),
-		}
+	if g.autofixer != nil && hasFixableIssues(issues) {
+		g.trackTiming("Autofix", func() {
+			g.autofix(ctx)
+		})
+	}
+
+	return res, nil
+}
+
+// runLinters returns the cached result or runs linters and caches their result
+func (g *githubGoPR) runLinters(ctx context.Context) (*result.Result, error) {
+	if g.cachedRes != nil {
+		return g.cachedRes, nil
+	}
+
+	runner := withModulesRunner(g.runner, g.patch, patchPath)
+	var res *result.Result
+	var err error
+	g.trackTiming("Analysis", func() {
+		res, err = runner.Run(ctx, g.linters, g.exec)
+	})
+	if err != nil {
+		return nil, err // don't wrap error, need to save it's type
 	}
 
+	g.cache.Put(ctx, g.exec, res)
 	return res, nil
 }
 
+// needsRepo returns false if the cached result is reported without fetching the repo:
+// autofix needs the repo to fix issues
+func (g githubGoPR) needsRepo() bool {
+	return g.cachedRes == nil || g.autofixer != nil && hasFixableIssues(g.cachedRes.Issues)
+}
+
+func (g *githubGoPR) autofix(ctx context.Context) {
+	if g.isSuperseded(ctx) {
+		// fixes of the outdated head commit would conflict with the newer analysis
+		analytics.Log(ctx).Infof("Analysis is superseded, don't deliver fixes of issues")
+		return
+	}
+
+	// fix every module with its patch like linters are run
+	fr := withModulesRunner(g.runner, g.patch, patchPath)
+	url, err := g.autofixer.fix(ctx, g.pr, fr, g.exec)
+	if err != nil {
+		// autofix is optional: don't fail the analysis
+		g.publicWarn("autofix", "Can't deliver fixes of issues to GitHub")
+		analytics.Log(ctx).Warnf("Autofix failed: %s", err)
+		return
+	}
+
+	if url != "" {
+		analytics.Log(ctx).Infof("Delivered fixes of issues: %s", url)
+	}
+}
+
+func (g githubGoPR) getDetailsURL() string {
+	c := g.context
+	return fmt.Sprintf("%s/r/github.com/%s/%s/pulls/%d",
+		os.Getenv("WEB_ROOT"), c.Repo.Owner, c.Repo.Name, g.pr.GetNumber())
+}
+
+// setupWorkspace prepares the executor for fetching of the repo: it returns false if the analysis is stopped
+func (g *githubGoPR) setupWorkspace(ctx context.Context) (bool, error) {
+	if g.newWorkspaceInstaller == nil {
+		g.gw = workspaces.NewGo(g.exec, g.infoFetcher)
+		if err := g.gw.Setup(ctx, g.getRepo(), "github.com", g.context.Repo.Owner, g.context.Repo.Name); err != nil {
+			publicError := fmt.Sprintf("failed to setup workspace: %s", err)
+			publicError = escapeErrorText(publicError, g.buildSecrets())
+			g.updateAnalysisState(ctx, nil, github.StatusError, publicError)
+			g.setCommitStatus(ctx, github.StatusError, "failed to setup")
+
+			return false, fmt.Errorf("can't setup go workspace: %s", err)
+		}
+		g.exec = g.gw.Executor()
+		return true, nil
+	}
+
+	startedAt := time.Now()
+	exec, resLog, err := g.newWorkspaceInstaller.Setup(ctx, g.getRepo(), "github.com", g.context.Repo.Owner, g.context.Repo.Name)
+	if err != nil {
+		publicError := fmt.Sprintf("failed to setup workspace: %s", err)
+		publicError = escapeErrorText(publicError, g.buildSecrets())
+		g.updateAnalysisState(ctx, nil, github.StatusError, publicError)
+		g.setCommitStatus(ctx, github.StatusError, "failed to setup")
+
+		return false, nil
+	}
+	g.exec = exec
+	g.resLog = resLog
+	g.addTimingFrom("Prepare", startedAt)
+	return true, nil
+}
+
+// startRun registers the run of the head commit and returns false if a run of a later queued analysis is registered.
+// Identical analyses of the same head commit are skipped by the deduper before.
+func (g *githubGoPR) startRun(ctx context.Context, queuedAt time.Time) bool {
+	run := &cancellation.Run{
+		AnalysisGUID: g.analysisGUID,
+		HeadSHA:      g.pr.GetHead().GetSHA(),
+		QueuedAt:     queuedAt,
+	}
+	key := cancellation.PullRequestKey(&g.context.Repo, g.pr.GetNumber())
+
+	by, err := g.runs.Start(ctx, key, run)
+	if err != nil {
+		analytics.Log(ctx).Warnf("Can't register analysis run, it can't be canceled by newer ones: %s", err)
+		return true
+	}
+
+	if by != nil {
+		analytics.Log(ctx).Infof("Analysis of %s is stale: analysis %s of %s queued at %s is started, skip it",
+			run.HeadSHA, by.AnalysisGUID, by.HeadSHA, by.QueuedAt)
+		g.publicWarn("process", "Pull request is already analyzed by a newer analysis, skip analysis")
+		g.saveAnalysisState(ctx, nil, statusSuperseded, "")
+		return false
+	}
+
+	g.run, g.runKey = run, key
+	return true
+}
+
+// finishRun ends the registered run: a succeeded run keeps blocking stale analyses for a day
+func (g githubGoPR) finishRun(ctx context.Context, succeeded bool) {
+	if g.run == nil {
+		return
+	}
+
+	if err := g.runs.Finish(ctx, g.runKey, g.run, succeeded); err != nil {
+		analytics.Log(ctx).Warnf("Can't finish analysis run: %s", err)
+	}
+}
+
+// isSuperseded returns true if a newer run of the pull request was started
+func (g githubGoPR) isSuperseded(ctx context.Context) bool {
+	if g.run == nil {
+		return false
+	}
+
+	latest, err := g.runs.Latest(ctx, g.runKey)
+	if err != nil {
+		analytics.Log(ctx).Warnf("Can't get the latest analysis run: %s", err)
+		return false
+	}
+
+	return cancellation.IsSuperseded(g.run, latest)
+}
+
 func (g githubGoPR) setCommitStatus(ctx context.Context, status github.Status, desc string) {
+	if g.isSuperseded(ctx) {
+		analytics.Log(ctx).Infof("Analysis is superseded, don't set commit status to %s", status)
+		return
+	}
+
 	var url string
 	if status == github.StatusFailure || status == github.StatusSuccess || status == github.StatusError {
-		c := g.context
-		url = fmt.Sprintf("%s/r/github.com/%s/%s/pulls/%d",
-			os.Getenv("WEB_ROOT"), c.Repo.Owner, c.Repo.Name, g.pr.GetNumber())
+		url = g.getDetailsURL()
 	}
 	err := g.client.SetCommitStatus(ctx, g.context, g.pr.GetHead().GetSHA(), status, desc, url)
 	if err != nil {
@@ -375,10 +701,12 @@ func (g githubGoPR) setCommitStatus(ctx context.Context, status github.Status, d
 }
 
 //nolint:gocyclo
-func (g githubGoPR) Process(ctx context.Context) error {
-	defer g.exec.Clean()
+func (g githubGoPR) Process(ctx context.Context) (err error) {
+	defer func() {
+		g.exec.Clean() // the executor of the workspace after its setup
+	}()
+	defer g.proxySession.Finish(ctx)
 
-	var err error
 	g.pr, err = g.client.GetPullRequest(ctx, g.context)
 	if err != nil {
 		if !github.IsRecoverableError(err) {
@@ -387,36 +715,32 @@ func (g githubGoPR) Process(ctx context.Context) error {
 		return fmt.Errorf("can't get pull request: %s", err)
 	}
 
-	g.setCommitStatus(ctx, github.StatusPending, "GolangCI is reviewing your Pull Request...")
-
-	if g.newWorkspaceInstaller == nil {
-		g.gw = workspaces.NewGo(g.exec, g.infoFetcher)
-		if err = g.gw.Setup(ctx, g.getRepo(), "github.com", g.context.Repo.Owner, g.context.Repo.Name); err != nil {
-			publicError := fmt.Sprintf("failed to setup workspace: %s", err)
-			publicError = escapeErrorText(publicError, g.buildSecrets())
-			g.updateAnalysisState(ctx, nil, github.StatusError, publicError)
-			g.setCommitStatus(ctx, github.StatusError, "failed to setup")
+	curState, stateErr := g.state.GetState(ctx, g.context.Repo.Owner, g.context.Repo.Name, g.analysisGUID)
+	var queuedAt time.Time
+	if stateErr == nil {
+		queuedAt = fromDBTime(curState.CreatedAt)
+	}
 
-			return fmt.Errorf("can't setup go workspace: %s", err)
-		}
-		defer g.gw.Clean(ctx)
-		g.exec = g.gw.Executor()
-	} else {
-		startedAt := time.Now()
-		exec, resLog, err := g.newWorkspaceInstaller.Setup(ctx, g.getRepo(), "github.com", g.context.Repo.Owner, g.context.Repo.Name) //nolint:govet
-		if err != nil {
-			publicError := fmt.Sprintf("failed to setup workspace: %s", err)
-			publicError = escapeErrorText(publicError, g.buildSecrets())
-			g.updateAnalysisState(ctx, nil, github.StatusError, publicError)
-			g.setCommitStatus(ctx, github.StatusError, "failed to setup")
+	if !g.startRun(ctx, queuedAt) {
+		return nil
+	}
+	if g.run != nil {
+		runCtx := ctx
+		defer func() {
+			if r := recover(); r != nil {
+				g.finishRun(runCtx, false)
+				panic(r)
+			}
+			g.finishRun(runCtx, err == nil)
+		}()
 
-			return nil
-		}
-		g.exec = exec
-		g.resLog = resLog
-		g.addTimingFrom("Prepare", startedAt)
+		var cancel context.CancelFunc
+		ctx, cancel = cancellation.Watch(ctx, g.runs, g.runKey, g.run, supersedeCheckInterval)
+		defer cancel()
 	}
 
+	g.setCommitStatus(ctx, github.StatusPending, "GolangCI is reviewing your Pull Request...")
+
 	patch, err := g.client.GetPullRequestPatch(ctx, g.context)
 	if err != nil {
 		if !github.IsRecoverableError(err) {
@@ -424,14 +748,31 @@ func (g githubGoPR) Process(ctx context.Context) error {
 		}
 		return fmt.Errorf("can't get patch: %s", err)
 	}
+	g.patch = patch
 
-	if err = storePatch(ctx, patch, g.exec); err != nil {
-		return fmt.Errorf("can't store patch: %s", err)
+	g.cache = resultcache.NewAnalysisCache(ctx, g.resultCache, g.client, &resultcache.Target{
+		Context:   g.context,
+		CommitSHA: g.pr.GetHead().GetSHA(),
+		Patch:     patch,
+	}, g.linters)
+	g.cachedRes = g.cache.Get(ctx)
+
+	if g.needsRepo() {
+		var ok bool
+		if ok, err = g.setupWorkspace(ctx); !ok {
+			return err
+		}
+		if g.gw != nil {
+			defer g.gw.Clean(ctx)
+		}
+
+		if err = storePatch(ctx, patch, g.exec); err != nil {
+			return fmt.Errorf("can't store patch: %s", err)
+		}
 	}
 
-	curState, err := g.state.GetState(ctx, g.context.Repo.Owner, g.context.Repo.Name, g.analysisGUID)
-	if err != nil {
-		analytics.Log(ctx).Warnf("Can't get current state: %s", err)
+	if stateErr != nil {
+		analytics.Log(ctx).Warnf("Can't get current state: %s", stateErr)
 	} else if curState.Status == statusSentToQueue {
 		g.addTimingFrom("In Queue", fromDBTime(curState.CreatedAt))
 		inQueue := time.Since(fromDBTime(curState.CreatedAt))
@@ -12,11 +12,14 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/analyze/cancellation"
 	"github.com/golangci/golangci-worker/app/analyze/linters"
 	"github.com/golangci/golangci-worker/app/analyze/linters/result"
 	"github.com/golangci/golangci-worker/app/analyze/prstate"
 	"github.com/golangci/golangci-worker/app/analyze/repoinfo"
 	"github.com/golangci/golangci-worker/app/analyze/reporters"
+	"github.com/golangci/golangci-worker/app/analyze/resultcache"
+	"github.com/golangci/golangci-worker/app/analyze/templates"
 	"github.com/golangci/golangci-worker/app/lib/executors"
 	"github.com/golangci/golangci-worker/app/lib/fetchers"
 	"github.com/golangci/golangci-worker/app/lib/github"
@@ -69,7 +72,7 @@ func getNopFetcher(ctrl *gomock.Controller) fetchers.Fetcher {
 
 func getNopReporter(ctrl *gomock.Controller) reporters.Reporter {
 	r := reporters.NewMockReporter(ctrl)
-	r.EXPECT().Report(testCtxMatcher, any, any).AnyTimes().Return(nil)
+	r.EXPECT().Report(testCtxMatcher, any).AnyTimes().Return(nil)
 	return r
 }
 
@@ -81,7 +84,7 @@ func getNopInfoFetcher(ctrl *gomock.Controller) repoinfo.Fetcher {
 
 func getErroredReporter(ctrl *gomock.Controller) reporters.Reporter {
 	r := reporters.NewMockReporter(ctrl)
-	r.EXPECT().Report(testCtxMatcher, any, any).Return(fmt.Errorf("can't report"))
+	r.EXPECT().Report(testCtxMatcher, any).Return(fmt.Errorf("can't report"))
 	return r
 }
 
@@ -100,6 +103,8 @@ func getNopExecutor(ctrl *gomock.Controller) executors.Executor {
 	e.EXPECT().WithWorkDir(any).Return(e).AnyTimes()
 	e.EXPECT().Run(testCtxMatcher, any, any).Return("", nil).AnyTimes()
 	e.EXPECT().Run(testCtxMatcher, any, any, any).Return("", nil).AnyTimes()
+	e.EXPECT().Run(testCtxMatcher, "find", ".", "-name", "go.mod", "-o", "-name", "go.work", "-o", "-name", "modules.txt").
+		Return("", nil).AnyTimes()
 	e.EXPECT().Clean().AnyTimes()
 	e.EXPECT().SetEnv(any, any).AnyTimes()
 	e.EXPECT().CopyFile(any, any, any).Return(nil)
@@ -122,6 +127,7 @@ func getFakeStatusGithubClient(t *testing.T, ctrl *gomock.Controller, status git
 		Return(nil)
 
 	gc.EXPECT().GetPullRequestPatch(any, any).AnyTimes().Return(getFakePatch(t), nil)
+	gc.EXPECT().GetRootFiles(any, c, any).AnyTimes().Return(nil, nil)
 
 	test.Init()
 	url := fmt.Sprintf("%s/r/github.com/%s/%s/pulls/%d", os.Getenv("WEB_ROOT"), c.Repo.Owner, c.Repo.Name, testPR.GetNumber())
@@ -138,6 +144,7 @@ func getNopGithubClient(t *testing.T, ctrl *gomock.Controller) github.Client {
 	gc.EXPECT().GetPullRequest(testCtxMatcher, c).AnyTimes().Return(testPR, nil)
 	gc.EXPECT().GetPullRequestPatch(any, any).AnyTimes().Return(getFakePatch(t))
 	gc.EXPECT().SetCommitStatus(any, any, testSHA, any, any, any).AnyTimes()
+	gc.EXPECT().GetRootFiles(any, c, any).AnyTimes().Return(nil, nil)
 	return gc
 }
 
@@ -163,6 +170,9 @@ func fillWithNops(t *testing.T, ctrl *gomock.Controller, cfg *githubGoPRConfig)
 	if cfg.state == nil {
 		cfg.state = getNopState(ctrl)
 	}
+	if cfg.runs == nil {
+		cfg.runs = cancellation.NewMemoryRegistry()
+	}
 }
 
 func getNopedProcessor(t *testing.T, ctrl *gomock.Controller, cfg githubGoPRConfig) *githubGoPR {
@@ -197,7 +207,7 @@ func TestSetCommitStatusFailureOneIssue(t *testing.T) {
 
 	testProcessor(t, ctrl, githubGoPRConfig{
 		linters: getFakeLinters(ctrl, fakeChangedIssue),
-		client:  getFakeStatusGithubClient(t, ctrl, github.StatusFailure, "1 issue found"),
+		client:  getFakeStatusGithubClient(t, ctrl, github.StatusFailure, "1 issue found: 1 error"),
 	})
 }
 
@@ -207,10 +217,55 @@ func TestSetCommitStatusFailureTwoIssues(t *testing.T) {
 
 	testProcessor(t, ctrl, githubGoPRConfig{
 		linters: getFakeLinters(ctrl, fakeChangedIssues...),
-		client:  getFakeStatusGithubClient(t, ctrl, github.StatusFailure, "2 issues found"),
+		client:  getFakeStatusGithubClient(t, ctrl, github.StatusFailure, "2 issues found: 2 errors"),
 	})
 }
 
+func TestGetGithubStatusForIssues(t *testing.T) {
+	issue := func(sev result.Severity) result.Issue {
+		i := fakeChangedIssue
+		i.Severity = sev
+		return i
+	}
+
+	type testCase struct {
+		issues       []result.Issue
+		failSeverity result.Severity
+		expStatus    github.Status
+		expDesc      string
+	}
+
+	testCases := []testCase{
+		{nil, result.SeverityError, github.StatusSuccess, "No issues found!"},
+		{
+			[]result.Issue{issue(result.SeverityInfo)}, "",
+			github.StatusFailure, "1 issue found: 1 info",
+		},
+		{
+			[]result.Issue{issue(result.SeverityWarning), issue(result.SeverityInfo), issue(result.SeverityInfo)},
+			result.SeverityError,
+			github.StatusSuccess, "3 non-blocking issues found: 1 warning, 2 info",
+		},
+		{
+			[]result.Issue{issue(result.SeverityWarning), issue(result.SeverityError), issue(result.SeverityWarning)},
+			result.SeverityWarning,
+			github.StatusFailure, "3 issues found: 1 error, 2 warnings",
+		},
+		{
+			[]result.Issue{issue(""), issue(result.SeverityWarning)},
+			result.SeverityError,
+			github.StatusFailure, "2 issues found: 1 error, 1 warning",
+		},
+	}
+
+	for _, tc := range testCases {
+		status, desc, err := getGithubStatusForIssues(templates.Default(), tc.issues, tc.failSeverity)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expStatus, status)
+		assert.Equal(t, tc.expDesc, desc)
+	}
+}
+
 func TestSetCommitStatusOnReportingError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -224,6 +279,212 @@ func TestSetCommitStatusOnReportingError(t *testing.T) {
 	assert.Error(t, p.Process(testCtx))
 }
 
+func TestSetCommitStatusOnOptionalReportingError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	reporter := reporters.NewComposite(
+		reporters.Target{Name: "github", Reporter: getNopReporter(ctrl), Required: true},
+		reporters.Target{Name: "webhook", Reporter: getErroredReporter(ctrl)},
+	)
+	p := getNopedProcessor(t, ctrl, githubGoPRConfig{
+		linters:  getFakeLinters(ctrl, fakeChangedIssue),
+		reporter: reporter,
+		client:   getFakeStatusGithubClient(t, ctrl, github.StatusFailure, "1 issue found: 1 error"),
+	})
+	assert.NoError(t, p.Process(testCtx))
+}
+
+func getSupersededState(ctrl *gomock.Controller, queuedAt time.Time) prstate.Storage {
+	r := prstate.NewMockStorage(ctrl)
+	r.EXPECT().GetState(any, any, any, any).AnyTimes().Return(&prstate.State{
+		Status:    statusSentToQueue,
+		CreatedAt: queuedAt,
+	}, nil)
+	r.EXPECT().UpdateState(any, any, any, testAnalysisGUID, &stateStatusMatcher{statusSuperseded}).Return(nil)
+	r.EXPECT().UpdateState(any, any, any, any, any).AnyTimes().Return(nil)
+	return r
+}
+
+type stateStatusMatcher struct {
+	status string
+}
+
+func (m stateStatusMatcher) Matches(x interface{}) bool {
+	s, ok := x.(*prstate.State)
+	return ok && s.Status == m.status
+}
+
+func (m stateStatusMatcher) String() string {
+	return fmt.Sprintf("has status %s", m.status)
+}
+
+func TestSkipStaleAnalysis(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// a task queued later is already analyzed
+	queuedAt := time.Now().Add(-time.Hour)
+	runs := cancellation.NewMemoryRegistry()
+	key := cancellation.PullRequestKey(&github.FakeContext.Repo, testPR.GetNumber())
+	_, err := runs.Start(testCtx, key, &cancellation.Run{
+		AnalysisGUID: "newer-guid",
+		HeadSHA:      "newerSHA",
+		QueuedAt:     queuedAt.Add(time.Minute),
+	})
+	assert.NoError(t, err)
+
+	gc := github.NewMockClient(ctrl)
+	gc.EXPECT().GetPullRequest(testCtxMatcher, &github.FakeContext).Return(testPR, nil)
+
+	exec := executors.NewMockExecutor(ctrl)
+	exec.EXPECT().Clean()
+
+	p := getNopedProcessor(t, ctrl, githubGoPRConfig{
+		client:      gc,
+		exec:        exec,
+		repoFetcher: fetchers.NewMockFetcher(ctrl), // repo must not be fetched
+		linters:     []linters.Linter{linters.NewMockLinter(ctrl)},
+		reporter:    reporters.NewMockReporter(ctrl),
+		state:       getSupersededState(ctrl, queuedAt),
+		runs:        runs,
+	})
+	assert.NoError(t, p.Process(testCtx))
+}
+
+func TestDontSetCommitStatusOfSupersededAnalysis(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	runs := cancellation.NewMemoryRegistry()
+	key := cancellation.PullRequestKey(&github.FakeContext.Repo, testPR.GetNumber())
+
+	c := &github.FakeContext
+	gc := github.NewMockClient(ctrl)
+	gc.EXPECT().GetPullRequest(testCtxMatcher, c).Return(testPR, nil)
+	gc.EXPECT().GetPullRequestPatch(any, any).Return(getFakePatch(t), nil)
+	gc.EXPECT().SetCommitStatus(testCtxMatcher, c, testSHA,
+		github.StatusPending, "GolangCI is reviewing your Pull Request...", "").Return(nil)
+	gc.EXPECT().GetRootFiles(any, c, any).Return(nil, nil)
+
+	fixableIssue := fakeChangedIssue
+	fixableIssue.Replacement = &result.Replacement{NeedOnlyDelete: true}
+
+	linter := linters.NewMockLinter(ctrl)
+	linter.EXPECT().Run(testCtxMatcher, any).Do(func(ctx context.Context, exec executors.Executor) {
+		// new commit was pushed during the analysis
+		_, err := runs.Start(ctx, key, &cancellation.Run{AnalysisGUID: "newer-guid", HeadSHA: "newerSHA"})
+		assert.NoError(t, err)
+	}).Return(&result.Result{Issues: []result.Issue{fixableIssue}}, nil)
+
+	p := getNopedProcessor(t, ctrl, githubGoPRConfig{
+		client:   gc,
+		linters:  []linters.Linter{linter},
+		reporter: reporters.NewMockReporter(ctrl), // review must not be created
+		state:    getSupersededState(ctrl, time.Now()),
+		runs:     runs,
+	})
+	fixer := &testFixer{}
+	p.autofixer = newAutofixer(autofixModePush, []linters.Linter{fixer}, gc, c)
+	assert.NoError(t, p.Process(testCtx))
+	assert.False(t, fixer.fixed, "fixes of the superseded analysis must not be pushed")
+}
+
+func TestLoadTemplatesOfBaseCommit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := &github.FakeContext
+	gc := github.NewMockClient(ctrl)
+	gc.EXPECT().GetRootFiles(any, c, "baseSHA").Return([]github.TreeFile{{Path: ".golangci.yml", SHA: "configSHA"}}, nil)
+	gc.EXPECT().GetBlob(any, c, "configSHA").
+		Return("service:\n  templates:\n    linter-docs-urls:\n      golint: https://wiki.example.com/go\n", nil)
+
+	g := githubGoPR{
+		githubGoPRConfig: githubGoPRConfig{client: gc},
+		context:          c,
+		pr: &gh.PullRequest{
+			Head: &gh.PullRequestBranch{SHA: gh.String(testSHA)}, // templates of the head commit aren't read
+			Base: &gh.PullRequestBranch{SHA: gh.String("baseSHA")},
+		},
+	}
+	tmpl, err := g.loadTemplates(testCtx)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://wiki.example.com/go", tmpl.LinterDocsURL("golint"))
+}
+
+func TestFailedAnalysisDoesntBlockCommit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	runs := cancellation.NewMemoryRegistry()
+	key := cancellation.PullRequestKey(&github.FakeContext.Repo, testPR.GetNumber())
+
+	c := &github.FakeContext
+	gc := github.NewMockClient(ctrl)
+	gc.EXPECT().GetPullRequest(testCtxMatcher, c).Return(testPR, nil)
+	gc.EXPECT().GetPullRequestPatch(any, any).Return("", fmt.Errorf("can't get patch"))
+	gc.EXPECT().SetCommitStatus(any, any, testSHA, any, any, any).AnyTimes()
+
+	exec := executors.NewMockExecutor(ctrl)
+	exec.EXPECT().WorkDir().Return("").AnyTimes()
+	exec.EXPECT().WithWorkDir(any).Return(exec).AnyTimes()
+	exec.EXPECT().Run(testCtxMatcher, any, any).Return("", nil).AnyTimes()
+	exec.EXPECT().Run(testCtxMatcher, any, any, any).Return("", nil).AnyTimes()
+	exec.EXPECT().Clean().AnyTimes()
+	exec.EXPECT().SetEnv(any, any).AnyTimes()
+
+	p := getNopedProcessor(t, ctrl, githubGoPRConfig{
+		client:  gc,
+		exec:    exec, // patch isn't stored
+		linters: []linters.Linter{linters.NewMockLinter(ctrl)},
+		runs:    runs,
+	})
+	assert.Error(t, p.Process(testCtx))
+
+	by, err := runs.Start(testCtx, key, &cancellation.Run{AnalysisGUID: "retry-guid", HeadSHA: testSHA})
+	assert.NoError(t, err)
+	assert.Nil(t, by, "commit of the failed analysis can be analyzed again")
+}
+
+func TestReportCachedResultWithoutFetchingRepo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	resultcache.WorkerVersion, resultcache.LintVersion = "test", "1.0"
+	defer func() {
+		resultcache.WorkerVersion, resultcache.LintVersion = "", ""
+	}()
+
+	linter := linters.NewMockLinter(ctrl) // linters must not be run
+	linter.EXPECT().Name().Return("linter").AnyTimes()
+	lnts := []linters.Linter{linter}
+
+	gc := getFakeStatusGithubClient(t, ctrl, github.StatusFailure, "1 issue found: 1 error")
+	gc.(*github.MockClient).EXPECT().GetTreeFiles(any, &github.FakeContext, testSHA).Return(nil, nil).AnyTimes()
+
+	store := resultcache.NewMemoryStore(time.Hour)
+	cache := resultcache.NewAnalysisCache(testCtx, store, gc, &resultcache.Target{
+		Context:   &github.FakeContext,
+		CommitSHA: testSHA,
+		Patch:     getFakePatch(t),
+	}, lnts)
+	fetchedExec := executors.NewMockExecutor(ctrl)
+	fetchedExec.EXPECT().Run(any, "git", "rev-parse", "HEAD").Return(testSHA, nil)
+	cache.Put(testCtx, fetchedExec, &result.Result{Issues: []result.Issue{fakeChangedIssue}})
+
+	exec := executors.NewMockExecutor(ctrl) // patch isn't stored
+	exec.EXPECT().Clean()
+
+	testProcessor(t, ctrl, githubGoPRConfig{
+		client:      gc,
+		exec:        exec,
+		repoFetcher: fetchers.NewMockFetcher(ctrl), // repo must not be fetched
+		linters:     lnts,
+		resultCache: store,
+	})
+}
+
 func getRealisticTestProcessor(ctx context.Context, t *testing.T, ctrl *gomock.Controller) *githubGoPR {
 	c := getTestingRepo(t)
 	cloneURL := fmt.Sprintf("git@github.com:%s/%s.git", c.Repo.Owner, c.Repo.Name)
@@ -239,6 +500,7 @@ func getRealisticTestProcessor(ctx context.Context, t *testing.T, ctrl *gomock.C
 	gc.EXPECT().GetPullRequest(testCtxMatcher, c).Return(pr, nil).AnyTimes()
 	gc.EXPECT().GetPullRequestPatch(any, any).AnyTimes().Return(getFakePatch(t), nil)
 	gc.EXPECT().SetCommitStatus(any, any, any, any, any, any).AnyTimes()
+	gc.EXPECT().GetRootFiles(any, any, any).AnyTimes().Return(nil, nil)
 
 	exec, err := executors.NewTempDirShell("gopath")
 	assert.NoError(t, err)
@@ -312,6 +574,7 @@ func getTestProcessorWithFakeGithub(ctx context.Context, t *testing.T, ctrl *gom
 	gc.EXPECT().GetPullRequestPatch(any, any).AnyTimes().Return(patch, nil)
 	gc.EXPECT().GetPullRequest(testCtxMatcher, c).Return(pr, nil)
 	gc.EXPECT().SetCommitStatus(any, any, any, any, any, any).AnyTimes()
+	gc.EXPECT().GetRootFiles(any, any, any).AnyTimes().Return(nil, nil)
 
 	cfg := githubGoPRConfig{
 		reporter: getNopReporter(ctrl),
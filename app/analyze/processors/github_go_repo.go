@@ -14,6 +14,7 @@ import (
 	"github.com/golangci/golangci-worker/app/analyze/linters/golinters"
 	"github.com/golangci/golangci-worker/app/analyze/linters/result"
 	"github.com/golangci/golangci-worker/app/analyze/repoinfo"
+	"github.com/golangci/golangci-worker/app/analyze/reporters"
 	"github.com/golangci/golangci-worker/app/analyze/repostate"
 	"github.com/golangci/golangci-worker/app/lib/errorutils"
 	"github.com/golangci/golangci-worker/app/lib/executors"
@@ -31,11 +32,13 @@ type GithubGoRepoConfig struct {
 	runner      linters.Runner
 	exec        executors.Executor
 	state       repostate.Storage
+	reporter    reporters.Reporter
 }
 
 type GithubGoRepo struct {
 	analysisGUID string
 	branch       string
+	commitSHA    string // fetched commit, empty until the repo is fetched
 	gw           *workspaces.Go
 	repo         *github.Repo
 
@@ -80,7 +83,15 @@ func NewGithubGoRepo(ctx context.Context, cfg GithubGoRepoConfig, analysisGUID,
 	}
 
 	if cfg.state == nil {
-		cfg.state = repostate.NewAPIStorage(httputils.GrequestsClient{})
+		state, err := repostate.NewStorageFromEnv(httputils.GrequestsClient{})
+		if err != nil {
+			return nil, fmt.Errorf("can't make state storage: %s", err)
+		}
+		cfg.state = state
+	}
+
+	if cfg.reporter == nil {
+		cfg.reporter = reporters.NewComposite(reporters.NewNotifiersFromEnv()...)
 	}
 
 	return &GithubGoRepo{
@@ -96,6 +107,7 @@ func (g *GithubGoRepo) getRepo() *fetchers.Repo {
 		CloneURL: fmt.Sprintf("https://github.com/%s/%s.git", g.repo.Owner, g.repo.Name),
 		Ref:      g.branch,
 		FullPath: fmt.Sprintf("github.com/%s/%s", g.repo.Owner, g.repo.Name),
+		Owner:    g.repo.Owner,
 	}
 }
 
@@ -135,18 +147,7 @@ func (g *GithubGoRepo) prepareRepo(ctx context.Context) error {
 }
 
 func (g GithubGoRepo) updateAnalysisState(ctx context.Context, res *result.Result, status, publicError string) {
-	resJSON := &resultJSON{
-		Version: 1,
-		WorkerRes: workerRes{
-			Timings:  g.timings,
-			Warnings: g.warnings,
-			Error:    publicError,
-		},
-	}
-
-	if res != nil {
-		resJSON.GolangciLintRes = res.ResultJSON
-	}
+	resJSON := g.buildResultDocument(res, publicError, nil)
 	s := &repostate.State{
 		Status:     status,
 		ResultJSON: resJSON,
@@ -166,6 +167,7 @@ func (g *GithubGoRepo) processWithGuaranteedGithubStatus(ctx context.Context) er
 	res, err := g.work(ctx)
 	analytics.Log(ctx).Infof("timings: %s", g.timings)
 
+	reportCtx := ctx
 	ctx = context.Background() // no timeout for state and status saving: it must be durable
 
 	var status string
@@ -194,6 +196,21 @@ func (g *GithubGoRepo) processWithGuaranteedGithubStatus(ctx context.Context) er
 		status = statusProcessed
 	}
 
+	a := &reporters.Analysis{
+		GUID:       g.analysisGUID,
+		Repo:       *g.repo,
+		Branch:     g.branch,
+		CommitSHA:  g.commitSHA,
+		Status:     status,
+		Error:      publicError,
+		DetailsURL: getRepoDetailsURL(g.repo),
+		Timings:    toReportersTimings(g.timings),
+		Result:     res,
+	}
+	if rerr := reportAnalysis(reportCtx, g.reporter, a, &g.resultCollector); rerr != nil {
+		analytics.Log(ctx).Warnf("Can't report repo analysis: %s", rerr)
+	}
+
 	g.updateAnalysisState(ctx, res, status, publicError)
 	return err
 }
@@ -232,6 +249,7 @@ func (g *GithubGoRepo) work(ctx context.Context) (res *result.Result, err error)
 	if err = g.prepareRepo(ctx); err != nil {
 		return nil, err // don't wrap error, need to save it's type
 	}
+	g.commitSHA = getFetchedCommitSHA(ctx, g.exec)
 
 	g.trackTiming("Analysis", func() {
 		res, err = g.runner.Run(ctx, g.linters, g.exec)
@@ -244,7 +262,9 @@ func (g *GithubGoRepo) work(ctx context.Context) (res *result.Result, err error)
 }
 
 func (g GithubGoRepo) Process(ctx context.Context) error {
-	defer g.exec.Clean()
+	defer func() {
+		g.exec.Clean() // it is replaced by the executor of the workspace
+	}()
 
 	curState, err := g.state.GetState(ctx, g.repo.Owner, g.repo.Name, g.analysisGUID)
 	if err != nil {
@@ -0,0 +1,53 @@
+package processors
+
+import (
+	"context"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/analyze/linters"
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+	"github.com/golangci/golangci-worker/app/lib/goutils/workspaces"
+)
+
+// modulesRunner finds go modules in the fetched repo and analyzes every module separately:
+// golangci-lint in the repo root doesn't analyze nested modules. Modules are found in the fetched tree
+// for repos prepared by any installer.
+type modulesRunner struct {
+	runner    linters.Runner
+	patch     string
+	patchPath string
+}
+
+func withModulesRunner(runner linters.Runner, patch, patchPath string) modulesRunner {
+	return modulesRunner{
+		runner:    runner,
+		patch:     patch,
+		patchPath: patchPath,
+	}
+}
+
+func (r modulesRunner) Run(ctx context.Context, lnts []linters.Linter, exec executors.Executor) (*result.Result, error) {
+	return r.newModulesRunner(ctx, exec).Run(ctx, lnts, exec)
+}
+
+// Fix runs fixers in every module: golangci-lint in the repo root doesn't fix nested modules
+func (r modulesRunner) Fix(ctx context.Context, lnts []linters.Linter, exec executors.Executor) error {
+	return r.newModulesRunner(ctx, exec).Fix(ctx, lnts, exec)
+}
+
+// newModulesRunner returns the runner of the repo as a whole if modules can't be found
+func (r modulesRunner) newModulesRunner(ctx context.Context, exec executors.Executor) *linters.ModulesRunner {
+	modules, err := workspaces.FindModules(ctx, exec)
+	if err != nil {
+		analytics.Log(ctx).Warnf("Can't find go modules, analyze the repo as a whole: %s", err)
+		return linters.NewModulesRunner(r.runner, nil, r.patch, r.patchPath)
+	}
+
+	var roots []string
+	if modules != nil {
+		roots = modules.Roots
+	}
+
+	return linters.NewModulesRunner(r.runner, roots, r.patch, r.patchPath)
+}
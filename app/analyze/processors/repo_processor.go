@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"runtime/debug"
 	"strings"
 	"time"
@@ -14,12 +15,15 @@ import (
 	"github.com/golangci/golangci-shared/pkg/logutil"
 	"github.com/golangci/golangci-worker/app/analyze/linters"
 	lintersResult "github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/reporters"
 	"github.com/golangci/golangci-worker/app/analyze/repostate"
+	"github.com/golangci/golangci-worker/app/analyze/resultcache"
 	"github.com/golangci/golangci-worker/app/lib/errorutils"
 	"github.com/golangci/golangci-worker/app/lib/executors"
 	"github.com/golangci/golangci-worker/app/lib/experiments"
 	"github.com/golangci/golangci-worker/app/lib/fetchers"
 	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/golangci/golangci-worker/app/lib/goutils/goproxy"
 	"github.com/golangci/golangci-worker/app/lib/goutils/workspaces"
 
 	"github.com/pkg/errors"
@@ -30,6 +34,9 @@ type StaticRepoConfig struct {
 	Linters     []linters.Linter
 	Runner      linters.Runner
 	State       repostate.Storage
+	Reporter    reporters.Reporter
+	ResultCache resultcache.Store // nil if results aren't cached
+	Client      github.Client
 	Cfg         config.Config
 	Et          apperrors.Tracker
 }
@@ -41,6 +48,8 @@ type RepoConfig struct {
 	Exec executors.Executor
 	Wi   workspaces.Installer
 	Ec   *experiments.Checker
+
+	ProxySession *goproxy.Session // nil if the go proxy isn't configured
 }
 
 type Repo struct {
@@ -52,6 +61,7 @@ type RepoContext struct {
 
 	AnalysisGUID string
 	Branch       string
+	CommitSHA    string       // head commit of the branch when the task was sent, can be empty
 	Repo         *github.Repo // TODO: abstract from repo provider
 }
 
@@ -59,6 +69,7 @@ type repoResult struct {
 	resultCollector
 	prepareLog *result.Log
 	lintRes    *lintersResult.Result
+	commitSHA  string // analyzed commit, empty if the repo wasn't fetched
 }
 
 func NewRepo(cfg *RepoConfig) *Repo {
@@ -67,7 +78,8 @@ func NewRepo(cfg *RepoConfig) *Repo {
 	}
 }
 
-func (r Repo) Process(ctx *RepoContext) {
+// Process analyzes the repo: the executor of the repo is replaced by the executor of the prepared workspace
+func (r *Repo) Process(ctx *RepoContext) {
 	res, err := r.processPanicSafe(ctx)
 	if res == nil {
 		res = &repoResult{}
@@ -76,7 +88,7 @@ func (r Repo) Process(ctx *RepoContext) {
 	r.submitResult(ctx, res, err)
 }
 
-func (r Repo) processPanicSafe(ctx *RepoContext) (retRes *repoResult, err error) {
+func (r *Repo) processPanicSafe(ctx *RepoContext) (retRes *repoResult, err error) {
 	defer func() {
 		if rerr := recover(); rerr != nil {
 			retRes = nil
@@ -90,11 +102,22 @@ func (r Repo) processPanicSafe(ctx *RepoContext) (retRes *repoResult, err error)
 	var res repoResult
 	r.updateStatusToInQueue(ctx, &res)
 
+	cache := resultcache.NewAnalysisCache(ctx.Ctx, r.ResultCache, r.Client, &resultcache.Target{
+		Context:   &github.Context{Repo: *ctx.Repo},
+		CommitSHA: ctx.CommitSHA,
+	}, r.Linters)
+	if lintRes := cache.Get(ctx.Ctx); lintRes != nil {
+		// the repo isn't fetched
+		res.addModuleResults(lintRes, buildSecrets())
+		res.lintRes = lintRes
+		return &res, nil
+	}
+
 	if err := r.prepare(ctx, &res); err != nil {
 		return nil, errors.Wrap(err, "failed to prepare repo")
 	}
 
-	if err := r.analyze(ctx, &res); err != nil {
+	if err := r.analyze(ctx, &res, cache); err != nil {
 		return nil, errors.Wrap(err, "failed to analyze repo")
 	}
 
@@ -125,27 +148,36 @@ func (r *Repo) prepare(ctx *RepoContext, res *repoResult) error {
 
 	r.Exec = exec
 	res.prepareLog = resLog
+	res.commitSHA = getFetchedCommitSHA(ctx.Ctx, exec)
 	return nil
 }
 
-func (r Repo) analyze(ctx *RepoContext, res *repoResult) error {
+func (r Repo) analyze(ctx *RepoContext, res *repoResult, cache *resultcache.AnalysisCache) error {
 	defer res.addTimingFrom("Analysis", time.Now())
 
-	lintRes, err := r.Runner.Run(ctx.Ctx, r.Linters, r.Exec)
+	runner := withModulesRunner(r.Runner, "", "")
+	lintRes, err := runner.Run(ctx.Ctx, r.Linters, r.Exec)
 	if err != nil {
 		return errors.Wrap(err, "failed running linters")
 	}
+	cache.Put(ctx.Ctx, r.Exec, lintRes)
 
+	res.addModuleResults(lintRes, buildSecrets())
 	res.lintRes = lintRes
 	return nil
 }
 
+func getRepoDetailsURL(repo *github.Repo) string {
+	return fmt.Sprintf("%s/r/github.com/%s/%s", os.Getenv("WEB_ROOT"), repo.Owner, repo.Name)
+}
+
 func buildFetchersRepo(ctx *RepoContext) *fetchers.Repo {
 	repo := ctx.Repo
 	return &fetchers.Repo{
 		CloneURL: fmt.Sprintf("https://github.com/%s/%s.git", repo.Owner, repo.Name),
 		Ref:      ctx.Branch,
 		FullPath: fmt.Sprintf("github.com/%s/%s", repo.Owner, repo.Name),
+		Owner:    repo.Owner,
 	}
 }
 
@@ -227,6 +259,8 @@ func (r Repo) submitResult(ctx *RepoContext, res *repoResult, err error) {
 		r.Log.Errorf("Failed repo analysis: %s, timings: %v", err, res.timings)
 	}
 
+	res.addFetchedModules(r.ProxySession.Finish(ctx.Ctx))
+
 	if res.prepareLog != nil {
 		for _, sg := range res.prepareLog.Groups {
 			for _, s := range sg.Steps {
@@ -239,18 +273,27 @@ func (r Repo) submitResult(ctx *RepoContext, res *repoResult, err error) {
 		}
 	}
 
-	resJSON := &resultJSON{
-		Version: 1,
-		WorkerRes: workerRes{
-			Timings:  res.timings,
-			Warnings: res.warnings,
-			Error:    publicErrorText,
-		},
+	commitSHA := res.commitSHA
+	if commitSHA == "" {
+		commitSHA = ctx.CommitSHA
 	}
 
-	if res.lintRes != nil {
-		resJSON.GolangciLintRes = res.lintRes.ResultJSON
+	a := &reporters.Analysis{
+		GUID:       ctx.AnalysisGUID,
+		Repo:       *ctx.Repo,
+		Branch:     ctx.Branch,
+		CommitSHA:  commitSHA,
+		Status:     status,
+		Error:      publicErrorText,
+		DetailsURL: getRepoDetailsURL(ctx.Repo),
+		Timings:    toReportersTimings(res.timings),
+		Result:     res.lintRes,
 	}
+	if rerr := reportAnalysis(ctx.Ctx, r.Reporter, a, &res.resultCollector); rerr != nil {
+		r.Log.Warnf("Can't report repo analysis: %s", rerr)
+	}
+
+	resJSON := res.buildResultDocument(res.lintRes, publicErrorText, buildPrepareLog(res.prepareLog, buildSecrets()))
 	s := &repostate.State{
 		Status:     status,
 		ResultJSON: resJSON,
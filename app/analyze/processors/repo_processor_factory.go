@@ -6,9 +6,12 @@ import (
 	"github.com/golangci/golangci-shared/pkg/logutil"
 	"github.com/golangci/golangci-worker/app/analyze/linters"
 	"github.com/golangci/golangci-worker/app/analyze/linters/golinters"
+	"github.com/golangci/golangci-worker/app/analyze/reporters"
 	"github.com/golangci/golangci-worker/app/analyze/repostate"
+	"github.com/golangci/golangci-worker/app/analyze/resultcache"
 	"github.com/golangci/golangci-worker/app/lib/experiments"
 	"github.com/golangci/golangci-worker/app/lib/fetchers"
+	"github.com/golangci/golangci-worker/app/lib/github"
 	"github.com/golangci/golangci-worker/app/lib/goutils/workspaces"
 	"github.com/golangci/golangci-worker/app/lib/httputils"
 	"github.com/pkg/errors"
@@ -44,7 +47,27 @@ func (f RepoProcessorFactory) BuildProcessor(ctx *RepoContext) (*Repo, func(), e
 	}
 
 	if cfg.State == nil {
-		cfg.State = repostate.NewAPIStorage(httputils.GrequestsClient{})
+		state, err := repostate.NewStorageFromEnv(httputils.GrequestsClient{})
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "can't make state storage")
+		}
+		cfg.State = state
+	}
+
+	if cfg.Reporter == nil {
+		cfg.Reporter = reporters.NewComposite(reporters.NewNotifiersFromEnv()...)
+	}
+
+	if cfg.ResultCache == nil {
+		cache, err := resultcache.NewStoreFromEnv(f.noCtxLog)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "can't make result cache")
+		}
+		cfg.ResultCache = cache
+	}
+
+	if cfg.Client == nil {
+		cfg.Client = github.NewMyClient()
 	}
 
 	if cfg.Cfg == nil {
@@ -73,16 +96,29 @@ func (f RepoProcessorFactory) BuildProcessor(ctx *RepoContext) (*Repo, func(), e
 		return nil, nil, errors.Wrap(err, "can't make executor")
 	}
 
-	cleanup := func() {
+	proxyExec, proxySession, err := withGoProxy(exec, ctx.AnalysisGUID, ctx.Repo.Owner)
+	if err != nil {
 		exec.Clean()
+		return nil, nil, err
+	}
+
+	var wi workspaces.Installer = workspaces.NewGo2(proxyExec, log, cfg.RepoFetcher)
+	if ec.IsActiveForAnalysis("go_modules_installer", ctx.Repo, false) {
+		wi = workspaces.NewGoModules(proxyExec, log, cfg.RepoFetcher, wi)
 	}
+
 	p := NewRepo(&RepoConfig{
 		StaticRepoConfig: cfg,
 		Log:              log,
-		Exec:             exec,
-		Wi:               workspaces.NewGo2(exec, log, cfg.RepoFetcher),
+		Exec:             proxyExec,
+		Wi:               wi,
 		Ec:               ec,
+		ProxySession:     proxySession,
 	})
+	cleanup := func() {
+		p.Exec.Clean() // the executor of the prepared workspace stops using the build cache
+		proxySession.Finish(ctx.Ctx)
+	}
 
 	return p, cleanup, nil
 }
@@ -0,0 +1,44 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/analyze/reporters"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+)
+
+// reportAnalysis reports the analysis and saves failed reporting targets as public warnings.
+// It returns an error only if a required target failed: failed notifiers are only logged.
+func reportAnalysis(ctx context.Context, r reporters.Reporter, a *reporters.Analysis, rc *resultCollector) error {
+	or, ok := r.(reporters.OutcomesReporter)
+	if !ok {
+		return r.Report(ctx, a)
+	}
+
+	outcomes, err := or.ReportOutcomes(ctx, a)
+	for _, o := range outcomes {
+		if o.Err != nil {
+			if !o.Required {
+				analytics.Log(ctx).Warnf("Can't report analysis to %s: %s", o.Name, o.Err)
+			}
+			rc.publicWarn("report", fmt.Sprintf("Can't report analysis to %s (attempts: %d)", o.Name, o.Attempts))
+		}
+	}
+
+	return err
+}
+
+// getFetchedCommitSHA returns the commit fetched in the working dir of exec: the branch
+// can be pushed after the analysis was queued. It returns an empty string if the commit can't be got.
+func getFetchedCommitSHA(ctx context.Context, exec executors.Executor) string {
+	out, err := exec.Run(ctx, "git", "rev-parse", "HEAD")
+	if err != nil {
+		analytics.Log(ctx).Warnf("Can't get fetched commit: %s, %s", err, out)
+		return ""
+	}
+
+	return strings.TrimSpace(out)
+}
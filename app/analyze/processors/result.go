@@ -1,68 +1,166 @@
 package processors
 
 import (
-	"strconv"
+	"fmt"
+	"strings"
 	"time"
-)
-
-type JSONDuration time.Duration
-
-func (d JSONDuration) MarshalJSON() ([]byte, error) {
-	return []byte(strconv.Itoa(int(time.Duration(d) / time.Millisecond))), nil
-}
 
-func (d JSONDuration) String() string {
-	return time.Duration(d).String()
-}
+	goenvresult "github.com/golangci/golangci-api/pkg/goenv/result"
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/reporters"
+	"github.com/golangci/golangci-worker/app/analyze/resultjson"
+	"github.com/golangci/golangci-worker/app/lib/goutils/goproxy"
+)
 
-type Timing struct {
-	Name     string
-	Duration JSONDuration `json:"DurationMs"`
-}
+func toReportersTimings(timings []resultjson.Timing) []reporters.Timing {
+	var ret []reporters.Timing
+	for _, t := range timings {
+		ret = append(ret, reporters.Timing{
+			Name:     t.Name,
+			Duration: time.Duration(t.Duration),
+		})
+	}
 
-type Warning struct {
-	Tag  string
-	Text string
+	return ret
 }
 
 type resultCollector struct {
-	timings  []Timing
-	warnings []Warning
+	timings  []resultjson.Timing
+	warnings []resultjson.Warning
+	modules  []resultjson.ModuleResult
+
+	fetchedModules []resultjson.FetchedModule // modules fetched through the go proxy
 }
 
 func (r *resultCollector) trackTiming(name string, f func()) {
 	startedAt := time.Now()
 	f()
-	r.timings = append(r.timings, Timing{
+	r.timings = append(r.timings, resultjson.Timing{
 		Name:     name,
-		Duration: JSONDuration(time.Since(startedAt)),
+		Duration: resultjson.JSONDuration(time.Since(startedAt)),
 	})
 }
 
 func (r *resultCollector) addTimingFrom(name string, from time.Time) {
-	r.timings = append(r.timings, Timing{
+	r.timings = append(r.timings, resultjson.Timing{
 		Name:     name,
-		Duration: JSONDuration(time.Since(from)),
+		Duration: resultjson.JSONDuration(time.Since(from)),
 	})
 }
 
 func (r *resultCollector) publicWarn(tag string, text string) {
-	r.warnings = append(r.warnings, Warning{
+	r.warnings = append(r.warnings, resultjson.Warning{
 		Tag:  tag,
 		Text: text,
 	})
 }
 
-type workerRes struct {
-	Timings  []Timing  `json:",omitempty"`
-	Warnings []Warning `json:",omitempty"`
-	Error    string    `json:",omitempty"`
+// addModuleResults keeps durations and errors of modules analyzed separately
+func (r *resultCollector) addModuleResults(res *result.Result, secrets map[string]string) {
+	for _, m := range res.Modules {
+		mr := resultjson.ModuleResult{
+			Dir:         m.Dir,
+			Duration:    resultjson.JSONDuration(m.Duration),
+			IssuesCount: m.IssuesCount,
+			Error:       escapeErrorText(m.Error, secrets),
+		}
+		r.modules = append(r.modules, mr)
+		if mr.Error != "" {
+			r.publicWarn("modules", fmt.Sprintf("Can't analyze module %s: %s", m.Dir, mr.Error))
+		}
+	}
+}
+
+// addFetchedModules keeps modules fetched through the go proxy
+func (r *resultCollector) addFetchedModules(modules []goproxy.Module) {
+	for _, m := range modules {
+		r.fetchedModules = append(r.fetchedModules, resultjson.FetchedModule{
+			Path:    m.Path,
+			Version: m.Version,
+		})
+	}
+}
+
+func (r resultCollector) buildResultDocument(res *result.Result, publicError string,
+	prepareLog *resultjson.PrepareLog) *resultjson.Document {
+
+	d := &resultjson.Document{
+		Version: resultjson.CurrentVersion,
+		WorkerRes: resultjson.WorkerResult{
+			Timings:        r.timings,
+			Warnings:       r.warnings,
+			Error:          publicError,
+			PrepareLog:     prepareLog,
+			Modules:        r.modules,
+			FetchedModules: r.fetchedModules,
+		},
+	}
+
+	if res != nil {
+		d.GolangciLintRes = res.ResultJSON
+	}
+
+	return d
+}
+
+const (
+	maxPrepareLogOutputLines = 100
+	maxPrepareLogLineLen     = 500
+)
+
+// truncateOutput keeps the last lines of the output: they usually contain the error
+func truncateOutput(lines []string, secrets map[string]string) ([]string, bool) {
+	truncated := false
+	if len(lines) > maxPrepareLogOutputLines {
+		lines = lines[len(lines)-maxPrepareLogOutputLines:]
+		truncated = true
+	}
+
+	var ret []string
+	for _, line := range lines {
+		line = escapeErrorText(line, secrets)
+		if r := []rune(line); len(r) > maxPrepareLogLineLen {
+			line = string(r[:maxPrepareLogLineLen]) + "..."
+			truncated = true
+		}
+		ret = append(ret, line)
+	}
+
+	return ret, truncated
+}
+
+func buildPrepareLogStep(s *goenvresult.Step, secrets map[string]string) resultjson.PrepareLogStep {
+	ret := resultjson.PrepareLogStep{
+		Description: escapeErrorText(s.Description, secrets),
+		Duration:    resultjson.JSONDuration(s.Duration),
+		Error:       escapeErrorText(s.Error, secrets),
+	}
+	if strings.HasPrefix(ret.Description, "$ ") { // steps running commands are described as "$ cmd args"
+		ret.Command = strings.TrimPrefix(ret.Description, "$ ")
+	}
+	ret.OutputLines, ret.OutputTruncated = truncateOutput(s.OutputLines, secrets)
+
+	return ret
 }
 
-type resultJSON struct {
-	Version         int
-	GolangciLintRes interface{}
-	WorkerRes       workerRes
+func buildPrepareLog(l *goenvresult.Log, secrets map[string]string) *resultjson.PrepareLog {
+	if l == nil {
+		return nil
+	}
+
+	ret := &resultjson.PrepareLog{}
+	for _, sg := range l.Groups {
+		group := resultjson.PrepareLogGroup{
+			Name:     sg.Name,
+			Duration: resultjson.JSONDuration(sg.Duration),
+		}
+		for _, s := range sg.Steps {
+			group.Steps = append(group.Steps, buildPrepareLogStep(s, secrets))
+		}
+		ret.Groups = append(ret.Groups, group)
+	}
+
+	return ret
 }
 
 func fromDBTime(t time.Time) time.Time {
@@ -0,0 +1,103 @@
+package processors
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	goenvresult "github.com/golangci/golangci-api/pkg/goenv/result"
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/resultjson"
+	"github.com/golangci/golangci-worker/app/lib/goutils/goproxy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildPrepareLog(t *testing.T) {
+	var output []string
+	for i := 0; i < maxPrepareLogOutputLines+10; i++ {
+		output = append(output, fmt.Sprintf("line %d", i))
+	}
+	output[len(output)-1] = "fetching with token secret_token"
+
+	l := &goenvresult.Log{
+		Groups: []*goenvresult.StepGroup{
+			{
+				Name:     "dependencies",
+				Duration: 3 * time.Second,
+				Steps: []*goenvresult.Step{
+					{
+						Description: "$ dep ensure",
+						Duration:    2 * time.Second,
+						Error:       "exit status 1",
+						OutputLines: output,
+					},
+					{
+						Description: "no vendor dir",
+						OutputLines: []string{strings.Repeat("x", maxPrepareLogLineLen+1)},
+					},
+				},
+			},
+		},
+	}
+
+	secrets := map[string]string{"secret_token": "{hidden}"}
+	expOutput := append([]string{}, output[10:]...)
+	expOutput[len(expOutput)-1] = "fetching with token {hidden}"
+
+	assert.Equal(t, &resultjson.PrepareLog{
+		Groups: []resultjson.PrepareLogGroup{
+			{
+				Name:     "dependencies",
+				Duration: resultjson.JSONDuration(3 * time.Second),
+				Steps: []resultjson.PrepareLogStep{
+					{
+						Description:     "$ dep ensure",
+						Command:         "dep ensure",
+						Duration:        resultjson.JSONDuration(2 * time.Second),
+						Error:           "exit status 1",
+						OutputLines:     expOutput,
+						OutputTruncated: true,
+					},
+					{
+						Description:     "no vendor dir",
+						OutputLines:     []string{strings.Repeat("x", maxPrepareLogLineLen) + "..."},
+						OutputTruncated: true,
+					},
+				},
+			},
+		},
+	}, buildPrepareLog(l, secrets))
+
+	assert.Nil(t, buildPrepareLog(nil, secrets))
+}
+
+func TestAddModuleResults(t *testing.T) {
+	res := &result.Result{
+		Modules: []result.ModuleResult{
+			{Dir: ".", Duration: time.Second, IssuesCount: 2},
+			{Dir: "tools", Duration: 2 * time.Second, Error: "can't load packages with token secret_token"},
+		},
+	}
+
+	var rc resultCollector
+	rc.addModuleResults(res, map[string]string{"secret_token": "{hidden}"})
+
+	assert.Equal(t, []resultjson.ModuleResult{
+		{Dir: ".", Duration: resultjson.JSONDuration(time.Second), IssuesCount: 2},
+		{Dir: "tools", Duration: resultjson.JSONDuration(2 * time.Second), Error: "can't load packages with token {hidden}"},
+	}, rc.buildResultDocument(res, "", nil).WorkerRes.Modules)
+	assert.Empty(t, rc.timings, "durations of modules are only in modules")
+	assert.Equal(t, []resultjson.Warning{
+		{Tag: "modules", Text: "Can't analyze module tools: can't load packages with token {hidden}"},
+	}, rc.warnings)
+}
+
+func TestAddFetchedModules(t *testing.T) {
+	var rc resultCollector
+	rc.addFetchedModules([]goproxy.Module{{Path: "github.com/pkg/errors", Version: "v0.8.0"}})
+	rc.addFetchedModules(nil)
+
+	assert.Equal(t, []resultjson.FetchedModule{{Path: "github.com/pkg/errors", Version: "v0.8.0"}},
+		rc.buildResultDocument(nil, "", nil).WorkerRes.FetchedModules)
+}
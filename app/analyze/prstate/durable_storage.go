@@ -0,0 +1,76 @@
+package prstate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golangci/golangci-worker/app/analyze/localstate"
+	"github.com/golangci/golangci-worker/app/lib/boltutils"
+	"github.com/golangci/golangci-worker/app/lib/httputils"
+)
+
+const bucket = "pr_states"
+
+// DurableStorage saves states locally and delivers them to the API through the outbox
+type DurableStorage struct {
+	states *localstate.DurableStorage
+	api    *APIStorage
+}
+
+func NewDurableStorage(outbox *localstate.Outbox, api *APIStorage) *DurableStorage {
+	return &DurableStorage{
+		states: localstate.NewDurableStorage(outbox, bucket, apiSender(api)),
+		api:    api,
+	}
+}
+
+// NewStorageFromEnv returns durable storage if STATE_DB_PATH is set and API storage otherwise
+func NewStorageFromEnv(client httputils.Client) (Storage, error) {
+	api := NewAPIStorage(client)
+	if !boltutils.IsConfigured() {
+		return api, nil
+	}
+
+	outbox, err := localstate.GetOutbox()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDurableStorage(outbox, api), nil
+}
+
+// RegisterAPISender makes the outbox deliver pr states to the API
+func RegisterAPISender(outbox *localstate.Outbox, api *APIStorage) {
+	localstate.RegisterAnalysisStateSender(outbox, bucket, apiSender(api))
+}
+
+func apiSender(api *APIStorage) localstate.AnalysisStateSender {
+	return func(ctx context.Context, owner, name, analysisID string, data []byte) error {
+		var state State
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("can't unmarshal state: %s", err)
+		}
+
+		return api.UpdateState(ctx, owner, name, analysisID, &state)
+	}
+}
+
+func (s DurableStorage) UpdateState(ctx context.Context, owner, name, analysisID string, state *State) error {
+	return s.states.UpdateState(ctx, owner, name, analysisID, state)
+}
+
+func (s DurableStorage) GetState(ctx context.Context, owner, name, analysisID string) (*State, error) {
+	var state State
+	found, err := s.states.GetState(owner, name, analysisID, &state)
+	if err != nil {
+		return nil, err
+	}
+
+	if found {
+		return &state, nil
+	}
+
+	// state is created by API
+	return s.api.GetState(ctx, owner, name, analysisID)
+}
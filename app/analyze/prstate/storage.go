@@ -3,6 +3,8 @@ package prstate
 import (
 	"context"
 	"time"
+
+	"github.com/golangci/golangci-worker/app/analyze/resultjson"
 )
 
 //go:generate mockgen -package prstate -source storage.go -destination storage_mock.go
@@ -11,7 +13,7 @@ type State struct {
 	CreatedAt           time.Time
 	Status              string
 	ReportedIssuesCount int
-	ResultJSON          interface{}
+	ResultJSON          *resultjson.Document
 }
 
 type Storage interface {
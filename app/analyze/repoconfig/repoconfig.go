@@ -0,0 +1,115 @@
+package repoconfig
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/golangci/golangci-worker/app/lib/executors"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var configFiles = []string{".golangci.yml", ".golangci.yaml"}
+
+// IsConfigFile returns true if the file at the path is a golangci config: of the root dir or of a nested one
+func IsConfigFile(filePath string) bool {
+	name := path.Base(filePath)
+	for _, fileName := range configFiles {
+		if name == fileName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RootConfigFile returns the config file of the root dir from the file paths of the repo:
+// it's the file read by Read. It returns empty string if there is no config file.
+func RootConfigFile(filePaths []string) string {
+	for _, fileName := range configFiles {
+		for _, p := range filePaths {
+			if p == fileName {
+				return fileName
+			}
+		}
+	}
+
+	return ""
+}
+
+// Read returns the file name and the content of the golangci config in the working dir of exec.
+// It returns empty strings if there is no config file.
+func Read(ctx context.Context, exec executors.Executor) (string, string, error) {
+	for _, fileName := range configFiles {
+		if _, err := exec.Run(ctx, "test", "-f", fileName); err != nil {
+			continue // no such file
+		}
+
+		out, err := exec.Run(ctx, "cat", fileName)
+		if err != nil {
+			return "", "", fmt.Errorf("can't read %s: %s, %s", fileName, err, out)
+		}
+
+		return fileName, out, nil
+	}
+
+	return "", "", nil
+}
+
+// Fetch returns the file name and the content of the golangci config of the root dir of the commit
+// without fetching the repo, like Read does in the fetched repo. It returns empty strings if there is no config file.
+func Fetch(ctx context.Context, client github.Client, c *github.Context, sha string) (string, string, error) {
+	files, err := client.GetRootFiles(ctx, c, sha)
+	if err != nil {
+		return "", "", err
+	}
+
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+
+	fileName := RootConfigFile(paths)
+	if fileName == "" {
+		return "", "", nil
+	}
+
+	for _, f := range files {
+		if f.Path == fileName {
+			content, err := client.GetBlob(ctx, c, f.SHA)
+			if err != nil {
+				return "", "", fmt.Errorf("can't read %s: %s", fileName, err)
+			}
+			return fileName, content, nil
+		}
+	}
+
+	return "", "", nil
+}
+
+// Load decodes the golangci config in the working dir of exec into v.
+// v isn't changed if there is no config file.
+func Load(ctx context.Context, exec executors.Executor, v interface{}) error {
+	fileName, content, err := Read(ctx, exec)
+	if err != nil {
+		return err
+	}
+
+	return Parse(fileName, content, v)
+}
+
+// Parse decodes the content of the golangci config file into v.
+// v isn't changed if the file name is empty: there is no config file.
+func Parse(fileName, content string, v interface{}) error {
+	if fileName == "" {
+		return nil
+	}
+
+	if err := yaml.Unmarshal([]byte(content), v); err != nil {
+		return errors.Wrapf(err, "failed to parse %s", fileName)
+	}
+
+	return nil
+}
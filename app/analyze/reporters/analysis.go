@@ -0,0 +1,55 @@
+package reporters
+
+import (
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/templates"
+	"github.com/golangci/golangci-worker/app/lib/github"
+)
+
+type Timing struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Analysis is a finished analysis of a pull request or a repo
+type Analysis struct {
+	GUID string
+	Repo github.Repo
+
+	PullRequestNumber int    // zero for repo analysis
+	Branch            string // analyzed branch of repo analysis
+	CommitSHA         string
+	Patch             string // diff of pull request analysis
+
+	Status     string // github status for pull request analysis, e.g. success, or repo analysis status, e.g. processed
+	StatusDesc string
+	Error      string // public error text, empty if analysis succeeded
+	DetailsURL string
+	Timings    []Timing
+
+	Result    *result.Result       // nil if analysis failed
+	Templates *templates.Templates // templates of the repo, nil means the default ones
+}
+
+// GetTemplates returns templates to render reports of the analysis
+func (a Analysis) GetTemplates() *templates.Templates {
+	if a.Templates == nil {
+		return templates.Default()
+	}
+
+	return a.Templates
+}
+
+func (a Analysis) IsPullRequest() bool {
+	return a.PullRequestNumber != 0
+}
+
+func (a Analysis) GetIssues() []result.Issue {
+	if a.Result == nil {
+		return nil
+	}
+
+	return a.Result.Issues
+}
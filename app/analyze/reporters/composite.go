@@ -0,0 +1,140 @@
+package reporters
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/lib/httputils"
+	"github.com/golangci/golangci-worker/app/lib/redisutils"
+	"github.com/pkg/errors"
+)
+
+const defaultRetries = 2
+
+// Target is a reporter inside Composite
+type Target struct {
+	Name     string
+	Reporter Reporter
+	Required bool   // failure of required target fails reporting
+	Retries  uint64 // count of retries after the first failed attempt
+}
+
+// Outcome is a result of reporting to one target
+type Outcome struct {
+	Name     string
+	Required bool
+	Attempts int
+	Err      error
+}
+
+// OutcomesReporter reports to several targets and returns outcome for every target
+type OutcomesReporter interface {
+	Reporter
+	ReportOutcomes(ctx context.Context, a *Analysis) ([]Outcome, error)
+}
+
+// Composite reports to all targets: every target is retried independently
+// and failure of one target doesn't stop reporting to others
+type Composite struct {
+	targets    []Target
+	newBackOff func() backoff.BackOff
+}
+
+var _ OutcomesReporter = &Composite{}
+
+func NewComposite(targets ...Target) *Composite {
+	return &Composite{
+		targets: targets,
+		newBackOff: func() backoff.BackOff {
+			b := backoff.NewExponentialBackOff()
+			b.InitialInterval = time.Second
+			b.MaxElapsedTime = time.Minute
+			return b
+		},
+	}
+}
+
+func (c Composite) reportToTarget(ctx context.Context, t *Target, a *Analysis) Outcome {
+	outcome := Outcome{
+		Name:     t.Name,
+		Required: t.Required,
+	}
+
+	f := func() error {
+		outcome.Attempts++
+		err := t.Reporter.Report(ctx, a)
+		if err != nil {
+			analytics.Log(ctx).Warnf("Reporting to %s failed on attempt %d: %s", t.Name, outcome.Attempts, err)
+		}
+		return err
+	}
+
+	var b backoff.BackOff = &backoff.StopBackOff{}
+	if t.Retries != 0 { // WithMaxRetries treats 0 as infinite retries
+		b = backoff.WithMaxRetries(c.newBackOff(), t.Retries)
+	}
+	outcome.Err = backoff.Retry(f, backoff.WithContext(b, ctx))
+	return outcome
+}
+
+func (c Composite) ReportOutcomes(ctx context.Context, a *Analysis) ([]Outcome, error) {
+	var outcomes []Outcome
+	var requiredErrs []string
+	for i := range c.targets {
+		outcome := c.reportToTarget(ctx, &c.targets[i], a)
+		outcomes = append(outcomes, outcome)
+
+		if outcome.Err != nil && outcome.Required {
+			requiredErrs = append(requiredErrs, fmt.Sprintf("%s: %s", outcome.Name, outcome.Err))
+		}
+	}
+
+	if len(requiredErrs) != 0 {
+		return outcomes, errors.New(strings.Join(requiredErrs, "; "))
+	}
+
+	return outcomes, nil
+}
+
+func (c Composite) Report(ctx context.Context, a *Analysis) error {
+	_, err := c.ReportOutcomes(ctx, a)
+	return err
+}
+
+// NewNotifiersFromEnv builds webhook reporters configured by env vars. They are never required:
+// a failed notification must not fail the analysis, it's only logged and shown as a warning.
+func NewNotifiersFromEnv() []Target {
+	client := httputils.GrequestsClient{}
+
+	var ret []Target
+	if url := os.Getenv("WEBHOOK_URL"); url != "" {
+		ret = append(ret, Target{
+			Name:     "webhook",
+			Reporter: NewWebhook(url, os.Getenv("WEBHOOK_SECRET"), client),
+			Retries:  defaultRetries,
+		})
+	}
+
+	if url := os.Getenv("SLACK_WEBHOOK_URL"); url != "" {
+		var history IssuesHistory
+		if redisutils.IsConfigured() {
+			history = NewRedisIssuesHistory(redisutils.GetPool())
+		} else {
+			history = memoryIssuesHistory
+		}
+
+		reportPullRequests := os.Getenv("SLACK_REPORT_PULL_REQUESTS") == "1"
+		ret = append(ret, Target{
+			Name:     "slack",
+			Reporter: NewSlack(url, client, history, reportPullRequests),
+			Retries:  defaultRetries,
+		})
+	}
+
+	return ret
+}
@@ -0,0 +1,93 @@
+package reporters
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/cenkalti/backoff"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestComposite(targets ...Target) *Composite {
+	c := NewComposite(targets...)
+	c.newBackOff = func() backoff.BackOff {
+		return &backoff.ZeroBackOff{}
+	}
+	return c
+}
+
+func TestCompositeRetriesEveryTarget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	a := &Analysis{}
+
+	flaky := NewMockReporter(ctrl)
+	first := flaky.EXPECT().Report(ctx, a).Return(errors.New("timeout"))
+	flaky.EXPECT().Report(ctx, a).Return(nil).After(first)
+
+	broken := NewMockReporter(ctrl)
+	broken.EXPECT().Report(ctx, a).Times(3).Return(errors.New("500"))
+
+	c := newTestComposite(
+		Target{Name: "flaky", Reporter: flaky, Required: true, Retries: 2},
+		Target{Name: "broken", Reporter: broken, Retries: 2},
+	)
+
+	outcomes, err := c.ReportOutcomes(ctx, a)
+	assert.NoError(t, err) // broken target isn't required
+	assert.Equal(t, []Outcome{
+		{Name: "flaky", Required: true, Attempts: 2},
+		{Name: "broken", Attempts: 3, Err: errors.New("500")},
+	}, outcomes)
+}
+
+func TestCompositeFailsOnRequiredTarget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	a := &Analysis{}
+
+	broken := NewMockReporter(ctrl)
+	broken.EXPECT().Report(ctx, a).Return(errors.New("500"))
+
+	ok := NewMockReporter(ctrl)
+	ok.EXPECT().Report(ctx, a).Return(nil)
+
+	c := newTestComposite(
+		Target{Name: "github", Reporter: broken, Required: true},
+		Target{Name: "webhook", Reporter: ok},
+	)
+
+	err := c.Report(ctx, a)
+	assert.EqualError(t, err, "github: 500")
+}
+
+func TestNotifiersAreNotRequired(t *testing.T) {
+	for k, v := range map[string]string{"WEBHOOK_URL": "http://hook", "SLACK_WEBHOOK_URL": "http://slack"} {
+		prev, wasSet := os.LookupEnv(k)
+		os.Setenv(k, v)
+		defer func(k, prev string, wasSet bool) {
+			if wasSet {
+				os.Setenv(k, prev)
+			} else {
+				os.Unsetenv(k)
+			}
+		}(k, prev, wasSet)
+	}
+
+	targets := NewNotifiersFromEnv()
+	assert.Len(t, targets, 2)
+	for _, target := range targets {
+		assert.False(t, target.Required, "failed %s must not fail the analysis", target.Name)
+	}
+
+	// notifiers are built for every analysis, the slack notifier compares issues counts of analyses
+	nextTargets := NewNotifiersFromEnv()
+	assert.True(t, targets[1].Reporter.(*Slack).history == nextTargets[1].Reporter.(*Slack).history)
+}
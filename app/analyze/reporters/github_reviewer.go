@@ -2,22 +2,33 @@ package reporters
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/golangci/golangci-worker/app/analytics"
 	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/templates"
 	"github.com/golangci/golangci-worker/app/lib/github"
-	gh "github.com/google/go-github/github"
 )
 
+const defaultMaxInlineComments = 30
+
 type GithubReviewer struct {
 	*github.Context
 	client            github.Client
 	includeLinterName bool
+	maxInlineComments int
+	eventsMode        ReviewEventsMode
 }
 
-func NewGithubReviewer(c *github.Context, client github.Client, includeLinterName bool) *GithubReviewer {
+func NewGithubReviewer(c *github.Context, client github.Client, includeLinterName bool,
+	eventsMode ReviewEventsMode) *GithubReviewer {
 	accessToken := os.Getenv("GITHUB_REVIEWER_ACCESS_TOKEN")
 	if accessToken != "" { // review as special user
 		cCopy := *c
@@ -28,20 +39,59 @@ func NewGithubReviewer(c *github.Context, client github.Client, includeLinterNam
 		Context:           c,
 		client:            client,
 		includeLinterName: includeLinterName,
+		maxInlineComments: defaultMaxInlineComments,
+		eventsMode:        eventsMode,
+	}
+	if n, err := strconv.Atoi(os.Getenv("GITHUB_REVIEWER_MAX_INLINE_COMMENTS")); err == nil && n >= 0 {
+		ret.maxInlineComments = n
 	}
 	return ret
 }
 
+// fingerprintRe matches a hidden fingerprint marker in a comment body
+var fingerprintRe = regexp.MustCompile(`<!-- golangci-fingerprint: ([0-9a-f]+) -->`)
+
+func fingerprintMarker(fingerprint string) string {
+	return fmt.Sprintf("<!-- golangci-fingerprint: %s -->", fingerprint)
+}
+
+// summaryRe matches a hidden marker of issues listed in the review summary
+var summaryRe = regexp.MustCompile(`<!-- golangci-summary: ([0-9a-f]+) -->`)
+
+func summaryMarker(key string) string {
+	return fmt.Sprintf("<!-- golangci-summary: %s -->", key)
+}
+
+// summaryKey identifies the set of issues which weren't commented inline: it doesn't depend on their order
+func summaryKey(issues []result.Issue) string {
+	var ids []string
+	for _, i := range issues {
+		id := i.Fingerprint
+		if id == "" {
+			id = fmt.Sprintf("%s:%s:%d:%s", i.FromLinter, i.File, i.LineNumber, i.Text)
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	h := sha256.Sum256([]byte(strings.Join(ids, "\n")))
+	return hex.EncodeToString(h[:])[:32]
+}
+
 type existingComment struct {
-	file string
-	line int
+	file        string
+	line        int
+	fingerprint string
 }
 
 type existingComments []existingComment
 
 func (ecs existingComments) contains(i *result.Issue) bool {
 	for _, c := range ecs {
-		if c.file == i.File && c.line == i.HunkPos {
+		if i.Fingerprint != "" && c.fingerprint == i.Fingerprint {
+			return true // the same issue was already commented, maybe in a previous commit
+		}
+		if c.line != 0 && c.file == i.File && c.line == i.HunkPos {
 			return true
 		}
 	}
@@ -57,63 +107,192 @@ func (gr GithubReviewer) fetchExistingComments(ctx context.Context) (existingCom
 
 	var ret existingComments
 	for _, c := range comments {
-		if c.Position == nil { // comment on outdated code, skip it
+		ec := existingComment{
+			file: c.GetPath(),
+			line: c.GetPosition(), // zero for comments on outdated code
+		}
+		if m := fingerprintRe.FindStringSubmatch(c.GetBody()); m != nil {
+			ec.fingerprint = m[1]
+		}
+		if ec.line == 0 && ec.fingerprint == "" {
 			continue
 		}
-		ret = append(ret, existingComment{
-			file: c.GetPath(),
-			line: c.GetPosition(),
-		})
+		ret = append(ret, ec)
 	}
 
 	return ret, nil
 }
 
-func (gr GithubReviewer) Report(ctx context.Context, ref string, issues []result.Issue) error {
+func (gr GithubReviewer) buildComment(t *templates.Templates, patch github.Patch,
+	i *result.Issue) (*github.ReviewComment, error) {
+	data := buildIssueData(t, i, gr.includeLinterName)
+	lineRange := i.GetLineRange()
+	multiLine := lineRange.To > lineRange.From
+	if multiLine && !patch.InOneHunk(i.File, lineRange.From, lineRange.To) {
+		// github rejects ranges outside of one hunk: comment only the line of the issue,
+		// a suggestion of a single-line comment would replace only this line
+		multiLine = false
+		data.Suggestion = ""
+	}
+
+	text, err := t.RenderComment(data)
+	if err != nil {
+		return nil, err
+	}
+	if i.Fingerprint != "" {
+		text += "\n\n" + fingerprintMarker(i.Fingerprint)
+	}
+
+	comment := &github.ReviewComment{
+		Path: i.File,
+		Body: text,
+	}
+
+	if multiLine {
+		// attach the comment to the whole range: a suggestion replaces all lines of the comment
+		comment.StartLine = lineRange.From
+		comment.StartSide = github.SideRight
+		comment.Line = lineRange.To
+		comment.Side = github.SideRight
+	} else {
+		comment.Position = i.HunkPos
+	}
+
+	return comment, nil
+}
+
+func (gr GithubReviewer) buildComments(ctx context.Context, t *templates.Templates, patch github.Patch,
+	issues []result.Issue) ([]*github.ReviewComment, []result.Issue, error) {
 	if len(issues) == 0 {
-		analytics.Log(ctx).Infof("Nothing to report")
-		return nil
+		return nil, nil, nil
 	}
 
 	existingComments, err := gr.fetchExistingComments(ctx)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	comments := []*gh.DraftReviewComment{}
+	comments := []*github.ReviewComment{}
+	var notCommented []result.Issue
 	for _, i := range issues {
 		if existingComments.contains(&i) {
 			continue // don't be annoying: don't comment on the same line twice
 		}
 
-		text := i.Text
-		if gr.includeLinterName && i.FromLinter != "" {
-			text += fmt.Sprintf(" (from `%s`)", i.FromLinter)
+		// issues outside of the diff can't be commented inline, too many comments flood the pull request
+		if i.HunkPos == 0 || len(comments) >= gr.maxInlineComments {
+			notCommented = append(notCommented, i)
+			continue
 		}
 
-		comment := &gh.DraftReviewComment{
-			Path:     gh.String(i.File),
-			Position: gh.Int(i.HunkPos),
-			Body:     gh.String(text),
+		comment, buildErr := gr.buildComment(t, patch, &i)
+		if buildErr != nil {
+			return nil, nil, buildErr
 		}
 		comments = append(comments, comment)
 	}
 
-	if len(comments) == 0 {
+	return comments, notCommented, nil
+}
+
+func (gr GithubReviewer) fetchOwnReviews(ctx context.Context) (ownReviews, error) {
+	reviews, err := gr.client.GetPullRequestReviews(ctx, gr.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterOwnReviews(reviews), nil
+}
+
+func (gr GithubReviewer) dismissChangesRequests(ctx context.Context, reviews ownReviews) error {
+	for _, r := range reviews.withChangesRequested() {
+		if err := gr.client.DismissReview(ctx, gr.Context, r.GetID(), dismissMessage); err != nil {
+			return fmt.Errorf("can't dismiss review %d: %s", r.GetID(), err)
+		}
+	}
+
+	analytics.Log(ctx).Infof("Dismissed own requests of changes")
+	return nil
+}
+
+func (gr GithubReviewer) Report(ctx context.Context, a *Analysis) error {
+	res := a.Result
+	if res == nil || !a.IsPullRequest() {
+		return nil // analysis failed: nothing to review
+	}
+
+	issues := res.Issues
+	if len(issues) == 0 && gr.eventsMode == ReviewEventsModeComment {
+		analytics.Log(ctx).Infof("Nothing to report")
+		return nil
+	}
+
+	t := a.GetTemplates()
+	comments, notCommented, err := gr.buildComments(ctx, t, github.ParsePatch(a.Patch), issues)
+	if err != nil {
+		return err
+	}
+
+	var reviews ownReviews
+	if gr.eventsMode != ReviewEventsModeComment || len(notCommented) != 0 {
+		if reviews, err = gr.fetchOwnReviews(ctx); err != nil {
+			return fmt.Errorf("can't fetch reviews: %s", err)
+		}
+	}
+
+	// issues outside of the diff are listed in the summary: don't post the same summary on every push
+	notSummarized := notCommented
+	if len(notCommented) != 0 && reviews.containSummary(summaryKey(notCommented)) {
+		notSummarized = nil
+	}
+
+	event := github.ReviewEventComment
+	needDismiss := false
+	if gr.eventsMode != ReviewEventsModeComment {
+		changesRequested := reviews.changesRequested()
+		switch {
+		case result.HasBlockingIssues(issues, res.FailSeverity):
+			if !changesRequested || len(comments) != 0 || len(notSummarized) != 0 {
+				event = github.ReviewEventRequestChanges
+			}
+		case changesRequested && gr.eventsMode == ReviewEventsModeApprove:
+			event = github.ReviewEventApprove
+		case changesRequested:
+			needDismiss = true
+		}
+	}
+
+	if len(comments) == 0 && len(notSummarized) == 0 && event == github.ReviewEventComment {
+		if needDismiss {
+			return gr.dismissChangesRequests(ctx, reviews)
+		}
 		return nil // all comments are already exist
 	}
 
-	review := &gh.PullRequestReviewRequest{
-		CommitID: gh.String(ref),
-		Event:    gh.String("COMMENT"),
-		Body:     gh.String(""),
+	summary, err := buildReviewSummary(t, issues, notCommented)
+	if err != nil {
+		return err
+	}
+
+	if len(notCommented) != 0 {
+		summary += "\n\n" + summaryMarker(summaryKey(notCommented))
+	}
+
+	review := &github.Review{
+		CommitID: a.CommitSHA,
+		Body:     summary + "\n\n" + reviewMarker,
+		Event:    event,
 		Comments: comments,
 	}
-	if err := gr.client.CreateReview(ctx, gr.Context, review); err != nil {
+	if err = gr.client.CreateReview(ctx, gr.Context, review); err != nil {
 		return fmt.Errorf("can't create review %+v: %s", review, err)
 	}
 
-	analytics.Log(ctx).Infof("Submitted review %+v, existing comments: %+v, issues: %+v",
-		review, existingComments, issues)
+	analytics.Log(ctx).Infof("Submitted review %+v, issues: %+v", review, issues)
+
+	if needDismiss {
+		return gr.dismissChangesRequests(ctx, reviews)
+	}
+
 	return nil
 }
@@ -0,0 +1,90 @@
+package reporters
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/templates"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	gh "github.com/google/go-github/github"
+	"github.com/stretchr/testify/assert"
+)
+
+const testReviewPatch = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package p
++var a = 1
+ var b = 2
+ var c = 3
+@@ -10,2 +11,3 @@
+ var d = 4
++var e = 5
+ var f = 6
+`
+
+func TestBuildCommentRange(t *testing.T) {
+	gr := GithubReviewer{}
+	patch := github.ParsePatch(testReviewPatch)
+	issue := result.Issue{
+		FromLinter:  "gofmt",
+		Text:        "not formatted",
+		File:        "main.go",
+		LineNumber:  2,
+		EndLine:     3,
+		HunkPos:     2,
+		Replacement: &result.Replacement{NewLines: []string{"var a, b = 1, 2"}},
+	}
+
+	comment, err := gr.buildComment(templates.Default(), patch, &issue)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, comment.StartLine)
+	assert.Equal(t, 3, comment.Line)
+	assert.Zero(t, comment.Position)
+	assert.Contains(t, comment.Body, "```suggestion")
+
+	// the range of two hunks is commented on the line of the issue without the suggestion
+	issue.EndLine = 12
+	comment, err = gr.buildComment(templates.Default(), patch, &issue)
+	assert.NoError(t, err)
+	assert.Zero(t, comment.StartLine)
+	assert.Zero(t, comment.Line)
+	assert.Equal(t, 2, comment.Position)
+	assert.False(t, strings.Contains(comment.Body, "```suggestion"))
+}
+
+func TestReportDoesntRepeatSummary(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	issues := []result.Issue{
+		{FromLinter: "govet", File: "main.go", LineNumber: 20, Text: "outside of diff", Fingerprint: "f1"},
+	}
+	a := &Analysis{
+		PullRequestNumber: 1,
+		CommitSHA:         "sha",
+		Result:            &result.Result{Issues: issues},
+	}
+
+	var postedReviews []*gh.PullRequestReview
+	client := github.NewMockClient(ctrl)
+	client.EXPECT().GetPullRequestComments(gomock.Any(), gomock.Any()).Return(nil, nil).Times(2)
+	client.EXPECT().GetPullRequestReviews(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(context.Context, *github.Context) ([]*gh.PullRequestReview, error) {
+			return postedReviews, nil
+		}).Times(2)
+	client.EXPECT().CreateReview(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ *github.Context, r *github.Review) error {
+			assert.Contains(t, r.Body, summaryMarker(summaryKey(issues)))
+			postedReviews = append(postedReviews, &gh.PullRequestReview{Body: gh.String(r.Body)})
+			return nil
+		})
+
+	gr := NewGithubReviewer(&github.FakeContext, client, false, ReviewEventsModeComment)
+	assert.NoError(t, gr.Report(context.Background(), a))
+	assert.NoError(t, gr.Report(context.Background(), a), "the same summary must not be posted again")
+}
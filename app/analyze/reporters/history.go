@@ -0,0 +1,89 @@
+package reporters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+//go:generate mockgen -package reporters -source history.go -destination history_mock.go
+
+// IssuesHistory keeps issues count of the latest analysis
+type IssuesHistory interface {
+	// Get returns issues count of the latest analysis, found is false if there was no analysis
+	Get(ctx context.Context, key string) (count int, found bool, err error)
+	Set(ctx context.Context, key string, count int) error
+}
+
+type MemoryIssuesHistory struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// memoryIssuesHistory is used by notifiers of all analyses of the worker if redis isn't configured:
+// notifiers are built for every analysis
+var memoryIssuesHistory = NewMemoryIssuesHistory()
+
+func NewMemoryIssuesHistory() *MemoryIssuesHistory {
+	return &MemoryIssuesHistory{
+		counts: map[string]int{},
+	}
+}
+
+func (h *MemoryIssuesHistory) Get(_ context.Context, key string) (int, bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	count, found := h.counts[key]
+	return count, found, nil
+}
+
+func (h *MemoryIssuesHistory) Set(_ context.Context, key string, count int) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.counts[key] = count
+	return nil
+}
+
+type RedisIssuesHistory struct {
+	pool *redis.Pool
+}
+
+func NewRedisIssuesHistory(pool *redis.Pool) *RedisIssuesHistory {
+	return &RedisIssuesHistory{
+		pool: pool,
+	}
+}
+
+func (h RedisIssuesHistory) redisKey(key string) string {
+	return "issues_history:" + key
+}
+
+func (h RedisIssuesHistory) Get(_ context.Context, key string) (int, bool, error) {
+	conn := h.pool.Get()
+	defer conn.Close()
+
+	count, err := redis.Int(conn.Do("GET", h.redisKey(key)))
+	if err == redis.ErrNil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("can't get issues count from redis: %s", err)
+	}
+
+	return count, true, nil
+}
+
+func (h RedisIssuesHistory) Set(_ context.Context, key string, count int) error {
+	conn := h.pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("SET", h.redisKey(key), count); err != nil {
+		return fmt.Errorf("can't set issues count in redis: %s", err)
+	}
+
+	return nil
+}
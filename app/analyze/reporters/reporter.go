@@ -1,13 +1,9 @@
 package reporters
 
-import (
-	"context"
-
-	"github.com/golangci/golangci-worker/app/analyze/linters/result"
-)
+import "context"
 
 //go:generate mockgen -package reporters -source reporter.go -destination reporter_mock.go
 
 type Reporter interface {
-	Report(ctx context.Context, ref string, issues []result.Issue) error
+	Report(ctx context.Context, a *Analysis) error
 }
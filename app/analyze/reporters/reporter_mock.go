@@ -6,7 +6,6 @@ package reporters
 import (
 	context "context"
 	gomock "github.com/golang/mock/gomock"
-	result "github.com/golangci/golangci-worker/app/analyze/linters/result"
 	reflect "reflect"
 )
 
@@ -34,13 +33,13 @@ func (_m *MockReporter) EXPECT() *MockReporterMockRecorder {
 }
 
 // Report mocks base method
-func (_m *MockReporter) Report(ctx context.Context, ref string, issues []result.Issue) error {
-	ret := _m.ctrl.Call(_m, "Report", ctx, ref, issues)
+func (_m *MockReporter) Report(ctx context.Context, a *Analysis) error {
+	ret := _m.ctrl.Call(_m, "Report", ctx, a)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Report indicates an expected call of Report
-func (_mr *MockReporterMockRecorder) Report(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "Report", reflect.TypeOf((*MockReporter)(nil).Report), arg0, arg1, arg2)
+func (_mr *MockReporterMockRecorder) Report(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "Report", reflect.TypeOf((*MockReporter)(nil).Report), arg0, arg1)
 }
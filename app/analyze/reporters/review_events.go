@@ -0,0 +1,78 @@
+package reporters
+
+import (
+	"strings"
+
+	"github.com/golangci/golangci-worker/app/lib/github"
+	gh "github.com/google/go-github/github"
+)
+
+type ReviewEventsMode string
+
+const (
+	// ReviewEventsModeComment submits all reviews as comments
+	ReviewEventsModeComment ReviewEventsMode = ""
+
+	// ReviewEventsModeDismiss requests changes if there are blocking issues
+	// and dismisses own requests of changes when they are fixed
+	ReviewEventsModeDismiss ReviewEventsMode = "dismiss"
+
+	// ReviewEventsModeApprove requests changes if there are blocking issues
+	// and approves the pull request when they are fixed
+	ReviewEventsModeApprove ReviewEventsMode = "approve"
+)
+
+// reviewMarker is a hidden marker in the body of reviews submitted by the reviewer
+const reviewMarker = "<!-- golangci-review -->"
+
+const dismissMessage = "All blocking issues are fixed"
+
+type ownReviews []*gh.PullRequestReview
+
+func filterOwnReviews(reviews []*gh.PullRequestReview) ownReviews {
+	var ret ownReviews
+	for _, r := range reviews {
+		if strings.Contains(r.GetBody(), reviewMarker) {
+			ret = append(ret, r)
+		}
+	}
+
+	return ret
+}
+
+// changesRequested returns true if the latest own review requesting changes
+// wasn't approved or dismissed: GitHub uses the latest such review as the reviewer's state
+func (rs ownReviews) changesRequested() bool {
+	for i := len(rs) - 1; i >= 0; i-- {
+		switch rs[i].GetState() {
+		case github.ReviewStateChangesRequested:
+			return true
+		case github.ReviewStateApproved, github.ReviewStateDismissed:
+			return false
+		}
+	}
+
+	return false
+}
+
+func (rs ownReviews) withChangesRequested() []*gh.PullRequestReview {
+	var ret []*gh.PullRequestReview
+	for _, r := range rs {
+		if r.GetState() == github.ReviewStateChangesRequested {
+			ret = append(ret, r)
+		}
+	}
+
+	return ret
+}
+
+// containSummary returns true if issues with the summary key were already listed in an own review
+func (rs ownReviews) containSummary(key string) bool {
+	for _, r := range rs {
+		if m := summaryRe.FindStringSubmatch(r.GetBody()); m != nil && m[1] == key {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package reporters
+
+import (
+	"testing"
+
+	"github.com/golangci/golangci-worker/app/lib/github"
+	gh "github.com/google/go-github/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeReview(state string, own bool) *gh.PullRequestReview {
+	body := "review"
+	if own {
+		body += "\n\n" + reviewMarker
+	}
+
+	return &gh.PullRequestReview{
+		Body:  gh.String(body),
+		State: gh.String(state),
+	}
+}
+
+func TestOwnReviewsChangesRequested(t *testing.T) {
+	type testCase struct {
+		name    string
+		reviews []*gh.PullRequestReview
+		exp     bool
+	}
+
+	testCases := []testCase{
+		{
+			name: "no reviews",
+		},
+		{
+			name: "changes requested",
+			reviews: []*gh.PullRequestReview{
+				makeReview(github.ReviewStateChangesRequested, true),
+				makeReview("COMMENTED", true),
+			},
+			exp: true,
+		},
+		{
+			name: "approved after changes requested",
+			reviews: []*gh.PullRequestReview{
+				makeReview(github.ReviewStateChangesRequested, true),
+				makeReview(github.ReviewStateApproved, true),
+			},
+		},
+		{
+			name: "dismissed",
+			reviews: []*gh.PullRequestReview{
+				makeReview(github.ReviewStateDismissed, true),
+			},
+		},
+		{
+			name: "changes requested by other user",
+			reviews: []*gh.PullRequestReview{
+				makeReview(github.ReviewStateChangesRequested, false),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exp, filterOwnReviews(tc.reviews).changesRequested())
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package reporters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/golangci/golangci-worker/app/lib/httputils"
+	"github.com/pkg/errors"
+)
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackAttachment struct {
+	Fallback  string       `json:"fallback"`
+	Color     string       `json:"color"`
+	Title     string       `json:"title"`
+	TitleLink string       `json:"title_link,omitempty"`
+	Fields    []slackField `json:"fields,omitempty"`
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+const (
+	slackColorDanger  = "danger"
+	slackColorGood    = "good"
+	slackColorWarning = "warning"
+)
+
+// Slack posts messages to a Slack incoming webhook.
+// Repo analyses are reported only if they became worse: issues count increased.
+type Slack struct {
+	webhookURL         string
+	client             httputils.Client
+	history            IssuesHistory
+	reportPullRequests bool
+}
+
+func NewSlack(webhookURL string, client httputils.Client, history IssuesHistory, reportPullRequests bool) *Slack {
+	return &Slack{
+		webhookURL:         webhookURL,
+		client:             client,
+		history:            history,
+		reportPullRequests: reportPullRequests,
+	}
+}
+
+func (s Slack) Report(ctx context.Context, a *Analysis) error {
+	if a.IsPullRequest() {
+		if !s.reportPullRequests {
+			return nil
+		}
+
+		return s.post(ctx, buildSlackPullRequestMessage(a))
+	}
+
+	if a.Result == nil {
+		return nil // analysis failed: can't compare
+	}
+
+	key := fmt.Sprintf("%s/%s", a.Repo.FullName(), a.Branch)
+	count := len(a.Result.Issues)
+	prevCount, found, err := s.history.Get(ctx, key)
+	if err != nil {
+		return errors.Wrap(err, "failed to get previous issues count")
+	}
+
+	if found && count > prevCount {
+		if err = s.post(ctx, buildSlackRepoMessage(a, prevCount)); err != nil {
+			return err // don't save the count to notify on retry
+		}
+	} else {
+		analytics.Log(ctx).Infof("Repo analysis didn't become worse: %d -> %d issues, don't notify Slack",
+			prevCount, count)
+	}
+
+	if err = s.history.Set(ctx, key, count); err != nil {
+		return errors.Wrap(err, "failed to save issues count")
+	}
+
+	return nil
+}
+
+func (s Slack) post(ctx context.Context, msg *slackMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal slack message")
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+	if err = s.client.Post(ctx, s.webhookURL, body, headers); err != nil {
+		return fmt.Errorf("can't send slack message: %s", err)
+	}
+
+	return nil
+}
+
+func buildSlackIssuesFields(issues []result.Issue) []slackField {
+	counts := map[result.Severity]int{}
+	for _, i := range issues {
+		counts[i.GetSeverity()]++
+	}
+
+	var ret []slackField
+	for _, sev := range result.Severities {
+		if counts[sev] != 0 {
+			ret = append(ret, slackField{
+				Title: strings.Title(string(sev)),
+				Value: fmt.Sprint(counts[sev]),
+				Short: true,
+			})
+		}
+	}
+
+	return ret
+}
+
+func buildSlackRepoMessage(a *Analysis, prevCount int) *slackMessage {
+	issues := a.GetIssues()
+	text := fmt.Sprintf("Analysis of %s@%s became worse: %d issues (+%d)",
+		a.Repo.FullName(), a.Branch, len(issues), len(issues)-prevCount)
+
+	return &slackMessage{
+		Text: text,
+		Attachments: []slackAttachment{
+			{
+				Fallback:  text,
+				Color:     slackColorDanger,
+				Title:     fmt.Sprintf("%s@%s", a.Repo.FullName(), a.Branch),
+				TitleLink: a.DetailsURL,
+				Fields:    buildSlackIssuesFields(issues),
+			},
+		},
+	}
+}
+
+func buildSlackPullRequestMessage(a *Analysis) *slackMessage {
+	text := fmt.Sprintf("Analysis of %s#%d: %s", a.Repo.FullName(), a.PullRequestNumber, a.StatusDesc)
+	if a.Error != "" {
+		text = fmt.Sprintf("Analysis of %s#%d failed: %s", a.Repo.FullName(), a.PullRequestNumber, a.Error)
+	}
+
+	color := slackColorGood
+	switch {
+	case a.Error != "":
+		color = slackColorWarning
+	case a.Status == string(github.StatusFailure):
+		color = slackColorDanger
+	}
+
+	return &slackMessage{
+		Text: text,
+		Attachments: []slackAttachment{
+			{
+				Fallback:  text,
+				Color:     color,
+				Title:     fmt.Sprintf("%s#%d", a.Repo.FullName(), a.PullRequestNumber),
+				TitleLink: a.DetailsURL,
+				Fields:    buildSlackIssuesFields(a.GetIssues()),
+			},
+		},
+	}
+}
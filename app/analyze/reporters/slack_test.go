@@ -0,0 +1,47 @@
+package reporters
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/golangci/golangci-worker/app/lib/httputils"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeRepoAnalysis(issuesCount int) *Analysis {
+	return &Analysis{
+		Repo:   github.Repo{Owner: "owner", Name: "repo"},
+		Branch: "master",
+		Result: &result.Result{
+			Issues: make([]result.Issue, issuesCount),
+		},
+	}
+}
+
+func TestSlackReportsWorseRepoAnalysis(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	const url = "https://hooks.slack.com/services/x"
+	client := httputils.NewMockClient(ctrl)
+	client.EXPECT().Post(gomock.Any(), url, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, body []byte, _ map[string]string) error {
+			var msg slackMessage
+			assert.NoError(t, json.Unmarshal(body, &msg))
+			assert.Equal(t, "Analysis of owner/repo@master became worse: 3 issues (+1)", msg.Text)
+			return nil
+		})
+
+	s := NewSlack(url, client, NewMemoryIssuesHistory(), false)
+	ctx := context.Background()
+
+	assert.NoError(t, s.Report(ctx, makeRepoAnalysis(2))) // no previous analysis
+	assert.NoError(t, s.Report(ctx, makeRepoAnalysis(3))) // worse: notify
+	assert.NoError(t, s.Report(ctx, makeRepoAnalysis(3))) // the same
+	assert.NoError(t, s.Report(ctx, makeRepoAnalysis(1))) // better
+	assert.NoError(t, s.Report(ctx, testAnalysis))        // pull requests aren't reported
+}
@@ -0,0 +1,36 @@
+package reporters
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+)
+
+var backticksRe = regexp.MustCompile("`{3,}")
+
+// buildSuggestion renders the replacement as a GitHub suggested change:
+// it replaces all lines the review comment is attached to
+func buildSuggestion(r *result.Replacement) string {
+	var newLines []string
+	if !r.NeedOnlyDelete {
+		newLines = r.NewLines
+	}
+
+	// the fence must be longer than any backticks sequence in the code
+	fence := "```"
+	for _, line := range newLines {
+		for _, m := range backticksRe.FindAllString(line, -1) {
+			if len(m) >= len(fence) {
+				fence = strings.Repeat("`", len(m)+1)
+			}
+		}
+	}
+
+	if len(newLines) == 0 {
+		return fmt.Sprintf("%ssuggestion\n%s", fence, fence)
+	}
+
+	return fmt.Sprintf("%ssuggestion\n%s\n%s", fence, strings.Join(newLines, "\n"), fence)
+}
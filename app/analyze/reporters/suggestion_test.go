@@ -0,0 +1,45 @@
+package reporters
+
+import (
+	"testing"
+
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSuggestion(t *testing.T) {
+	type testCase struct {
+		name        string
+		replacement result.Replacement
+		exp         string
+	}
+
+	testCases := []testCase{
+		{
+			name:        "one line",
+			replacement: result.Replacement{NewLines: []string{"\tx := 1"}},
+			exp:         "```suggestion\n\tx := 1\n```",
+		},
+		{
+			name:        "multiple lines",
+			replacement: result.Replacement{NewLines: []string{"import (", `	"fmt"`, ")"}},
+			exp:         "```suggestion\nimport (\n\t\"fmt\"\n)\n```",
+		},
+		{
+			name:        "delete",
+			replacement: result.Replacement{NeedOnlyDelete: true, NewLines: []string{"ignored"}},
+			exp:         "```suggestion\n```",
+		},
+		{
+			name:        "backticks in code",
+			replacement: result.Replacement{NewLines: []string{"s := `````"}},
+			exp:         "``````suggestion\ns := `````\n``````",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exp, buildSuggestion(&tc.replacement))
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package reporters
+
+import (
+	"sort"
+
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/templates"
+)
+
+func countIssuesBy(issues []result.Issue, key func(i *result.Issue) string) []templates.CountData {
+	counts := map[string]int{}
+	for idx := range issues {
+		name := key(&issues[idx])
+		if name == "" {
+			name = "unknown"
+		}
+		counts[name]++
+	}
+
+	var ret []templates.CountData
+	for name, count := range counts {
+		ret = append(ret, templates.CountData{Name: name, Count: count})
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Count != ret[j].Count {
+			return ret[i].Count > ret[j].Count
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+func buildIssueData(t *templates.Templates, i *result.Issue, includeLinterName bool) *templates.IssueData {
+	ret := &templates.IssueData{
+		Text:              i.Text,
+		Linter:            i.FromLinter,
+		LinterDocsURL:     t.LinterDocsURL(i.FromLinter),
+		File:              i.File,
+		Line:              i.LineNumber,
+		Severity:          string(i.GetSeverity()),
+		Fingerprint:       i.Fingerprint,
+		IncludeLinterName: includeLinterName,
+	}
+	if i.Replacement != nil {
+		ret.Suggestion = buildSuggestion(i.Replacement)
+	}
+
+	return ret
+}
+
+// buildReviewSummary renders the review body: counts of all found issues
+// and a list of issues which weren't commented inline
+func buildReviewSummary(t *templates.Templates, issues, notCommented []result.Issue) (string, error) {
+	data := &templates.SummaryData{
+		Total: len(issues),
+		ByLinter: countIssuesBy(issues, func(i *result.Issue) string {
+			return i.FromLinter
+		}),
+		ByFile: countIssuesBy(issues, func(i *result.Issue) string {
+			return i.File
+		}),
+	}
+	for idx := range notCommented {
+		data.NotCommented = append(data.NotCommented, *buildIssueData(t, &notCommented[idx], true))
+	}
+
+	return t.RenderSummary(data)
+}
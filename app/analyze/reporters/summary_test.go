@@ -0,0 +1,33 @@
+package reporters
+
+import (
+	"testing"
+
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/templates"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildReviewSummary(t *testing.T) {
+	issues := []result.Issue{
+		{FromLinter: "golint", File: "a.go", LineNumber: 1, Text: "exported func should have comment"},
+		{FromLinter: "govet", File: "b.go", LineNumber: 2, Text: "a | b\nc"},
+		{FromLinter: "golint", File: "b.go", LineNumber: 3, Text: "x"},
+	}
+
+	exp := "GolangCI found 3 issues.\n\n" +
+		"| Linter | Issues |\n| --- | ---: |\n| `golint` | 2 |\n| `govet` | 1 |\n" +
+		"\n" +
+		"| File | Issues |\n| --- | ---: |\n| `b.go` | 2 |\n| `a.go` | 1 |\n"
+	summary, err := buildReviewSummary(templates.Default(), issues, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, exp, summary)
+
+	expDetails := exp + "\n<details>\n<summary>1 issue not commented inline</summary>\n\n" +
+		"| File | Line | Linter | Issue |\n| --- | ---: | --- | --- |\n" +
+		"| `b.go` | 2 | govet | a \\| b c |\n" +
+		"\n</details>"
+	summary, err = buildReviewSummary(templates.Default(), issues, issues[1:2])
+	assert.NoError(t, err)
+	assert.Equal(t, expDetails, summary)
+}
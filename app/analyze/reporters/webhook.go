@@ -0,0 +1,121 @@
+package reporters
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/lib/httputils"
+	"github.com/pkg/errors"
+)
+
+const (
+	webhookEventHeader     = "X-GolangCI-Event"
+	webhookSignatureHeader = "X-GolangCI-Signature"
+	webhookEventAnalysis   = "analysis"
+)
+
+type webhookTiming struct {
+	Name       string `json:"name"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type webhookPayload struct {
+	AnalysisGUID      string `json:"analysis_guid"`
+	Repo              string `json:"repo"`
+	PullRequestNumber int    `json:"pull_request_number,omitempty"`
+	Branch            string `json:"branch,omitempty"`
+	CommitSHA         string `json:"commit_sha,omitempty"`
+
+	Status            string `json:"status"`
+	StatusDescription string `json:"status_description,omitempty"`
+	Error             string `json:"error,omitempty"`
+	DetailsURL        string `json:"details_url,omitempty"`
+
+	IssuesCount      int            `json:"issues_count"`
+	IssuesBySeverity map[string]int `json:"issues_by_severity"`
+	IssuesByLinter   map[string]int `json:"issues_by_linter"`
+
+	Timings []webhookTiming `json:"timings"`
+}
+
+func buildWebhookPayload(a *Analysis) *webhookPayload {
+	ret := &webhookPayload{
+		AnalysisGUID:      a.GUID,
+		Repo:              a.Repo.FullName(),
+		PullRequestNumber: a.PullRequestNumber,
+		Branch:            a.Branch,
+		CommitSHA:         a.CommitSHA,
+		Status:            a.Status,
+		StatusDescription: a.StatusDesc,
+		Error:             a.Error,
+		DetailsURL:        a.DetailsURL,
+		IssuesBySeverity:  map[string]int{},
+		IssuesByLinter:    map[string]int{},
+		Timings:           []webhookTiming{},
+	}
+
+	issues := a.GetIssues()
+	ret.IssuesCount = len(issues)
+	for _, i := range issues {
+		ret.IssuesBySeverity[string(i.GetSeverity())]++
+		ret.IssuesByLinter[i.FromLinter]++
+	}
+
+	for _, t := range a.Timings {
+		ret.Timings = append(ret.Timings, webhookTiming{
+			Name:       t.Name,
+			DurationMs: int64(t.Duration / time.Millisecond),
+		})
+	}
+
+	return ret
+}
+
+// signPayload returns hex-encoded HMAC-SHA256 of the body: receivers
+// check it to be sure the payload was sent by the worker
+func signPayload(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body) //nolint:errcheck
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Webhook posts a JSON with the analysis outcome to the url
+type Webhook struct {
+	url    string
+	secret string
+	client httputils.Client
+}
+
+func NewWebhook(url, secret string, client httputils.Client) *Webhook {
+	return &Webhook{
+		url:    url,
+		secret: secret,
+		client: client,
+	}
+}
+
+func (w Webhook) Report(ctx context.Context, a *Analysis) error {
+	body, err := json.Marshal(buildWebhookPayload(a))
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal webhook payload")
+	}
+
+	headers := map[string]string{
+		"Content-Type":     "application/json",
+		webhookEventHeader: webhookEventAnalysis,
+	}
+	if w.secret != "" {
+		headers[webhookSignatureHeader] = signPayload(body, w.secret)
+	}
+
+	if err = w.client.Post(ctx, w.url, body, headers); err != nil {
+		return fmt.Errorf("can't send webhook: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package reporters
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/golangci/golangci-worker/app/lib/httputils"
+	"github.com/stretchr/testify/assert"
+)
+
+var testAnalysis = &Analysis{
+	GUID:              "guid",
+	Repo:              github.Repo{Owner: "owner", Name: "repo"},
+	PullRequestNumber: 1,
+	CommitSHA:         "sha",
+	Status:            string(github.StatusFailure),
+	StatusDesc:        "2 issues found: 1 error, 1 warning",
+	DetailsURL:        "https://golangci.com/r/github.com/owner/repo/pulls/1",
+	Timings:           []Timing{{Name: "Analysis", Duration: 1500 * time.Millisecond}},
+	Result: &result.Result{
+		Issues: []result.Issue{
+			{FromLinter: "govet", Severity: result.SeverityError},
+			{FromLinter: "golint", Severity: result.SeverityWarning},
+		},
+	},
+}
+
+func TestWebhookReport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	const url = "https://example.com/hook"
+	client := httputils.NewMockClient(ctrl)
+	client.EXPECT().Post(gomock.Any(), url, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, body []byte, headers map[string]string) error {
+			assert.Equal(t, signPayload(body, "secret"), headers[webhookSignatureHeader])
+			assert.Equal(t, webhookEventAnalysis, headers[webhookEventHeader])
+
+			var payload webhookPayload
+			assert.NoError(t, json.Unmarshal(body, &payload))
+			assert.Equal(t, "owner/repo", payload.Repo)
+			assert.Equal(t, 1, payload.PullRequestNumber)
+			assert.Equal(t, 2, payload.IssuesCount)
+			assert.Equal(t, map[string]int{"error": 1, "warning": 1}, payload.IssuesBySeverity)
+			assert.Equal(t, []webhookTiming{{Name: "Analysis", DurationMs: 1500}}, payload.Timings)
+			return nil
+		})
+
+	assert.NoError(t, NewWebhook(url, "secret", client).Report(context.Background(), testAnalysis))
+}
+
+func TestSignPayload(t *testing.T) {
+	// echo -n '{}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=77325902caca812dc259733aacd046b73817372c777b8d95b402647474516e13", signPayload([]byte("{}"), "secret"))
+}
@@ -3,6 +3,8 @@ package repostate
 import (
 	"context"
 	"time"
+
+	"github.com/golangci/golangci-worker/app/analyze/resultjson"
 )
 
 //go:generate mockgen -package repostate -source storage.go -destination storage_mock.go
@@ -10,7 +12,7 @@ import (
 type State struct {
 	CreatedAt  time.Time
 	Status     string
-	ResultJSON interface{}
+	ResultJSON *resultjson.Document
 }
 
 type Storage interface {
@@ -0,0 +1,162 @@
+package resultcache
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/analyze/linters"
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/repoconfig"
+	"github.com/golangci/golangci-worker/app/analyze/severity"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+	"github.com/golangci/golangci-worker/app/lib/github"
+)
+
+// Target is the analyzed commit
+type Target struct {
+	Context   *github.Context // the access token can be empty for public repos
+	CommitSHA string          // results aren't cached if it's empty
+	Patch     string          // set for pull requests
+}
+
+// AnalysisCache gets and saves results of linters of one analysis. Its key is built from the commit,
+// versions and golangci configs listed by github API before the repo is fetched: the analysis with
+// a cached result doesn't need to prepare the repo.
+type AnalysisCache struct {
+	store  Store
+	client github.Client
+	target *Target
+	key    Key
+
+	configFiles []github.TreeFile
+}
+
+// NewAnalysisCache returns nil if results of the target can't be cached:
+// nil AnalysisCache has no results and doesn't save them
+func NewAnalysisCache(ctx context.Context, store Store, client github.Client,
+	target *Target, lnts []linters.Linter) *AnalysisCache {
+
+	if store == nil {
+		return nil
+	}
+
+	if target.CommitSHA == "" {
+		analytics.Log(ctx).Infof("Commit isn't known before fetching the repo, don't cache result")
+		return nil
+	}
+
+	files, err := client.GetTreeFiles(ctx, target.Context, target.CommitSHA)
+	if err != nil {
+		analytics.Log(ctx).Warnf("Can't list files of commit %s, don't cache result: %s", target.CommitSHA, err)
+		return nil
+	}
+
+	c := &AnalysisCache{
+		store:  store,
+		client: client,
+		target: target,
+	}
+	for _, f := range files {
+		if repoconfig.IsConfigFile(f.Path) {
+			c.configFiles = append(c.configFiles, f)
+		}
+	}
+
+	c.key = c.buildKey(lnts)
+	if !c.key.IsValid() {
+		analytics.Log(ctx).Infof("Result cache key %+v isn't complete, don't cache result", c.key)
+		return nil
+	}
+
+	return c
+}
+
+// buildKey hashes golangci configs of all dirs: nested modules are analyzed with their own configs.
+// Arguments and env of linters and the deployment fail severity change results too.
+func (c AnalysisCache) buildKey(lnts []linters.Linter) Key {
+	var configs []string
+	for _, f := range c.configFiles {
+		configs = append(configs, f.Path+" "+f.SHA)
+	}
+	sort.Strings(configs)
+
+	parts := configs
+	for _, l := range lnts {
+		parts = append(parts, linters.Fingerprint(l))
+	}
+	parts = append(parts, string(severity.DeploymentFailSeverity()))
+
+	key := Key{
+		Repo:          strings.ToLower(c.target.Context.Repo.FullName()),
+		CommitSHA:     c.target.CommitSHA,
+		LintVersion:   getLintVersion(),
+		ConfigHash:    hash(strings.Join(parts, "\n")),
+		WorkerVersion: getWorkerVersion(),
+	}
+	if c.target.Patch != "" {
+		key.PatchHash = hash(c.target.Patch)
+	}
+
+	return key
+}
+
+// Get returns the cached result for the patch or for the whole commit, it returns nil if there is no result
+func (c *AnalysisCache) Get(ctx context.Context) *result.Result {
+	if c == nil {
+		return nil
+	}
+
+	keys := []Key{c.key}
+	if c.key.PatchHash != "" {
+		keys = append(keys, c.key.WithoutPatch())
+	}
+
+	for _, k := range keys {
+		e, err := c.store.Get(ctx, k)
+		if err != nil {
+			analytics.Log(ctx).Warnf("Can't get cached result: %s", err)
+			return nil
+		}
+
+		if e != nil {
+			res := e.ResultForPatch(c.target.Patch)
+			analytics.Log(ctx).Infof("Reused cached result of commit %s: %d issues", k.CommitSHA, len(res.Issues))
+			return res
+		}
+	}
+
+	return nil
+}
+
+// Put saves the result of linters of the repo fetched in the working dir of exec.
+// The result isn't saved if the fetched commit isn't the commit of the key: e.g. the branch was pushed after
+// the analysis had been queued.
+func (c *AnalysisCache) Put(ctx context.Context, exec executors.Executor, res *result.Result) {
+	if c == nil {
+		return
+	}
+
+	out, err := exec.Run(ctx, "git", "rev-parse", "HEAD")
+	if err != nil {
+		analytics.Log(ctx).Warnf("Can't get fetched commit, don't save result to cache: %s, %s", err, out)
+		return
+	}
+	if fetchedSHA := strings.TrimSpace(out); fetchedSHA != c.key.CommitSHA {
+		analytics.Log(ctx).Infof("Fetched commit %s isn't analyzed commit %s, don't save result to cache",
+			fetchedSHA, c.key.CommitSHA)
+		return
+	}
+
+	e := &Entry{
+		Issues:       append([]result.Issue{}, res.Issues...),
+		ResultJSON:   res.ResultJSON,
+		FailSeverity: res.FailSeverity,
+		Modules:      res.Modules,
+		Complete:     c.key.PatchHash == "",
+	}
+	if err = c.store.Put(ctx, c.key, e); err != nil {
+		analytics.Log(ctx).Warnf("Can't save result to cache: %s", err)
+	}
+}
@@ -0,0 +1,136 @@
+package resultcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golangci/golangci-worker/app/analyze/linters"
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/stretchr/testify/assert"
+)
+
+var any = gomock.Any()
+
+type testLinter struct {
+	linters.Linter
+	args string
+}
+
+func (l testLinter) Fingerprint() string {
+	return "linter " + l.args
+}
+
+func setTestVersions(lintVersion string) (restore func()) {
+	WorkerVersion, LintVersion = "test", lintVersion
+	return func() {
+		WorkerVersion, LintVersion = "", ""
+	}
+}
+
+func getTestExecutor(ctrl *gomock.Controller, sha string) executors.Executor {
+	e := executors.NewMockExecutor(ctrl)
+	e.EXPECT().Run(any, "git", "rev-parse", "HEAD").Return(sha+"\n", nil).AnyTimes()
+	return e
+}
+
+func getTestClient(ctrl *gomock.Controller, files ...github.TreeFile) github.Client {
+	c := github.NewMockClient(ctrl)
+	c.EXPECT().GetTreeFiles(any, &github.FakeContext, "sha").Return(files, nil).AnyTimes()
+	return c
+}
+
+func newTestCache(ctx context.Context, store Store, client github.Client, patch string, lnts ...linters.Linter) *AnalysisCache {
+	if lnts == nil {
+		lnts = []linters.Linter{testLinter{}}
+	}
+
+	return NewAnalysisCache(ctx, store, client, &Target{
+		Context:   &github.FakeContext,
+		CommitSHA: "sha",
+		Patch:     patch,
+	}, lnts)
+}
+
+func TestAnalysisCacheReusesResultOfCommit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	defer setTestVersions("1.0")()
+
+	ctx := context.Background()
+	store := NewMemoryStore(time.Hour)
+	client := getTestClient(ctrl, github.TreeFile{Path: "main.go", SHA: "mainSHA"})
+
+	// branch push: the whole commit is analyzed
+	repoCache := newTestCache(ctx, store, client, "")
+	assert.Nil(t, repoCache.Get(ctx))
+	repoCache.Put(ctx, getTestExecutor(ctrl, "sha"), &result.Result{
+		Issues: []result.Issue{
+			result.NewIssue("linter", "added line", "main.go", 12, 0),
+			result.NewIssue("linter", "not changed line", "main.go", 11, 0),
+		},
+		Modules: []result.ModuleResult{{Dir: "."}},
+	})
+
+	// pull request of the same commit: linters aren't run again
+	res := newTestCache(ctx, store, client, testPatch).Get(ctx)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, []result.Issue{result.NewIssue("linter", "added line", "main.go", 12, 10)}, res.Issues)
+		assert.Equal(t, []result.ModuleResult{{Dir: "."}}, res.Modules)
+	}
+}
+
+func TestAnalysisCacheKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	defer setTestVersions("1.0")()
+
+	ctx := context.Background()
+	store := NewMemoryStore(time.Hour)
+	files := []github.TreeFile{
+		{Path: ".golangci.yml", SHA: "rootConfigSHA"},
+		{Path: "sub/.golangci.yml", SHA: "subConfigSHA"},
+		{Path: "sub/main.go", SHA: "mainSHA"},
+	}
+	newTestCache(ctx, store, getTestClient(ctrl, files...), "").Put(ctx, getTestExecutor(ctrl, "sha"), &result.Result{})
+	assert.NotNil(t, newTestCache(ctx, store, getTestClient(ctrl, files...), "").Get(ctx))
+
+	changedNestedConfig := append([]github.TreeFile{}, files...)
+	changedNestedConfig[1].SHA = "newSubConfigSHA"
+	assert.Nil(t, newTestCache(ctx, store, getTestClient(ctrl, changedNestedConfig...), "").Get(ctx),
+		"nested configs are part of the key")
+
+	assert.Nil(t, newTestCache(ctx, store, getTestClient(ctrl, files...), "", testLinter{args: "--fast"}).Get(ctx),
+		"arguments of linters are part of the key")
+
+	defer setTestVersions("1.1")()
+	assert.Nil(t, newTestCache(ctx, store, getTestClient(ctrl, files...), "").Get(ctx),
+		"golangci-lint version is part of the key")
+}
+
+func TestAnalysisCacheWithoutVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := newTestCache(context.Background(), NewMemoryStore(time.Hour), getTestClient(ctrl), "")
+	assert.Nil(t, c, "results aren't cached without versions")
+	assert.Nil(t, c.Get(context.Background()))
+	c.Put(context.Background(), executors.NewMockExecutor(ctrl), &result.Result{})
+}
+
+func TestAnalysisCacheDoesntSaveResultOfOtherCommit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	defer setTestVersions("1.0")()
+
+	ctx := context.Background()
+	store := NewMemoryStore(time.Hour)
+	client := getTestClient(ctrl)
+
+	// the branch was pushed after the analysis had been queued
+	newTestCache(ctx, store, client, "").Put(ctx, getTestExecutor(ctrl, "newerSHA"), &result.Result{})
+	assert.Nil(t, newTestCache(ctx, store, client, "").Get(ctx))
+}
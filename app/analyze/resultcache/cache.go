@@ -0,0 +1,136 @@
+// Package resultcache keeps results of linters by commit, linters version and config:
+// analyses of an already analyzed commit reuse the result instead of running linters.
+package resultcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golangci/golangci-shared/pkg/logutil"
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/resultjson"
+	"github.com/golangci/golangci-worker/app/lib/redisutils"
+)
+
+// WorkerVersion is the version of the worker, it's set by
+// -ldflags "-X github.com/golangci/golangci-worker/app/analyze/resultcache.WorkerVersion=<commit>".
+// WORKER_VERSION env var overrides it, on heroku the commit of the slug is used if it's not set.
+// Results aren't cached if the version is unknown.
+var WorkerVersion = ""
+
+// LintVersion is the version of golangci-lint installed in executors: it's known before the analysis is prepared.
+// GOLANGCI_LINT_VERSION env var overrides it. Results aren't cached if the version is unknown.
+var LintVersion = ""
+
+func getWorkerVersion() string {
+	for _, k := range []string{"WORKER_VERSION", "HEROKU_SLUG_COMMIT"} {
+		if v := os.Getenv(k); v != "" {
+			return v
+		}
+	}
+
+	return WorkerVersion
+}
+
+func getLintVersion() string {
+	if v := os.Getenv("GOLANGCI_LINT_VERSION"); v != "" {
+		return v
+	}
+
+	return LintVersion
+}
+
+// Key identifies the result of linters: all fields except PatchHash must be set
+type Key struct {
+	Repo          string
+	CommitSHA     string
+	LintVersion   string
+	ConfigHash    string
+	WorkerVersion string
+
+	// PatchHash is set for results of pull requests: only issues of the patch are reported
+	PatchHash string
+}
+
+func (k Key) IsValid() bool {
+	return k.Repo != "" && k.CommitSHA != "" && k.LintVersion != "" && k.ConfigHash != "" && k.WorkerVersion != ""
+}
+
+// WithoutPatch returns the key of the result of the whole commit
+func (k Key) WithoutPatch() Key {
+	k.PatchHash = ""
+	return k
+}
+
+func (k Key) String() string {
+	parts := []string{k.Repo, k.CommitSHA, k.LintVersion, k.ConfigHash, k.WorkerVersion, k.PatchHash}
+	return hash(strings.Join(parts, "\n"))
+}
+
+func hash(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
+
+// Entry is the cached result of linters
+type Entry struct {
+	Issues       []result.Issue
+	ResultJSON   *resultjson.LintResult
+	FailSeverity result.Severity
+	Modules      []result.ModuleResult
+
+	// Complete is true if issues of the whole commit are cached, not only issues of a patch
+	Complete bool
+}
+
+type Store interface {
+	// Get returns nil if there is no entry for the key
+	Get(ctx context.Context, key Key) (*Entry, error)
+	Put(ctx context.Context, key Key, e *Entry) error
+}
+
+const defaultTTL = 24 * time.Hour
+
+var defaultMemoryStore = NewMemoryStore(defaultTTL)
+
+// NewStoreFromEnv returns redis store if redis is configured: it's shared by all workers.
+// Otherwise it returns in-memory store shared by all analyses of this worker.
+// It returns nil if the cache is disabled by RESULT_CACHE_TTL_SEC=0 or if versions
+// of the worker or golangci-lint are unknown: results of other versions could be reused.
+func NewStoreFromEnv(log logutil.Log) (Store, error) {
+	ttl := defaultTTL
+	if v := os.Getenv("RESULT_CACHE_TTL_SEC"); v != "" {
+		sec, err := strconv.Atoi(v)
+		if err != nil || sec < 0 {
+			return nil, fmt.Errorf("invalid RESULT_CACHE_TTL_SEC value %q: must be a non-negative integer", v)
+		}
+		if sec == 0 {
+			return nil, nil
+		}
+		ttl = time.Duration(sec) * time.Second
+	}
+
+	if getWorkerVersion() == "" {
+		log.Warnf("Result cache is disabled: worker version is unknown, set WORKER_VERSION")
+		return nil, nil
+	}
+
+	if getLintVersion() == "" {
+		log.Warnf("Result cache is disabled: golangci-lint version is unknown, set GOLANGCI_LINT_VERSION")
+		return nil, nil
+	}
+
+	if redisutils.IsConfigured() {
+		return NewRedisStore(redisutils.GetPool(), ttl), nil
+	}
+
+	if ttl == defaultTTL {
+		return defaultMemoryStore, nil
+	}
+
+	return NewMemoryStore(ttl), nil
+}
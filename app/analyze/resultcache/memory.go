@@ -0,0 +1,61 @@
+package resultcache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	entry     Entry
+	expiresAt time.Time
+}
+
+// MemoryStore keeps entries in memory: it's used in tests and when redis isn't configured
+type MemoryStore struct {
+	lock    sync.Mutex
+	entries map[string]memoryEntry
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+var _ Store = &MemoryStore{}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		entries: map[string]memoryEntry{},
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key Key) (*Entry, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, ok := s.entries[key.String()]
+	if !ok || !e.expiresAt.After(s.now()) {
+		return nil, nil
+	}
+
+	ret := e.entry
+	return &ret, nil
+}
+
+func (s *MemoryStore) Put(_ context.Context, key Key, e *Entry) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	for k, me := range s.entries {
+		if !me.expiresAt.After(now) {
+			delete(s.entries, k)
+		}
+	}
+
+	s.entries[key.String()] = memoryEntry{
+		entry:     *e,
+		expiresAt: now.Add(s.ttl),
+	}
+	return nil
+}
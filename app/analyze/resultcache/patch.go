@@ -0,0 +1,51 @@
+package resultcache
+
+import (
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/resultjson"
+	"github.com/golangci/golangci-worker/app/lib/github"
+)
+
+// ResultForPatch returns the cached result with HunkPos of issues remapped to positions in the patch.
+// Issues of a complete entry which aren't on added lines of the patch are dropped:
+// only new issues are reported for pull requests. Empty patch means the analysis of the whole repo.
+func (e Entry) ResultForPatch(patch string) *result.Result {
+	ret := &result.Result{
+		FailSeverity: e.FailSeverity,
+		Modules:      e.Modules,
+	}
+
+	var jsonIssues []resultjson.Issue
+	hasJSONIssues := e.ResultJSON != nil && len(e.ResultJSON.Issues) == len(e.Issues)
+	positions := github.ParsePatch(patch)
+	for ind, i := range e.Issues {
+		hunkPos := 0
+		if patch != "" {
+			hunkPos = positions.Position(i.File, i.LineNumber)
+			if hunkPos == 0 && e.Complete {
+				continue
+			}
+		}
+
+		i.HunkPos = hunkPos
+		ret.Issues = append(ret.Issues, i)
+		if hasJSONIssues {
+			ji := e.ResultJSON.Issues[ind]
+			ji.HunkPos = hunkPos
+			jsonIssues = append(jsonIssues, ji)
+		}
+	}
+
+	if e.ResultJSON != nil {
+		resJSON := *e.ResultJSON
+		if hasJSONIssues {
+			resJSON.Issues = jsonIssues
+			if resJSON.Issues == nil {
+				resJSON.Issues = []resultjson.Issue{}
+			}
+		}
+		ret.ResultJSON = &resJSON
+	}
+
+	return ret
+}
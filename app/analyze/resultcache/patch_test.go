@@ -0,0 +1,78 @@
+package resultcache
+
+import (
+	"testing"
+
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/resultjson"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPatch = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,5 +1,6 @@
+ package p
+ 
+-func F0() error {
++func F0New() error {
++	// comment
+ 	return nil
+ }
+@@ -10,3 +11,4 @@ func F1() {
+ 	a()
++	b()
+ 	c()
+ }
+diff --git a/new.go b/new.go
+new file mode 100644
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1,2 @@
++package p
++var x = 1
+\ No newline at end of file
+diff --git a/removed.go b/removed.go
+deleted file mode 100644
+--- a/removed.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package p
+`
+
+func TestResultForPatch(t *testing.T) {
+	issues := []result.Issue{
+		result.NewIssue("linter", "added line", "main.go", 12, 100),
+		result.NewIssue("linter", "not changed line", "main.go", 11, 0),
+		result.NewIssue("linter", "other file", "other.go", 1, 0),
+	}
+	e := Entry{
+		Issues: issues,
+		ResultJSON: &resultjson.LintResult{
+			Issues: []resultjson.Issue{
+				{Text: "added line", HunkPos: 100},
+				{Text: "not changed line"},
+				{Text: "other file"},
+			},
+		},
+		FailSeverity: result.SeverityWarning,
+	}
+
+	res := e.ResultForPatch(testPatch)
+	assert.Equal(t, result.SeverityWarning, res.FailSeverity)
+	assert.Len(t, res.Issues, 3, "issues of not complete entry must be kept")
+	assert.Equal(t, 10, res.Issues[0].HunkPos)
+	assert.Equal(t, 0, res.Issues[1].HunkPos)
+	assert.Equal(t, 10, res.ResultJSON.Issues[0].HunkPos)
+	assert.Equal(t, 100, issues[0].HunkPos, "cached issues must not be changed")
+
+	e.Complete = true
+	res = e.ResultForPatch(testPatch)
+	assert.Equal(t, []result.Issue{result.NewIssue("linter", "added line", "main.go", 12, 10)}, res.Issues)
+	assert.Equal(t, []resultjson.Issue{{Text: "added line", HunkPos: 10}}, res.ResultJSON.Issues)
+
+	res = e.ResultForPatch("")
+	assert.Len(t, res.Issues, 3, "all issues must be reported for the repo")
+	assert.Equal(t, 0, res.Issues[0].HunkPos)
+}
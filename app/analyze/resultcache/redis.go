@@ -0,0 +1,65 @@
+package resultcache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// RedisStore keeps entries in redis: they are shared between all workers
+type RedisStore struct {
+	pool *redis.Pool
+	ttl  time.Duration
+}
+
+var _ Store = &RedisStore{}
+
+func NewRedisStore(pool *redis.Pool, ttl time.Duration) *RedisStore {
+	return &RedisStore{
+		pool: pool,
+		ttl:  ttl,
+	}
+}
+
+func getRedisKey(key Key) string {
+	return "result_cache:" + key.String()
+}
+
+func (s RedisStore) Get(_ context.Context, key Key) (*Entry, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	data, err := redis.Bytes(conn.Do("GET", getRedisKey(key)))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't get cached result from redis: %s", err)
+	}
+
+	var e Entry
+	if err = json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("can't unmarshal cached result: %s", err)
+	}
+
+	return &e, nil
+}
+
+func (s RedisStore) Put(_ context.Context, key Key, e *Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("can't marshal cached result: %s", err)
+	}
+
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	if _, err = conn.Do("SET", getRedisKey(key), data, "EX", int(s.ttl/time.Second)); err != nil {
+		return fmt.Errorf("can't save cached result to redis: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+// Package resultjson describes the result document of an analysis: it's saved
+// as ResultJSON of the analysis state and is read by the API and dashboards.
+package resultjson
+
+import (
+	"strconv"
+	"time"
+)
+
+//go:generate go run gen_schema.go
+
+// CurrentVersion is the version of documents written by the worker.
+// Version 2 added issue severities and fingerprints to the schema and the prepare log.
+const CurrentVersion = 2
+
+type Document struct {
+	Version         int
+	GolangciLintRes *LintResult
+	WorkerRes       WorkerResult
+}
+
+// LintResult is the golangci-lint json output with computed fields of issues.
+// Fields of the output unknown to the worker are kept in Extra of structs.
+type LintResult struct {
+	Issues []Issue
+	Report *Report `json:",omitempty"`
+
+	Extra Extra `json:"-"`
+}
+
+type Position struct {
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
+
+	Extra Extra `json:"-"`
+}
+
+type Range struct {
+	From, To int
+
+	Extra Extra `json:"-"`
+}
+
+type Replacement struct {
+	NeedOnlyDelete bool
+	NewLines       []string
+
+	Extra Extra `json:"-"`
+}
+
+type Issue struct {
+	FromLinter  string
+	Text        string
+	Pos         Position
+	LineRange   *Range `json:",omitempty"`
+	HunkPos     int    `json:",omitempty"`
+	SourceLines []string
+	Replacement *Replacement
+
+	Fingerprint string
+	Severity    string
+	EndLine     int `json:",omitempty"`
+
+	Extra Extra `json:"-"`
+}
+
+type ReportWarning struct {
+	Tag  string `json:",omitempty"`
+	Text string
+
+	Extra Extra `json:"-"`
+}
+
+type LinterData struct {
+	Name             string
+	Enabled          bool `json:",omitempty"`
+	EnabledByDefault bool `json:",omitempty"`
+
+	Extra Extra `json:"-"`
+}
+
+type Report struct {
+	Warnings []ReportWarning `json:",omitempty"`
+	Linters  []LinterData    `json:",omitempty"`
+	Error    string          `json:",omitempty"`
+
+	Extra Extra `json:"-"`
+}
+
+// JSONDuration is marshaled as milliseconds
+type JSONDuration time.Duration
+
+func (d JSONDuration) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Itoa(int(time.Duration(d) / time.Millisecond))), nil
+}
+
+func (d *JSONDuration) UnmarshalJSON(data []byte) error {
+	ms, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*d = JSONDuration(time.Duration(ms) * time.Millisecond)
+	return nil
+}
+
+func (d JSONDuration) String() string {
+	return time.Duration(d).String()
+}
+
+type Timing struct {
+	Name     string
+	Duration JSONDuration `json:"DurationMs"`
+}
+
+type Warning struct {
+	Tag  string
+	Text string
+}
+
+// PrepareLog is the log of preparing the environment of the repo: fetching, deps installation etc
+type PrepareLog struct {
+	Groups []PrepareLogGroup
+}
+
+type PrepareLogGroup struct {
+	Name     string
+	Duration JSONDuration `json:"DurationMs"`
+	Steps    []PrepareLogStep
+}
+
+type PrepareLogStep struct {
+	Description string
+	Command     string       `json:",omitempty"` // set if the step is a command run
+	Duration    JSONDuration `json:"DurationMs"`
+	Error       string       `json:",omitempty"`
+
+	// OutputLines are the last lines of the output
+	OutputLines     []string `json:",omitempty"`
+	OutputTruncated bool     `json:",omitempty"`
+}
+
+// ModuleResult is the result of linters in a module of a multi-module repo
+type ModuleResult struct {
+	Dir         string       // relative to the repo root
+	Duration    JSONDuration `json:"DurationMs"`
+	IssuesCount int
+	Error       string `json:",omitempty"`
+}
+
+// FetchedModule is a module version fetched by go commands of the analysis through the go proxy of the worker
+type FetchedModule struct {
+	Path    string
+	Version string
+}
+
+type WorkerResult struct {
+	Timings        []Timing        `json:",omitempty"`
+	Warnings       []Warning       `json:",omitempty"`
+	Error          string          `json:",omitempty"`
+	PrepareLog     *PrepareLog     `json:",omitempty"`
+	Modules        []ModuleResult  `json:",omitempty"` // set if modules of the repo were analyzed separately
+	FetchedModules []FetchedModule `json:",omitempty"`
+}
@@ -0,0 +1,127 @@
+package resultjson
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaIsUpToDate(t *testing.T) {
+	schema, err := Schema()
+	assert.NoError(t, err)
+
+	saved, err := ioutil.ReadFile("schema.json")
+	assert.NoError(t, err)
+	assert.Equal(t, string(saved), string(schema)+"\n", "run go generate to update schema.json")
+}
+
+func TestParseV1(t *testing.T) {
+	data := `{
+		"Version": 1,
+		"GolangciLintRes": {
+			"Issues": [{
+				"FromLinter": "govet",
+				"Text": "unreachable code",
+				"Pos": {"Filename": "main.go", "Line": 5, "Column": 2},
+				"LineRange": {"From": 5, "To": 7},
+				"SourceLines": ["return", "x := 1", "_ = x"],
+				"Replacement": null
+			}],
+			"Report": {"Linters": [{"Name": "govet", "Enabled": true}]}
+		},
+		"WorkerRes": {"Timings": [{"Name": "Clone", "DurationMs": 1500}]}
+	}`
+
+	d, err := Parse([]byte(data))
+	assert.NoError(t, err)
+	assert.Equal(t, CurrentVersion, d.Version)
+	assert.Equal(t, []Issue{{
+		FromLinter:  "govet",
+		Text:        "unreachable code",
+		Pos:         Position{Filename: "main.go", Line: 5, Column: 2},
+		LineRange:   &Range{From: 5, To: 7},
+		SourceLines: []string{"return", "x := 1", "_ = x"},
+		Severity:    "error",
+		EndLine:     7,
+	}}, d.GolangciLintRes.Issues)
+	assert.Equal(t, []Timing{{Name: "Clone", Duration: JSONDuration(1500 * time.Millisecond)}}, d.WorkerRes.Timings)
+}
+
+func TestParseWithoutResult(t *testing.T) {
+	d, err := Parse([]byte(`{"GolangciLintRes": null, "WorkerRes": {"Error": "can't clone git repo"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &Document{
+		Version:   CurrentVersion,
+		WorkerRes: WorkerResult{Error: "can't clone git repo"},
+	}, d)
+}
+
+func TestParseUnsupportedVersion(t *testing.T) {
+	_, err := Parse([]byte(`{"Version": 100}`))
+	assert.Error(t, err)
+}
+
+func TestCurrentVersionRoundTrip(t *testing.T) {
+	d := &Document{
+		Version: CurrentVersion,
+		GolangciLintRes: &LintResult{
+			Issues: []Issue{{FromLinter: "golint", Severity: "warning", Fingerprint: "abc"}},
+		},
+		WorkerRes: WorkerResult{
+			Warnings: []Warning{{Tag: "deps", Text: "dep ensure failed"}},
+			PrepareLog: &PrepareLog{
+				Groups: []PrepareLogGroup{{Name: "deps", Steps: []PrepareLogStep{{Description: "dep ensure"}}}},
+			},
+		},
+	}
+
+	data, err := json.Marshal(d)
+	assert.NoError(t, err)
+
+	parsed, err := Parse(data)
+	assert.NoError(t, err)
+	assert.Equal(t, d, parsed)
+}
+
+func TestUnknownLintFieldsRoundTrip(t *testing.T) {
+	data := `{
+		"Version": 2,
+		"GolangciLintRes": {
+			"Issues": [{
+				"FromLinter": "nolintlint",
+				"Text": "directive is unused",
+				"Pos": {"Filename": "main.go", "Offset": 40, "Line": 5, "Column": 2},
+				"SourceLines": null,
+				"Replacement": {"NeedOnlyDelete": false, "NewLines": null, "Inline": {"StartCol": 3, "Length": 10, "NewString": ""}},
+				"ExpectNoLint": true,
+				"Fingerprint": "abc",
+				"Severity": "warning"
+			}],
+			"Report": {"Linters": [{"Name": "govet", "Enabled": true, "Fast": false}], "Stats": {"govet": 1}},
+			"Config": {"Run": {"Timeout": "1m"}}
+		},
+		"WorkerRes": {}
+	}`
+
+	d, err := Parse([]byte(data))
+	assert.NoError(t, err)
+
+	issue := d.GolangciLintRes.Issues[0]
+	assert.Equal(t, "abc", issue.Fingerprint)
+	assert.Equal(t, Extra{"ExpectNoLint": json.RawMessage("true")}, issue.Extra)
+	assert.Equal(t, Extra{"Inline": json.RawMessage(`{"StartCol": 3, "Length": 10, "NewString": ""}`)}, issue.Replacement.Extra)
+	assert.Equal(t, Extra{"Stats": json.RawMessage(`{"govet": 1}`)}, d.GolangciLintRes.Report.Extra)
+	assert.Equal(t, Extra{"Fast": json.RawMessage("false")}, d.GolangciLintRes.Report.Linters[0].Extra)
+	assert.Nil(t, issue.Pos.Extra)
+
+	saved, err := json.Marshal(d)
+	assert.NoError(t, err)
+
+	var got, want map[string]interface{}
+	assert.NoError(t, json.Unmarshal(saved, &got))
+	assert.NoError(t, json.Unmarshal([]byte(data), &want))
+	assert.Equal(t, want["GolangciLintRes"], got["GolangciLintRes"])
+}
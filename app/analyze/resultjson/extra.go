@@ -0,0 +1,150 @@
+package resultjson
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Extra keeps fields of golangci-lint output unknown to the worker: newer golangci-lint versions
+// add fields and they are saved to the document as is
+type Extra map[string]json.RawMessage
+
+// types without json methods to (un)marshal known fields
+type (
+	lintResultFields    LintResult
+	positionFields      Position
+	rangeFields         Range
+	replacementFields   Replacement
+	issueFields         Issue
+	reportWarningFields ReportWarning
+	linterDataFields    LinterData
+	reportFields        Report
+)
+
+func (r LintResult) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(lintResultFields(r), r.Extra)
+}
+
+func (r *LintResult) UnmarshalJSON(data []byte) error {
+	return unmarshalWithExtra(data, (*lintResultFields)(r), &r.Extra)
+}
+
+func (p Position) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(positionFields(p), p.Extra)
+}
+
+func (p *Position) UnmarshalJSON(data []byte) error {
+	return unmarshalWithExtra(data, (*positionFields)(p), &p.Extra)
+}
+
+func (r Range) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(rangeFields(r), r.Extra)
+}
+
+func (r *Range) UnmarshalJSON(data []byte) error {
+	return unmarshalWithExtra(data, (*rangeFields)(r), &r.Extra)
+}
+
+func (r Replacement) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(replacementFields(r), r.Extra)
+}
+
+func (r *Replacement) UnmarshalJSON(data []byte) error {
+	return unmarshalWithExtra(data, (*replacementFields)(r), &r.Extra)
+}
+
+func (i Issue) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(issueFields(i), i.Extra)
+}
+
+func (i *Issue) UnmarshalJSON(data []byte) error {
+	return unmarshalWithExtra(data, (*issueFields)(i), &i.Extra)
+}
+
+func (w ReportWarning) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(reportWarningFields(w), w.Extra)
+}
+
+func (w *ReportWarning) UnmarshalJSON(data []byte) error {
+	return unmarshalWithExtra(data, (*reportWarningFields)(w), &w.Extra)
+}
+
+func (d LinterData) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(linterDataFields(d), d.Extra)
+}
+
+func (d *LinterData) UnmarshalJSON(data []byte) error {
+	return unmarshalWithExtra(data, (*linterDataFields)(d), &d.Extra)
+}
+
+func (r Report) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(reportFields(r), r.Extra)
+}
+
+func (r *Report) UnmarshalJSON(data []byte) error {
+	return unmarshalWithExtra(data, (*reportFields)(r), &r.Extra)
+}
+
+func marshalWithExtra(known interface{}, extra Extra) ([]byte, error) {
+	data, err := json.Marshal(known)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for name, value := range extra {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// unmarshalWithExtra decodes known fields to known (a pointer to a struct) and other fields to extra
+func unmarshalWithExtra(data []byte, known interface{}, extra *Extra) error {
+	if err := json.Unmarshal(data, known); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	t := reflect.TypeOf(known).Elem()
+	for name, value := range fields {
+		if isKnownField(t, name) {
+			continue
+		}
+
+		if *extra == nil {
+			*extra = Extra{}
+		}
+		(*extra)[name] = value
+	}
+
+	return nil
+}
+
+// isKnownField matches names case-insensitively like encoding/json does
+func isKnownField(t reflect.Type, name string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		fieldName, _ := parseJSONTag(f)
+		if fieldName != "-" && strings.EqualFold(fieldName, name) {
+			return true
+		}
+	}
+
+	return false
+}
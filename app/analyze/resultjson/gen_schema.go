@@ -0,0 +1,22 @@
+//go:build ignore
+// +build ignore
+
+package main
+
+import (
+	"io/ioutil"
+	"log"
+
+	"github.com/golangci/golangci-worker/app/analyze/resultjson"
+)
+
+func main() {
+	schema, err := resultjson.Schema()
+	if err != nil {
+		log.Fatalf("Can't generate schema: %s", err)
+	}
+
+	if err = ioutil.WriteFile("schema.json", append(schema, '\n'), 0644); err != nil {
+		log.Fatalf("Can't write schema: %s", err)
+	}
+}
@@ -0,0 +1,74 @@
+package resultjson
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// defaultSeverity is the severity of issues from documents without severities
+const defaultSeverity = "error"
+
+// migrations[v] migrates a document of version v to version v+1
+var migrations = map[int]func(d *Document){
+	1: migrateV1,
+}
+
+// Parse reads the document of any supported version and migrates it to the current version
+func Parse(data []byte) (*Document, error) {
+	var d Document
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal result document")
+	}
+
+	if err := Migrate(&d); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// Migrate upgrades the document to the current version in place
+func Migrate(d *Document) error {
+	if d.Version == 0 {
+		d.Version = 1 // the first documents were written without version
+	}
+
+	if d.Version > CurrentVersion {
+		return fmt.Errorf("unsupported result document version %d: max supported is %d", d.Version, CurrentVersion)
+	}
+
+	for d.Version < CurrentVersion {
+		migrate := migrations[d.Version]
+		if migrate == nil {
+			return fmt.Errorf("no migration from result document version %d", d.Version)
+		}
+
+		migrate(d)
+		d.Version++
+	}
+
+	return nil
+}
+
+// migrateV1 fills fields which could be absent in the first version documents
+func migrateV1(d *Document) {
+	if d.GolangciLintRes == nil {
+		return
+	}
+
+	if d.GolangciLintRes.Issues == nil {
+		d.GolangciLintRes.Issues = []Issue{}
+	}
+
+	for i := range d.GolangciLintRes.Issues {
+		issue := &d.GolangciLintRes.Issues[i]
+		if issue.Severity == "" {
+			issue.Severity = defaultSeverity
+		}
+		if issue.EndLine == 0 && issue.LineRange != nil {
+			issue.EndLine = issue.LineRange.To
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package resultjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+var jsonDurationType = reflect.TypeOf(JSONDuration(0))
+
+// Schema returns JSON Schema of the current version document generated from Go types
+func Schema() ([]byte, error) {
+	defs := map[string]interface{}{}
+	root := map[string]interface{}{
+		"$schema": schemaDraft,
+		"$id":     fmt.Sprintf("https://golangci.com/schemas/result.v%d.json", CurrentVersion),
+		"title":   "GolangCI analysis result",
+	}
+	// inline the document schema: keywords next to $ref are ignored by draft-07 validators
+	for k, v := range structSchema(reflect.TypeOf(Document{}), defs) {
+		root[k] = v
+	}
+	root["definitions"] = defs
+
+	return json.MarshalIndent(root, "", "  ")
+}
+
+func typeSchema(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	if t == jsonDurationType {
+		return map[string]interface{}{"type": "integer", "description": "duration in milliseconds"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := typeSchema(t.Elem(), defs)
+		return map[string]interface{}{
+			"anyOf": []interface{}{s, map[string]interface{}{"type": "null"}},
+		}
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = nil // reserve the name before recursion
+			defs[t.Name()] = structSchema(t, defs)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  []string{"array", "null"},
+			"items": typeSchema(t.Elem(), defs),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": typeSchema(t.Elem(), defs),
+		}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+
+	return map[string]interface{}{} // any value
+}
+
+func structSchema(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	props := map[string]interface{}{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+
+		name, omitEmpty := parseJSONTag(f)
+		if name == "-" {
+			continue
+		}
+
+		props[name] = typeSchema(f.Type, defs)
+		if !omitEmpty {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+		"required":   required,
+	}
+}
+
+func parseJSONTag(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = f.Name
+	}
+
+	omitEmpty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+
+	return name, omitEmpty
+}
@@ -0,0 +1,22 @@
+package severity
+
+type Rule struct {
+	Linters  []string `yaml:"linters"`
+	Text     string   `yaml:"text"` // regexp
+	Severity string   `yaml:"severity"`
+}
+
+// Config is set in the service.severity section of .golangci.yml:
+//
+//	service:
+//	  severity:
+//	    default: warning
+//	    fail-on: error
+//	    rules:
+//	      - linters: [golint]
+//	        severity: info
+type Config struct {
+	Default string `yaml:"default"`
+	FailOn  string `yaml:"fail-on"`
+	Rules   []Rule `yaml:"rules"`
+}
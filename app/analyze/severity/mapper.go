@@ -0,0 +1,125 @@
+package severity
+
+import (
+	"regexp"
+
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/pkg/errors"
+)
+
+// defaultRules are applied after repo rules
+var defaultRules = []Rule{
+	{
+		Linters:  []string{"gofmt", "goimports", "misspell", "lll"},
+		Severity: string(result.SeverityInfo),
+	},
+	{
+		Linters: []string{"golint", "stylecheck", "gocyclo", "dupl", "goconst", "interfacer",
+			"unparam", "unconvert", "maligned", "prealloc", "nakedret", "gocritic", "scopelint",
+			"gochecknoglobals", "gochecknoinits"},
+		Severity: string(result.SeverityWarning),
+	},
+}
+
+type compiledRule struct {
+	linters  map[string]bool
+	text     *regexp.Regexp
+	severity result.Severity
+}
+
+func (r compiledRule) match(i *result.Issue) bool {
+	if len(r.linters) != 0 && !r.linters[i.FromLinter] {
+		return false
+	}
+
+	if r.text != nil && !r.text.MatchString(i.Text) {
+		return false
+	}
+
+	return true
+}
+
+type Mapper struct {
+	rules        []compiledRule
+	def          result.Severity
+	failSeverity result.Severity
+}
+
+func compileRule(r *Rule) (*compiledRule, error) {
+	sev, err := result.ParseSeverity(r.Severity)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &compiledRule{
+		linters:  map[string]bool{},
+		severity: sev,
+	}
+	for _, l := range r.Linters {
+		ret.linters[l] = true
+	}
+
+	if r.Text != "" {
+		if ret.text, err = regexp.Compile(r.Text); err != nil {
+			return nil, errors.Wrapf(err, "invalid text regexp %q", r.Text)
+		}
+	}
+
+	if len(ret.linters) == 0 && ret.text == nil {
+		return nil, errors.New("rule must have linters or text")
+	}
+
+	return ret, nil
+}
+
+// NewMapper builds a mapper from repo config: repo rules have priority over default rules
+func NewMapper(cfg *Config, deploymentFailSeverity result.Severity) (*Mapper, error) {
+	ret := &Mapper{
+		def:          result.DefaultSeverity,
+		failSeverity: deploymentFailSeverity,
+	}
+
+	var err error
+	if cfg.Default != "" {
+		if ret.def, err = result.ParseSeverity(cfg.Default); err != nil {
+			return nil, errors.Wrap(err, "invalid default severity")
+		}
+	}
+
+	if cfg.FailOn != "" {
+		if ret.failSeverity, err = result.ParseSeverity(cfg.FailOn); err != nil {
+			return nil, errors.Wrap(err, "invalid fail-on severity")
+		}
+	}
+
+	rules := append(append([]Rule{}, cfg.Rules...), defaultRules...)
+	for i := range rules {
+		r, compileErr := compileRule(&rules[i])
+		if compileErr != nil {
+			return nil, errors.Wrapf(compileErr, "invalid severity rule #%d", i+1)
+		}
+		ret.rules = append(ret.rules, *r)
+	}
+
+	return ret, nil
+}
+
+func (m Mapper) FailSeverity() result.Severity {
+	return m.failSeverity
+}
+
+func (m Mapper) GetSeverity(i *result.Issue) result.Severity {
+	for _, r := range m.rules {
+		if r.match(i) {
+			return r.severity
+		}
+	}
+
+	return m.def
+}
+
+func (m Mapper) Apply(issues []result.Issue) {
+	for i := range issues {
+		issues[i].Severity = m.GetSeverity(&issues[i])
+	}
+}
@@ -0,0 +1,61 @@
+package severity
+
+import (
+	"testing"
+
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapperDefaults(t *testing.T) {
+	m, err := NewMapper(&Config{}, result.SeverityInfo)
+	assert.NoError(t, err)
+	assert.Equal(t, result.SeverityInfo, m.FailSeverity())
+
+	issues := []result.Issue{
+		result.NewIssue("govet", "unreachable code", "p/f.go", 1, 1),
+		result.NewIssue("golint", "exported func F should have comment", "p/f.go", 2, 2),
+		result.NewIssue("gofmt", "File is not gofmt-ed", "p/f.go", 3, 3),
+	}
+	m.Apply(issues)
+	assert.Equal(t, result.SeverityError, issues[0].Severity)
+	assert.Equal(t, result.SeverityWarning, issues[1].Severity)
+	assert.Equal(t, result.SeverityInfo, issues[2].Severity)
+}
+
+func TestMapperRepoOverrides(t *testing.T) {
+	cfg := &Config{
+		Default: "warning",
+		FailOn:  "error",
+		Rules: []Rule{
+			{Text: "should have comment", Severity: "info"},
+			{Linters: []string{"gofmt"}, Severity: "error"},
+		},
+	}
+	m, err := NewMapper(cfg, result.SeverityInfo)
+	assert.NoError(t, err)
+	assert.Equal(t, result.SeverityError, m.FailSeverity())
+
+	issues := []result.Issue{
+		result.NewIssue("govet", "unreachable code", "p/f.go", 1, 1),
+		result.NewIssue("golint", "exported func F should have comment", "p/f.go", 2, 2),
+		result.NewIssue("golint", "don't use underscores", "p/f.go", 3, 3),
+		result.NewIssue("gofmt", "File is not gofmt-ed", "p/f.go", 4, 4),
+	}
+	m.Apply(issues)
+	assert.Equal(t, result.SeverityWarning, issues[0].Severity)
+	assert.Equal(t, result.SeverityInfo, issues[1].Severity)
+	assert.Equal(t, result.SeverityWarning, issues[2].Severity)
+	assert.Equal(t, result.SeverityError, issues[3].Severity)
+}
+
+func TestMapperInvalidConfig(t *testing.T) {
+	_, err := NewMapper(&Config{FailOn: "fatal"}, result.SeverityInfo)
+	assert.Error(t, err)
+
+	_, err = NewMapper(&Config{Rules: []Rule{{Severity: "info"}}}, result.SeverityInfo)
+	assert.Error(t, err)
+
+	_, err = NewMapper(&Config{Rules: []Rule{{Text: "(", Severity: "info"}}}, result.SeverityInfo)
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package severity
+
+import (
+	"context"
+	"os"
+
+	"github.com/golangci/golangci-worker/app/analyze/linters/result"
+	"github.com/golangci/golangci-worker/app/analyze/repoconfig"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+)
+
+type repoConfig struct {
+	Service struct {
+		Severity Config `yaml:"severity"`
+	} `yaml:"service"`
+}
+
+// LoadRepoConfig reads severity settings from the golangci config in the working dir of exec.
+// It returns an empty config if there is no config file.
+func LoadRepoConfig(ctx context.Context, exec executors.Executor) (*Config, error) {
+	var cfg repoConfig
+	if err := repoconfig.Load(ctx, exec, &cfg); err != nil {
+		return nil, err
+	}
+
+	return &cfg.Service.Severity, nil
+}
+
+// DeploymentFailSeverity is the minimal severity failing analysis for repos without fail-on setting.
+// By default any issue fails analysis.
+func DeploymentFailSeverity() result.Severity {
+	if s := os.Getenv("SEVERITY_FAIL_ON"); s != "" {
+		if sev, err := result.ParseSeverity(s); err == nil {
+			return sev
+		}
+	}
+
+	return result.SeverityInfo
+}
@@ -0,0 +1,41 @@
+package templates
+
+// IssueData is the data of the comment template. It's also used for issues listed in the summary.
+type IssueData struct {
+	Text          string // text of the issue from the linter
+	Linter        string // name of the linter, e.g. golint
+	LinterDocsURL string // link to the docs of the linter, empty if unknown
+	File          string
+	Line          int
+	Severity      string // error, warning or info
+	Fingerprint   string // identifies the issue across commits
+
+	// Suggestion is a GitHub suggested change block fixing the issue, empty if the issue isn't fixable
+	Suggestion string
+
+	// IncludeLinterName is true if the include_linter_name_in_comment experiment is enabled for the repo
+	IncludeLinterName bool
+}
+
+type CountData struct {
+	Name  string
+	Count int
+}
+
+// SummaryData is the data of the review summary template
+type SummaryData struct {
+	Total    int         // count of all found issues
+	ByLinter []CountData // counts of all found issues by linters, from the largest
+	ByFile   []CountData // counts of all found issues by files, from the largest
+
+	// NotCommented are issues which weren't commented inline:
+	// they are outside of the diff or comments limit was reached
+	NotCommented []IssueData
+}
+
+// StatusData is the data of the commit status description template
+type StatusData struct {
+	Total    int         // count of all found issues
+	Blocking bool        // true if issues fail the commit status
+	Counts   []CountData // counts of issues by severities, from the most important; zero counts are omitted
+}
@@ -0,0 +1,81 @@
+package templates
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/golangci/golangci-worker/app/analyze/repoconfig"
+	"github.com/golangci/golangci-worker/app/lib/github"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type repoConfig struct {
+	Service struct {
+		Templates Config `yaml:"templates"`
+	} `yaml:"service"`
+}
+
+// FetchRepoConfig reads templates from the golangci config of the commit without fetching the repo.
+// It returns an empty config if there is no config file.
+func FetchRepoConfig(ctx context.Context, client github.Client, c *github.Context, sha string) (*Config, error) {
+	fileName, content, err := repoconfig.Fetch(ctx, client, c, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg repoConfig
+	if err = repoconfig.Parse(fileName, content, &cfg); err != nil {
+		return nil, err
+	}
+
+	return &cfg.Service.Templates, nil
+}
+
+// LoadDeploymentConfig reads templates for all repos from the yaml file set by TEMPLATES_CONFIG env var.
+// It returns an empty config if the env var isn't set.
+func LoadDeploymentConfig() (*Config, error) {
+	path := os.Getenv("TEMPLATES_CONFIG")
+	if path == "" {
+		return &Config{}, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read templates config %s", path)
+	}
+
+	var cfg Config
+	if err = yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse templates config %s", path)
+	}
+
+	return &cfg, nil
+}
+
+var deploymentConfig *Config
+var deploymentConfigErr error
+var deploymentOnce sync.Once
+
+// GetDeploymentConfig returns the deployment config loaded once and checked by building templates from it:
+// the worker must fail at startup if it's invalid
+func GetDeploymentConfig() (*Config, error) {
+	deploymentOnce.Do(func() {
+		cfg, err := LoadDeploymentConfig()
+		if err != nil {
+			deploymentConfigErr = err
+			return
+		}
+
+		if _, err = New(cfg); err != nil {
+			deploymentConfigErr = errors.Wrapf(err, "invalid templates config %s", os.Getenv("TEMPLATES_CONFIG"))
+			return
+		}
+
+		deploymentConfig = cfg
+	})
+
+	return deploymentConfig, deploymentConfigErr
+}
@@ -0,0 +1,236 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// Config overrides built-in templates, see data.go for the data passed to them.
+// It's set in the service.templates section of .golangci.yml or in the file from TEMPLATES_CONFIG env var:
+//
+//	service:
+//	  templates:
+//	    comment: "{{ .Text }}, see [style guide](https://wiki.example.com/go#{{ .Linter }})"
+//	    linter-docs-urls:
+//	      golint: https://wiki.example.com/go
+type Config struct {
+	Comment string `yaml:"comment"`
+	Summary string `yaml:"summary"`
+	Status  string `yaml:"status"`
+
+	LinterDocsURLs map[string]string `yaml:"linter-docs-urls"`
+}
+
+const defaultCommentTemplate = "{{ .Text }}" +
+	"{{ if and .IncludeLinterName .Linter }} (from `{{ .Linter }}`){{ end }}" +
+	"{{ if .Suggestion }}\n\n{{ .Suggestion }}{{ end }}"
+
+const defaultSummaryTemplate = `GolangCI found {{ if .Total }}{{ pluralize .Total "issue" "issues" }}{{ else }}no issues{{ end }}.
+{{- if .Total }}
+
+| Linter | Issues |
+| --- | ---: |
+{{ range .ByLinter }}| ` + "`{{ cell .Name }}`" + ` | {{ .Count }} |
+{{ end }}
+| File | Issues |
+| --- | ---: |
+{{ range .ByFile }}| ` + "`{{ cell .Name }}`" + ` | {{ .Count }} |
+{{ end }}
+{{- end }}
+{{- if .NotCommented }}
+<details>
+<summary>{{ pluralize (len .NotCommented) "issue" "issues" }} not commented inline</summary>
+
+| File | Line | Linter | Issue |
+| --- | ---: | --- | --- |
+{{ range .NotCommented }}| ` + "`{{ cell .File }}`" + ` | {{ .Line }} | {{ cell .Linter }} | {{ cell .Text }} |
+{{ end }}
+</details>
+{{- end }}`
+
+const defaultStatusTemplate = `{{ if not .Total }}No issues found!
+{{- else }}
+{{- if .Blocking }}{{ pluralize .Total "issue" "issues" }}
+{{- else }}{{ pluralize .Total "non-blocking issue" "non-blocking issues" }}{{ end }} found:
+{{- range $i, $c := .Counts }}{{ if $i }},{{ end }} {{ if eq $c.Name "info" }}{{ $c.Count }} info
+{{- else }}{{ pluralize $c.Count $c.Name (print $c.Name "s") }}{{ end }}{{ end }}
+{{- end }}`
+
+// maxStatusDescriptionLen is a GitHub limit for commit status description
+const maxStatusDescriptionLen = 140
+
+var defaultLinterDocsURLs = map[string]string{
+	"govet":            "https://golang.org/cmd/vet/",
+	"gofmt":            "https://golang.org/cmd/gofmt/",
+	"goimports":        "https://godoc.org/golang.org/x/tools/cmd/goimports",
+	"golint":           "https://github.com/golang/lint",
+	"errcheck":         "https://github.com/kisielk/errcheck",
+	"staticcheck":      "https://staticcheck.io/docs/checks",
+	"gosimple":         "https://staticcheck.io/docs/checks",
+	"unused":           "https://github.com/dominikh/go-tools/tree/master/cmd/unused",
+	"megacheck":        "https://github.com/dominikh/go-tools",
+	"ineffassign":      "https://github.com/gordonklaus/ineffassign",
+	"deadcode":         "https://github.com/remyoudompheng/go-misc/tree/master/deadcode",
+	"structcheck":      "https://github.com/opennota/check",
+	"varcheck":         "https://github.com/opennota/check",
+	"gosec":            "https://github.com/securego/gosec",
+	"misspell":         "https://github.com/client9/misspell",
+	"lll":              "https://github.com/walle/lll",
+	"gocyclo":          "https://github.com/alecthomas/gocyclo",
+	"dupl":             "https://github.com/mibk/dupl",
+	"goconst":          "https://github.com/jgautheron/goconst",
+	"interfacer":       "https://github.com/mvdan/interfacer",
+	"unconvert":        "https://github.com/mdempsky/unconvert",
+	"unparam":          "https://github.com/mvdan/unparam",
+	"maligned":         "https://github.com/mdempsky/maligned",
+	"depguard":         "https://github.com/OpenPeeDeeP/depguard",
+	"nakedret":         "https://github.com/alexkohler/nakedret",
+	"prealloc":         "https://github.com/alexkohler/prealloc",
+	"scopelint":        "https://github.com/kyoh86/scopelint",
+	"gocritic":         "https://github.com/go-critic/go-critic",
+	"gochecknoinits":   "https://github.com/leighmcculloch/gochecknoinits",
+	"gochecknoglobals": "https://github.com/leighmcculloch/gochecknoglobals",
+}
+
+var funcs = template.FuncMap{
+	"pluralize": pluralize,
+	"cell":      escapeTableCell,
+}
+
+func pluralize(count int, singular, plural string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, singular)
+	}
+
+	return fmt.Sprintf("%d %s", count, plural)
+}
+
+// escapeTableCell makes text safe for a cell of markdown table
+func escapeTableCell(s string) string {
+	s = strings.Replace(s, "|", `\|`, -1)
+	s = strings.Replace(s, "\r", "", -1)
+	return strings.Replace(s, "\n", " ", -1)
+}
+
+type Templates struct {
+	comment, summary, status *template.Template
+	linterDocsURLs           map[string]string
+}
+
+var defaultTemplates = mustNew()
+
+// Default returns built-in templates
+func Default() *Templates {
+	return defaultTemplates
+}
+
+func mustNew() *Templates {
+	t, err := New()
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+// New builds templates from built-in defaults overridden by configs: later configs have higher priority
+func New(configs ...*Config) (*Templates, error) {
+	merged := Config{
+		Comment:        defaultCommentTemplate,
+		Summary:        defaultSummaryTemplate,
+		Status:         defaultStatusTemplate,
+		LinterDocsURLs: map[string]string{},
+	}
+	for name, url := range defaultLinterDocsURLs {
+		merged.LinterDocsURLs[name] = url
+	}
+
+	for _, cfg := range configs {
+		if cfg == nil {
+			continue
+		}
+
+		if cfg.Comment != "" {
+			merged.Comment = cfg.Comment
+		}
+		if cfg.Summary != "" {
+			merged.Summary = cfg.Summary
+		}
+		if cfg.Status != "" {
+			merged.Status = cfg.Status
+		}
+		for name, url := range cfg.LinterDocsURLs {
+			merged.LinterDocsURLs[name] = url
+		}
+	}
+
+	ret := &Templates{
+		linterDocsURLs: merged.LinterDocsURLs,
+	}
+
+	var err error
+	if ret.comment, err = parse("comment", merged.Comment, &IssueData{}); err != nil {
+		return nil, err
+	}
+	if ret.summary, err = parse("summary", merged.Summary, &SummaryData{NotCommented: []IssueData{{}}}); err != nil {
+		return nil, err
+	}
+	if ret.status, err = parse("status", merged.Status, &StatusData{Counts: []CountData{{}}}); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// parse parses the template and executes it with the sample data to catch errors before reporting
+func parse(name, text string, sample interface{}) (*template.Template, error) {
+	t, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s template", name)
+	}
+
+	if err = t.Execute(&bytes.Buffer{}, sample); err != nil {
+		return nil, errors.Wrapf(err, "failed to execute %s template", name)
+	}
+
+	return t, nil
+}
+
+func execute(t *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", errors.Wrapf(err, "failed to execute %s template", t.Name())
+	}
+
+	return buf.String(), nil
+}
+
+func (t Templates) LinterDocsURL(linter string) string {
+	return t.linterDocsURLs[linter]
+}
+
+func (t Templates) RenderComment(data *IssueData) (string, error) {
+	return execute(t.comment, data)
+}
+
+func (t Templates) RenderSummary(data *SummaryData) (string, error) {
+	return execute(t.summary, data)
+}
+
+func (t Templates) RenderStatus(data *StatusData) (string, error) {
+	desc, err := execute(t.status, data)
+	if err != nil {
+		return "", err
+	}
+
+	runes := []rune(strings.TrimSpace(desc))
+	if len(runes) > maxStatusDescriptionLen {
+		return string(runes[:maxStatusDescriptionLen-3]) + "...", nil
+	}
+
+	return string(runes), nil
+}
@@ -0,0 +1,123 @@
+package templates
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderStatus(t *testing.T) {
+	type testCase struct {
+		data StatusData
+		exp  string
+	}
+
+	testCases := []testCase{
+		{StatusData{}, "No issues found!"},
+		{
+			StatusData{Total: 1, Blocking: true, Counts: []CountData{{"info", 1}}},
+			"1 issue found: 1 info",
+		},
+		{
+			StatusData{Total: 3, Counts: []CountData{{"warning", 1}, {"info", 2}}},
+			"3 non-blocking issues found: 1 warning, 2 info",
+		},
+		{
+			StatusData{Total: 3, Blocking: true, Counts: []CountData{{"error", 1}, {"warning", 2}}},
+			"3 issues found: 1 error, 2 warnings",
+		},
+	}
+
+	for _, tc := range testCases {
+		desc, err := Default().RenderStatus(&tc.data)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.exp, desc)
+	}
+}
+
+func TestRenderStatusTruncates(t *testing.T) {
+	tmpl, err := New(&Config{Status: strings.Repeat("ы", 200)})
+	assert.NoError(t, err)
+
+	desc, err := tmpl.RenderStatus(&StatusData{})
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat("ы", maxStatusDescriptionLen-3)+"...", desc)
+}
+
+func TestRenderComment(t *testing.T) {
+	data := &IssueData{
+		Text:       "var x is unused",
+		Linter:     "deadcode",
+		Suggestion: "```suggestion\n```",
+	}
+
+	text, err := Default().RenderComment(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "var x is unused\n\n```suggestion\n```", text)
+
+	data.IncludeLinterName = true
+	text, err = Default().RenderComment(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "var x is unused (from `deadcode`)\n\n```suggestion\n```", text)
+}
+
+func TestOverrides(t *testing.T) {
+	deployment := &Config{
+		Comment:        "{{ .Text }}, see {{ .LinterDocsURL }}",
+		LinterDocsURLs: map[string]string{"golint": "https://wiki.example.com/go"},
+	}
+	repo := &Config{
+		Comment: "[{{ .Severity }}] {{ .Text }}, see {{ .LinterDocsURL }}",
+	}
+
+	tmpl, err := New(deployment, repo)
+	assert.NoError(t, err)
+
+	data := &IssueData{
+		Text:          "exported func should have comment",
+		Severity:      "warning",
+		LinterDocsURL: tmpl.LinterDocsURL("golint"),
+	}
+	text, err := tmpl.RenderComment(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "[warning] exported func should have comment, see https://wiki.example.com/go", text)
+
+	assert.Equal(t, "https://github.com/kisielk/errcheck", tmpl.LinterDocsURL("errcheck"))
+	assert.Equal(t, "https://golang.org/cmd/vet/", Default().LinterDocsURL("govet"))
+	assert.Equal(t, "https://github.com/golang/lint", Default().LinterDocsURL("golint"))
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	_, err := New(&Config{Comment: "{{ .Text "})
+	assert.Error(t, err)
+
+	_, err = New(&Config{Summary: "{{ .NoSuchField }}"})
+	assert.Error(t, err)
+}
+
+func TestInvalidDeploymentConfig(t *testing.T) {
+	f, err := ioutil.TempFile("", "templates")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString("comment: \"{{ .Unknown }}\"\n")
+	assert.NoError(t, err)
+	f.Close()
+
+	prev, wasSet := os.LookupEnv("TEMPLATES_CONFIG")
+	os.Setenv("TEMPLATES_CONFIG", f.Name())
+	defer func() {
+		if wasSet {
+			os.Setenv("TEMPLATES_CONFIG", prev)
+		} else {
+			os.Unsetenv("TEMPLATES_CONFIG")
+		}
+	}()
+
+	// the worker fails at startup instead of failing analyses
+	_, err = GetDeploymentConfig()
+	assert.Error(t, err)
+}
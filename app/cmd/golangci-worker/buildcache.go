@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golangci/golangci-worker/app/lib/goutils/buildcache"
+)
+
+const buildCacheUsage = `Usage: golangci-worker buildcache <command>
+
+Commands:
+  trim                 remove least recently used entries until the cache is within BUILD_CACHE_MAX_SIZE_MB
+`
+
+func runBuildCacheCommand(args []string) error {
+	if len(args) != 1 || args[0] != "trim" {
+		return errors.New(buildCacheUsage)
+	}
+
+	if !buildcache.IsConfigured() {
+		return errors.New("BUILD_CACHE_DIR isn't set")
+	}
+
+	cache, err := buildcache.Get()
+	if err != nil {
+		return err
+	}
+
+	res, err := cache.Trim(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed %d entries of %d bytes, cache size is %d bytes\n", res.Removed, res.RemovedSize, res.Size)
+	return nil
+}
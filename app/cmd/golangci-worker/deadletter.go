@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analyze/analyzequeue"
+	"github.com/golangci/golangci-worker/app/analyze/deadletter"
+	"github.com/golangci/golangci-worker/app/lib/httputils"
+	"github.com/golangci/golangci-worker/app/lib/queue"
+	"github.com/golangci/golangci-worker/app/lib/redisutils"
+)
+
+const deadLetterUsage = `Usage: golangci-worker deadletter <command> [arguments]
+
+Commands:
+  list                 list dead tasks from the newest to the oldest
+  inspect <id>         show task payload, all attempts and the last warnings
+  requeue <id>...      send tasks back to their queues
+  requeue --all        send all dead tasks back to their queues
+  purge <id>...        remove tasks
+  purge --all          remove all dead tasks
+`
+
+const maxListedErrorLen = 80
+
+func runDeadLetterCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(deadLetterUsage)
+	}
+
+	if !redisutils.IsConfigured() {
+		return errors.New("REDIS_URL isn't set: dead-letter queue is kept in redis")
+	}
+
+	store, err := deadletter.NewStoreFromEnv()
+	if err != nil {
+		return err
+	}
+
+	tokens := analyzequeue.NewAPIAccessTokenFetcher(httputils.GrequestsClient{})
+	cli := deadLetterCLI{
+		store: store,
+		out:   os.Stdout,
+		requeue: func(ctx context.Context, id string) error {
+			queue.Init()
+			return analyzequeue.RequeueDeadTask(ctx, store, tokens, id)
+		},
+	}
+	return cli.run(context.Background(), args)
+}
+
+type deadLetterCLI struct {
+	store   deadletter.Store
+	out     io.Writer
+	requeue func(ctx context.Context, id string) error
+}
+
+func (cli deadLetterCLI) run(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(cli.out)
+	all := fs.Bool("all", false, "apply to all dead tasks")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		return cli.listDeadTasks(ctx)
+	case "inspect":
+		if fs.NArg() != 1 {
+			return errors.New("inspect requires exactly one task id")
+		}
+		return cli.inspectDeadTask(ctx, fs.Arg(0))
+	case "requeue":
+		ids, err := getDeadTaskIDs(ctx, cli.store, fs.Args(), *all)
+		if err != nil {
+			return err
+		}
+		return cli.requeueDeadTasks(ctx, ids)
+	case "purge":
+		return purgeDeadTasks(ctx, cli.store, fs.Args(), *all)
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", args[0], deadLetterUsage)
+	}
+}
+
+func getDeadTaskIDs(ctx context.Context, store deadletter.Store, args []string, all bool) ([]string, error) {
+	if !all {
+		if len(args) == 0 {
+			return nil, errors.New("task ids or --all are required")
+		}
+		return args, nil
+	}
+
+	tasks, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, t := range tasks {
+		ids = append(ids, t.ID)
+	}
+	return ids, nil
+}
+
+func purgeDeadTasks(ctx context.Context, store deadletter.Store, ids []string, all bool) error {
+	if all {
+		return store.Purge(ctx)
+	}
+
+	if len(ids) == 0 {
+		return errors.New("task ids or --all are required")
+	}
+
+	for _, id := range ids {
+		if err := store.Remove(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cli deadLetterCLI) requeueDeadTasks(ctx context.Context, ids []string) error {
+	for _, id := range ids {
+		if err := cli.requeue(ctx, id); err != nil {
+			return fmt.Errorf("can't requeue task %s: %s", id, err)
+		}
+		fmt.Fprintf(cli.out, "Requeued task %s\n", id)
+	}
+
+	return nil
+}
+
+func (cli deadLetterCLI) listDeadTasks(ctx context.Context) error {
+	tasks, err := cli.store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cli.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tQUEUE\tATTEMPTS\tDEAD AT\tLAST ERROR")
+	for _, t := range tasks {
+		lastError := ""
+		if len(t.Attempts) != 0 {
+			lastError = t.Attempts[len(t.Attempts)-1].Error
+		}
+		lastError = strings.Replace(lastError, "\n", " ", -1)
+		if len(lastError) > maxListedErrorLen {
+			lastError = lastError[:maxListedErrorLen] + "..."
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", t.ID, t.Queue, len(t.Attempts),
+			t.DeadAt.Format(time.RFC3339), lastError)
+	}
+
+	return w.Flush()
+}
+
+func (cli deadLetterCLI) inspectDeadTask(ctx context.Context, id string) error {
+	t, err := cli.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return fmt.Errorf("no dead task %s", id)
+	}
+
+	t.Payload = maskPayloadSecrets(t.Payload)
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cli.out, string(data))
+	return nil
+}
+
+// maskPayloadSecrets hides the GitHub access token of tasks saved before tokens were removed from dead tasks
+func maskPayloadSecrets(payload json.RawMessage) json.RawMessage {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return payload
+	}
+
+	if token, ok := fields["GithubAccessToken"].(string); ok && token != "" {
+		fields["GithubAccessToken"] = "{hidden}"
+	}
+
+	ret, err := json.Marshal(fields)
+	if err != nil {
+		return payload
+	}
+
+	return ret
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analyze/deadletter"
+	"github.com/stretchr/testify/assert"
+)
+
+type testDeadLetterCLI struct {
+	deadLetterCLI
+	out      *bytes.Buffer
+	requeued []string
+}
+
+func newTestDeadLetterCLI(t *testing.T) *testDeadLetterCLI {
+	store := deadletter.NewMemoryStore(deadletter.DefaultRetention)
+	now := time.Now()
+	tasks := []*deadletter.Task{
+		{
+			ID:       "analyzeV2:old",
+			Name:     "analyzeV2",
+			Queue:    "analyze_pr",
+			Payload:  []byte(`{"GithubAccessToken":"legacy_token","AnalysisGUID":"old"}`),
+			Attempts: []deadletter.Attempt{{Error: "can't clone\nrepo"}},
+			DeadAt:   now.Add(-time.Hour),
+		},
+		{
+			ID:       "analyzeRepoPayload:new",
+			Name:     "analyzeRepoPayload",
+			Queue:    "analyze_repo",
+			Payload:  []byte(`{"Name":"owner/name","AnalysisGUID":"new"}`),
+			Attempts: []deadletter.Attempt{{Error: strings.Repeat("x", maxListedErrorLen+1)}},
+			DeadAt:   now,
+		},
+	}
+	for _, dt := range tasks {
+		assert.NoError(t, store.Add(context.Background(), dt))
+	}
+
+	cli := &testDeadLetterCLI{
+		out: &bytes.Buffer{},
+	}
+	cli.deadLetterCLI = deadLetterCLI{
+		store: store,
+		out:   cli.out,
+		requeue: func(ctx context.Context, id string) error {
+			if id == "analyzeV2:failing" {
+				return errors.New("no such task")
+			}
+			cli.requeued = append(cli.requeued, id)
+			return store.Remove(ctx, id)
+		},
+	}
+	return cli
+}
+
+func (cli *testDeadLetterCLI) run(args ...string) error {
+	return cli.deadLetterCLI.run(context.Background(), args)
+}
+
+func (cli *testDeadLetterCLI) listedIDs(t *testing.T) []string {
+	tasks, err := cli.store.List(context.Background())
+	assert.NoError(t, err)
+
+	var ids []string
+	for _, dt := range tasks {
+		ids = append(ids, dt.ID)
+	}
+	return ids
+}
+
+func TestDeadLetterList(t *testing.T) {
+	cli := newTestDeadLetterCLI(t)
+	assert.NoError(t, cli.run("list"))
+
+	lines := strings.Split(strings.TrimSpace(cli.out.String()), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.True(t, strings.HasPrefix(lines[0], "ID"))
+		assert.True(t, strings.HasPrefix(lines[1], "analyzeRepoPayload:new"), "newest task goes first")
+		assert.Contains(t, lines[1], strings.Repeat("x", maxListedErrorLen)+"...")
+		assert.Contains(t, lines[2], "can't clone repo")
+	}
+}
+
+func TestDeadLetterInspectHidesToken(t *testing.T) {
+	cli := newTestDeadLetterCLI(t)
+	assert.NoError(t, cli.run("inspect", "analyzeV2:old"))
+	assert.NotContains(t, cli.out.String(), "legacy_token")
+	assert.Contains(t, cli.out.String(), "{hidden}")
+
+	assert.Error(t, cli.run("inspect", "unknown"))
+	assert.Error(t, cli.run("inspect"))
+}
+
+func TestDeadLetterRequeue(t *testing.T) {
+	cli := newTestDeadLetterCLI(t)
+	assert.Error(t, cli.run("requeue"), "ids or --all are required")
+
+	assert.NoError(t, cli.run("requeue", "analyzeV2:old"))
+	assert.Equal(t, []string{"analyzeV2:old"}, cli.requeued)
+	assert.Contains(t, cli.out.String(), "Requeued task analyzeV2:old")
+
+	assert.Error(t, cli.run("requeue", "analyzeV2:failing"))
+
+	assert.NoError(t, cli.run("requeue", "--all"))
+	assert.Equal(t, []string{"analyzeV2:old", "analyzeRepoPayload:new"}, cli.requeued)
+	assert.Empty(t, cli.listedIDs(t))
+}
+
+func TestDeadLetterPurge(t *testing.T) {
+	cli := newTestDeadLetterCLI(t)
+	assert.Error(t, cli.run("purge"), "ids or --all are required")
+
+	assert.NoError(t, cli.run("purge", "analyzeV2:old"))
+	assert.Equal(t, []string{"analyzeRepoPayload:new"}, cli.listedIDs(t))
+
+	assert.NoError(t, cli.run("purge", "--all"))
+	assert.Empty(t, cli.listedIDs(t))
+}
+
+func TestDeadLetterUnknownCommand(t *testing.T) {
+	cli := newTestDeadLetterCLI(t)
+	assert.Error(t, cli.run("unknown"))
+	assert.Error(t, runDeadLetterCommand(nil))
+}
@@ -1,12 +1,28 @@
 package main
 
 import (
+	"os"
+
 	"github.com/golangci/golangci-worker/app/analyze/analyzequeue"
 	"github.com/golangci/golangci-worker/app/lib/queue"
 	"github.com/sirupsen/logrus"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "deadletter" {
+		if err := runDeadLetterCommand(os.Args[2:]); err != nil {
+			logrus.Fatalf("Dead-letter command failed: %s", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "buildcache" {
+		if err := runBuildCacheCommand(os.Args[2:]); err != nil {
+			logrus.Fatalf("Build cache command failed: %s", err)
+		}
+		return
+	}
+
 	queue.Init()
 	analyzequeue.RegisterTasks()
 	if err := analyzequeue.RunWorker(); err != nil {
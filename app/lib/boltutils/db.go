@@ -0,0 +1,36 @@
+package boltutils
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var db *bolt.DB
+var dbErr error
+var initOnce sync.Once
+
+func initDB() {
+	path := os.Getenv("STATE_DB_PATH")
+	db, dbErr = bolt.Open(path, 0600, &bolt.Options{
+		Timeout: 10 * time.Second, // db file is locked by another worker process
+	})
+	if dbErr != nil {
+		dbErr = fmt.Errorf("can't open bolt db %s: %s", path, dbErr)
+	}
+}
+
+// GetDB returns the bolt db from STATE_DB_PATH env var. The db can be opened only by one process:
+// every worker process must have its own db.
+func GetDB() (*bolt.DB, error) {
+	initOnce.Do(initDB)
+	return db, dbErr
+}
+
+// IsConfigured returns true if bolt db path is set
+func IsConfigured() bool {
+	return os.Getenv("STATE_DB_PATH") != ""
+}
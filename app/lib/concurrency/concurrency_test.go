@@ -0,0 +1,165 @@
+package concurrency
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLimiter()
+	slots := func(ownerLimit, repoLimit int) []Slot {
+		return []Slot{{Key: "owner", Limit: ownerLimit}, {Key: "repo", Limit: repoLimit}}
+	}
+
+	acquired, err := l.TryAcquire(ctx, "1", slots(2, 1), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = l.TryAcquire(ctx, "2", slots(2, 1), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, acquired, "repo slot is full")
+
+	acquired, err = l.TryAcquire(ctx, "1", slots(2, 1), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired, "the same task can reacquire its slots")
+
+	acquired, err = l.TryAcquire(ctx, "2", slots(2, 0), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired, "0 is unlimited")
+
+	acquired, err = l.TryAcquire(ctx, "3", slots(2, 0), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, acquired, "owner slot is full")
+
+	assert.NoError(t, l.Release(ctx, "1", slots(2, 1)))
+	acquired, err = l.TryAcquire(ctx, "3", slots(2, 1), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func TestMemoryLimiterExpiration(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLimiter()
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	slots := []Slot{{Key: "repo", Limit: 1}}
+
+	acquired, err := l.TryAcquire(ctx, "crashed", slots, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = l.TryAcquire(ctx, "2", slots, time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+
+	now = now.Add(time.Minute)
+	acquired, err = l.TryAcquire(ctx, "2", slots, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired, "slot of the crashed task must expire")
+}
+
+// acquireAll starts waiting for slots in the given order and returns groups in order of grants
+func acquireAll(t *testing.T, s *FairScheduler, groups []string) <-chan string {
+	granted := make(chan string, len(groups))
+	for i, g := range groups {
+		g := g
+		go func() {
+			priority := 0
+			if strings.HasPrefix(g, "paid") {
+				priority = 1
+			}
+			release, err := s.Acquire(context.Background(), g, priority)
+			assert.NoError(t, err)
+			granted <- g
+			release()
+		}()
+		waitForWaiters(s, i+1)
+	}
+
+	return granted
+}
+
+func waitForWaiters(s *FairScheduler, n int) {
+	for i := 0; i < 1000; i++ {
+		s.lock.Lock()
+		waiting := 0
+		for _, g := range s.groups {
+			waiting += len(g.waiting)
+		}
+		s.lock.Unlock()
+
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFairSchedulerInterleavesOwners(t *testing.T) {
+	s := NewFairScheduler(1, nil)
+	release, err := s.Acquire(context.Background(), "busy", 0)
+	assert.NoError(t, err)
+
+	granted := acquireAll(t, s, []string{"busy", "busy", "busy", "small"})
+	release()
+
+	var order []string
+	for i := 0; i < 4; i++ {
+		order = append(order, <-granted)
+	}
+	assert.Equal(t, []string{"small", "busy", "busy", "busy"}, order)
+}
+
+func TestFairSchedulerWeights(t *testing.T) {
+	s := NewFairScheduler(1, map[string]float64{"heavy": 2})
+	release, err := s.Acquire(context.Background(), "blocker", 0)
+	assert.NoError(t, err)
+
+	granted := acquireAll(t, s, []string{"light", "light", "light", "heavy", "heavy", "heavy", "heavy"})
+	release()
+
+	var order []string
+	for i := 0; i < 7; i++ {
+		order = append(order, <-granted)
+	}
+	assert.Equal(t, []string{"heavy", "light", "heavy", "heavy", "light", "heavy", "light"}, order)
+}
+
+func TestFairSchedulerPriority(t *testing.T) {
+	s := NewFairScheduler(1, nil)
+	release, err := s.Acquire(context.Background(), "blocker", 0)
+	assert.NoError(t, err)
+
+	granted := acquireAll(t, s, []string{"free", "free", "paid1", "paid2", "paid1"})
+	release()
+
+	var order []string
+	for i := 0; i < 5; i++ {
+		order = append(order, <-granted)
+	}
+	assert.Equal(t, []string{"paid1", "paid2", "paid1", "free", "free"}, order)
+}
+
+func TestFairSchedulerCancel(t *testing.T) {
+	s := NewFairScheduler(1, nil)
+	release, err := s.Acquire(context.Background(), "a", 0)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = s.Acquire(ctx, "b", 0)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	release()
+	release() // must be idempotent
+
+	secondRelease, err := s.Acquire(context.Background(), "b", 0)
+	assert.NoError(t, err)
+	secondRelease()
+	assert.Equal(t, 1, s.free)
+}
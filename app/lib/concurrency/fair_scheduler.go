@@ -0,0 +1,191 @@
+package concurrency
+
+import (
+	"context"
+	"sync"
+)
+
+// FairScheduler limits count of concurrently running tasks of this process and grants
+// free slots to tasks with the greatest priority first. Tasks with the same priority get slots
+// in weighted fair order between their groups (e.g. repo owners): when groups have waiting tasks,
+// a group with the weight 2 gets twice more slots than a group with the weight 1.
+// It's a start-time fair queueing: every grant moves the virtual time of the group by 1/weight
+// and the next slot goes to the waiting group with the least virtual time.
+type FairScheduler struct {
+	lock    sync.Mutex
+	free    int
+	weights map[string]float64
+	groups  map[string]*fairGroup
+	vtime   float64 // virtual start time of the last grant
+}
+
+type fairGroup struct {
+	finish  float64 // virtual finish time of the last grant
+	waiting []*waiter
+}
+
+type waiter struct {
+	granted  chan struct{}
+	priority int
+}
+
+// next returns index of the first waiter with the greatest priority
+func (g fairGroup) next() int {
+	ret := 0
+	for i, w := range g.waiting {
+		if w.priority > g.waiting[ret].priority {
+			ret = i
+		}
+	}
+
+	return ret
+}
+
+func NewFairScheduler(slots int, weights map[string]float64) *FairScheduler {
+	return &FairScheduler{
+		free:    slots,
+		weights: weights,
+		groups:  map[string]*fairGroup{},
+	}
+}
+
+// Acquire waits for a free slot for the task of the group. Call the returned release func when the task is done.
+func (s *FairScheduler) Acquire(ctx context.Context, group string, priority int) (func(), error) {
+	s.lock.Lock()
+
+	g := s.groups[group]
+	if g == nil {
+		g = &fairGroup{}
+		s.groups[group] = g
+	}
+
+	if s.free > 0 && !s.hasWaitingLocked() {
+		s.grantLocked(group, g)
+		s.lock.Unlock()
+		return s.releaseFunc(), nil
+	}
+
+	w := &waiter{
+		granted:  make(chan struct{}),
+		priority: priority,
+	}
+	g.waiting = append(g.waiting, w)
+	s.lock.Unlock()
+
+	select {
+	case <-w.granted:
+		return s.releaseFunc(), nil
+	case <-ctx.Done():
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i, gw := range g.waiting {
+		if gw == w {
+			g.waiting = append(g.waiting[:i], g.waiting[i+1:]...)
+			return nil, ctx.Err()
+		}
+	}
+
+	// the slot was granted concurrently with the cancellation: pass it to others
+	s.free++
+	s.dispatchLocked()
+	return nil, ctx.Err()
+}
+
+func (s *FairScheduler) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.lock.Lock()
+			defer s.lock.Unlock()
+
+			s.free++
+			s.dispatchLocked()
+		})
+	}
+}
+
+func (s *FairScheduler) weight(group string) float64 {
+	if w, ok := s.weights[group]; ok && w > 0 {
+		return w
+	}
+
+	return 1
+}
+
+func (s *FairScheduler) startTime(g *fairGroup) float64 {
+	if g.finish > s.vtime {
+		return g.finish
+	}
+
+	return s.vtime
+}
+
+func (s *FairScheduler) grantLocked(group string, g *fairGroup) {
+	start := s.startTime(g)
+	g.finish = start + 1/s.weight(group)
+	s.vtime = start
+	s.free--
+}
+
+func (s *FairScheduler) hasWaitingLocked() bool {
+	for _, g := range s.groups {
+		if len(g.waiting) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *FairScheduler) isBefore(name string, g *fairGroup, otherName string, other *fairGroup) bool {
+	if p, otherP := g.waiting[g.next()].priority, other.waiting[other.next()].priority; p != otherP {
+		return p > otherP
+	}
+
+	if start, otherStart := s.startTime(g), s.startTime(other); start != otherStart {
+		return start < otherStart
+	}
+
+	return name < otherName
+}
+
+func (s *FairScheduler) nextGroupLocked() (string, *fairGroup) {
+	var nextName string
+	var next *fairGroup
+	for name, g := range s.groups {
+		if len(g.waiting) == 0 {
+			continue
+		}
+
+		if next == nil || s.isBefore(name, g, nextName, next) {
+			nextName, next = name, g
+		}
+	}
+
+	return nextName, next
+}
+
+func (s *FairScheduler) dispatchLocked() {
+	for s.free > 0 {
+		name, g := s.nextGroupLocked()
+		if g == nil {
+			break
+		}
+
+		i := g.next()
+		w := g.waiting[i]
+		g.waiting = append(g.waiting[:i], g.waiting[i+1:]...)
+		s.grantLocked(name, g)
+		close(w.granted)
+	}
+
+	// idle groups which didn't get ahead of others are the same as new ones
+	for name, g := range s.groups {
+		if len(g.waiting) == 0 && g.finish <= s.vtime {
+			delete(s.groups, name)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package concurrency
+
+import (
+	"context"
+	"time"
+)
+
+// Slot limits count of concurrently running tasks with the same key
+type Slot struct {
+	Key   string
+	Limit int // 0 means unlimited
+}
+
+// Limiter counts running tasks in slots
+type Limiter interface {
+	// TryAcquire atomically takes all slots for the task with the id: it returns false
+	// and takes nothing if any slot is full. Slots are released automatically after the ttl:
+	// then a crashed worker doesn't block slots forever.
+	TryAcquire(ctx context.Context, id string, slots []Slot, ttl time.Duration) (bool, error)
+
+	Release(ctx context.Context, id string, slots []Slot) error
+}
+
+func limitedSlots(slots []Slot) []Slot {
+	var ret []Slot
+	for _, s := range slots {
+		if s.Limit > 0 {
+			ret = append(ret, s)
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,63 @@
+package concurrency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter counts tasks of this process only: it's used in tests and when redis isn't configured
+type MemoryLimiter struct {
+	lock  sync.Mutex
+	slots map[string]map[string]time.Time // slot key -> task id -> expiration time
+	now   func() time.Time
+}
+
+var _ Limiter = &MemoryLimiter{}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		slots: map[string]map[string]time.Time{},
+		now:   time.Now,
+	}
+}
+
+func (l *MemoryLimiter) TryAcquire(_ context.Context, id string, slots []Slot, ttl time.Duration) (bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	slots = limitedSlots(slots)
+	for _, s := range slots {
+		tasks := l.slots[s.Key]
+		for taskID, expiresAt := range tasks {
+			if !expiresAt.After(now) {
+				delete(tasks, taskID)
+			}
+		}
+
+		if _, ok := tasks[id]; !ok && len(tasks) >= s.Limit {
+			return false, nil
+		}
+	}
+
+	for _, s := range slots {
+		if l.slots[s.Key] == nil {
+			l.slots[s.Key] = map[string]time.Time{}
+		}
+		l.slots[s.Key][id] = now.Add(ttl)
+	}
+
+	return true, nil
+}
+
+func (l *MemoryLimiter) Release(_ context.Context, id string, slots []Slot) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, s := range slots {
+		delete(l.slots[s.Key], id)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package concurrency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// acquireScript takes all slots or nothing. Every slot is a sorted set of task ids scored by expiration time.
+// KEYS - slot keys, ARGV[1] - task id, ARGV[2] - now, ARGV[3] - expiration time, ARGV[4] - ttl in seconds,
+// ARGV[4+i] - limit of the i-th slot
+var acquireScript = redis.NewScript(-1, `
+for i, key in ipairs(KEYS) do
+	redis.call('ZREMRANGEBYSCORE', key, '-inf', ARGV[2])
+	if not redis.call('ZSCORE', key, ARGV[1]) and redis.call('ZCARD', key) >= tonumber(ARGV[4 + i]) then
+		return 0
+	end
+end
+for _, key in ipairs(KEYS) do
+	redis.call('ZADD', key, ARGV[3], ARGV[1])
+	redis.call('EXPIRE', key, ARGV[4])
+end
+return 1
+`)
+
+// RedisLimiter counts tasks of all worker processes
+type RedisLimiter struct {
+	pool *redis.Pool
+}
+
+var _ Limiter = &RedisLimiter{}
+
+func NewRedisLimiter(pool *redis.Pool) *RedisLimiter {
+	return &RedisLimiter{
+		pool: pool,
+	}
+}
+
+func (l RedisLimiter) redisKey(key string) string {
+	return "concurrency_slot:" + key
+}
+
+func (l RedisLimiter) TryAcquire(_ context.Context, id string, slots []Slot, ttl time.Duration) (bool, error) {
+	slots = limitedSlots(slots)
+	if len(slots) == 0 {
+		return true, nil
+	}
+
+	now := time.Now()
+	args := []interface{}{len(slots)}
+	for _, s := range slots {
+		args = append(args, l.redisKey(s.Key))
+	}
+	args = append(args, id, toMillis(now), toMillis(now.Add(ttl)), int(ttl/time.Second))
+	for _, s := range slots {
+		args = append(args, s.Limit)
+	}
+
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	acquired, err := redis.Bool(acquireScript.Do(conn, args...))
+	if err != nil {
+		return false, fmt.Errorf("can't acquire slots in redis: %s", err)
+	}
+
+	return acquired, nil
+}
+
+func (l RedisLimiter) Release(_ context.Context, id string, slots []Slot) error {
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	for _, s := range limitedSlots(slots) {
+		if _, err := conn.Do("ZREM", l.redisKey(s.Key), id); err != nil {
+			return fmt.Errorf("can't release slot %s in redis: %s", s.Key, err)
+		}
+	}
+
+	return nil
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
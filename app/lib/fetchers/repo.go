@@ -4,4 +4,5 @@ type Repo struct {
 	CloneURL string
 	Ref      string
 	FullPath string
+	Owner    string // the owner of the analyzed repo, the clone url can be a fork of another owner
 }
@@ -34,8 +34,18 @@ type Client interface {
 	GetPullRequest(ctx context.Context, c *Context) (*gh.PullRequest, error)
 	GetPullRequestComments(ctx context.Context, c *Context) ([]*gh.PullRequestComment, error)
 	GetPullRequestPatch(ctx context.Context, c *Context) (string, error)
-	CreateReview(ctx context.Context, c *Context, review *gh.PullRequestReviewRequest) error
+	CreateReview(ctx context.Context, c *Context, review *Review) error
+	GetPullRequestReviews(ctx context.Context, c *Context) ([]*gh.PullRequestReview, error)
+	DismissReview(ctx context.Context, c *Context, reviewID int64, message string) error
 	SetCommitStatus(ctx context.Context, c *Context, ref string, status Status, desc, url string) error
+	ResetBranch(ctx context.Context, c *Context, branch, sha string) error
+	PushCommit(ctx context.Context, c *Context, branch string, commit *Commit) (string, error)
+	CreatePullRequest(ctx context.Context, c *Context, pull *gh.NewPullRequest) (*gh.PullRequest, error)
+	GetOpenPullRequest(ctx context.Context, c *Context, head, base string) (*gh.PullRequest, error)
+	GetTreeFiles(ctx context.Context, c *Context, sha string) ([]TreeFile, error)
+	GetRootFiles(ctx context.Context, c *Context, sha string) ([]TreeFile, error)
+	GetBlob(ctx context.Context, c *Context, sha string) (string, error)
+	GetBranchSHA(ctx context.Context, c *Context, branch string) (string, error)
 }
 
 type MyClient struct{}
@@ -99,9 +109,40 @@ func (gc *MyClient) GetPullRequest(ctx context.Context, c *Context) (*gh.PullReq
 	return retPR, nil
 }
 
-func (gc *MyClient) CreateReview(ctx context.Context, c *Context, review *gh.PullRequestReviewRequest) error {
-	_, _, err := c.GetClient(ctx).PullRequests.CreateReview(ctx, c.Repo.Owner, c.Repo.Name, c.PullRequestNumber, review)
+// GetBranchSHA returns the head commit of the branch
+func (gc *MyClient) GetBranchSHA(ctx context.Context, c *Context, branch string) (string, error) {
+	var ret string
+
+	f := func() error {
+		b, _, err := c.GetClient(ctx).Repositories.GetBranch(ctx, c.Repo.Owner, c.Repo.Name, branch)
+		if err != nil {
+			return err
+		}
+
+		ret = b.GetCommit().GetSHA()
+		return nil
+	}
+
+	if err := retryGet(f); err != nil {
+		if terr := transformGithubError(err); terr != nil {
+			return "", terr
+		}
+
+		return "", fmt.Errorf("can't get branch %s from github: %s", branch, err)
+	}
+
+	return ret, nil
+}
+
+func (gc *MyClient) CreateReview(ctx context.Context, c *Context, review *Review) error {
+	client := c.GetClient(ctx)
+	u := fmt.Sprintf("repos/%s/%s/pulls/%d/reviews", c.Repo.Owner, c.Repo.Name, c.PullRequestNumber)
+	req, err := client.NewRequest(http.MethodPost, u, review)
 	if err != nil {
+		return fmt.Errorf("can't build github review request: %s", err)
+	}
+
+	if _, err = client.Do(ctx, req, nil); err != nil {
 		if terr := transformGithubError(err); terr != nil {
 			return terr
 		}
@@ -112,6 +153,57 @@ func (gc *MyClient) CreateReview(ctx context.Context, c *Context, review *gh.Pul
 	return nil
 }
 
+func (gc *MyClient) GetPullRequestReviews(ctx context.Context, c *Context) ([]*gh.PullRequestReview, error) {
+	var ret []*gh.PullRequestReview
+
+	f := func() error {
+		ret = nil
+		opt := &gh.ListOptions{
+			PerPage: 100, // max allowed value
+		}
+		for {
+			reviews, resp, err := c.GetClient(ctx).PullRequests.ListReviews(ctx, c.Repo.Owner, c.Repo.Name,
+				c.PullRequestNumber, opt)
+			if err != nil {
+				return err
+			}
+
+			ret = append(ret, reviews...)
+			if resp.NextPage == 0 {
+				return nil
+			}
+			opt.Page = resp.NextPage
+		}
+	}
+
+	if err := retryGet(f); err != nil {
+		if terr := transformGithubError(err); terr != nil {
+			return nil, terr
+		}
+
+		return nil, fmt.Errorf("can't get pull request %d reviews from github: %s", c.PullRequestNumber, err)
+	}
+
+	return ret, nil
+}
+
+func (gc *MyClient) DismissReview(ctx context.Context, c *Context, reviewID int64, message string) error {
+	req := &gh.PullRequestReviewDismissalRequest{
+		Message: gh.String(message),
+	}
+	_, _, err := c.GetClient(ctx).PullRequests.DismissReview(ctx, c.Repo.Owner, c.Repo.Name,
+		c.PullRequestNumber, reviewID, req)
+	if err != nil {
+		if terr := transformGithubError(err); terr != nil {
+			return terr
+		}
+
+		return fmt.Errorf("can't dismiss review %d: %s", reviewID, err)
+	}
+
+	return nil
+}
+
 func (gc *MyClient) GetPullRequestPatch(ctx context.Context, c *Context) (string, error) {
 	var ret string
 
@@ -187,3 +279,189 @@ func (gc *MyClient) GetPullRequestComments(ctx context.Context, c *Context) ([]*
 
 	return ret, nil
 }
+
+// ResetBranch points the branch to the commit sha: the branch is created if it doesn't exist
+// and is force-updated otherwise
+func (gc *MyClient) ResetBranch(ctx context.Context, c *Context, branch, sha string) error {
+	client := c.GetClient(ctx)
+	ref := &gh.Reference{
+		Ref: gh.String("refs/heads/" + branch),
+		Object: &gh.GitObject{
+			SHA: gh.String(sha),
+		},
+	}
+
+	_, resp, err := client.Git.CreateRef(ctx, c.Repo.Owner, c.Repo.Name, ref)
+	if err == nil {
+		return nil
+	}
+	// GitHub responds 422 if the branch already exists
+	if resp == nil || resp.StatusCode != http.StatusUnprocessableEntity {
+		if terr := transformGithubError(err); terr != nil {
+			return terr
+		}
+
+		return fmt.Errorf("can't create branch %s from %s: %s", branch, sha, err)
+	}
+
+	if _, _, err = client.Git.UpdateRef(ctx, c.Repo.Owner, c.Repo.Name, ref, true); err != nil {
+		if terr := transformGithubError(err); terr != nil {
+			return terr
+		}
+
+		return fmt.Errorf("can't reset branch %s to %s: %s", branch, sha, err)
+	}
+
+	return nil
+}
+
+// PushCommit creates the commit on top of commit.ParentSHA and fast-forwards the branch to it.
+// It fails if the branch head isn't commit.ParentSHA anymore.
+func (gc *MyClient) PushCommit(ctx context.Context, c *Context, branch string, commit *Commit) (string, error) {
+	client := c.GetClient(ctx)
+	owner, name := c.Repo.Owner, c.Repo.Name
+
+	parent, _, err := client.Git.GetCommit(ctx, owner, name, commit.ParentSHA)
+	if err != nil {
+		if terr := transformGithubError(err); terr != nil {
+			return "", terr
+		}
+
+		return "", fmt.Errorf("can't get parent commit %s: %s", commit.ParentSHA, err)
+	}
+
+	tree, err := createTree(ctx, client, c, parent.GetTree().GetSHA(), commit.Files)
+	if err != nil {
+		return "", fmt.Errorf("can't create git tree: %s", err)
+	}
+
+	now := time.Now()
+	author := &gh.CommitAuthor{
+		Name:  gh.String(commit.AuthorName),
+		Email: gh.String(commit.AuthorEmail),
+		Date:  &now,
+	}
+	newCommit, _, err := client.Git.CreateCommit(ctx, owner, name, &gh.Commit{
+		Message:   gh.String(commit.Message),
+		Tree:      tree,
+		Parents:   []gh.Commit{{SHA: gh.String(commit.ParentSHA)}},
+		Author:    author,
+		Committer: author,
+	})
+	if err != nil {
+		return "", fmt.Errorf("can't create git commit: %s", err)
+	}
+
+	ref := &gh.Reference{
+		Ref: gh.String("refs/heads/" + branch),
+		Object: &gh.GitObject{
+			SHA: newCommit.SHA,
+		},
+	}
+	if _, _, err = client.Git.UpdateRef(ctx, owner, name, ref, false); err != nil {
+		if terr := transformGithubError(err); terr != nil {
+			return "", terr
+		}
+
+		return "", fmt.Errorf("can't update branch %s to commit %s: %s", branch, newCommit.GetSHA(), err)
+	}
+
+	return newCommit.GetSHA(), nil
+}
+
+// deletedTreeEntry removes the file from the base tree: GitHub removes entries with null sha
+type deletedTreeEntry struct {
+	Path string  `json:"path"`
+	Mode string  `json:"mode"`
+	Type string  `json:"type"`
+	SHA  *string `json:"sha"`
+}
+
+// createTree creates the tree with changed and removed files on top of the base tree:
+// gh.TreeEntry can't remove files because it omits null sha
+func createTree(ctx context.Context, client *gh.Client, c *Context, baseTree string, files []FileChange) (*gh.Tree, error) {
+	var entries []interface{}
+	for _, f := range files {
+		if f.Deleted {
+			entries = append(entries, deletedTreeEntry{
+				Path: f.Path,
+				Mode: RegularFileMode,
+				Type: "blob",
+			})
+			continue
+		}
+
+		mode := f.Mode
+		if mode == "" {
+			mode = RegularFileMode
+		}
+		entries = append(entries, gh.TreeEntry{
+			Path:    gh.String(f.Path),
+			Mode:    gh.String(mode),
+			Type:    gh.String("blob"),
+			Content: gh.String(f.Content),
+		})
+	}
+
+	body := struct {
+		BaseTree string        `json:"base_tree"`
+		Entries  []interface{} `json:"tree"`
+	}{
+		BaseTree: baseTree,
+		Entries:  entries,
+	}
+	u := fmt.Sprintf("repos/%s/%s/git/trees", c.Repo.Owner, c.Repo.Name)
+	req, err := client.NewRequest("POST", u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := new(gh.Tree)
+	if _, err = client.Do(ctx, req, tree); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+func (gc *MyClient) CreatePullRequest(ctx context.Context, c *Context, pull *gh.NewPullRequest) (*gh.PullRequest, error) {
+	pr, _, err := c.GetClient(ctx).PullRequests.Create(ctx, c.Repo.Owner, c.Repo.Name, pull)
+	if err != nil {
+		if terr := transformGithubError(err); terr != nil {
+			return nil, terr
+		}
+
+		return nil, fmt.Errorf("can't create pull request from %s to %s: %s", pull.GetHead(), pull.GetBase(), err)
+	}
+
+	return pr, nil
+}
+
+// GetOpenPullRequest returns the open pull request from the branch head of the repo to the branch base
+// or nil if there is no such pull request
+func (gc *MyClient) GetOpenPullRequest(ctx context.Context, c *Context, head, base string) (*gh.PullRequest, error) {
+	var ret []*gh.PullRequest
+	f := func() error {
+		var err error
+		ret, _, err = c.GetClient(ctx).PullRequests.List(ctx, c.Repo.Owner, c.Repo.Name, &gh.PullRequestListOptions{
+			State: "open",
+			Head:  fmt.Sprintf("%s:%s", c.Repo.Owner, head),
+			Base:  base,
+		})
+		return err
+	}
+
+	if err := retryGet(f); err != nil {
+		if terr := transformGithubError(err); terr != nil {
+			return nil, terr
+		}
+
+		return nil, fmt.Errorf("can't list pull requests from %s to %s: %s", head, base, err)
+	}
+
+	if len(ret) == 0 {
+		return nil, nil
+	}
+
+	return ret[0], nil
+}
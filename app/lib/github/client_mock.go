@@ -73,7 +73,7 @@ func (_mr *MockClientMockRecorder) GetPullRequestPatch(arg0, arg1 interface{}) *
 }
 
 // CreateReview mocks base method
-func (_m *MockClient) CreateReview(ctx context.Context, c *Context, review *github.PullRequestReviewRequest) error {
+func (_m *MockClient) CreateReview(ctx context.Context, c *Context, review *Review) error {
 	ret := _m.ctrl.Call(_m, "CreateReview", ctx, c, review)
 	ret0, _ := ret[0].(error)
 	return ret0
@@ -84,6 +84,31 @@ func (_mr *MockClientMockRecorder) CreateReview(arg0, arg1, arg2 interface{}) *g
 	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "CreateReview", reflect.TypeOf((*MockClient)(nil).CreateReview), arg0, arg1, arg2)
 }
 
+// GetPullRequestReviews mocks base method
+func (_m *MockClient) GetPullRequestReviews(ctx context.Context, c *Context) ([]*github.PullRequestReview, error) {
+	ret := _m.ctrl.Call(_m, "GetPullRequestReviews", ctx, c)
+	ret0, _ := ret[0].([]*github.PullRequestReview)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPullRequestReviews indicates an expected call of GetPullRequestReviews
+func (_mr *MockClientMockRecorder) GetPullRequestReviews(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "GetPullRequestReviews", reflect.TypeOf((*MockClient)(nil).GetPullRequestReviews), arg0, arg1)
+}
+
+// DismissReview mocks base method
+func (_m *MockClient) DismissReview(ctx context.Context, c *Context, reviewID int64, message string) error {
+	ret := _m.ctrl.Call(_m, "DismissReview", ctx, c, reviewID, message)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DismissReview indicates an expected call of DismissReview
+func (_mr *MockClientMockRecorder) DismissReview(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "DismissReview", reflect.TypeOf((*MockClient)(nil).DismissReview), arg0, arg1, arg2, arg3)
+}
+
 // SetCommitStatus mocks base method
 func (_m *MockClient) SetCommitStatus(ctx context.Context, c *Context, ref string, status Status, desc string, url string) error {
 	ret := _m.ctrl.Call(_m, "SetCommitStatus", ctx, c, ref, status, desc, url)
@@ -95,3 +120,106 @@ func (_m *MockClient) SetCommitStatus(ctx context.Context, c *Context, ref strin
 func (_mr *MockClientMockRecorder) SetCommitStatus(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "SetCommitStatus", reflect.TypeOf((*MockClient)(nil).SetCommitStatus), arg0, arg1, arg2, arg3, arg4, arg5)
 }
+
+// ResetBranch mocks base method
+func (_m *MockClient) ResetBranch(ctx context.Context, c *Context, branch string, sha string) error {
+	ret := _m.ctrl.Call(_m, "ResetBranch", ctx, c, branch, sha)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetBranch indicates an expected call of ResetBranch
+func (_mr *MockClientMockRecorder) ResetBranch(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "ResetBranch", reflect.TypeOf((*MockClient)(nil).ResetBranch), arg0, arg1, arg2, arg3)
+}
+
+// PushCommit mocks base method
+func (_m *MockClient) PushCommit(ctx context.Context, c *Context, branch string, commit *Commit) (string, error) {
+	ret := _m.ctrl.Call(_m, "PushCommit", ctx, c, branch, commit)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PushCommit indicates an expected call of PushCommit
+func (_mr *MockClientMockRecorder) PushCommit(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "PushCommit", reflect.TypeOf((*MockClient)(nil).PushCommit), arg0, arg1, arg2, arg3)
+}
+
+// CreatePullRequest mocks base method
+func (_m *MockClient) CreatePullRequest(ctx context.Context, c *Context, pull *github.NewPullRequest) (*github.PullRequest, error) {
+	ret := _m.ctrl.Call(_m, "CreatePullRequest", ctx, c, pull)
+	ret0, _ := ret[0].(*github.PullRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePullRequest indicates an expected call of CreatePullRequest
+func (_mr *MockClientMockRecorder) CreatePullRequest(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "CreatePullRequest", reflect.TypeOf((*MockClient)(nil).CreatePullRequest), arg0, arg1, arg2)
+}
+
+// GetOpenPullRequest mocks base method
+func (_m *MockClient) GetOpenPullRequest(ctx context.Context, c *Context, head string, base string) (*github.PullRequest, error) {
+	ret := _m.ctrl.Call(_m, "GetOpenPullRequest", ctx, c, head, base)
+	ret0, _ := ret[0].(*github.PullRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOpenPullRequest indicates an expected call of GetOpenPullRequest
+func (_mr *MockClientMockRecorder) GetOpenPullRequest(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "GetOpenPullRequest", reflect.TypeOf((*MockClient)(nil).GetOpenPullRequest), arg0, arg1, arg2, arg3)
+}
+
+// GetTreeFiles mocks base method
+func (_m *MockClient) GetTreeFiles(ctx context.Context, c *Context, sha string) ([]TreeFile, error) {
+	ret := _m.ctrl.Call(_m, "GetTreeFiles", ctx, c, sha)
+	ret0, _ := ret[0].([]TreeFile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTreeFiles indicates an expected call of GetTreeFiles
+func (_mr *MockClientMockRecorder) GetTreeFiles(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "GetTreeFiles", reflect.TypeOf((*MockClient)(nil).GetTreeFiles), arg0, arg1, arg2)
+}
+
+// GetRootFiles mocks base method
+func (_m *MockClient) GetRootFiles(ctx context.Context, c *Context, sha string) ([]TreeFile, error) {
+	ret := _m.ctrl.Call(_m, "GetRootFiles", ctx, c, sha)
+	ret0, _ := ret[0].([]TreeFile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRootFiles indicates an expected call of GetRootFiles
+func (_mr *MockClientMockRecorder) GetRootFiles(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "GetRootFiles", reflect.TypeOf((*MockClient)(nil).GetRootFiles), arg0, arg1, arg2)
+}
+
+// GetBlob mocks base method
+func (_m *MockClient) GetBlob(ctx context.Context, c *Context, sha string) (string, error) {
+	ret := _m.ctrl.Call(_m, "GetBlob", ctx, c, sha)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlob indicates an expected call of GetBlob
+func (_mr *MockClientMockRecorder) GetBlob(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "GetBlob", reflect.TypeOf((*MockClient)(nil).GetBlob), arg0, arg1, arg2)
+}
+
+// GetBranchSHA mocks base method
+func (_m *MockClient) GetBranchSHA(ctx context.Context, c *Context, branch string) (string, error) {
+	ret := _m.ctrl.Call(_m, "GetBranchSHA", ctx, c, branch)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBranchSHA indicates an expected call of GetBranchSHA
+func (_mr *MockClientMockRecorder) GetBranchSHA(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "GetBranchSHA", reflect.TypeOf((*MockClient)(nil).GetBranchSHA), arg0, arg1, arg2)
+}
@@ -0,0 +1,25 @@
+package github
+
+// FileChange is a new content of a file in a commit
+type FileChange struct {
+	Path    string
+	Content string
+	Mode    string // git mode of the file, regular file mode if it's empty
+	Deleted bool   // the file is removed: content and mode are ignored
+}
+
+type Commit struct {
+	ParentSHA string
+	Message   string
+
+	AuthorName  string
+	AuthorEmail string
+
+	Files []FileChange
+}
+
+// git modes of files
+const (
+	RegularFileMode    = "100644"
+	ExecutableFileMode = "100755"
+)
@@ -0,0 +1,41 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	gh "github.com/google/go-github/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTreeKeepsModesAndRemovesFiles(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/owner/name/git/trees", r.URL.Path)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_, _ = w.Write([]byte(`{"sha":"treeSHA"}`))
+	}))
+	defer server.Close()
+
+	client := gh.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	tree, err := createTree(context.Background(), client, &FakeContext, "baseSHA", []FileChange{
+		{Path: "main.go", Content: "package main\n"},
+		{Path: "run.sh", Content: "#!/bin/sh\n", Mode: ExecutableFileMode},
+		{Path: "unused.go", Deleted: true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "treeSHA", tree.GetSHA())
+
+	assert.Equal(t, "baseSHA", body["base_tree"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"path": "main.go", "mode": RegularFileMode, "type": "blob", "content": "package main\n"},
+		map[string]interface{}{"path": "run.sh", "mode": ExecutableFileMode, "type": "blob", "content": "#!/bin/sh\n"},
+		map[string]interface{}{"path": "unused.go", "mode": RegularFileMode, "type": "blob", "sha": nil},
+	}, body["tree"])
+}
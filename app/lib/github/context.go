@@ -23,7 +23,12 @@ type Context struct {
 	PullRequestNumber int
 }
 
+// GetClient returns the client authorized by the access token: without the token it can access only public repos
 func (c Context) GetClient(ctx context.Context) *github.Client {
+	if c.GithubAccessToken == "" {
+		return github.NewClient(nil)
+	}
+
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: c.GithubAccessToken},
 	)
@@ -0,0 +1,185 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HunkLines is the range of lines of the new version of a file in a diff hunk
+type HunkLines struct {
+	From, To int
+}
+
+// FilePatch is the diff of a file
+type FilePatch struct {
+	// Positions maps number of an added line to its position in the diff of the file:
+	// the position is the number of lines down from the first hunk header of the file, like in github reviews
+	Positions map[int]int
+
+	Hunks []HunkLines
+}
+
+// Patch maps names of changed files to their diffs
+type Patch map[string]*FilePatch
+
+// Position returns the position of the added line in the diff or zero if the line isn't added
+func (p Patch) Position(file string, line int) int {
+	fp := p[file]
+	if fp == nil {
+		return 0
+	}
+
+	return fp.Positions[line]
+}
+
+// InOneHunk returns true if lines of the range are in one hunk: github attaches multi-line
+// comments only to such ranges
+func (p Patch) InOneHunk(file string, from, to int) bool {
+	fp := p[file]
+	if fp == nil {
+		return false
+	}
+
+	for _, h := range fp.Hunks {
+		if from >= h.From && to <= h.To {
+			return true
+		}
+	}
+
+	return false
+}
+
+type patchParser struct {
+	ret Patch
+
+	file           string
+	pos            int
+	newLine        int
+	inHunk         bool
+	oldLeft        int
+	newLeft        int
+	wasFileHunkPos bool
+}
+
+// ParsePatch parses the unified diff of a pull request
+func ParsePatch(patch string) Patch {
+	p := patchParser{
+		ret: Patch{},
+	}
+	for _, line := range strings.Split(patch, "\n") {
+		p.parseLine(line)
+	}
+
+	return p.ret
+}
+
+func (p *patchParser) filePatch() *FilePatch {
+	fp := p.ret[p.file]
+	if fp == nil {
+		fp = &FilePatch{
+			Positions: map[int]int{},
+		}
+		p.ret[p.file] = fp
+	}
+
+	return fp
+}
+
+func (p *patchParser) parseLine(line string) {
+	if p.inHunk {
+		p.parseHunkLine(line)
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(line, "diff "):
+		p.file = ""
+	case strings.HasPrefix(line, "+++ "):
+		p.file = parsePatchFileName(line[len("+++ "):])
+		p.pos = 0
+		p.wasFileHunkPos = false
+	case strings.HasPrefix(line, "@@ ") && p.file != "":
+		var oldFrom, newFrom int
+		if !parseHunkHeader(line, &oldFrom, &p.oldLeft, &newFrom, &p.newLeft) {
+			return
+		}
+
+		if p.wasFileHunkPos {
+			p.pos++ // headers of next hunks are counted
+		}
+		p.wasFileHunkPos = true
+		p.newLine = newFrom
+		p.inHunk = p.oldLeft != 0 || p.newLeft != 0
+		if p.newLeft != 0 {
+			fp := p.filePatch()
+			fp.Hunks = append(fp.Hunks, HunkLines{From: newFrom, To: newFrom + p.newLeft - 1})
+		}
+	}
+}
+
+func (p *patchParser) parseHunkLine(line string) {
+	p.pos++
+	if line == "" {
+		line = " " // some tools trim trailing space of empty context lines
+	}
+
+	switch line[0] {
+	case '+':
+		p.filePatch().Positions[p.newLine] = p.pos
+		p.newLine++
+		p.newLeft--
+	case '-':
+		p.oldLeft--
+	case '\\': // no newline at end of file
+	default:
+		p.newLine++
+		p.newLeft--
+		p.oldLeft--
+	}
+
+	if p.oldLeft <= 0 && p.newLeft <= 0 {
+		p.inHunk = false
+	}
+}
+
+func parsePatchFileName(s string) string {
+	if i := strings.IndexByte(s, '\t'); i != -1 {
+		s = s[:i]
+	}
+
+	if s == "/dev/null" {
+		return ""
+	}
+
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		return s[2:]
+	}
+
+	return s
+}
+
+// parseHunkHeader parses header like "@@ -1,5 +1,11 @@ func F()": count of lines is 1 if it's omitted
+func parseHunkHeader(line string, oldFrom, oldCount, newFrom, newCount *int) bool {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return false
+	}
+
+	return parseHunkRange(fields[1], "-", oldFrom, oldCount) && parseHunkRange(fields[2], "+", newFrom, newCount)
+}
+
+func parseHunkRange(s, prefix string, from, count *int) bool {
+	if !strings.HasPrefix(s, prefix) {
+		return false
+	}
+	s = s[len(prefix):]
+
+	*count = 1
+	if strings.Contains(s, ",") {
+		_, err := fmt.Sscanf(s, "%d,%d", from, count)
+		return err == nil
+	}
+
+	_, err := fmt.Sscanf(s, "%d", from)
+	return err == nil
+}
@@ -0,0 +1,66 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPatch = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,5 +1,6 @@
+ package p
+ 
+-func F0() error {
++func F0New() error {
++	// comment
+ 	return nil
+ }
+@@ -10,3 +11,4 @@ func F1() {
+ 	a()
++	b()
+ 	c()
+ }
+diff --git a/new.go b/new.go
+new file mode 100644
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1,2 @@
++package p
++var x = 1
+\ No newline at end of file
+diff --git a/removed.go b/removed.go
+deleted file mode 100644
+--- a/removed.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package p
+`
+
+func TestParsePatch(t *testing.T) {
+	assert.Equal(t, Patch{
+		"main.go": {
+			Positions: map[int]int{3: 4, 4: 5, 12: 10},
+			Hunks:     []HunkLines{{From: 1, To: 6}, {From: 11, To: 14}},
+		},
+		"new.go": {
+			Positions: map[int]int{1: 1, 2: 2},
+			Hunks:     []HunkLines{{From: 1, To: 2}},
+		},
+	}, ParsePatch(testPatch))
+}
+
+func TestPatchInOneHunk(t *testing.T) {
+	p := ParsePatch(testPatch)
+	assert.True(t, p.InOneHunk("main.go", 3, 5))
+	assert.True(t, p.InOneHunk("main.go", 11, 14), "context lines can be commented")
+	assert.False(t, p.InOneHunk("main.go", 5, 12), "range of two hunks")
+	assert.False(t, p.InOneHunk("main.go", 7, 8), "lines between hunks")
+	assert.False(t, p.InOneHunk("other.go", 1, 2))
+
+	assert.Equal(t, 10, p.Position("main.go", 12))
+	assert.Equal(t, 0, p.Position("main.go", 11))
+	assert.Equal(t, 0, p.Position("other.go", 1))
+}
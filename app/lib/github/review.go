@@ -0,0 +1,40 @@
+package github
+
+// ReviewComment is a draft review comment. The vendored go-github doesn't support
+// multi-line comments, so we send reviews with our own types.
+type ReviewComment struct {
+	Path string `json:"path"`
+	Body string `json:"body"`
+
+	// Position is a position in the diff hunk: it's used for single-line comments
+	Position int `json:"position,omitempty"`
+
+	// StartLine and Line are lines in the file: they're used for multi-line comments
+	StartLine int    `json:"start_line,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	StartSide string `json:"start_side,omitempty"`
+	Side      string `json:"side,omitempty"`
+}
+
+type Review struct {
+	CommitID string           `json:"commit_id"`
+	Body     string           `json:"body"`
+	Event    string           `json:"event"`
+	Comments []*ReviewComment `json:"comments,omitempty"`
+}
+
+const (
+	ReviewEventComment        = "COMMENT"
+	ReviewEventRequestChanges = "REQUEST_CHANGES"
+	ReviewEventApprove        = "APPROVE"
+)
+
+// states of submitted reviews
+const (
+	ReviewStateChangesRequested = "CHANGES_REQUESTED"
+	ReviewStateApproved         = "APPROVED"
+	ReviewStateDismissed        = "DISMISSED"
+)
+
+// SideRight is a side of a diff with the new version of a file
+const SideRight = "RIGHT"
@@ -0,0 +1,127 @@
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	gh "github.com/google/go-github/github"
+)
+
+// ErrTreeTruncated is returned if github returned only part of files of a big tree
+var ErrTreeTruncated = errors.New("tree is too big: github truncated it")
+
+// TreeFile is a file of the tree of a commit
+type TreeFile struct {
+	Path string
+	SHA  string // sha of the blob: it changes only when the content of the file changes
+}
+
+type treeResponse struct {
+	Entries []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+		SHA  string `json:"sha"`
+	} `json:"tree"`
+	Truncated bool `json:"truncated"`
+}
+
+// GetTreeFiles returns all files of the commit without fetching the repo
+func (gc *MyClient) GetTreeFiles(ctx context.Context, c *Context, sha string) ([]TreeFile, error) {
+	return gc.getTreeFiles(ctx, c, sha, true)
+}
+
+// GetRootFiles returns files of the root dir of the commit without fetching the repo
+func (gc *MyClient) GetRootFiles(ctx context.Context, c *Context, sha string) ([]TreeFile, error) {
+	return gc.getTreeFiles(ctx, c, sha, false)
+}
+
+func (gc *MyClient) getTreeFiles(ctx context.Context, c *Context, sha string, recursive bool) ([]TreeFile, error) {
+	var ret []TreeFile
+	truncated := false
+
+	f := func() error {
+		var err error
+		ret, truncated, err = getTreeFiles(ctx, c.GetClient(ctx), c, sha, recursive)
+		return err
+	}
+
+	if err := retryGet(f); err != nil {
+		if terr := transformGithubError(err); terr != nil {
+			return nil, terr
+		}
+
+		return nil, fmt.Errorf("can't get tree of commit %s: %s", sha, err)
+	}
+
+	if truncated {
+		return nil, ErrTreeTruncated
+	}
+
+	return ret, nil
+}
+
+func getTreeFiles(ctx context.Context, client *gh.Client, c *Context, sha string,
+	recursive bool) ([]TreeFile, bool, error) {
+
+	u := fmt.Sprintf("repos/%s/%s/git/trees/%s", c.Repo.Owner, c.Repo.Name, sha)
+	if recursive {
+		u += "?recursive=1"
+	}
+	req, err := client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var tree treeResponse
+	if _, err = client.Do(ctx, req, &tree); err != nil {
+		return nil, false, err
+	}
+
+	var ret []TreeFile
+	for _, e := range tree.Entries {
+		if e.Type == "blob" {
+			ret = append(ret, TreeFile{Path: e.Path, SHA: e.SHA})
+		}
+	}
+
+	return ret, tree.Truncated, nil
+}
+
+// GetBlob returns the content of the file by the sha of its blob
+func (gc *MyClient) GetBlob(ctx context.Context, c *Context, sha string) (string, error) {
+	var ret string
+
+	f := func() error {
+		blob, _, err := c.GetClient(ctx).Git.GetBlob(ctx, c.Repo.Owner, c.Repo.Name, sha)
+		if err != nil {
+			return err
+		}
+
+		if blob.GetEncoding() != "base64" {
+			ret = blob.GetContent()
+			return nil
+		}
+
+		// github splits base64 content by lines
+		content, err := base64.StdEncoding.DecodeString(strings.Replace(blob.GetContent(), "\n", "", -1))
+		if err != nil {
+			return err
+		}
+
+		ret = string(content)
+		return nil
+	}
+
+	if err := retryGet(f); err != nil {
+		if terr := transformGithubError(err); terr != nil {
+			return "", terr
+		}
+
+		return "", fmt.Errorf("can't get blob %s: %s", sha, err)
+	}
+
+	return ret, nil
+}
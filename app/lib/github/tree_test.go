@@ -0,0 +1,36 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	gh "github.com/google/go-github/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTreeFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/owner/name/git/trees/commitSHA", r.URL.Path)
+		assert.Equal(t, "1", r.URL.Query().Get("recursive"))
+		_, _ = w.Write([]byte(`{"sha":"commitSHA","truncated":true,"tree":[
+			{"path":".golangci.yml","type":"blob","sha":"configSHA"},
+			{"path":"sub","type":"tree","sha":"subSHA"},
+			{"path":"sub/main.go","type":"blob","sha":"mainSHA"}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := gh.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	files, truncated, err := getTreeFiles(context.Background(), client, &FakeContext, "commitSHA", true)
+	assert.NoError(t, err)
+	assert.True(t, truncated)
+	assert.Equal(t, []TreeFile{
+		{Path: ".golangci.yml", SHA: "configSHA"},
+		{Path: "sub/main.go", SHA: "mainSHA"},
+	}, files)
+}
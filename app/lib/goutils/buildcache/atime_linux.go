@@ -0,0 +1,22 @@
+package buildcache
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// lastUsed returns the access time of the file if it's after the modification time
+func lastUsed(fi os.FileInfo) time.Time {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fi.ModTime()
+	}
+
+	atime := time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec))
+	if atime.After(fi.ModTime()) {
+		return atime
+	}
+
+	return fi.ModTime()
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package buildcache
+
+import (
+	"os"
+	"time"
+)
+
+// lastUsed returns the modification time: access time isn't read on this platform
+func lastUsed(fi os.FileInfo) time.Time {
+	return fi.ModTime()
+}
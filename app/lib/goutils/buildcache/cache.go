@@ -0,0 +1,187 @@
+// Package buildcache shares the go module cache (one per repo owner), the go build cache and the golangci-lint cache
+// between analyses. All of them are content-addressed and safe for concurrent use by go and golangci-lint:
+// analyses only add entries and read them, entries are removed only by Trim between analyses.
+package buildcache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+)
+
+const defaultMaxSizeMB = 10 * 1024
+
+// Cache is the directory with shared caches on the host of executors
+type Cache struct {
+	dir     string
+	maxSize int64
+}
+
+func New(dir string, maxSize int64) *Cache {
+	return &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+	}
+}
+
+var defaultCache *Cache
+var defaultCacheErr error
+var initOnce sync.Once
+
+func initDefault() {
+	maxSizeMB := defaultMaxSizeMB
+	if v := os.Getenv("BUILD_CACHE_MAX_SIZE_MB"); v != "" {
+		var err error
+		maxSizeMB, err = strconv.Atoi(v)
+		if err != nil || maxSizeMB <= 0 {
+			defaultCacheErr = fmt.Errorf("invalid BUILD_CACHE_MAX_SIZE_MB value %q: must be a positive integer", v)
+			return
+		}
+	}
+
+	defaultCache = New(os.Getenv("BUILD_CACHE_DIR"), int64(maxSizeMB)*1024*1024)
+}
+
+// Get returns the cache in the dir from BUILD_CACHE_DIR env var
+func Get() (*Cache, error) {
+	initOnce.Do(initDefault)
+	return defaultCache, defaultCacheErr
+}
+
+// IsConfigured returns true if the cache dir is set
+func IsConfigured() bool {
+	return os.Getenv("BUILD_CACHE_DIR") != ""
+}
+
+func (c Cache) Dir() string {
+	return c.dir
+}
+
+var ownerRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+func (c Cache) ownersDir() string {
+	return filepath.Join(c.dir, "owners")
+}
+
+// modDir returns the module cache of repos of the owner: the go proxy fetches private modules
+// with credentials of the owner, analyses of other owners must not read them from the cache
+func (c Cache) modDir(owner string) string {
+	return filepath.Join(c.ownersDir(), owner, "mod")
+}
+
+// legacyModDir is the module cache shared by all owners in previous versions
+func (c Cache) legacyModDir() string {
+	return filepath.Join(c.dir, "mod")
+}
+
+func (c Cache) buildDir() string {
+	return filepath.Join(c.dir, "build")
+}
+
+func (c Cache) lintDir() string {
+	return filepath.Join(c.dir, "golangci-lint")
+}
+
+// Env returns env vars making go and golangci-lint use the shared caches for repos of the owner
+func (c Cache) Env(owner string) map[string]string {
+	return map[string]string{
+		"BUILD_CACHE_DIR":     c.dir, // cleanup.sh doesn't clean the shared caches
+		"GOMODCACHE":          c.modDir(owner),
+		"GOCACHE":             c.buildDir(),
+		"GOLANGCI_LINT_CACHE": c.lintDir(),
+	}
+}
+
+// WithEnv returns the executor with env of the shared caches for repos of the owner
+func (c Cache) WithEnv(exec executors.Executor, owner string) executors.Executor {
+	for k, v := range c.Env(owner) {
+		exec = exec.WithEnv(k, v)
+	}
+
+	return exec
+}
+
+// Setup makes the executor use the shared caches for a repo of the owner. If gopath is set the module cache
+// in it is linked to the shared one: go versions without GOMODCACHE support keep modules in $GOPATH/pkg/mod.
+// It returns ErrBeingTrimmed if the cache is being trimmed. The analysis must clean the returned executor
+// when it's done: the trim waits for analyses using the cache.
+func (c Cache) Setup(ctx context.Context, exec executors.Executor, owner, gopath string) (executors.Executor, error) {
+	owner = strings.ToLower(owner)
+	if !ownerRe.MatchString(owner) {
+		return nil, fmt.Errorf("invalid repo owner %q", owner)
+	}
+
+	out, err := exec.Run(ctx, "mkdir", "-p", c.modDir(owner), c.buildDir(), c.lintDir(), c.usersDir())
+	if err != nil {
+		return nil, fmt.Errorf("can't make cache dirs: %s, %s", err, out)
+	}
+
+	release, err := c.markUsed(ctx, exec)
+	if err != nil {
+		return nil, err
+	}
+
+	if gopath != "" {
+		if err = c.linkModDir(ctx, exec, owner, gopath); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return usingExecutor{Executor: c.WithEnv(exec, owner), release: release}, nil
+}
+
+func (c Cache) linkModDir(ctx context.Context, exec executors.Executor, owner, gopath string) error {
+	pkgDir := filepath.Join(gopath, "pkg")
+	if out, err := exec.Run(ctx, "mkdir", "-p", pkgDir); err != nil {
+		return fmt.Errorf("can't make dir %s: %s, %s", pkgDir, err, out)
+	}
+
+	if out, err := exec.Run(ctx, "ln", "-sfn", c.modDir(owner), filepath.Join(pkgDir, "mod")); err != nil {
+		return fmt.Errorf("can't link module cache: %s, %s", err, out)
+	}
+
+	return nil
+}
+
+// RunTrimmer trims the cache every interval if the cache dir is on this host
+func (c Cache) RunTrimmer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.trimIfLocal(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c Cache) trimIfLocal(ctx context.Context) {
+	if _, err := os.Stat(c.dir); err != nil {
+		return // the cache dir is on hosts of remote executors
+	}
+
+	res, err := c.Trim(ctx)
+	if err != nil {
+		analytics.Log(ctx).Errorf("Can't trim build cache: %s", err)
+		return
+	}
+
+	if res.Removed != 0 {
+		analytics.Log(ctx).Infof("Trimmed build cache: removed %d entries of %d bytes, size is %d bytes",
+			res.Removed, res.RemovedSize, res.Size)
+	}
+}
@@ -0,0 +1,303 @@
+package buildcache
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+)
+
+// staleLockAge is the age of the lock of a trim which crashed: a trim waits for analyses at most maxUseDuration
+const staleLockAge = time.Hour
+
+// entry is a unit of eviction: files of a module version or a file of the build cache
+type entry struct {
+	key      string
+	paths    []string
+	size     int64
+	lastUsed time.Time
+}
+
+type TrimResult struct {
+	Size        int64 // total size after the trim
+	Removed     int
+	RemovedSize int64
+}
+
+// Trim removes the least recently used entries until the total size of the caches is within the max size.
+// The last use is the access time: with the default relatime mount option it's updated at least once a day.
+// Entries are removed only between analyses: the trim waits for analyses using the cache, analyses
+// starting during the trim use their own caches. The cache isn't locked if it's within the max size.
+// It must run on the host of the cache dir. Concurrent trims are skipped.
+func (c Cache) Trim(ctx context.Context) (*TrimResult, error) {
+	// analyses don't use the module cache shared by all owners since it's split by owners
+	if err := removeEntry(entry{paths: []string{c.legacyModDir()}}); err != nil {
+		return nil, err
+	}
+
+	entries, err := c.listEntries()
+	if err != nil {
+		return nil, err
+	}
+	if size := totalSize(entries); size <= c.maxSize {
+		return &TrimResult{Size: size}, nil
+	}
+
+	unlock, err := c.lock()
+	if err != nil {
+		return nil, err
+	}
+	if unlock == nil {
+		analytics.Log(ctx).Infof("Build cache %s is already being trimmed", c.dir)
+		return &TrimResult{}, nil
+	}
+	defer unlock()
+
+	if err = c.waitForUsers(ctx); err != nil {
+		return nil, err
+	}
+
+	// analyses added entries while the trim waited for them
+	if entries, err = c.listEntries(); err != nil {
+		return nil, err
+	}
+
+	return c.trimEntries(entries)
+}
+
+func totalSize(entries []entry) int64 {
+	var size int64
+	for _, e := range entries {
+		size += e.size
+	}
+
+	return size
+}
+
+func (c Cache) trimEntries(entries []entry) (*TrimResult, error) {
+	ret := TrimResult{
+		Size: totalSize(entries),
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].lastUsed.Equal(entries[j].lastUsed) {
+			return entries[i].lastUsed.Before(entries[j].lastUsed)
+		}
+		return entries[i].key < entries[j].key // entries are listed from maps
+	})
+
+	for _, e := range entries {
+		if ret.Size <= c.maxSize {
+			break
+		}
+
+		if err := removeEntry(e); err != nil {
+			return nil, err
+		}
+
+		ret.Size -= e.size
+		ret.Removed++
+		ret.RemovedSize += e.size
+	}
+
+	return &ret, nil
+}
+
+// lock returns nil unlock func if the cache is locked by another trim
+func (c Cache) lock() (func(), error) {
+	path := c.lockPath()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if os.IsExist(err) {
+		fi, statErr := os.Stat(path)
+		if statErr != nil || time.Since(fi.ModTime()) < staleLockAge {
+			return nil, nil
+		}
+
+		if err = os.Remove(path); err != nil {
+			return nil, fmt.Errorf("can't remove stale lock: %s", err)
+		}
+		return c.lock()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't lock build cache: %s", err)
+	}
+	f.Close()
+
+	return func() {
+		os.Remove(path)
+	}, nil
+}
+
+func (c Cache) listEntries() ([]entry, error) {
+	entries := map[string]*entry{}
+	owners, err := ioutil.ReadDir(c.ownersDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("can't list owners of module caches: %s", err)
+	}
+	for _, owner := range owners {
+		if err = listModEntries(c.modDir(owner.Name()), owner.Name()+"/", entries); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dir := range []string{c.buildDir(), c.lintDir()} {
+		if err := listFileEntries(dir, entries); err != nil {
+			return nil, err
+		}
+	}
+
+	var ret []entry
+	for _, e := range entries {
+		ret = append(ret, *e)
+	}
+
+	return ret, nil
+}
+
+func addToEntry(entries map[string]*entry, key, path string, size int64, lastUsed time.Time) {
+	e := entries[key]
+	if e == nil {
+		e = &entry{key: key}
+		entries[key] = e
+	}
+
+	if len(e.paths) == 0 || e.paths[len(e.paths)-1] != path {
+		e.paths = append(e.paths, path)
+	}
+	e.size += size
+	if lastUsed.After(e.lastUsed) {
+		e.lastUsed = lastUsed
+	}
+}
+
+// listFileEntries lists every file of the go build cache or the golangci-lint cache as an entry
+func listFileEntries(dir string, entries map[string]*entry) error {
+	return walk(dir, func(path string, fi os.FileInfo) error {
+		if !fi.IsDir() {
+			addToEntry(entries, path, path, fi.Size(), lastUsed(fi))
+		}
+		return nil
+	})
+}
+
+// listModEntries lists module versions of the module cache: a module version is the extracted
+// dir <module>@<version> and files <module>/@v/<version>.* in cache/download. Keys of entries
+// start with the prefix: module caches of owners have the same module versions.
+func listModEntries(dir, keyPrefix string, entries map[string]*entry) error {
+	downloadDir := filepath.Join(dir, "cache", "download")
+	vcsDir := filepath.Join(dir, "cache", "vcs")
+	return walk(dir, func(path string, fi os.FileInfo) error {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case path == vcsDir:
+			return nil
+		case filepath.Dir(path) == vcsDir: // repos cloned without proxy
+			return addDirToEntry(entries, path, path)
+		case strings.HasPrefix(path, downloadDir+string(filepath.Separator)):
+			addDownloadedToEntry(entries, keyPrefix, downloadDir, path, fi)
+			return nil
+		case fi.IsDir() && strings.Contains(fi.Name(), "@"):
+			return addDirToEntry(entries, keyPrefix+rel, path)
+		}
+
+		return nil
+	})
+}
+
+func addDownloadedToEntry(entries map[string]*entry, keyPrefix, downloadDir, path string, fi os.FileInfo) {
+	if fi.IsDir() || filepath.Base(filepath.Dir(path)) != "@v" {
+		return
+	}
+
+	version, ok := trimDownloadExt(fi.Name())
+	if !ok {
+		return // list files and temp files of running downloads
+	}
+
+	modPath, err := filepath.Rel(downloadDir, filepath.Dir(filepath.Dir(path)))
+	if err != nil {
+		return
+	}
+
+	addToEntry(entries, keyPrefix+modPath+"@"+version, path, fi.Size(), lastUsed(fi))
+}
+
+var downloadExts = []string{".info", ".mod", ".zip", ".ziphash", ".lock", ".partial"}
+
+// trimDownloadExt returns the version from the name of a downloaded file: v1.2.3.zip -> v1.2.3
+func trimDownloadExt(name string) (string, bool) {
+	for _, ext := range downloadExts {
+		if strings.HasSuffix(name, ext) {
+			version := strings.TrimSuffix(name, ext)
+			if ext == ".partial" || ext == ".lock" {
+				version = strings.TrimSuffix(version, ".zip")
+			}
+			return version, version != ""
+		}
+	}
+
+	return "", false
+}
+
+// addDirToEntry adds all files of the dir to the entry and skips the dir in the walk
+func addDirToEntry(entries map[string]*entry, key, dir string) error {
+	err := walk(dir, func(path string, fi os.FileInfo) error {
+		addToEntry(entries, key, dir, fi.Size(), lastUsed(fi))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return filepath.SkipDir
+}
+
+func walk(dir string, f func(path string, fi os.FileInfo) error) error {
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil // removed by go during the walk
+			}
+			return err
+		}
+
+		if path == dir {
+			return nil
+		}
+
+		return f(path, fi)
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("can't walk %s: %s", dir, err)
+	}
+
+	return nil
+}
+
+func removeEntry(e entry) error {
+	for _, path := range e.paths {
+		// go makes files of the module cache read-only
+		_ = filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+			if err == nil && fi.IsDir() {
+				_ = os.Chmod(p, 0755)
+			}
+			return nil
+		})
+
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("can't remove %s: %s", path, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package buildcache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCacheFile(t *testing.T, path string, size int, lastUsed time.Time) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, ioutil.WriteFile(path, make([]byte, size), 0444))
+	assert.NoError(t, os.Chtimes(path, lastUsed, lastUsed))
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestTrim(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buildcache")
+	assert.NoError(t, err)
+	defer func() {
+		_ = removeEntry(entry{paths: []string{dir}})
+	}()
+
+	now := time.Now()
+	recent := now.Add(-time.Minute)
+	old := now.Add(-time.Hour)
+	older := now.Add(-2 * time.Hour)
+
+	c := New(dir, 150)
+	oldModDir := filepath.Join(c.modDir("golangci"), "github.com", "!old", "mod@v1.0.0")
+	oldModZip := filepath.Join(c.modDir("golangci"), "cache", "download", "github.com", "!old", "mod", "@v", "v1.0.0.zip")
+	writeCacheFile(t, filepath.Join(oldModDir, "a.go"), 50, older)
+	writeCacheFile(t, oldModZip, 50, older)
+	assert.NoError(t, os.Chmod(oldModDir, 0555))
+
+	usedModDir := filepath.Join(c.modDir("golangci"), "github.com", "used", "mod@v1.0.0")
+	writeCacheFile(t, filepath.Join(usedModDir, "a.go"), 50, older)
+	writeCacheFile(t, filepath.Join(usedModDir, "b.go"), 50, recent) // the module is used recently
+
+	oldBuildFile := filepath.Join(c.buildDir(), "00", "hash-d")
+	writeCacheFile(t, oldBuildFile, 100, old)
+	newBuildFile := filepath.Join(c.buildDir(), "01", "hash-d")
+	writeCacheFile(t, newBuildFile, 50, now)
+
+	res, err := c.Trim(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &TrimResult{Size: 150, Removed: 2, RemovedSize: 200}, res)
+
+	assert.False(t, exists(oldModDir))
+	assert.False(t, exists(oldModZip))
+	assert.False(t, exists(oldBuildFile))
+	assert.True(t, exists(usedModDir))
+	assert.True(t, exists(newBuildFile))
+	assert.False(t, exists(filepath.Join(dir, ".trim.lock")))
+
+	// the size is enforced regardless of the age of entries
+	c.maxSize = 60
+	res, err = c.Trim(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &TrimResult{Size: 50, Removed: 1, RemovedSize: 100}, res)
+	assert.False(t, exists(usedModDir))
+	assert.True(t, exists(newBuildFile))
+}
+
+func TestTrimWaitsForUsers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buildcache")
+	assert.NoError(t, err)
+	defer func() {
+		_ = removeEntry(entry{paths: []string{dir}})
+	}()
+
+	c := New(dir, 0)
+	buildFile := filepath.Join(c.buildDir(), "00", "hash-d")
+	writeCacheFile(t, buildFile, 100, time.Now())
+
+	expiredMarker := filepath.Join(c.usersDir(), "expired")
+	writeCacheFile(t, expiredMarker, 0, time.Now().Add(-maxUseDuration))
+	activeMarker := filepath.Join(c.usersDir(), "active")
+	writeCacheFile(t, activeMarker, 0, time.Now())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Trim(ctx)
+	assert.Error(t, err)
+	assert.True(t, exists(buildFile))
+	assert.False(t, exists(expiredMarker))
+	assert.False(t, exists(c.lockPath()))
+
+	assert.NoError(t, os.Remove(activeMarker))
+	res, err := c.Trim(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &TrimResult{Removed: 1, RemovedSize: 100}, res)
+	assert.False(t, exists(buildFile))
+}
+
+func TestTrimEntriesUsedAtSameTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buildcache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	writeCacheFile(t, a, 50, now)
+	writeCacheFile(t, b, 50, now)
+
+	c := New(dir, 50)
+	res, err := c.trimEntries([]entry{
+		{key: "b", paths: []string{b}, size: 50, lastUsed: now},
+		{key: "a", paths: []string{a}, size: 50, lastUsed: now},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &TrimResult{Size: 50, Removed: 1, RemovedSize: 50}, res)
+	assert.False(t, exists(a))
+	assert.True(t, exists(b))
+}
+
+func TestTrimWithinMaxSizeDoesntWaitForUsers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buildcache")
+	assert.NoError(t, err)
+	defer func() {
+		_ = removeEntry(entry{paths: []string{dir}})
+	}()
+
+	c := New(dir, 100)
+	writeCacheFile(t, filepath.Join(c.buildDir(), "00", "hash-d"), 100, time.Now())
+	writeCacheFile(t, filepath.Join(c.usersDir(), "active"), 0, time.Now())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err := c.Trim(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, &TrimResult{Size: 100}, res)
+	assert.False(t, exists(c.lockPath()))
+}
+
+func TestTrimOwnerModCaches(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buildcache")
+	assert.NoError(t, err)
+	defer func() {
+		_ = removeEntry(entry{paths: []string{dir}})
+	}()
+
+	now := time.Now()
+	c := New(dir, 60)
+	oldModDir := filepath.Join(c.modDir("a"), "github.com", "pkg", "errors@v0.8.0")
+	writeCacheFile(t, filepath.Join(oldModDir, "errors.go"), 50, now.Add(-time.Hour))
+	usedModDir := filepath.Join(c.modDir("b"), "github.com", "pkg", "errors@v0.8.0")
+	writeCacheFile(t, filepath.Join(usedModDir, "errors.go"), 50, now)
+	legacyModFile := filepath.Join(c.legacyModDir(), "github.com", "private", "mod@v1.0.0", "a.go")
+	writeCacheFile(t, legacyModFile, 50, now)
+
+	res, err := c.Trim(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &TrimResult{Size: 50, Removed: 1, RemovedSize: 50}, res)
+	assert.False(t, exists(oldModDir))
+	assert.True(t, exists(usedModDir))
+	assert.False(t, exists(c.legacyModDir()))
+}
+
+func TestTrimDownloadExt(t *testing.T) {
+	cases := map[string]string{
+		"v1.2.3.zip":                             "v1.2.3",
+		"v1.2.3.ziphash":                         "v1.2.3",
+		"v1.2.3.mod":                             "v1.2.3",
+		"v1.2.3.info":                            "v1.2.3",
+		"v1.2.3.lock":                            "v1.2.3",
+		"v0.0.0-20181023235946-059132a15dd0.mod": "v0.0.0-20181023235946-059132a15dd0",
+		"list":                                   "",
+		"v1.2.3.info.123456.tmp":                 "",
+	}
+
+	for name, exp := range cases {
+		version, ok := trimDownloadExt(name)
+		assert.Equal(t, exp != "", ok, name)
+		assert.Equal(t, exp, version, name)
+	}
+}
@@ -0,0 +1,138 @@
+package buildcache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+)
+
+// ErrBeingTrimmed is returned by Setup if the cache is being trimmed: the analysis must use its own caches
+var ErrBeingTrimmed = errors.New("build cache is being trimmed")
+
+// maxUseDuration is the max time an analysis uses the cache after Setup: analyses time out in 10 minutes.
+// Analyses remove their use markers on Clean of the executor, a marker of a crashed analysis expires in this time.
+const maxUseDuration = 15 * time.Minute
+
+const usersPollInterval = 10 * time.Second
+
+func (c Cache) usersDir() string {
+	return filepath.Join(c.dir, ".users")
+}
+
+func (c Cache) lockPath() string {
+	return filepath.Join(c.dir, ".trim.lock")
+}
+
+// markUsed marks the cache as used by the analysis and returns the func removing the marker. The marker is created
+// before the check of the trim lock and Trim lists markers after taking the lock: either the analysis sees the lock
+// or the trim sees the marker.
+func (c Cache) markUsed(ctx context.Context, exec executors.Executor) (func(), error) {
+	id, err := newUseID()
+	if err != nil {
+		return nil, err
+	}
+
+	marker := filepath.Join(c.usersDir(), id)
+	if out, err := exec.Run(ctx, "touch", marker); err != nil {
+		return nil, fmt.Errorf("can't mark build cache as used: %s, %s", err, out)
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			// the context of the analysis can be already canceled
+			if out, err := exec.Run(context.Background(), "rm", "-f", marker); err != nil {
+				analytics.Log(ctx).Warnf("Can't remove build cache use marker: %s, %s", err, out)
+			}
+		})
+	}
+
+	if _, err := exec.Run(ctx, "test", "!", "-e", c.lockPath()); err != nil {
+		release() // don't make the trim wait for the analysis
+		return nil, ErrBeingTrimmed
+	}
+
+	return release, nil
+}
+
+// usingExecutor marks the cache as not used by the analysis on Clean: the trim doesn't wait for finished analyses
+type usingExecutor struct {
+	executors.Executor
+	release func()
+}
+
+func (e usingExecutor) WithEnv(k, v string) executors.Executor {
+	return usingExecutor{Executor: e.Executor.WithEnv(k, v), release: e.release}
+}
+
+func (e usingExecutor) WithWorkDir(wd string) executors.Executor {
+	return usingExecutor{Executor: e.Executor.WithWorkDir(wd), release: e.release}
+}
+
+func (e usingExecutor) Clean() {
+	e.release()
+	e.Executor.Clean()
+}
+
+func newUseID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("can't generate build cache use id: %s", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// waitForUsers waits until analyses which started to use the cache before the trim lock are done
+func (c Cache) waitForUsers(ctx context.Context) error {
+	for {
+		active, err := c.removeExpiredMarkers(time.Now())
+		if err != nil {
+			return err
+		}
+		if active == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("build cache is used by %d analyses: %s", active, ctx.Err())
+		case <-time.After(usersPollInterval):
+		}
+	}
+}
+
+// removeExpiredMarkers returns the count of active use markers
+func (c Cache) removeExpiredMarkers(now time.Time) (int, error) {
+	fis, err := ioutil.ReadDir(c.usersDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("can't list build cache users: %s", err)
+	}
+
+	active := 0
+	for _, fi := range fis {
+		if now.Sub(fi.ModTime()) < maxUseDuration {
+			active++
+			continue
+		}
+
+		if err = os.Remove(filepath.Join(c.usersDir(), fi.Name())); err != nil && !os.IsNotExist(err) {
+			return 0, fmt.Errorf("can't remove expired build cache use marker: %s", err)
+		}
+	}
+
+	return active, nil
+}
@@ -0,0 +1,77 @@
+package buildcache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	osexec "os/exec"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+	"github.com/stretchr/testify/assert"
+)
+
+func runLocally(_ context.Context, name string, args ...string) (string, error) {
+	out, err := osexec.Command(name, args...).CombinedOutput()
+	return string(out), err
+}
+
+func TestSetupMarksCacheUsed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buildcache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exec := executors.NewMockExecutor(ctrl)
+	any := gomock.Any()
+	exec.EXPECT().Run(any, "mkdir", "-p", any, any, any, any).DoAndReturn(runLocally).Times(3)
+	exec.EXPECT().Run(any, "touch", any).DoAndReturn(runLocally).Times(3)
+	exec.EXPECT().Run(any, "test", "!", "-e", any).DoAndReturn(runLocally).Times(3)
+	exec.EXPECT().Run(any, "rm", "-f", any).DoAndReturn(runLocally).Times(2)
+	exec.EXPECT().WithEnv(any, any).Return(exec).AnyTimes()
+	exec.EXPECT().WithWorkDir("/repo").Return(exec)
+	exec.EXPECT().Clean()
+
+	c := New(dir, 0)
+	usingExec, err := c.Setup(context.Background(), exec, "golangci", "")
+	assert.NoError(t, err)
+
+	active, err := c.removeExpiredMarkers(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, active)
+
+	// the analysis is done
+	usingExec.WithEnv("GOFLAGS", "-mod=mod").WithWorkDir("/repo").Clean()
+	active, err = c.removeExpiredMarkers(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, active)
+
+	_, err = c.Setup(context.Background(), exec, "golangci", "")
+	assert.NoError(t, err)
+
+	// analyses don't wait for a trim and don't make it wait for them
+	unlock, err := c.lock()
+	assert.NoError(t, err)
+	defer unlock()
+
+	_, err = c.Setup(context.Background(), exec, "golangci", "")
+	assert.Equal(t, ErrBeingTrimmed, err)
+
+	markers, err := ioutil.ReadDir(c.usersDir())
+	assert.NoError(t, err)
+	assert.Len(t, markers, 1)
+}
+
+func TestSetupInvalidOwner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := New("/cache", 0)
+	_, err := c.Setup(context.Background(), executors.NewMockExecutor(ctrl), "../golangci", "")
+	assert.EqualError(t, err, `invalid repo owner "../golangci"`)
+	assert.Equal(t, "/cache/owners/golangci/mod", c.Env("golangci")["GOMODCACHE"])
+}
@@ -0,0 +1,151 @@
+package goproxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+)
+
+const defaultUpstream = "https://proxy.golang.org,direct"
+
+var defaultServer *Server
+var defaultServerErr error
+var initOnce sync.Once
+
+func initDefault() {
+	cfg, err := configFromEnv()
+	if err != nil {
+		defaultServerErr = err
+		return
+	}
+
+	// listen before returning the server: sessions must not point executors to a closed port
+	l, err := net.Listen("tcp", listenAddr(os.Getenv("GOPROXY_LISTEN_ADDR")))
+	if err != nil {
+		defaultServerErr = fmt.Errorf("can't listen: %s", err)
+		return
+	}
+
+	if cfg.PublicURL == "" {
+		cfg.PublicURL = fmt.Sprintf("http://127.0.0.1:%d", l.Addr().(*net.TCPAddr).Port)
+	}
+
+	defaultServer, defaultServerErr = NewServer(*cfg)
+	if defaultServerErr != nil {
+		l.Close()
+		return
+	}
+
+	go func() {
+		if serveErr := http.Serve(l, defaultServer); serveErr != nil {
+			analytics.Log(context.Background()).Errorf("Go proxy server failed: %s", serveErr)
+		}
+	}()
+}
+
+// listenAddr binds to loopback if the host isn't set: executors on other hosts need
+// an explicit address of the worker
+func listenAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+func configFromEnv() (*Config, error) {
+	creds, err := parseCredentials(os.Getenv("GOPROXY_CREDENTIALS"))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		Dir:         os.Getenv("GOPROXY_DIR"),
+		PrivateDir:  os.Getenv("GOPROXY_PRIVATE_DIR"),
+		PublicURL:   strings.TrimSuffix(os.Getenv("GOPROXY_PUBLIC_URL"), "/"),
+		Upstream:    os.Getenv("GOPROXY_UPSTREAM"),
+		SumDB:       os.Getenv("GOPROXY_SUMDB"),
+		Private:     os.Getenv("GOPROXY_PRIVATE"),
+		Credentials: creds,
+		Allow:       os.Getenv("GOPROXY_ALLOW"),
+		Deny:        os.Getenv("GOPROXY_DENY"),
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = filepath.Join(os.TempDir(), "golangci-goproxy")
+	}
+	if cfg.PrivateDir == "" {
+		cfg.PrivateDir = filepath.Join(os.TempDir(), "golangci-goproxy-private")
+	}
+	if cfg.Upstream == "" {
+		cfg.Upstream = defaultUpstream
+	}
+	if cfg.Private == "" {
+		// hosts with credentials are private by default
+		var hosts []string
+		seen := map[string]bool{}
+		for _, c := range creds {
+			if !seen[c.Host] {
+				seen[c.Host] = true
+				hosts = append(hosts, c.Host)
+			}
+		}
+		cfg.Private = strings.Join(hosts, ",")
+	}
+
+	return cfg, nil
+}
+
+// parseCredentials parses credentials of repo owners in format owner1/host1=login1:password1,owner2/host2=login2:password2
+func parseCredentials(s string) ([]Credential, error) {
+	var ret []Credential
+	for _, ownerCred := range strings.Split(s, ",") {
+		ownerCred = strings.TrimSpace(ownerCred)
+		if ownerCred == "" {
+			continue
+		}
+
+		parts := strings.SplitN(ownerCred, "=", 2)
+		ownerHost := strings.SplitN(parts[0], "/", 2)
+		if len(parts) != 2 || len(ownerHost) != 2 || !ownerRe.MatchString(strings.ToLower(ownerHost[0])) ||
+			ownerHost[1] == "" {
+			return nil, fmt.Errorf("invalid GOPROXY_CREDENTIALS: no owner/host in %q", parts[0])
+		}
+
+		loginPassword := strings.SplitN(parts[1], ":", 2)
+		if len(loginPassword) != 2 || loginPassword[0] == "" || loginPassword[1] == "" {
+			return nil, fmt.Errorf("invalid GOPROXY_CREDENTIALS: no login:password for %s", parts[0])
+		}
+
+		if strings.ContainsAny(parts[1], " \t\n") {
+			return nil, fmt.Errorf("invalid GOPROXY_CREDENTIALS: whitespace in credentials of %s", parts[0])
+		}
+
+		ret = append(ret, Credential{
+			Owner:    strings.ToLower(ownerHost[0]),
+			Host:     ownerHost[1],
+			Login:    loginPassword[0],
+			Password: loginPassword[1],
+		})
+	}
+
+	return ret, nil
+}
+
+// Get returns the server listening on GOPROXY_LISTEN_ADDR: it's started on the first call
+func Get() (*Server, error) {
+	initOnce.Do(initDefault)
+	return defaultServer, defaultServerErr
+}
+
+// IsConfigured returns true if the proxy listen address is set
+func IsConfigured() bool {
+	return os.Getenv("GOPROXY_LISTEN_ADDR") != ""
+}
@@ -0,0 +1,75 @@
+package goproxy
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// escapePath escapes module path or version for urls and file names of the GOPROXY protocol:
+// every upper-case letter is replaced by "!" and the lower-case letter
+func escapePath(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteByte('!')
+			b.WriteRune(r + 'a' - 'A')
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func unescapePath(s string) (string, error) {
+	if !utf8.ValidString(s) {
+		return "", fmt.Errorf("invalid escaped path %q", s)
+	}
+
+	var b strings.Builder
+	bang := false
+	for _, r := range s {
+		switch {
+		case bang:
+			if r < 'a' || r > 'z' {
+				return "", fmt.Errorf("invalid escaped path %q: '!' must be followed by a lower-case letter", s)
+			}
+			b.WriteRune(r + 'A' - 'a')
+			bang = false
+		case r == '!':
+			bang = true
+		case r >= 'A' && r <= 'Z':
+			return "", fmt.Errorf("invalid escaped path %q: upper-case letters must be escaped", s)
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	if bang {
+		return "", fmt.Errorf("invalid escaped path %q: trailing '!'", s)
+	}
+
+	return b.String(), nil
+}
+
+// isValidModulePath checks the module path like module.CheckPath of golang.org/x/mod does for elements:
+// an element starting with '-' could be parsed as a flag of go commands of the fetcher
+func isValidModulePath(mod string) bool {
+	if mod == "" || strings.HasPrefix(mod, "/") || strings.HasSuffix(mod, "/") {
+		return false
+	}
+
+	for _, elem := range strings.Split(mod, "/") {
+		if elem == "" || elem == "." || elem == ".." || strings.HasPrefix(elem, "-") {
+			return false
+		}
+	}
+
+	return !strings.ContainsAny(mod, "@\\ ")
+}
+
+func isValidVersion(version string) bool {
+	return version != "" && version != "." && version != ".." && !strings.HasPrefix(version, "-") &&
+		!strings.ContainsAny(version, "/\\@ ")
+}
@@ -0,0 +1,163 @@
+package goproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// moduleInfo is the output of go list -m -json and go mod download -json
+type moduleInfo struct {
+	Path    string
+	Version string
+	Time    *time.Time `json:",omitempty"`
+	GoMod   string     `json:",omitempty"` // file of go.mod in the module cache
+	Zip     string     `json:",omitempty"`
+	Error   json.RawMessage
+}
+
+// fetcher fetches modules from upstream into the module cache
+type fetcher interface {
+	// Versions returns tagged versions of the module
+	Versions(ctx context.Context, mod string) ([]string, error)
+	// Query resolves the version query (a version, a branch, latest) and fetches go.mod of the version
+	Query(ctx context.Context, mod, query string) (*moduleInfo, error)
+	// Download fetches the zip of the module version
+	Download(ctx context.Context, mod, version string) (*moduleInfo, error)
+}
+
+// goFetcher fetches modules by go command: it supports all upstreams of GOPROXY, VCS
+// of private modules and checks sums of downloaded modules.
+// Go >= 1.15 is required for GOMODCACHE.
+type goFetcher struct {
+	env     []string // HOME has .netrc with credentials
+	workDir string   // empty dir outside of any module
+}
+
+// newGoFetcher makes a fetcher into the module cache dir: only the given credentials are in its .netrc
+func newGoFetcher(cfg *Config, dir string, creds []Credential) (*goFetcher, error) {
+	homeDir, err := ioutil.TempDir("", "goproxy-home")
+	if err != nil {
+		return nil, fmt.Errorf("can't make home dir: %s", err)
+	}
+
+	netrc := buildNetrc(creds)
+	if err = ioutil.WriteFile(filepath.Join(homeDir, ".netrc"), []byte(netrc), 0600); err != nil {
+		return nil, fmt.Errorf("can't write .netrc: %s", err)
+	}
+
+	workDir := filepath.Join(homeDir, "work")
+	if err = os.Mkdir(workDir, 0700); err != nil {
+		return nil, fmt.Errorf("can't make work dir: %s", err)
+	}
+
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + homeDir,
+		"GOPATH=" + filepath.Join(homeDir, "go"),
+		"GOCACHE=" + filepath.Join(homeDir, "gocache"),
+		"GOMODCACHE=" + dir,
+		"GOPROXY=" + cfg.Upstream,
+		"GOPRIVATE=" + cfg.Private,
+		"GO111MODULE=on",
+		"GOFLAGS=-mod=mod",
+		"GIT_TERMINAL_PROMPT=0", // fail instead of waiting for a password of a private repo
+	}
+	if cfg.SumDB != "" {
+		env = append(env, "GOSUMDB="+cfg.SumDB)
+	}
+
+	return &goFetcher{
+		workDir: workDir,
+		env:     env,
+	}, nil
+}
+
+func buildNetrc(creds []Credential) string {
+	var b strings.Builder
+	for _, c := range creds {
+		fmt.Fprintf(&b, "machine %s login %s password %s\n", c.Host, c.Login, c.Password)
+	}
+
+	return b.String()
+}
+
+func (f goFetcher) Versions(ctx context.Context, mod string) ([]string, error) {
+	var res struct {
+		Versions []string
+	}
+	if err := f.run(ctx, &res, "list", "-m", "-versions", "-json", "--", mod); err != nil {
+		return nil, err
+	}
+
+	return res.Versions, nil
+}
+
+func (f goFetcher) Query(ctx context.Context, mod, query string) (*moduleInfo, error) {
+	var res moduleInfo
+	if err := f.run(ctx, &res, "list", "-m", "-json", "--", mod+"@"+query); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (f goFetcher) Download(ctx context.Context, mod, version string) (*moduleInfo, error) {
+	var res moduleInfo
+	if err := f.run(ctx, &res, "mod", "download", "-json", "--", mod+"@"+version); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (f goFetcher) run(ctx context.Context, res interface{}, args ...string) error {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = f.workDir
+	cmd.Env = f.env
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	var info moduleInfo
+	if json.Unmarshal(stdout.Bytes(), &info) == nil && len(info.Error) != 0 {
+		return errors.New(parseGoError(info.Error))
+	}
+	if runErr != nil {
+		return fmt.Errorf("go %s failed: %s: %s", strings.Join(args, " "), runErr,
+			strings.TrimSpace(stderr.String()))
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), res); err != nil {
+		return fmt.Errorf("can't parse output of go %s: %s", strings.Join(args, " "), err)
+	}
+
+	return nil
+}
+
+// parseGoError parses the error of go mod download (a string) and go list (an object)
+func parseGoError(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+
+	var obj struct {
+		Err string
+	}
+	if json.Unmarshal(raw, &obj) == nil && obj.Err != "" {
+		return obj.Err
+	}
+
+	return string(raw)
+}
@@ -0,0 +1,51 @@
+package goproxy
+
+import (
+	"path"
+	"strings"
+)
+
+// filter allows or denies modules by comma-separated glob patterns in the syntax of GOPRIVATE:
+// a pattern matches a module if it matches a prefix of the module path with the same count of elements
+type filter struct {
+	allow string // empty allows all modules
+	deny  string
+}
+
+func (f filter) isAllowed(mod string) bool {
+	if matchPrefixPatterns(f.deny, mod) {
+		return false
+	}
+
+	return f.allow == "" || matchPrefixPatterns(f.allow, mod)
+}
+
+func matchPrefixPatterns(patterns, mod string) bool {
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		prefix, ok := cutPathElems(mod, strings.Count(pattern, "/")+1)
+		if !ok {
+			continue
+		}
+
+		if matched, _ := path.Match(pattern, prefix); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cutPathElems returns first n elements of the path
+func cutPathElems(p string, n int) (string, bool) {
+	elems := strings.Split(p, "/")
+	if len(elems) < n {
+		return "", false
+	}
+
+	return strings.Join(elems[:n], "/"), true
+}
@@ -0,0 +1,67 @@
+package goproxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	f := filter{
+		allow: "github.com/golangci,*.example.com",
+		deny:  "github.com/golangci/private*, git.example.com",
+	}
+
+	cases := map[string]bool{
+		"github.com/golangci/golangci-lint":      true,
+		"github.com/golangci/private-lib/sub":    false,
+		"github.com/other/lib":                   false,
+		"git.corp.example.com/team/lib":          true,
+		"corp.example.com/team/lib":              true,
+		"git.example.com/team/lib":               false,
+		"github.com":                             false,
+		"github.com/golangci-fork/golangci-lint": false,
+	}
+	for mod, allowed := range cases {
+		assert.Equal(t, allowed, f.isAllowed(mod), mod)
+	}
+
+	assert.True(t, filter{}.isAllowed("github.com/any/mod"))
+}
+
+func TestEscapePath(t *testing.T) {
+	for _, p := range []string{"github.com/Sirupsen/logrus", "example.com/lower", "v1.0.0-RC1"} {
+		escaped := escapePath(p)
+		assert.NotRegexp(t, "[A-Z]", escaped)
+
+		unescaped, err := unescapePath(escaped)
+		assert.NoError(t, err)
+		assert.Equal(t, p, unescaped)
+	}
+
+	for _, p := range []string{"github.com/Sirupsen", "github.com/!", "github.com/!1"} {
+		_, err := unescapePath(p)
+		assert.Error(t, err, p)
+	}
+}
+
+func TestParseCredentials(t *testing.T) {
+	creds, err := parseCredentials("Owner/git.example.com=user:pass:word, other/github.com=x-access-token:token")
+	assert.NoError(t, err)
+	assert.Equal(t, []Credential{
+		{Owner: "owner", Host: "git.example.com", Login: "user", Password: "pass:word"},
+		{Owner: "other", Host: "github.com", Login: "x-access-token", Password: "token"},
+	}, creds)
+
+	for _, s := range []string{"git.example.com", "=user:pass", "git.example.com=user:pass", "o/=user:pass",
+		"../h=user:pass", "o/h=token", "o/h=user:pass word"} {
+		_, err = parseCredentials(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestListenAddr(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:3001", listenAddr(":3001"))
+	assert.Equal(t, "0.0.0.0:3001", listenAddr("0.0.0.0:3001"))
+	assert.Equal(t, "10.0.0.1:3001", listenAddr("10.0.0.1:3001"))
+}
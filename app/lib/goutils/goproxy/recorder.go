@@ -0,0 +1,65 @@
+package goproxy
+
+import (
+	"sort"
+	"sync"
+)
+
+// Module is a module version fetched through the proxy
+type Module struct {
+	Path    string
+	Version string
+}
+
+func (m Module) String() string {
+	return m.Path + "@" + m.Version
+}
+
+// recorder records modules fetched by every running session
+type recorder struct {
+	lock    sync.Mutex
+	modules map[string]map[Module]bool
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		modules: map[string]map[Module]bool{},
+	}
+}
+
+func (r *recorder) start(token string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.modules[token] = map[Module]bool{}
+}
+
+// record does nothing for finished sessions
+func (r *recorder) record(token string, m Module) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if modules := r.modules[token]; modules != nil {
+		modules[m] = true
+	}
+}
+
+func (r *recorder) finish(token string) []Module {
+	r.lock.Lock()
+	modules := r.modules[token]
+	delete(r.modules, token)
+	r.lock.Unlock()
+
+	var ret []Module
+	for m := range modules {
+		ret = append(ret, m)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Path != ret[j].Path {
+			return ret[i].Path < ret[j].Path
+		}
+		return ret[i].Version < ret[j].Version
+	})
+
+	return ret
+}
@@ -0,0 +1,84 @@
+package goproxy
+
+import (
+	"fmt"
+	"strings"
+)
+
+type requestKind int
+
+const (
+	requestList requestKind = iota
+	requestLatest
+	requestInfo
+	requestMod
+	requestZip
+)
+
+var versionFileKinds = map[string]requestKind{
+	".info": requestInfo,
+	".mod":  requestMod,
+	".zip":  requestZip,
+}
+
+type request struct {
+	kind    requestKind
+	mod     string
+	version string // empty for list and latest
+}
+
+// parseRequest parses the escaped path of the GOPROXY protocol without the leading slash
+func parseRequest(p string) (*request, error) {
+	if strings.HasSuffix(p, "/@latest") {
+		mod, err := parseModulePath(strings.TrimSuffix(p, "/@latest"))
+		if err != nil {
+			return nil, err
+		}
+		return &request{kind: requestLatest, mod: mod}, nil
+	}
+
+	i := strings.LastIndex(p, "/@v/")
+	if i == -1 {
+		return nil, fmt.Errorf("invalid path %q", p)
+	}
+
+	mod, err := parseModulePath(p[:i])
+	if err != nil {
+		return nil, err
+	}
+
+	file := p[i+len("/@v/"):]
+	if file == "list" {
+		return &request{kind: requestList, mod: mod}, nil
+	}
+
+	for ext, kind := range versionFileKinds {
+		if !strings.HasSuffix(file, ext) {
+			continue
+		}
+
+		version, err := unescapePath(strings.TrimSuffix(file, ext))
+		if err != nil {
+			return nil, err
+		}
+		if !isValidVersion(version) {
+			return nil, fmt.Errorf("invalid version %q", version)
+		}
+		return &request{kind: kind, mod: mod, version: version}, nil
+	}
+
+	return nil, fmt.Errorf("invalid file %q", file)
+}
+
+func parseModulePath(escaped string) (string, error) {
+	mod, err := unescapePath(escaped)
+	if err != nil {
+		return "", err
+	}
+
+	if !isValidModulePath(mod) {
+		return "", fmt.Errorf("invalid module path %q", mod)
+	}
+
+	return mod, nil
+}
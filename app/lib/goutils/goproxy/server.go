@@ -0,0 +1,387 @@
+// Package goproxy is a go module proxy (the GOPROXY protocol) built into the worker.
+// Executors fetch modules only through it: modules are served from the local module cache dir
+// and are fetched into it from upstream proxies or VCS of private modules with credentials kept in the worker.
+// Only sessions of running analyses are served: every session has an unguessable token in its URL.
+package goproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+)
+
+// Credential is a login to a private module host of a repo owner
+type Credential struct {
+	Owner    string // lowercased repo owner, credentials are used only by analyses of its repos
+	Host     string
+	Login    string
+	Password string
+}
+
+type Config struct {
+	Dir        string // module cache dir of public modules, files are served from Dir/cache/download
+	PrivateDir string // module cache dirs of private modules of owners are in PrivateDir/<owner>
+	PublicURL  string // url of the proxy for executors
+
+	Upstream string // GOPROXY of fetches, off serves only cached modules
+	SumDB    string // GOSUMDB of fetches and executors, empty is go's default
+	Private  string // GOPRIVATE of fetches: modules fetched directly from VCS with credentials of the owner
+
+	Credentials []Credential
+
+	Allow string // only these modules are served if it's set, patterns are in GOPRIVATE syntax
+	Deny  string
+}
+
+// newFetcherFunc makes a fetcher into the module cache dir with credentials of an owner
+type newFetcherFunc func(dir string, creds []Credential) (fetcher, error)
+
+type Server struct {
+	cfg        Config
+	newFetcher newFetcherFunc
+	filter     filter
+	recorder   *recorder
+	sumDB      http.Handler // nil if the checksum database isn't proxied
+
+	lock     sync.Mutex
+	sessions map[string]*session // by token
+	fetchers map[string]fetcher  // by cache dir
+}
+
+var _ http.Handler = &Server{}
+
+func NewServer(cfg Config) (*Server, error) {
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("can't make module cache dir: %s", err)
+	}
+	if err := os.MkdirAll(cfg.PrivateDir, 0700); err != nil {
+		return nil, fmt.Errorf("can't make private module cache dir: %s", err)
+	}
+
+	return newServer(cfg, func(dir string, creds []Credential) (fetcher, error) {
+		return newGoFetcher(&cfg, dir, creds)
+	}), nil
+}
+
+func newServer(cfg Config, newFetcher newFetcherFunc) *Server {
+	return &Server{
+		cfg:        cfg,
+		newFetcher: newFetcher,
+		filter:     filter{allow: cfg.Allow, deny: cfg.Deny},
+		recorder:   newRecorder(),
+		sumDB:      newSumDBProxy(cfg),
+		sessions:   map[string]*session{},
+		fetchers:   map[string]fetcher{},
+	}
+}
+
+// newSumDBProxy proxies requests of go to the default checksum database: executors
+// can have no access to the internet
+func newSumDBProxy(cfg Config) http.Handler {
+	if cfg.Upstream == "off" || (cfg.SumDB != "" && cfg.SumDB != sumDBName) {
+		return nil
+	}
+
+	target := &url.URL{Scheme: "https", Host: sumDBName}
+	p := httputil.NewSingleHostReverseProxy(target)
+	director := p.Director
+	p.Director = func(r *http.Request) {
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, "/sumdb/"+sumDBName)
+		director(r)
+		r.Host = target.Host
+	}
+
+	return p
+}
+
+const sumDBName = "sum.golang.org"
+
+const sessionPathPrefix = "/session/"
+
+// ServeHTTP serves paths /session/<token>/ + <module>/@v/list, <module>/@v/<version>.(info|mod|zip),
+// <module>/@latest and sumdb/<sumdb>/... Unknown and finished sessions are forbidden.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method isn't allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sess, p := s.parseSession(r.URL.Path)
+	if sess == nil {
+		http.Error(w, "unknown or finished session", http.StatusForbidden)
+		return
+	}
+
+	if strings.HasPrefix(p, "/sumdb/") {
+		s.serveSumDB(w, r, p)
+		return
+	}
+
+	req, err := parseRequest(strings.TrimPrefix(p, "/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if !s.filter.isAllowed(req.mod) {
+		http.Error(w, fmt.Sprintf("module %s is denied by the proxy policy", req.mod), http.StatusForbidden)
+		return
+	}
+
+	sc, err := s.scopeOf(sess, req.mod)
+	if err != nil {
+		analytics.Log(r.Context()).Errorf("Go proxy: can't make fetcher: %s", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	s.serveModule(w, r, req, sc)
+}
+
+// parseSession returns the running session of the path and the rest of the path
+func (s *Server) parseSession(p string) (*session, string) {
+	if !strings.HasPrefix(p, sessionPathPrefix) {
+		return nil, ""
+	}
+
+	rest := strings.TrimPrefix(p, sessionPathPrefix)
+	i := strings.IndexByte(rest, '/')
+	if i == -1 {
+		return nil, ""
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.sessions[rest[:i]], rest[i:]
+}
+
+// scope is the module cache and the fetcher of a module for a session
+type scope struct {
+	dir     string
+	fetcher fetcher
+	session *session
+}
+
+// scopeOf returns the module cache of the owner of the session for private modules:
+// they are fetched with credentials of the owner and are never served to analyses of other owners
+func (s *Server) scopeOf(sess *session, mod string) (*scope, error) {
+	dir := s.cfg.Dir
+	var creds []Credential
+	if sess.owner != "" && matchPrefixPatterns(s.cfg.Private, mod) {
+		dir = filepath.Join(s.cfg.PrivateDir, sess.owner)
+		for _, c := range s.cfg.Credentials {
+			if c.Owner == sess.owner {
+				creds = append(creds, c)
+			}
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	f := s.fetchers[dir]
+	if f == nil {
+		var err error
+		if f, err = s.newFetcher(dir, creds); err != nil {
+			return nil, err
+		}
+		s.fetchers[dir] = f
+	}
+
+	return &scope{dir: dir, fetcher: f, session: sess}, nil
+}
+
+func (s *Server) serveSumDB(w http.ResponseWriter, r *http.Request, p string) {
+	if s.sumDB == nil || !strings.HasPrefix(p, "/sumdb/"+sumDBName+"/") {
+		http.NotFound(w, r) // go falls back to the direct access to the checksum database
+		return
+	}
+
+	if p == "/sumdb/"+sumDBName+"/supported" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	r.URL.Path = p
+	r.URL.RawPath = ""
+	s.sumDB.ServeHTTP(w, r)
+}
+
+func (s *Server) serveModule(w http.ResponseWriter, r *http.Request, req *request, sc *scope) {
+	ctx := r.Context()
+	var err error
+	switch req.kind {
+	case requestList:
+		err = s.serveList(w, r, req.mod, sc)
+	case requestLatest:
+		err = s.serveQuery(w, r, req.mod, "latest", sc)
+	case requestInfo:
+		err = s.serveInfo(w, r, req.mod, req.version, sc)
+	case requestMod:
+		err = s.serveGoMod(w, r, req.mod, req.version, sc)
+	case requestZip:
+		err = s.serveZip(w, r, req.mod, req.version, sc)
+	}
+
+	if err != nil {
+		// go treats 404 as a missing module or version and tries the next proxy if it's set
+		analytics.Log(ctx).Infof("Go proxy: can't serve %s for analysis %s: %s", req.mod, sc.session.analysisID, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+	}
+}
+
+func (s *Server) serveList(w http.ResponseWriter, r *http.Request, mod string, sc *scope) error {
+	versions, err := sc.fetcher.Versions(r.Context(), mod)
+	if err != nil {
+		// upstream is unavailable or forbidden, it's ok to serve only cached versions
+		versions = cachedVersions(sc.dir, mod)
+		if len(versions) == 0 {
+			return err
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	for _, v := range versions {
+		fmt.Fprintln(w, v)
+	}
+	return nil
+}
+
+func (s *Server) serveQuery(w http.ResponseWriter, r *http.Request, mod, query string, sc *scope) error {
+	info, err := sc.fetcher.Query(r.Context(), mod, query)
+	if err != nil {
+		return err
+	}
+
+	s.recorder.record(sc.session.token, Module{Path: mod, Version: info.Version})
+	return writeInfo(w, info)
+}
+
+func (s *Server) serveInfo(w http.ResponseWriter, r *http.Request, mod, version string, sc *scope) error {
+	path := cachedPath(sc.dir, mod, version, ".info")
+	if !isFile(path) {
+		return s.serveQuery(w, r, mod, version, sc) // version can be a branch or a commit
+	}
+
+	s.recorder.record(sc.session.token, Module{Path: mod, Version: version})
+	return serveFile(w, r, path, "application/json")
+}
+
+func (s *Server) serveGoMod(w http.ResponseWriter, r *http.Request, mod, version string, sc *scope) error {
+	path := cachedPath(sc.dir, mod, version, ".mod")
+	if !isFile(path) {
+		info, err := sc.fetcher.Query(r.Context(), mod, version)
+		if err != nil {
+			return err
+		}
+		if info.GoMod == "" || info.Version != version {
+			return fmt.Errorf("no go.mod of %s@%s", mod, version)
+		}
+		path = info.GoMod
+	}
+
+	s.recorder.record(sc.session.token, Module{Path: mod, Version: version})
+	return serveFile(w, r, path, "text/plain; charset=UTF-8")
+}
+
+func (s *Server) serveZip(w http.ResponseWriter, r *http.Request, mod, version string, sc *scope) error {
+	path := cachedPath(sc.dir, mod, version, ".zip")
+	if !isFile(path) {
+		info, err := sc.fetcher.Download(r.Context(), mod, version)
+		if err != nil {
+			return err
+		}
+		if info.Zip == "" || info.Version != version {
+			return fmt.Errorf("no zip of %s@%s", mod, version)
+		}
+		path = info.Zip
+	}
+
+	s.recorder.record(sc.session.token, Module{Path: mod, Version: version})
+	return serveFile(w, r, path, "application/zip")
+}
+
+func writeInfo(w http.ResponseWriter, info *moduleInfo) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(struct {
+		Version string
+		Time    *time.Time `json:",omitempty"`
+	}{
+		Version: info.Version,
+		Time:    info.Time,
+	})
+}
+
+func downloadDir(dir, mod string) string {
+	return filepath.Join(dir, "cache", "download", filepath.FromSlash(escapePath(mod)), "@v")
+}
+
+func cachedPath(dir, mod, version, ext string) string {
+	return filepath.Join(downloadDir(dir, mod), escapePath(version)+ext)
+}
+
+// cachedVersions returns versions of the module which were fetched into the module cache dir
+func cachedVersions(dir, mod string) []string {
+	files, err := filepath.Glob(filepath.Join(downloadDir(dir, mod), "*.info"))
+	if err != nil {
+		return nil
+	}
+
+	var ret []string
+	for _, f := range files {
+		version, err := unescapePath(strings.TrimSuffix(filepath.Base(f), ".info"))
+		if err == nil && !isPseudoVersion(version) {
+			ret = append(ret, version)
+		}
+	}
+
+	return ret
+}
+
+// isPseudoVersion checks for versions like v0.0.0-20181023235946-059132a15dd0:
+// they are never listed
+func isPseudoVersion(version string) bool {
+	parts := strings.Split(version, "-")
+	if len(parts) < 3 {
+		return false
+	}
+
+	rev := parts[len(parts)-1]
+	timestamp := parts[len(parts)-2]
+	if i := strings.LastIndexByte(timestamp, '.'); i != -1 {
+		timestamp = timestamp[i+1:] // v1.2.4-0.20181023235946-059132a15dd0
+	}
+
+	return len(rev) == 12 && len(timestamp) == 14
+}
+
+func isFile(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && !fi.IsDir()
+}
+
+func serveFile(w http.ResponseWriter, r *http.Request, path, contentType string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("can't open cached file: %s", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("can't stat cached file: %s", err)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, "", fi.ModTime(), f)
+	return nil
+}
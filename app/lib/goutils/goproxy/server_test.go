@@ -0,0 +1,304 @@
+package goproxy
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeFetcher fetches modules into the cache like go does
+type fakeFetcher struct {
+	dir      string
+	creds    []Credential
+	versions map[string][]string
+	fetched  *[]string
+}
+
+func (f *fakeFetcher) Versions(ctx context.Context, mod string) ([]string, error) {
+	versions, ok := f.versions[mod]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return versions, nil
+}
+
+func (f *fakeFetcher) Query(ctx context.Context, mod, query string) (*moduleInfo, error) {
+	versions, ok := f.versions[mod]
+	if !ok || len(versions) == 0 {
+		return nil, errors.New("not found")
+	}
+
+	version := query
+	if query == "latest" {
+		version = versions[len(versions)-1]
+	}
+	*f.fetched = append(*f.fetched, "query "+mod+"@"+version)
+
+	goMod := f.write(mod, version, ".mod", "module "+mod+"\n")
+	f.write(mod, version, ".info", `{"Version":"`+version+`"}`)
+	return &moduleInfo{Path: mod, Version: version, GoMod: goMod}, nil
+}
+
+func (f *fakeFetcher) Download(ctx context.Context, mod, version string) (*moduleInfo, error) {
+	if !hasVersion(f.versions[mod], version) {
+		return nil, errors.New("not found")
+	}
+	*f.fetched = append(*f.fetched, "download "+mod+"@"+version)
+
+	zip := f.write(mod, version, ".zip", "zip")
+	return &moduleInfo{Path: mod, Version: version, Zip: zip}, nil
+}
+
+func hasVersion(versions []string, version string) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeFetcher) write(mod, version, ext, content string) string {
+	dir := filepath.Join(f.dir, "cache", "download", escapePath(mod), "@v")
+	_ = os.MkdirAll(dir, 0755)
+	path := filepath.Join(dir, escapePath(version)+ext)
+	_ = ioutil.WriteFile(path, []byte(content), 0644)
+	return path
+}
+
+// testFetchers are fetchers made by the test server by module cache dirs
+type testFetchers struct {
+	byDir    map[string]*fakeFetcher
+	versions map[string][]string
+	fetched  []string
+}
+
+func newTestServer(t *testing.T, cfg Config) (*Server, *testFetchers, func()) {
+	dir, err := ioutil.TempDir("", "goproxy")
+	assert.NoError(t, err)
+
+	tf := &testFetchers{
+		byDir: map[string]*fakeFetcher{},
+		versions: map[string][]string{
+			"github.com/Sirupsen/logrus": {"v1.0.0", "v1.1.0"},
+			"private.example.com/lib":    {"v0.1.0"},
+		},
+	}
+	cfg.Dir = filepath.Join(dir, "public")
+	cfg.PrivateDir = filepath.Join(dir, "private")
+	cfg.Upstream = "off"
+	cfg.PublicURL = "http://proxy:8080"
+	s := newServer(cfg, func(dir string, creds []Credential) (fetcher, error) {
+		f := &fakeFetcher{dir: dir, creds: creds, versions: tf.versions, fetched: &tf.fetched}
+		tf.byDir[dir] = f
+		return f, nil
+	})
+	return s, tf, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func startTestSession(t *testing.T, s *Server, owner string) *Session {
+	sess, err := s.StartSession("guid", owner)
+	assert.NoError(t, err)
+	return sess
+}
+
+func get(s *Server, path string) (int, string) {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w.Code, w.Body.String()
+}
+
+func sessionPath(sess *Session, p string) string {
+	return sessionPathPrefix + sess.token + p
+}
+
+func TestServeModules(t *testing.T) {
+	s, tf, cleanup := newTestServer(t, Config{})
+	defer cleanup()
+	sess := startTestSession(t, s, "")
+
+	code, body := get(s, sessionPath(sess, "/github.com/!sirupsen/logrus/@v/list"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "v1.0.0\nv1.1.0\n", body)
+
+	code, body = get(s, sessionPath(sess, "/github.com/!sirupsen/logrus/@latest"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"Version":"v1.1.0"}`, body)
+
+	code, body = get(s, sessionPath(sess, "/github.com/!sirupsen/logrus/@v/v1.0.0.mod"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "module github.com/Sirupsen/logrus\n", body)
+
+	code, body = get(s, sessionPath(sess, "/github.com/!sirupsen/logrus/@v/v1.0.0.zip"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "zip", body)
+
+	// cached files are served without fetching
+	code, body = get(s, sessionPath(sess, "/github.com/!sirupsen/logrus/@v/v1.0.0.info"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"Version":"v1.0.0"}`, body)
+	code, _ = get(s, sessionPath(sess, "/github.com/!sirupsen/logrus/@v/v1.0.0.zip"))
+	assert.Equal(t, http.StatusOK, code)
+
+	assert.Equal(t, []string{
+		"query github.com/Sirupsen/logrus@v1.1.0",
+		"query github.com/Sirupsen/logrus@v1.0.0",
+		"download github.com/Sirupsen/logrus@v1.0.0",
+	}, tf.fetched)
+}
+
+func TestServeCachedVersionsIfUpstreamFails(t *testing.T) {
+	s, tf, cleanup := newTestServer(t, Config{})
+	defer cleanup()
+	sess := startTestSession(t, s, "")
+
+	code, _ := get(s, sessionPath(sess, "/private.example.com/lib/@v/v0.1.0.info"))
+	assert.Equal(t, http.StatusOK, code)
+	tf.byDir[s.cfg.Dir].write("private.example.com/lib", "v0.0.0-20181023235946-059132a15dd0", ".info", "{}")
+	delete(tf.versions, "private.example.com/lib")
+
+	code, body := get(s, sessionPath(sess, "/private.example.com/lib/@v/list"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "v0.1.0\n", body)
+}
+
+func TestServeErrors(t *testing.T) {
+	s, tf, cleanup := newTestServer(t, Config{
+		Deny: "private.example.com",
+	})
+	defer cleanup()
+	sess := startTestSession(t, s, "")
+
+	code, _ := get(s, sessionPath(sess, "/github.com/unknown/mod/@v/list"))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = get(s, sessionPath(sess, "/github.com/!sirupsen/logrus/@v/v9.0.0.zip"))
+	assert.Equal(t, http.StatusNotFound, code, "download of unknown version failed")
+
+	code, _ = get(s, sessionPath(sess, "/github.com/Sirupsen/logrus/@v/list"))
+	assert.Equal(t, http.StatusNotFound, code, "path isn't escaped")
+
+	code, _ = get(s, sessionPath(sess, "/github.com/../logrus/@v/list"))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	fetchedBefore := len(tf.fetched)
+	code, _ = get(s, sessionPath(sess, "/-modfile=go.mod/@v/v1.0.0.info"))
+	assert.Equal(t, http.StatusNotFound, code, "module path is a flag of go")
+	code, _ = get(s, sessionPath(sess, "/github.com/-x/logrus/@v/list"))
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = get(s, sessionPath(sess, "/github.com/!sirupsen/logrus/@v/-x.info"))
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Len(t, tf.fetched, fetchedBefore, "invalid requests reached the fetcher")
+
+	code, body := get(s, sessionPath(sess, "/private.example.com/lib/@v/v0.1.0.zip"))
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Contains(t, body, "denied")
+
+	code, _ = get(s, sessionPath(sess, "/sumdb/sum.golang.org/supported"))
+	assert.Equal(t, http.StatusNotFound, code, "checksum database isn't proxied in offline mode")
+}
+
+func TestServeOnlyRunningSessions(t *testing.T) {
+	s, tf, cleanup := newTestServer(t, Config{})
+	defer cleanup()
+	sess := startTestSession(t, s, "")
+
+	for _, p := range []string{
+		"/github.com/!sirupsen/logrus/@v/list",
+		"/session/guid/github.com/!sirupsen/logrus/@v/list",
+		"/session/" + sess.token,
+		"/sumdb/sum.golang.org/supported",
+	} {
+		code, _ := get(s, p)
+		assert.Equal(t, http.StatusForbidden, code, p)
+	}
+
+	sess.Finish(context.Background())
+	code, _ := get(s, sessionPath(sess, "/github.com/!sirupsen/logrus/@v/list"))
+	assert.Equal(t, http.StatusForbidden, code, "session is finished")
+	assert.Empty(t, tf.fetched)
+
+	other := startTestSession(t, s, "")
+	assert.NotEqual(t, sess.token, other.token)
+	assert.Len(t, other.token, 64)
+}
+
+func TestPrivateModulesAreScopedByOwner(t *testing.T) {
+	s, tf, cleanup := newTestServer(t, Config{
+		Private: "private.example.com",
+		Credentials: []Credential{
+			{Owner: "owner", Host: "private.example.com", Login: "login", Password: "password"},
+			{Owner: "other", Host: "private.example.com", Login: "other", Password: "password"},
+		},
+	})
+	defer cleanup()
+
+	_, err := s.StartSession("guid", "../owner")
+	assert.Error(t, err)
+
+	ownerSess := startTestSession(t, s, "Owner")
+	code, _ := get(s, sessionPath(ownerSess, "/private.example.com/lib/@v/v0.1.0.zip"))
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = get(s, sessionPath(ownerSess, "/github.com/!sirupsen/logrus/@v/v1.0.0.zip"))
+	assert.Equal(t, http.StatusOK, code)
+
+	anonSess := startTestSession(t, s, "")
+	code, _ = get(s, sessionPath(anonSess, "/private.example.com/lib/@v/v0.1.0.zip"))
+	assert.Equal(t, http.StatusOK, code)
+
+	assert.Equal(t, []string{
+		"download private.example.com/lib@v0.1.0",
+		"download github.com/Sirupsen/logrus@v1.0.0",
+		"download private.example.com/lib@v0.1.0", // isn't served from the cache of the owner
+	}, tf.fetched)
+
+	ownerDir := filepath.Join(s.cfg.PrivateDir, "owner")
+	assert.Equal(t, []Credential{s.cfg.Credentials[0]}, tf.byDir[ownerDir].creds)
+	assert.Empty(t, tf.byDir[s.cfg.Dir].creds)
+	assert.Len(t, tf.byDir, 2)
+}
+
+func TestSessionRecordsModules(t *testing.T) {
+	s, _, cleanup := newTestServer(t, Config{Private: "private.example.com"})
+	defer cleanup()
+
+	sess := startTestSession(t, s, "owner")
+	other := startTestSession(t, s, "owner")
+	assert.Equal(t, "http://proxy:8080/session/"+sess.token, sess.URL())
+	assert.Equal(t, map[string]string{
+		"GOPROXY":   "http://proxy:8080/session/" + sess.token,
+		"GONOPROXY": "",
+		"GOPRIVATE": "",
+		"GONOSUMDB": "private.example.com",
+		"GOSUMDB":   "off",
+	}, sess.Env())
+
+	for _, p := range []string{
+		sessionPath(sess, "/github.com/!sirupsen/logrus/@v/list"),
+		sessionPath(sess, "/github.com/!sirupsen/logrus/@v/v1.1.0.mod"),
+		sessionPath(sess, "/github.com/!sirupsen/logrus/@v/v1.1.0.zip"),
+		sessionPath(sess, "/private.example.com/lib/@v/v0.1.0.info"),
+		sessionPath(other, "/github.com/!sirupsen/logrus/@v/v1.0.0.zip"),
+	} {
+		code, body := get(s, p)
+		assert.Equal(t, http.StatusOK, code, p+": "+body)
+	}
+
+	assert.Equal(t, []Module{
+		{Path: "github.com/Sirupsen/logrus", Version: "v1.1.0"},
+		{Path: "private.example.com/lib", Version: "v0.1.0"},
+	}, sess.Finish(context.Background()))
+	assert.Empty(t, sess.Finish(context.Background()))
+
+	var nilSession *Session
+	assert.Empty(t, nilSession.Finish(context.Background()))
+}
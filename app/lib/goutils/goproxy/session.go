@@ -0,0 +1,125 @@
+package goproxy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/golangci/golangci-worker/app/analytics"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+)
+
+// session is a running analysis allowed to fetch modules
+type session struct {
+	token      string
+	analysisID string
+	owner      string // lowercased, empty if private modules aren't fetched
+}
+
+// Session records modules fetched by go commands of an analysis
+type Session struct {
+	server *Server
+	*session
+}
+
+var ownerRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// StartSession allows the analysis of a repo of the owner to fetch modules: private modules are fetched
+// with credentials of the owner. The session is served until Finish.
+func (s *Server) StartSession(analysisID, owner string) (*Session, error) {
+	owner = strings.ToLower(owner)
+	if owner != "" && !ownerRe.MatchString(owner) {
+		return nil, fmt.Errorf("invalid repo owner %q", owner)
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	sess := &session{
+		token:      token,
+		analysisID: analysisID,
+		owner:      owner,
+	}
+
+	s.lock.Lock()
+	s.sessions[token] = sess
+	s.lock.Unlock()
+
+	s.recorder.start(token)
+	return &Session{
+		server:  s,
+		session: sess,
+	}, nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("can't generate session token: %s", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func (sess Session) URL() string {
+	return sess.server.cfg.PublicURL + sessionPathPrefix + sess.token
+}
+
+// Env returns env vars making go fetch modules only through the proxy
+func (sess Session) Env() map[string]string {
+	cfg := sess.server.cfg
+	env := map[string]string{
+		"GOPROXY":   sess.URL(),
+		"GONOPROXY": "",
+		"GOPRIVATE": "",
+		// private modules aren't in the public checksum database
+		"GONOSUMDB": cfg.Private,
+	}
+
+	if cfg.SumDB != "" {
+		env["GOSUMDB"] = cfg.SumDB
+	} else if cfg.Upstream == "off" {
+		// executors can't check new modules, modules in the cache were checked when they were fetched
+		env["GOSUMDB"] = "off"
+	}
+
+	return env
+}
+
+// WithEnv returns the executor with env of the session
+func (sess Session) WithEnv(exec executors.Executor) executors.Executor {
+	for k, v := range sess.Env() {
+		exec = exec.WithEnv(k, v)
+	}
+
+	return exec
+}
+
+// Finish stops serving the session and returns fetched modules, it does nothing for nil session.
+// Next calls return nothing.
+func (sess *Session) Finish(ctx context.Context) []Module {
+	if sess == nil {
+		return nil
+	}
+
+	sess.server.lock.Lock()
+	delete(sess.server.sessions, sess.token)
+	sess.server.lock.Unlock()
+
+	modules := sess.server.recorder.finish(sess.token)
+	if len(modules) != 0 {
+		var names []string
+		for _, m := range modules {
+			names = append(names, m.String())
+		}
+		analytics.Log(ctx).Infof("Go proxy: analysis %s fetched %d modules: %s",
+			sess.analysisID, len(modules), strings.Join(names, ", "))
+	}
+
+	return modules
+}
@@ -12,14 +12,16 @@ import (
 	"github.com/golangci/golangci-worker/app/analyze/repoinfo"
 	"github.com/golangci/golangci-worker/app/lib/executors"
 	"github.com/golangci/golangci-worker/app/lib/fetchers"
+	"github.com/golangci/golangci-worker/app/lib/goutils/buildcache"
 	"github.com/golangci/golangci-worker/app/lib/goutils/environments"
 	"github.com/pkg/errors"
 )
 
 type Go struct {
-	gopath      string
-	exec        executors.Executor
-	infoFetcher repoinfo.Fetcher
+	gopath           string
+	exec             executors.Executor
+	infoFetcher      repoinfo.Fetcher
+	sharedBuildCache bool
 }
 
 func NewGo(exec executors.Executor, infoFetcher repoinfo.Fetcher) *Go {
@@ -56,12 +58,38 @@ func (w *Go) Setup(ctx context.Context, repo *fetchers.Repo, projectPathParts ..
 	goEnv := environments.NewGolang(gopath)
 	goEnv.Setup(w.exec)
 
+	if buildcache.IsConfigured() {
+		if err = w.setupBuildCache(ctx, repo.Owner, gopath); err != nil {
+			return err
+		}
+	}
+
 	w.exec = w.exec.WithWorkDir(wd) // XXX: clean gopath, but work in subdir of gopath
 
 	w.gopath = gopath
 	return nil
 }
 
+func (w *Go) setupBuildCache(ctx context.Context, owner, gopath string) error {
+	cache, err := buildcache.Get()
+	if err != nil {
+		return errors.Wrap(err, "failed to get build cache")
+	}
+
+	exec, err := cache.Setup(ctx, w.exec, owner, gopath)
+	if err == buildcache.ErrBeingTrimmed {
+		analytics.Log(ctx).Infof("Build cache is being trimmed, use own caches")
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to setup build cache")
+	}
+
+	w.exec = exec
+	w.sharedBuildCache = true
+	return nil
+}
+
 func (w Go) Executor() executors.Executor {
 	return w.exec
 }
@@ -91,6 +119,10 @@ func (w Go) FetchDeps(ctx context.Context, fullRepoPath string) (*ensuredeps.Res
 }
 
 func (w Go) Clean(ctx context.Context) {
+	if w.sharedBuildCache {
+		return // modules are used by other analyses, they are removed by the trim of the cache
+	}
+
 	out, err := w.exec.Run(ctx, "go", "clean", "-modcache")
 	if err != nil {
 		analytics.Log(ctx).Warnf("Can't clean go modcache: %s, %s", err, out)
@@ -20,6 +20,7 @@ type Go2 struct {
 }
 
 var _ Installer = &Go2{}
+var _ fetchedRepoInstaller = &Go2{}
 
 func NewGo2(exec executors.Executor, log logutil.Log, repoFetcher fetchers.Fetcher) *Go2 {
 	return &Go2{
@@ -34,6 +35,18 @@ func (w *Go2) Setup(ctx context.Context, repo *fetchers.Repo, projectPathParts .
 		return nil, nil, errors.Wrap(err, "failed to fetch repo")
 	}
 
+	return w.setupFetched(ctx, repo, projectPathParts...)
+}
+
+func (w *Go2) setupFetched(ctx context.Context, repo *fetchers.Repo,
+	projectPathParts ...string) (executors.Executor, *result.Log, error) {
+
+	cachedExec, sharedBuildCache, err := setupBuildCache(ctx, w.exec, repo.Owner)
+	if err != nil {
+		return nil, nil, err
+	}
+	w.exec = cachedExec
+
 	exec := w.exec.WithEnv("REPO", path.Join(projectPathParts...)).WithEnv("FORMAT_JSON", "1")
 	out, err := exec.Run(ctx, "goenvbuild")
 	if err != nil {
@@ -55,5 +68,23 @@ func (w *Go2) Setup(ctx context.Context, repo *fetchers.Repo, projectPathParts .
 		retExec = retExec.WithEnv(k, v)
 	}
 
+	if sharedBuildCache {
+		if err = w.verifyModules(ctx, retExec, envbuildResult.Log); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	return retExec, envbuildResult.Log, nil
 }
+
+// verifyModules verifies modules of go.mod in the work dir downloaded by goenvbuild
+func (w Go2) verifyModules(ctx context.Context, exec executors.Executor, resLog *result.Log) error {
+	if _, err := exec.Run(ctx, "test", "-f", "go.mod"); err != nil {
+		return nil // not a go modules project
+	}
+
+	if resLog == nil {
+		resLog = &result.Log{}
+	}
+	return verifyModules(ctx, exec, []string{"."}, addStepGroup(resLog, "verify modules"))
+}
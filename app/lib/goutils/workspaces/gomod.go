@@ -0,0 +1,367 @@
+package workspaces
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golangci/golangci-api/pkg/goenv/result"
+	"github.com/golangci/golangci-shared/pkg/logutil"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+	"github.com/golangci/golangci-worker/app/lib/fetchers"
+	pkgerrors "github.com/pkg/errors"
+)
+
+// ErrNoGoModules is returned by GoModules without fallback for repos without go.mod
+var ErrNoGoModules = errors.New("no go.mod in repo")
+
+// GoModules prepares go modules projects without goenvbuild: it finds module roots,
+// downloads their dependencies and returns the log of steps like goenvbuild does.
+// Repos without go.mod are prepared by the fallback installer.
+type GoModules struct {
+	exec        executors.Executor
+	log         logutil.Log
+	repoFetcher fetchers.Fetcher
+	fallback    Installer // nil if there is no fallback
+}
+
+var _ Installer = &GoModules{}
+
+// GoModulesInfo describes go modules of the repo
+type GoModulesInfo struct {
+	Roots     []string // dirs of go.mod relative to the repo root, the repo root is "."
+	Workspace bool     // roots are modules of go.work in the repo root
+	Vendor    bool     // all roots have vendored dependencies
+}
+
+func NewGoModules(exec executors.Executor, log logutil.Log, repoFetcher fetchers.Fetcher, fallback Installer) *GoModules {
+	return &GoModules{
+		exec:        exec,
+		log:         log,
+		repoFetcher: repoFetcher,
+		fallback:    fallback,
+	}
+}
+
+func (w *GoModules) Setup(ctx context.Context, repo *fetchers.Repo, projectPathParts ...string) (executors.Executor, *result.Log, error) {
+	resLog := &result.Log{}
+
+	fetchGroup := addStepGroup(resLog, "fetch repo")
+	fetchStep := startStep(fetchGroup, fmt.Sprintf("fetch %s@%s", repo.CloneURL, repo.Ref))
+	err := w.repoFetcher.Fetch(ctx, repo, w.exec)
+	fetchStep.finish("", err)
+	if err != nil {
+		return nil, nil, pkgerrors.Wrap(err, "failed to fetch repo")
+	}
+
+	modules, err := findModules(ctx, w.exec, addStepGroup(resLog, "find modules"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if modules == nil {
+		return w.setupFallback(ctx, repo, resLog, projectPathParts...)
+	}
+
+	exec, sharedBuildCache, err := setupBuildCache(ctx, w.exec, repo.Owner)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exec = exec.WithEnv("GO111MODULE", "on").WithEnv("GOFLAGS", modulesGoFlags(modules))
+	if err = downloadModules(ctx, exec, modules, addStepGroup(resLog, "download modules")); err != nil {
+		return nil, nil, err
+	}
+	if sharedBuildCache && !modules.Vendor {
+		if err = verifyModules(ctx, exec, downloadRoots(modules), addStepGroup(resLog, "verify modules")); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return exec, resLog, nil
+}
+
+// setupFallback prepares the repo without go.mod by the fallback installer: installers which can prepare
+// the fetched repo reuse it, others fetch the repo again to the cleaned work dir
+func (w *GoModules) setupFallback(ctx context.Context, repo *fetchers.Repo, resLog *result.Log,
+	projectPathParts ...string) (executors.Executor, *result.Log, error) {
+
+	if w.fallback == nil {
+		return nil, nil, ErrNoGoModules
+	}
+
+	if fallback, ok := w.fallback.(fetchedRepoInstaller); ok {
+		w.log.Infof("No go.mod in repo, prepare the fetched repo by the fallback installer")
+		exec, fallbackLog, err := fallback.setupFetched(ctx, repo, projectPathParts...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if fallbackLog != nil {
+			resLog.Groups = append(resLog.Groups, fallbackLog.Groups...)
+		}
+		return exec, resLog, nil
+	}
+
+	w.log.Infof("No go.mod in repo, prepare it by the fallback installer")
+	if out, err := w.exec.Run(ctx, "find", ".", "-delete"); err != nil {
+		return nil, nil, fmt.Errorf("can't clean repo for the fallback installer: %s, %s", err, out)
+	}
+	return w.fallback.Setup(ctx, repo, projectPathParts...)
+}
+
+// modulesGoFlags returns GOFLAGS for linters: go.mod can be changed to add missing requirements,
+// vendored dependencies are used if all modules have them. go.work allows only -mod=vendor or readonly.
+func modulesGoFlags(modules *GoModulesInfo) string {
+	if modules.Vendor {
+		return "-mod=vendor"
+	}
+	if modules.Workspace {
+		return ""
+	}
+
+	return "-mod=mod"
+}
+
+// FindModules finds go modules of the repo fetched to the working dir of exec: it doesn't depend
+// on the installer which prepared the repo. It returns nil if the repo isn't a go modules project.
+func FindModules(ctx context.Context, exec executors.Executor) (*GoModulesInfo, error) {
+	return findModules(ctx, exec, &result.StepGroup{})
+}
+
+// findModules returns nil if the repo isn't a go modules project
+func findModules(ctx context.Context, exec executors.Executor, group *result.StepGroup) (*GoModulesInfo, error) {
+	step := startStep(group, "$ find . -name go.mod -o -name go.work -o -name modules.txt")
+	out, err := exec.Run(ctx, "find", ".", "-name", "go.mod", "-o", "-name", "go.work", "-o", "-name", "modules.txt")
+	step.finish("", err)
+	if err != nil {
+		return nil, fmt.Errorf("can't find go.mod files: %s, %s", err, out)
+	}
+
+	files := parseFoundFiles(out)
+	var modules *GoModulesInfo
+	if files["go.work"] {
+		step = startStep(group, "$ cat go.work")
+		out, err = exec.Run(ctx, "cat", "go.work")
+		step.finish(out, err)
+		if err != nil {
+			return nil, fmt.Errorf("can't read go.work: %s, %s", err, out)
+		}
+
+		modules = &GoModulesInfo{
+			Roots:     parseGoWorkUses(out),
+			Workspace: true,
+		}
+	} else {
+		modules = &GoModulesInfo{
+			Roots: findModuleRoots(files),
+		}
+	}
+
+	if len(modules.Roots) == 0 {
+		addInfoStep(group, "no go modules found")
+		return nil, nil
+	}
+
+	modules.Vendor = isVendored(modules, files)
+
+	addInfoStep(group, fmt.Sprintf("found modules: %s", strings.Join(modules.Roots, ", ")))
+	return modules, nil
+}
+
+// isVendored checks vendor/modules.txt of every module or of the workspace
+func isVendored(modules *GoModulesInfo, files map[string]bool) bool {
+	if modules.Workspace {
+		return files["vendor/modules.txt"]
+	}
+
+	for _, root := range modules.Roots {
+		if !files[path.Join(root, "vendor", "modules.txt")] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseFoundFiles returns set of found files relative to the repo root
+func parseFoundFiles(out string) map[string]bool {
+	ret := map[string]bool{}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			ret[path.Clean(line)] = true
+		}
+	}
+
+	return ret
+}
+
+// findModuleRoots returns dirs of go.mod files, modules in vendor and testdata dirs aren't analyzed
+func findModuleRoots(files map[string]bool) []string {
+	var roots []string
+	for f := range files {
+		if path.Base(f) != "go.mod" {
+			continue
+		}
+
+		dir := path.Dir(f)
+		skip := false
+		for _, elem := range strings.Split(dir, "/") {
+			if elem == "vendor" || elem == "testdata" || (strings.HasPrefix(elem, ".") && elem != ".") ||
+				strings.HasPrefix(elem, "_") {
+				skip = true // go ignores such dirs in ./...
+				break
+			}
+		}
+		if !skip {
+			roots = append(roots, dir)
+		}
+	}
+
+	sortRoots(roots)
+	return roots
+}
+
+// parseGoWorkUses returns dirs of use directives of go.work: use ./a, use (./a ./b) and use blocks
+// with comments. Modules outside of the repo aren't analyzed.
+func parseGoWorkUses(goWork string) []string {
+	var roots []string
+	inUseBlock := false
+	scanner := bufio.NewScanner(strings.NewReader(goWork))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		dir := ""
+		switch {
+		case inUseBlock && fields[0] == ")":
+			inUseBlock = false
+		case inUseBlock:
+			dir = fields[0]
+		case fields[0] == "use" && len(fields) >= 2 && fields[1] == "(":
+			inUseBlock = true
+		case fields[0] == "use" && len(fields) >= 2:
+			dir = fields[1]
+		}
+
+		if dir = cleanUseDir(dir); isRepoDir(dir) {
+			roots = append(roots, dir)
+		}
+	}
+
+	sortRoots(roots)
+	return roots
+}
+
+func cleanUseDir(dir string) string {
+	dir = strings.Trim(dir, `"`+"`")
+	if dir == "" {
+		return ""
+	}
+
+	return path.Clean(dir)
+}
+
+func isRepoDir(dir string) bool {
+	return dir != "" && !path.IsAbs(dir) && dir != ".." && !strings.HasPrefix(dir, "../")
+}
+
+// sortRoots sorts roots with the repo root first
+func sortRoots(roots []string) {
+	sort.Slice(roots, func(i, j int) bool {
+		if (roots[i] == ".") != (roots[j] == ".") {
+			return roots[i] == "."
+		}
+		return roots[i] < roots[j]
+	})
+}
+
+func downloadModules(ctx context.Context, exec executors.Executor, modules *GoModulesInfo, group *result.StepGroup) error {
+	if modules.Vendor {
+		addInfoStep(group, "dependencies are vendored")
+		return nil
+	}
+
+	for _, root := range downloadRoots(modules) {
+		description := "$ go mod download"
+		if root != "." {
+			description += " # in " + root
+		}
+
+		step := startStep(group, description)
+		out, err := exec.WithWorkDir(path.Join(exec.WorkDir(), root)).Run(ctx, "go", "mod", "download")
+		step.finish(out, err)
+		if err != nil {
+			return fmt.Errorf("can't download modules of %s: %s, %s", root, err, out)
+		}
+	}
+
+	return nil
+}
+
+// downloadRoots returns dirs to run go mod commands in: go mod download in the root of a workspace
+// downloads dependencies of all its modules
+func downloadRoots(modules *GoModulesInfo) []string {
+	if modules.Workspace {
+		return []string{"."}
+	}
+
+	return modules.Roots
+}
+
+func addStepGroup(l *result.Log, name string) *result.StepGroup {
+	group := &result.StepGroup{
+		Name: name,
+	}
+	l.Groups = append(l.Groups, group)
+	return group
+}
+
+type runningStep struct {
+	group     *result.StepGroup
+	step      *result.Step
+	startedAt time.Time
+}
+
+// startStep adds the step to the group, finish must be called when the step is done
+func startStep(group *result.StepGroup, description string) *runningStep {
+	step := &result.Step{
+		Description: description,
+	}
+	group.Steps = append(group.Steps, step)
+	return &runningStep{
+		group:     group,
+		step:      step,
+		startedAt: time.Now(),
+	}
+}
+
+func (s runningStep) finish(out string, err error) {
+	s.step.Duration = time.Since(s.startedAt)
+	s.group.Duration += s.step.Duration
+
+	if out = strings.TrimSpace(out); out != "" {
+		s.step.OutputLines = strings.Split(out, "\n")
+	}
+	if err != nil {
+		s.step.Error = err.Error()
+	}
+}
+
+func addInfoStep(group *result.StepGroup, description string) {
+	group.Steps = append(group.Steps, &result.Step{
+		Description: description,
+	})
+}
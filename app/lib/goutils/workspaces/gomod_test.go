@@ -0,0 +1,187 @@
+package workspaces
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golangci/golangci-api/pkg/goenv/result"
+	"github.com/golangci/golangci-shared/pkg/logutil"
+	"github.com/golangci/golangci-worker/app/lib/executors"
+	"github.com/golangci/golangci-worker/app/lib/fetchers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindModuleRoots(t *testing.T) {
+	files := parseFoundFiles(`./go.mod
+./tools/go.mod
+./vendor/github.com/pkg/errors/go.mod
+./vendor/modules.txt
+./api/v2/go.mod
+./internal/testdata/mod/go.mod
+./.github/actions/go.mod
+./_examples/go.mod
+`)
+
+	assert.Equal(t, []string{".", "api/v2", "tools"}, findModuleRoots(files))
+
+	modules := &GoModulesInfo{Roots: []string{"."}}
+	assert.True(t, isVendored(modules, files))
+	modules.Roots = findModuleRoots(files)
+	assert.False(t, isVendored(modules, files), "only the root module is vendored")
+}
+
+func TestParseGoWorkUses(t *testing.T) {
+	goWork := `go 1.21
+
+use ./tools // single use
+use (
+	.
+	./api/v2
+	"./quoted"
+	../outside
+	// ./commented
+)
+
+replace example.com/a => ./a
+`
+	assert.Equal(t, []string{".", "api/v2", "quoted", "tools"}, parseGoWorkUses(goWork))
+}
+
+func TestModulesGoFlags(t *testing.T) {
+	assert.Equal(t, "-mod=mod", modulesGoFlags(&GoModulesInfo{Roots: []string{"."}}))
+	assert.Equal(t, "-mod=vendor", modulesGoFlags(&GoModulesInfo{Roots: []string{"."}, Vendor: true}))
+	assert.Equal(t, "", modulesGoFlags(&GoModulesInfo{Roots: []string{"."}, Workspace: true}))
+}
+
+var testRepo = &fetchers.Repo{CloneURL: "https://github.com/golangci/test.git", Ref: "master", Owner: "golangci"}
+
+func newTestGoModules(ctrl *gomock.Controller, foundFiles string) (*GoModules, *executors.MockExecutor) {
+	exec := executors.NewMockExecutor(ctrl)
+	exec.EXPECT().WorkDir().Return("/repo").AnyTimes()
+	exec.EXPECT().Run(gomock.Any(), "find", ".", "-name", "go.mod", "-o", "-name", "go.work", "-o", "-name", "modules.txt").
+		Return(foundFiles, nil)
+
+	fetcher := fetchers.NewMockFetcher(ctrl)
+	fetcher.EXPECT().Fetch(gomock.Any(), testRepo, exec).Return(nil) // the repo is fetched once
+
+	log := logutil.NewStderrLog("test")
+	return NewGoModules(exec, log, fetcher, NewGo2(exec, log, fetcher)), exec
+}
+
+func getStepDescriptions(l *result.Log) map[string][]string {
+	ret := map[string][]string{}
+	for _, g := range l.Groups {
+		ret[g.Name] = []string{}
+		for _, s := range g.Steps {
+			ret[g.Name] = append(ret[g.Name], s.Description)
+		}
+	}
+
+	return ret
+}
+
+func TestGoModulesSetupFallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gm, exec := newTestGoModules(ctrl, "./vendor/modules.txt\n")
+
+	envbuildOut, err := json.Marshal(result.Result{
+		WorkDir:     "/go/src/github.com/golangci/test",
+		Environment: map[string]string{"GOPATH": "/go"},
+		Log:         &result.Log{Groups: []*result.StepGroup{{Name: "dep ensure"}}},
+	})
+	assert.NoError(t, err)
+
+	envbuildExec := executors.NewMockExecutor(ctrl)
+	exec.EXPECT().WithEnv("REPO", "github.com/golangci/test").Return(envbuildExec)
+	envbuildExec.EXPECT().WithEnv("FORMAT_JSON", "1").Return(envbuildExec)
+	envbuildExec.EXPECT().Run(gomock.Any(), "goenvbuild").Return(string(envbuildOut), nil)
+	exec.EXPECT().WithWorkDir("/go/src/github.com/golangci/test").Return(exec)
+	exec.EXPECT().WithEnv("GOPATH", "/go").Return(exec)
+
+	retExec, resLog, err := gm.Setup(context.Background(), testRepo, "github.com", "golangci", "test")
+	assert.NoError(t, err)
+	assert.Equal(t, exec, retExec)
+	assert.Equal(t, map[string][]string{
+		"fetch repo":   {"fetch https://github.com/golangci/test.git@master"},
+		"find modules": {"$ find . -name go.mod -o -name go.work -o -name modules.txt", "no go modules found"},
+		"dep ensure":   {},
+	}, getStepDescriptions(resLog))
+}
+
+func TestGoModulesSetup(t *testing.T) {
+	type testCase struct {
+		name       string
+		foundFiles string
+		goWork     string
+		goFlags    string
+		downloads  int
+		steps      []string
+	}
+
+	cases := []testCase{
+		{
+			name:       "vendor",
+			foundFiles: "./go.mod\n./vendor/modules.txt\n",
+			goFlags:    "-mod=vendor",
+			steps:      []string{"dependencies are vendored"},
+		},
+		{
+			name:       "workspace",
+			foundFiles: "./go.work\n./a/go.mod\n./b/go.mod\n",
+			goWork:     "go 1.21\n\nuse (\n\t./a\n\t./b\n)\n",
+			goFlags:    "",
+			downloads:  1,
+			steps:      []string{"$ go mod download"},
+		},
+		{
+			name:       "modules",
+			foundFiles: "./go.mod\n./tools/go.mod\n",
+			goFlags:    "-mod=mod",
+			downloads:  2,
+			steps:      []string{"$ go mod download", "$ go mod download # in tools"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			gm, exec := newTestGoModules(ctrl, tc.foundFiles)
+			if tc.goWork != "" {
+				exec.EXPECT().Run(gomock.Any(), "cat", "go.work").Return(tc.goWork, nil)
+			}
+
+			modExec := executors.NewMockExecutor(ctrl)
+			modExec.EXPECT().WorkDir().Return("/repo").AnyTimes()
+			exec.EXPECT().WithEnv("GO111MODULE", "on").Return(exec)
+			exec.EXPECT().WithEnv("GOFLAGS", tc.goFlags).Return(modExec)
+			modExec.EXPECT().WithWorkDir(gomock.Any()).Return(modExec).AnyTimes()
+			modExec.EXPECT().Run(gomock.Any(), "go", "mod", "download").Return("", nil).Times(tc.downloads)
+
+			retExec, resLog, err := gm.Setup(context.Background(), testRepo, "github.com", "golangci", "test")
+			assert.NoError(t, err)
+			assert.Equal(t, modExec, retExec)
+			assert.Equal(t, tc.steps, getStepDescriptions(resLog)["download modules"])
+		})
+	}
+}
+
+func TestGoModulesSetupDownloadError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gm, exec := newTestGoModules(ctrl, "./go.mod\n")
+	exec.EXPECT().WithEnv(gomock.Any(), gomock.Any()).Return(exec).Times(2)
+	exec.EXPECT().WithWorkDir("/repo").Return(exec)
+	exec.EXPECT().Run(gomock.Any(), "go", "mod", "download").Return("unknown revision v1.0.0", errors.New("exit status 1"))
+
+	_, _, err := gm.Setup(context.Background(), testRepo, "github.com", "golangci", "test")
+	assert.EqualError(t, err, "can't download modules of .: exit status 1, unknown revision v1.0.0")
+}
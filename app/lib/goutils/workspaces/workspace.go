@@ -2,12 +2,67 @@ package workspaces
 
 import (
 	"context"
+	"fmt"
+	"path"
 
 	"github.com/golangci/golangci-api/pkg/goenv/result"
+	"github.com/golangci/golangci-worker/app/analytics"
 	"github.com/golangci/golangci-worker/app/lib/executors"
 	"github.com/golangci/golangci-worker/app/lib/fetchers"
+	"github.com/golangci/golangci-worker/app/lib/goutils/buildcache"
+	"github.com/pkg/errors"
 )
 
 type Installer interface {
 	Setup(ctx context.Context, repo *fetchers.Repo, projectPathParts ...string) (executors.Executor, *result.Log, error)
 }
+
+// fetchedRepoInstaller prepares the repo which is already fetched to the work dir of the executor
+// of the installer: GoModules doesn't fetch the repo again for such fallback installer
+type fetchedRepoInstaller interface {
+	setupFetched(ctx context.Context, repo *fetchers.Repo, projectPathParts ...string) (executors.Executor, *result.Log, error)
+}
+
+// setupBuildCache makes the executor use the shared build cache for a repo of the owner if it's configured
+// and isn't being trimmed. It returns true if the shared cache is used.
+func setupBuildCache(ctx context.Context, exec executors.Executor, owner string) (executors.Executor, bool, error) {
+	if !buildcache.IsConfigured() {
+		return exec, false, nil
+	}
+
+	cache, err := buildcache.Get()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to get build cache")
+	}
+
+	cachedExec, err := cache.Setup(ctx, exec, owner, "")
+	if err == buildcache.ErrBeingTrimmed {
+		analytics.Log(ctx).Infof("Build cache is being trimmed, use own caches")
+		return exec, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to setup build cache")
+	}
+
+	return cachedExec, true, nil
+}
+
+// verifyModules checks that dependencies of modules in the shared module cache match go.sum:
+// the cache is writable by analyses of all repos, an analysis must not use modules changed by another one
+func verifyModules(ctx context.Context, exec executors.Executor, roots []string, group *result.StepGroup) error {
+	for _, root := range roots {
+		description := "$ go mod verify"
+		if root != "." {
+			description += " # in " + root
+		}
+
+		step := startStep(group, description)
+		out, err := exec.WithWorkDir(path.Join(exec.WorkDir(), root)).Run(ctx, "go", "mod", "verify")
+		step.finish(out, err)
+		if err != nil {
+			return fmt.Errorf("modules of %s in the shared module cache don't match go.sum: %s, %s", root, err, out)
+		}
+	}
+
+	return nil
+}
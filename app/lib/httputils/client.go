@@ -1,6 +1,7 @@
 package httputils
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -14,6 +15,7 @@ import (
 type Client interface {
 	Get(ctx context.Context, url string) (io.ReadCloser, error)
 	Put(ctx context.Context, url string, jsonObj interface{}) error
+	Post(ctx context.Context, url string, body []byte, headers map[string]string) error
 }
 
 type GrequestsClient struct{}
@@ -56,3 +58,26 @@ func (c GrequestsClient) Put(ctx context.Context, url string, jsonObj interface{
 
 	return nil
 }
+
+func (c GrequestsClient) Post(ctx context.Context, url string, body []byte, headers map[string]string) error {
+	resp, err := grequests.Post(url, &grequests.RequestOptions{
+		Context:     ctx,
+		RequestBody: bytes.NewReader(body),
+		Headers:     headers,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to make POST http request %q: %s", url, err)
+	}
+
+	defer func() {
+		if cerr := resp.Close(); cerr != nil {
+			analytics.Log(ctx).Warnf("Can't close %q response: %s", url, cerr)
+		}
+	}()
+
+	if !resp.Ok {
+		return fmt.Errorf("got error code from %q: %d", url, resp.StatusCode)
+	}
+
+	return nil
+}
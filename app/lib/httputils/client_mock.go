@@ -57,3 +57,15 @@ func (_m *MockClient) Put(ctx context.Context, url string, jsonObj interface{})
 func (_mr *MockClientMockRecorder) Put(arg0, arg1, arg2 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "Put", reflect.TypeOf((*MockClient)(nil).Put), arg0, arg1, arg2)
 }
+
+// Post mocks base method
+func (_m *MockClient) Post(ctx context.Context, url string, body []byte, headers map[string]string) error {
+	ret := _m.ctrl.Call(_m, "Post", ctx, url, body, headers)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Post indicates an expected call of Post
+func (_mr *MockClientMockRecorder) Post(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "Post", reflect.TypeOf((*MockClient)(nil).Post), arg0, arg1, arg2, arg3)
+}
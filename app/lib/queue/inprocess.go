@@ -0,0 +1,218 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const inProcessQueueSize = 1024
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// InProcessBackend runs tasks in this process by channels: it's used in tests and
+// self-hosted deployments without redis. Queued tasks are lost on restart.
+type InProcessBackend struct {
+	lock   sync.Mutex
+	queues map[string]*inProcessQueue
+	stopCh chan struct{}
+}
+
+type inProcessQueue struct {
+	tasks    chan *Task
+	handlers map[string]reflect.Value
+}
+
+var _ Backend = &InProcessBackend{}
+
+func NewInProcessBackend() *InProcessBackend {
+	return &InProcessBackend{
+		queues: map[string]*inProcessQueue{},
+		stopCh: make(chan struct{}),
+	}
+}
+
+func (b *InProcessBackend) queue(name string) *inProcessQueue {
+	if name == "" {
+		name = DefaultQueue
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	q := b.queues[name]
+	if q == nil {
+		q = &inProcessQueue{
+			tasks:    make(chan *Task, inProcessQueueSize),
+			handlers: map[string]reflect.Value{},
+		}
+		b.queues[name] = q
+	}
+
+	return q
+}
+
+func (b *InProcessBackend) Send(t *Task) error {
+	if t.ETA != nil {
+		if delay := time.Until(*t.ETA); delay > 0 {
+			time.AfterFunc(delay, func() {
+				if err := b.enqueue(t); err != nil {
+					logrus.Warnf("Can't send delayed task %s: %s", t.Name, err)
+				}
+			})
+			return nil
+		}
+	}
+
+	return b.enqueue(t)
+}
+
+func (b *InProcessBackend) enqueue(t *Task) error {
+	select {
+	case b.queue(t.Queue).tasks <- t:
+		return nil
+	default:
+		return fmt.Errorf("queue %q is full", t.Queue)
+	}
+}
+
+func (b *InProcessBackend) RegisterTasks(queueName string, handlers map[string]interface{}) error {
+	q := b.queue(queueName)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for name, h := range handlers {
+		hv := reflect.ValueOf(h)
+		ht := hv.Type()
+		if ht.Kind() != reflect.Func || ht.NumIn() == 0 || ht.In(0) != contextType ||
+			ht.NumOut() != 1 || ht.Out(0) != errorType {
+			return fmt.Errorf("handler of task %s must be func(context.Context, ...) error", name)
+		}
+
+		q.handlers[name] = hv
+	}
+
+	return nil
+}
+
+// RunWorker runs concurrency goroutines taking tasks from all queues until Stop is called:
+// a free goroutine takes a task from the first queue which has one
+func (b *InProcessBackend) RunWorker(queueNames []string, concurrency int) error {
+	var queues []*inProcessQueue
+	cases := []reflect.SelectCase{{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(b.stopCh)}}
+	for _, name := range queueNames {
+		q := b.queue(name)
+		queues = append(queues, q)
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(q.tasks)})
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				q, t := b.next(queues, cases)
+				if t == nil {
+					return
+				}
+				b.process(q, t)
+			}
+		}()
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// next waits for the next task of queues ordered by priority: it returns nil task if the worker is stopped
+func (b *InProcessBackend) next(queues []*inProcessQueue, cases []reflect.SelectCase) (*inProcessQueue, *Task) {
+	select {
+	case <-b.stopCh:
+		return nil, nil
+	default:
+	}
+
+	for _, q := range queues {
+		select {
+		case t := <-q.tasks:
+			return q, t
+		default:
+		}
+	}
+
+	chosen, v, _ := reflect.Select(cases)
+	if chosen == 0 {
+		return nil, nil
+	}
+
+	return queues[chosen-1], v.Interface().(*Task)
+}
+
+// Stop stops all workers: running tasks are finished before RunWorker returns
+func (b *InProcessBackend) Stop() {
+	close(b.stopCh)
+}
+
+func (b *InProcessBackend) process(q *inProcessQueue, t *Task) {
+	err := b.call(q, t)
+	if err == nil {
+		return
+	}
+
+	if t.RetryCount <= 0 {
+		logrus.Errorf("Task %s failed: %s", t.Name, err)
+		return
+	}
+
+	logrus.Warnf("Task %s failed, retry it in %ds: %s", t.Name, t.RetryTimeout, err)
+	retry := *t
+	retry.RetryCount--
+	eta := time.Now().Add(time.Duration(t.RetryTimeout) * time.Second)
+	retry.ETA = &eta
+	if err = b.Send(&retry); err != nil {
+		logrus.Errorf("Can't retry task %s: %s", t.Name, err)
+	}
+}
+
+func (b *InProcessBackend) call(q *inProcessQueue, t *Task) (err error) {
+	b.lock.Lock()
+	h, ok := q.handlers[t.Name]
+	b.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("no registered handler of task %s", t.Name)
+	}
+
+	ht := h.Type()
+	if ht.NumIn() != len(t.Args)+1 {
+		return fmt.Errorf("task %s has %d args, handler needs %d", t.Name, len(t.Args), ht.NumIn()-1)
+	}
+
+	in := []reflect.Value{reflect.ValueOf(context.Background())}
+	for i, arg := range t.Args {
+		v := reflect.ValueOf(arg)
+		if !v.IsValid() || !v.Type().AssignableTo(ht.In(i+1)) {
+			return fmt.Errorf("arg %d of task %s has type %T, handler needs %s", i, t.Name, arg, ht.In(i+1))
+		}
+		in = append(in, v)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic in task %s: %v", t.Name, r)
+		}
+	}()
+
+	ret := h.Call(in)[0].Interface()
+	if ret == nil {
+		return nil
+	}
+
+	return ret.(error)
+}
@@ -0,0 +1,129 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runInProcessWorker(t *testing.T, b *InProcessBackend, queueNames ...string) {
+	go func() {
+		assert.NoError(t, b.RunWorker(queueNames, 2))
+	}()
+}
+
+func TestInProcessSendReceive(t *testing.T) {
+	b := NewInProcessBackend()
+	defer b.Stop()
+
+	received := make(chan string, 1)
+	err := b.RegisterTasks("q", map[string]interface{}{
+		"task": func(_ context.Context, payload string, n int) error {
+			assert.Equal(t, 1, n)
+			received <- payload
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+	runInProcessWorker(t, b, "q")
+
+	assert.NoError(t, b.Send(&Task{Name: "task", Queue: "q", Args: []interface{}{"payload", 1}}))
+
+	select {
+	case payload := <-received:
+		assert.Equal(t, "payload", payload)
+	case <-time.After(time.Second):
+		t.Fatalf("Timeouted waiting of processing")
+	}
+}
+
+func TestInProcessRetryAndETA(t *testing.T) {
+	b := NewInProcessBackend()
+	defer b.Stop()
+
+	attempts := make(chan time.Time, 3)
+	err := b.RegisterTasks(DefaultQueue, map[string]interface{}{
+		"task": func(_ context.Context) error {
+			attempts <- time.Now()
+			return errors.New("failed")
+		},
+	})
+	assert.NoError(t, err)
+	runInProcessWorker(t, b, DefaultQueue)
+
+	sentAt := time.Now()
+	eta := sentAt.Add(50 * time.Millisecond)
+	assert.NoError(t, b.Send(&Task{Name: "task", RetryCount: 1, ETA: &eta}))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case at := <-attempts:
+			assert.True(t, !at.Before(eta), "task must not run before ETA")
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timeouted waiting of attempt %d", i+1)
+		}
+	}
+
+	select {
+	case <-attempts:
+		t.Fatalf("Task must be retried only once")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestInProcessInvalidHandler(t *testing.T) {
+	b := NewInProcessBackend()
+	err := b.RegisterTasks("q", map[string]interface{}{
+		"task": func(payload string) error { return nil },
+	})
+	assert.Error(t, err)
+}
+
+func TestInProcessQueuesShareConcurrency(t *testing.T) {
+	b := NewInProcessBackend()
+	defer b.Stop()
+
+	release := make(chan struct{})
+	started := make(chan string, 2)
+	for _, q := range []string{"high", "low"} {
+		q := q
+		err := b.RegisterTasks(q, map[string]interface{}{
+			"task": func(_ context.Context) error {
+				started <- q
+				<-release
+				return nil
+			},
+		})
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, b.Send(&Task{Name: "task", Queue: "low"}))
+	assert.NoError(t, b.Send(&Task{Name: "task", Queue: "high"}))
+	go func() {
+		assert.NoError(t, b.RunWorker([]string{"high", "low"}, 1))
+	}()
+
+	select {
+	case q := <-started:
+		assert.Equal(t, "high", q, "task of the queue with greater priority must run first")
+	case <-time.After(time.Second):
+		t.Fatalf("Timeouted waiting of processing")
+	}
+
+	select {
+	case <-started:
+		t.Fatalf("Queues must share concurrency")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case q := <-started:
+		assert.Equal(t, "low", q)
+	case <-time.After(time.Second):
+		t.Fatalf("Timeouted waiting of processing")
+	}
+}
@@ -0,0 +1,159 @@
+package queue
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1"
+	"github.com/RichardKnop/machinery/v1/config"
+	"github.com/RichardKnop/machinery/v1/tasks"
+)
+
+// MachineryBackend runs tasks by machinery with redis broker: tasks are shared by all worker processes
+type MachineryBackend struct {
+	redisURL string
+
+	serversLock sync.Mutex
+	servers     map[string]*machinery.Server
+}
+
+var _ Backend = &MachineryBackend{}
+
+func NewMachineryBackend(redisURL string) *MachineryBackend {
+	return &MachineryBackend{
+		redisURL: redisURL,
+		servers:  map[string]*machinery.Server{},
+	}
+}
+
+// server returns server which workers consume tasks from the queue: machinery worker
+// consumes only the default queue of its server. Tasks can be sent to any queue by any server
+// by setting routing key of the task signature to the queue name.
+func (b *MachineryBackend) server(queueName string) (*machinery.Server, error) {
+	b.serversLock.Lock()
+	defer b.serversLock.Unlock()
+
+	if s := b.servers[queueName]; s != nil {
+		return s, nil
+	}
+
+	cnf := &config.Config{
+		Broker:          b.redisURL,
+		DefaultQueue:    queueName,
+		ResultBackend:   b.redisURL,
+		ResultsExpireIn: int((7 * 24 * time.Hour).Seconds()), // store results for 1 week
+	}
+
+	s, err := machinery.NewServer(cnf)
+	if err != nil {
+		return nil, fmt.Errorf("can't init machinery queue server for queue %s: %s", queueName, err)
+	}
+
+	b.servers[queueName] = s
+	return s, nil
+}
+
+func (b *MachineryBackend) Send(t *Task) error {
+	var args []tasks.Arg
+	for i, arg := range t.Args {
+		if arg == nil {
+			return fmt.Errorf("arg %d of task %s is nil: machinery can't send untyped args", i, t.Name)
+		}
+
+		args = append(args, tasks.Arg{
+			Type:  reflect.TypeOf(arg).String(),
+			Value: arg,
+		})
+	}
+
+	s, err := b.server(DefaultQueue)
+	if err != nil {
+		return err
+	}
+
+	queueName := t.Queue
+	if queueName == "" {
+		queueName = DefaultQueue
+	}
+
+	signature := &tasks.Signature{
+		Name:         t.Name,
+		Args:         args,
+		RetryCount:   t.RetryCount,
+		RetryTimeout: t.RetryTimeout,
+		RoutingKey:   queueName,
+		ETA:          t.ETA,
+	}
+
+	_, err = s.SendTask(signature)
+	return err
+}
+
+func (b *MachineryBackend) RegisterTasks(queueName string, handlers map[string]interface{}) error {
+	s, err := b.server(queueName)
+	if err != nil {
+		return err
+	}
+
+	return s.RegisterTasks(handlers)
+}
+
+// RunWorker runs machinery worker for every queue: machinery worker consumes only one queue and
+// takes a task only when it has a free slot, so concurrency is split between queues. Every queue
+// gets at least one slot, the remainder goes to queues with greater priority.
+func (b *MachineryBackend) RunWorker(queueNames []string, concurrency int) error {
+	var workers []*machinery.Worker
+	for i, queueName := range queueNames {
+		s, err := b.server(queueName)
+		if err != nil {
+			return err
+		}
+
+		workers = append(workers, s.NewWorker("worker_"+queueName, splitConcurrency(concurrency, len(queueNames), i)))
+	}
+
+	type workerResult struct {
+		i   int
+		err error
+	}
+	resCh := make(chan workerResult, len(workers))
+	for i, w := range workers {
+		i, w := i, w
+		go func() {
+			resCh <- workerResult{i: i, err: w.Launch()}
+		}()
+	}
+
+	// stop all workers together: the worker process must not run without some of its queues
+	first := <-resCh
+	for i, w := range workers {
+		if i != first.i {
+			go w.Quit()
+		}
+	}
+	for range workers[1:] {
+		<-resCh
+	}
+
+	if first.err != nil {
+		return fmt.Errorf("worker of queue %s failed: %s", queueNames[first.i], first.err)
+	}
+
+	return nil
+}
+
+// splitConcurrency returns the share of concurrency of the i-th of n queues ordered by priority
+func splitConcurrency(concurrency, n, i int) int {
+	ret := concurrency / n
+	if i < concurrency%n {
+		ret++
+	}
+
+	if ret == 0 {
+		return 1
+	}
+
+	return ret
+}
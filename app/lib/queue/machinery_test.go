@@ -0,0 +1,35 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitConcurrency(t *testing.T) {
+	cases := []struct {
+		concurrency, n int
+		exp            []int
+	}{
+		{8, 4, []int{2, 2, 2, 2}},
+		{6, 4, []int{2, 2, 1, 1}},
+		{2, 5, []int{1, 1, 1, 1, 1}},
+	}
+
+	for _, c := range cases {
+		var got []int
+		for i := 0; i < c.n; i++ {
+			got = append(got, splitConcurrency(c.concurrency, c.n, i))
+		}
+		assert.Equal(t, c.exp, got, "%d between %d queues", c.concurrency, c.n)
+	}
+}
+
+func TestMachinerySendNilArg(t *testing.T) {
+	b := NewMachineryBackend("redis://127.0.0.1:0")
+	err := b.Send(&Task{
+		Name: "task",
+		Args: []interface{}{"arg", nil},
+	})
+	assert.EqualError(t, err, "arg 1 of task task is nil: machinery can't send untyped args")
+}
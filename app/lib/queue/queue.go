@@ -7,36 +7,66 @@ import (
 	"sync"
 	"time"
 
-	"github.com/RichardKnop/machinery/v1"
-	"github.com/RichardKnop/machinery/v1/config"
 	"github.com/sirupsen/logrus"
 )
 
-var server *machinery.Server
-var initOnce sync.Once
+// DefaultQueue is a queue of tasks sent without queue name
+const DefaultQueue = "machinery_tasks"
 
-func initServer() {
-	redisURL := fmt.Sprintf("%s/1", os.Getenv("REDIS_URL")) // use separate DB #1 for queue
-	logrus.Infof("REDIS_URL=%q", redisURL)
+// Task is a task to send to a queue
+type Task struct {
+	Name         string
+	Args         []interface{} // args of the task handler after the context
+	Queue        string        // DefaultQueue if it's empty
+	RetryCount   int
+	RetryTimeout int        // seconds before the first retry
+	ETA          *time.Time // run the task not earlier than ETA, nil means now
+}
 
-	cnf := &config.Config{
-		Broker:          redisURL,
-		DefaultQueue:    "machinery_tasks",
-		ResultBackend:   redisURL,
-		ResultsExpireIn: int((7 * 24 * time.Hour).Seconds()), // store results for 1 week
-	}
+// Backend sends tasks to queues and runs workers consuming them
+type Backend interface {
+	Send(t *Task) error
+
+	// RegisterTasks registers handlers of tasks from the queue by task names.
+	// A handler is a func with context.Context as the first arg and task args as others returning error.
+	RegisterTasks(queueName string, handlers map[string]interface{}) error
+
+	// RunWorker runs worker consuming the queues: at most concurrency tasks of all queues are taken at the same time,
+	// queues are ordered by priority. It blocks until the worker of any queue is stopped: then all queues are stopped.
+	RunWorker(queueNames []string, concurrency int) error
+}
 
+var backend Backend
+var initOnce sync.Once
+
+func initBackend() {
 	var err error
-	server, err = machinery.NewServer(cnf)
+	backend, err = newBackendFromEnv()
 	if err != nil {
-		log.Fatalf("Can't init machinery queue server: %s", err)
+		log.Fatalf("Can't init queue backend: %s", err)
+	}
+}
+
+// newBackendFromEnv returns backend by QUEUE_BACKEND env var: "machinery" (default) runs tasks by machinery
+// with redis broker, "inprocess" runs tasks in this process without redis.
+func newBackendFromEnv() (Backend, error) {
+	switch b := os.Getenv("QUEUE_BACKEND"); b {
+	case "", "machinery":
+		redisURL := fmt.Sprintf("%s/1", os.Getenv("REDIS_URL")) // use separate DB #1 for queue
+		logrus.Infof("REDIS_URL=%q", redisURL)
+		return NewMachineryBackend(redisURL), nil
+	case "inprocess":
+		logrus.Infof("Using in-process queue: tasks are lost on restart")
+		return NewInProcessBackend(), nil
+	default:
+		return nil, fmt.Errorf("invalid QUEUE_BACKEND %q: must be machinery or inprocess", b)
 	}
 }
 
 func Init() {
-	initOnce.Do(initServer)
+	initOnce.Do(initBackend)
 }
 
-func GetServer() *machinery.Server {
-	return server
+func GetBackend() Backend {
+	return backend
 }
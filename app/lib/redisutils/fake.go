@@ -0,0 +1,765 @@
+package redisutils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// FakeDB is an in-memory redis database for tests: it supports only commands used by the worker
+// and doesn't support scripts. Now can be replaced to test expiration.
+type FakeDB struct {
+	Now func() time.Time
+
+	lock    sync.Mutex
+	values  map[string]interface{} // []byte, map[string][]byte, [][]byte or map[string]float64
+	expires map[string]time.Time
+}
+
+func NewFakeDB() *FakeDB {
+	return &FakeDB{
+		Now:     time.Now,
+		values:  map[string]interface{}{},
+		expires: map[string]time.Time{},
+	}
+}
+
+// Pool returns the pool of connections to the database
+func (db *FakeDB) Pool() *redis.Pool {
+	return &redis.Pool{
+		MaxIdle: 1,
+		Dial: func() (redis.Conn, error) {
+			return &fakeConn{db: db}, nil
+		},
+	}
+}
+
+type fakeCommand struct {
+	name string
+	args []interface{}
+}
+
+// fakeConn runs commands when they are received: it's enough for tests without concurrent transactions
+type fakeConn struct {
+	db      *FakeDB
+	pending []fakeCommand
+	multi   bool
+	queued  []fakeCommand
+}
+
+var _ redis.Conn = &fakeConn{}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Err() error {
+	return nil
+}
+
+func (c *fakeConn) Send(name string, args ...interface{}) error {
+	c.pending = append(c.pending, fakeCommand{name: name, args: args})
+	return nil
+}
+
+func (c *fakeConn) Flush() error {
+	return nil
+}
+
+func (c *fakeConn) Receive() (interface{}, error) {
+	return nil, errors.New("fake redis doesn't support receiving replies")
+}
+
+func (c *fakeConn) Do(name string, args ...interface{}) (interface{}, error) {
+	pending := c.pending
+	c.pending = nil
+
+	var replies []interface{}
+	var err error
+	for _, cmd := range pending {
+		reply := c.run(cmd)
+		if e, ok := reply.(redis.Error); ok && err == nil {
+			err = e
+		}
+		replies = append(replies, reply)
+	}
+
+	if name == "" {
+		return replies, nil
+	}
+
+	reply := c.run(fakeCommand{name: name, args: args})
+	if e, ok := reply.(redis.Error); ok && err == nil {
+		err = e
+	}
+
+	return reply, err
+}
+
+func (c *fakeConn) run(cmd fakeCommand) interface{} {
+	name := strings.ToUpper(cmd.name)
+	switch name {
+	case "MULTI":
+		c.multi = true
+		return "OK"
+	case "EXEC":
+		if !c.multi {
+			return redis.Error("ERR EXEC without MULTI")
+		}
+		c.multi = false
+		queued := c.queued
+		c.queued = nil
+
+		replies := []interface{}{}
+		for _, q := range queued {
+			replies = append(replies, c.db.run(q.name, q.args))
+		}
+		return replies
+	case "DISCARD":
+		c.multi = false
+		c.queued = nil
+		return "OK"
+	case "WATCH", "UNWATCH":
+		return "OK" // commands are run one by one: watched keys aren't changed by other connections
+	}
+
+	if c.multi {
+		c.queued = append(c.queued, fakeCommand{name: name, args: cmd.args})
+		return "QUEUED"
+	}
+
+	return c.db.run(name, cmd.args)
+}
+
+type fakeHandler func(db *FakeDB, args []string) interface{}
+
+var fakeHandlers = map[string]fakeHandler{
+	"PING":             func(*FakeDB, []string) interface{} { return "PONG" },
+	"GET":              (*FakeDB).get,
+	"SET":              (*FakeDB).set,
+	"DEL":              (*FakeDB).del,
+	"EXISTS":           (*FakeDB).exists,
+	"EXPIRE":           (*FakeDB).expire,
+	"TTL":              (*FakeDB).ttl,
+	"INCR":             (*FakeDB).incr,
+	"HSET":             (*FakeDB).hset,
+	"HGET":             (*FakeDB).hget,
+	"HDEL":             (*FakeDB).hdel,
+	"HVALS":            (*FakeDB).hvals,
+	"HLEN":             (*FakeDB).hlen,
+	"RPUSH":            (*FakeDB).rpush,
+	"LRANGE":           (*FakeDB).lrange,
+	"ZADD":             (*FakeDB).zadd,
+	"ZREM":             (*FakeDB).zrem,
+	"ZCARD":            (*FakeDB).zcard,
+	"ZSCORE":           (*FakeDB).zscore,
+	"ZRANGE":           (*FakeDB).zrange,
+	"ZREVRANGE":        (*FakeDB).zrevrange,
+	"ZRANGEBYSCORE":    (*FakeDB).zrangebyscore,
+	"ZREMRANGEBYSCORE": (*FakeDB).zremrangebyscore,
+}
+
+// minArgs is the min count of args of commands
+var minArgs = map[string]int{
+	"GET": 1, "SET": 2, "DEL": 1, "EXISTS": 1, "EXPIRE": 2, "TTL": 1, "INCR": 1,
+	"HSET": 3, "HGET": 2, "HDEL": 2, "HVALS": 1, "HLEN": 1,
+	"RPUSH": 2, "LRANGE": 3,
+	"ZADD": 3, "ZREM": 2, "ZCARD": 1, "ZSCORE": 2, "ZRANGE": 3, "ZREVRANGE": 3,
+	"ZRANGEBYSCORE": 3, "ZREMRANGEBYSCORE": 3,
+}
+
+var errWrongType = redis.Error("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+func (db *FakeDB) run(name string, args []interface{}) interface{} {
+	name = strings.ToUpper(name)
+	h := fakeHandlers[name]
+	if h == nil {
+		return redis.Error(fmt.Sprintf("ERR fake redis doesn't support command %q", name))
+	}
+
+	strArgs := make([]string, 0, len(args))
+	for _, arg := range args {
+		strArgs = append(strArgs, formatFakeArg(arg))
+	}
+	if len(strArgs) < minArgs[name] {
+		return redis.Error(fmt.Sprintf("ERR wrong number of arguments for %q command", name))
+	}
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	for _, key := range strArgs[:keyArgsCount(name, strArgs)] {
+		db.expireKey(key)
+	}
+
+	return h(db, strArgs)
+}
+
+// keyArgsCount returns count of key args of the command: expired keys are removed before running it
+func keyArgsCount(name string, args []string) int {
+	switch name {
+	case "PING":
+		return 0
+	case "DEL", "EXISTS":
+		return len(args)
+	default:
+		return 1
+	}
+}
+
+func formatFakeArg(arg interface{}) string {
+	switch arg := arg.(type) {
+	case string:
+		return arg
+	case []byte:
+		return string(arg)
+	case int:
+		return strconv.Itoa(arg)
+	case int64:
+		return strconv.FormatInt(arg, 10)
+	case float64:
+		return strconv.FormatFloat(arg, 'g', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(arg)
+	}
+}
+
+func (db *FakeDB) expireKey(key string) {
+	if at, ok := db.expires[key]; ok && !db.Now().Before(at) {
+		delete(db.values, key)
+		delete(db.expires, key)
+	}
+}
+
+func (db *FakeDB) setValue(key string, v interface{}) {
+	db.values[key] = v
+}
+
+func (db *FakeDB) deleteKey(key string) {
+	delete(db.values, key)
+	delete(db.expires, key)
+}
+
+func (db *FakeDB) get(args []string) interface{} {
+	v, ok := db.values[args[0]]
+	if !ok {
+		return nil
+	}
+
+	s, ok := v.([]byte)
+	if !ok {
+		return errWrongType
+	}
+
+	return s
+}
+
+func (db *FakeDB) set(args []string) interface{} {
+	key := args[0]
+	var ttl time.Duration
+	var nx, xx bool
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX":
+			if i+1 == len(args) {
+				return redis.Error("ERR syntax error")
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 {
+				return redis.Error("ERR invalid expire time in set")
+			}
+			unit := time.Second
+			if strings.ToUpper(args[i]) == "PX" {
+				unit = time.Millisecond
+			}
+			ttl = time.Duration(n) * unit
+			i++
+		default:
+			return redis.Error("ERR syntax error")
+		}
+	}
+
+	_, exists := db.values[key]
+	if (nx && exists) || (xx && !exists) {
+		return nil
+	}
+
+	db.deleteKey(key)
+	db.setValue(key, []byte(args[1]))
+	if ttl != 0 {
+		db.expires[key] = db.Now().Add(ttl)
+	}
+	return "OK"
+}
+
+func (db *FakeDB) del(args []string) interface{} {
+	var n int64
+	for _, key := range args {
+		if _, ok := db.values[key]; ok {
+			db.deleteKey(key)
+			n++
+		}
+	}
+
+	return n
+}
+
+func (db *FakeDB) exists(args []string) interface{} {
+	var n int64
+	for _, key := range args {
+		if _, ok := db.values[key]; ok {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (db *FakeDB) expire(args []string) interface{} {
+	sec, err := strconv.Atoi(args[1])
+	if err != nil {
+		return redis.Error("ERR value is not an integer or out of range")
+	}
+
+	if _, ok := db.values[args[0]]; !ok {
+		return int64(0)
+	}
+
+	db.expires[args[0]] = db.Now().Add(time.Duration(sec) * time.Second)
+	db.expireKey(args[0])
+	return int64(1)
+}
+
+func (db *FakeDB) ttl(args []string) interface{} {
+	if _, ok := db.values[args[0]]; !ok {
+		return int64(-2)
+	}
+
+	at, ok := db.expires[args[0]]
+	if !ok {
+		return int64(-1)
+	}
+
+	return int64(math.Ceil(at.Sub(db.Now()).Seconds()))
+}
+
+func (db *FakeDB) incr(args []string) interface{} {
+	var n int64
+	if v, ok := db.values[args[0]]; ok {
+		s, ok := v.([]byte)
+		if !ok {
+			return errWrongType
+		}
+		var err error
+		if n, err = strconv.ParseInt(string(s), 10, 64); err != nil {
+			return redis.Error("ERR value is not an integer or out of range")
+		}
+	}
+
+	n++
+	db.setValue(args[0], []byte(strconv.FormatInt(n, 10)))
+	return n
+}
+
+func (db *FakeDB) hash(key string, create bool) (map[string][]byte, error) {
+	v, ok := db.values[key]
+	if !ok {
+		if !create {
+			return nil, nil
+		}
+		h := map[string][]byte{}
+		db.setValue(key, h)
+		return h, nil
+	}
+
+	h, ok := v.(map[string][]byte)
+	if !ok {
+		return nil, errWrongType
+	}
+
+	return h, nil
+}
+
+func (db *FakeDB) hset(args []string) interface{} {
+	if len(args)%2 == 0 {
+		return redis.Error("ERR wrong number of arguments for 'hset' command")
+	}
+
+	h, err := db.hash(args[0], true)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	for i := 1; i < len(args); i += 2 {
+		if _, ok := h[args[i]]; !ok {
+			n++
+		}
+		h[args[i]] = []byte(args[i+1])
+	}
+
+	return n
+}
+
+func (db *FakeDB) hget(args []string) interface{} {
+	h, err := db.hash(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	v, ok := h[args[1]]
+	if !ok {
+		return nil
+	}
+
+	return v
+}
+
+func (db *FakeDB) hdel(args []string) interface{} {
+	h, err := db.hash(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	for _, field := range args[1:] {
+		if _, ok := h[field]; ok {
+			delete(h, field)
+			n++
+		}
+	}
+	if h != nil && len(h) == 0 {
+		db.deleteKey(args[0])
+	}
+
+	return n
+}
+
+func (db *FakeDB) hvals(args []string) interface{} {
+	h, err := db.hash(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	var fields []string
+	for field := range h {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	ret := []interface{}{}
+	for _, field := range fields {
+		ret = append(ret, h[field])
+	}
+
+	return ret
+}
+
+func (db *FakeDB) hlen(args []string) interface{} {
+	h, err := db.hash(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	return int64(len(h))
+}
+
+func (db *FakeDB) list(key string) ([][]byte, error) {
+	v, ok := db.values[key]
+	if !ok {
+		return nil, nil
+	}
+
+	l, ok := v.([][]byte)
+	if !ok {
+		return nil, errWrongType
+	}
+
+	return l, nil
+}
+
+func (db *FakeDB) rpush(args []string) interface{} {
+	l, err := db.list(args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, v := range args[1:] {
+		l = append(l, []byte(v))
+	}
+	db.setValue(args[0], l)
+	return int64(len(l))
+}
+
+// rangeBounds converts redis inclusive start and stop indexes, possibly negative, to slice bounds
+func rangeBounds(startArg, stopArg string, n int) (int, int, error) {
+	start, err := strconv.Atoi(startArg)
+	if err != nil {
+		return 0, 0, redis.Error("ERR value is not an integer or out of range")
+	}
+	stop, err := strconv.Atoi(stopArg)
+	if err != nil {
+		return 0, 0, redis.Error("ERR value is not an integer or out of range")
+	}
+
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop {
+		return 0, 0, nil
+	}
+
+	return start, stop + 1, nil
+}
+
+func (db *FakeDB) lrange(args []string) interface{} {
+	l, err := db.list(args[0])
+	if err != nil {
+		return err
+	}
+
+	from, to, err := rangeBounds(args[1], args[2], len(l))
+	if err != nil {
+		return err
+	}
+
+	ret := []interface{}{}
+	for _, v := range l[from:to] {
+		ret = append(ret, v)
+	}
+
+	return ret
+}
+
+func (db *FakeDB) zset(key string, create bool) (map[string]float64, error) {
+	v, ok := db.values[key]
+	if !ok {
+		if !create {
+			return nil, nil
+		}
+		z := map[string]float64{}
+		db.setValue(key, z)
+		return z, nil
+	}
+
+	z, ok := v.(map[string]float64)
+	if !ok {
+		return nil, errWrongType
+	}
+
+	return z, nil
+}
+
+// sortedMembers returns members ordered by score and then lexicographically
+func sortedMembers(z map[string]float64) []string {
+	var members []string
+	for m := range z {
+		members = append(members, m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if z[members[i]] != z[members[j]] {
+			return z[members[i]] < z[members[j]]
+		}
+		return members[i] < members[j]
+	})
+
+	return members
+}
+
+func (db *FakeDB) zadd(args []string) interface{} {
+	if len(args)%2 == 0 {
+		return redis.Error("ERR syntax error")
+	}
+
+	z, err := db.zset(args[0], true)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	for i := 1; i < len(args); i += 2 {
+		score, parseErr := strconv.ParseFloat(args[i], 64)
+		if parseErr != nil {
+			return redis.Error("ERR value is not a valid float")
+		}
+		if _, ok := z[args[i+1]]; !ok {
+			n++
+		}
+		z[args[i+1]] = score
+	}
+
+	return n
+}
+
+func (db *FakeDB) zrem(args []string) interface{} {
+	z, err := db.zset(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	for _, m := range args[1:] {
+		if _, ok := z[m]; ok {
+			delete(z, m)
+			n++
+		}
+	}
+	if z != nil && len(z) == 0 {
+		db.deleteKey(args[0])
+	}
+
+	return n
+}
+
+func (db *FakeDB) zcard(args []string) interface{} {
+	z, err := db.zset(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	return int64(len(z))
+}
+
+func (db *FakeDB) zscore(args []string) interface{} {
+	z, err := db.zset(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	score, ok := z[args[1]]
+	if !ok {
+		return nil
+	}
+
+	return []byte(strconv.FormatFloat(score, 'g', -1, 64))
+}
+
+func membersReply(members []string) interface{} {
+	ret := []interface{}{}
+	for _, m := range members {
+		ret = append(ret, []byte(m))
+	}
+
+	return ret
+}
+
+func (db *FakeDB) zrange(args []string) interface{} {
+	z, err := db.zset(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	members := sortedMembers(z)
+	from, to, err := rangeBounds(args[1], args[2], len(members))
+	if err != nil {
+		return err
+	}
+
+	return membersReply(members[from:to])
+}
+
+func (db *FakeDB) zrevrange(args []string) interface{} {
+	z, err := db.zset(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	members := sortedMembers(z)
+	for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+		members[i], members[j] = members[j], members[i]
+	}
+	from, to, err := rangeBounds(args[1], args[2], len(members))
+	if err != nil {
+		return err
+	}
+
+	return membersReply(members[from:to])
+}
+
+// parseScoreBound parses min or max of ZRANGEBYSCORE: "-inf", "+inf", "1" or exclusive "(1"
+func parseScoreBound(s string) (float64, bool, error) {
+	exclusive := strings.HasPrefix(s, "(")
+	s = strings.TrimPrefix(s, "(")
+
+	var v float64
+	switch s {
+	case "-inf":
+		v = math.Inf(-1)
+	case "+inf", "inf":
+		v = math.Inf(1)
+	default:
+		var err error
+		if v, err = strconv.ParseFloat(s, 64); err != nil {
+			return 0, false, redis.Error("ERR min or max is not a float")
+		}
+	}
+
+	return v, exclusive, nil
+}
+
+func (db *FakeDB) membersByScore(args []string) ([]string, error) {
+	z, err := db.zset(args[0], false)
+	if err != nil {
+		return nil, err
+	}
+
+	min, minExclusive, err := parseScoreBound(args[1])
+	if err != nil {
+		return nil, err
+	}
+	max, maxExclusive, err := parseScoreBound(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []string
+	for _, m := range sortedMembers(z) {
+		score := z[m]
+		if score < min || (minExclusive && score == min) || score > max || (maxExclusive && score == max) {
+			continue
+		}
+		ret = append(ret, m)
+	}
+
+	return ret, nil
+}
+
+func (db *FakeDB) zrangebyscore(args []string) interface{} {
+	members, err := db.membersByScore(args)
+	if err != nil {
+		return err
+	}
+
+	return membersReply(members)
+}
+
+func (db *FakeDB) zremrangebyscore(args []string) interface{} {
+	members, err := db.membersByScore(args)
+	if err != nil {
+		return err
+	}
+
+	return db.zrem(append([]string{args[0]}, members...))
+}
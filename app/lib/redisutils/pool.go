@@ -0,0 +1,35 @@
+package redisutils
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var pool *redis.Pool
+var initOnce sync.Once
+
+func initPool() {
+	redisURL := fmt.Sprintf("%s/2", os.Getenv("REDIS_URL")) // use separate DB #2 for worker state
+	pool = &redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 4 * time.Minute,
+		Dial: func() (redis.Conn, error) {
+			return redis.DialURL(redisURL)
+		},
+	}
+}
+
+// GetPool returns the pool of connections to redis from REDIS_URL env var
+func GetPool() *redis.Pool {
+	initOnce.Do(initPool)
+	return pool
+}
+
+// IsConfigured returns true if redis url is set
+func IsConfigured() bool {
+	return os.Getenv("REDIS_URL") != ""
+}
@@ -1,4 +1,7 @@
-go clean -cache
+if [ -z "$BUILD_CACHE_DIR" ]; then
+  # the shared build cache is used by other analyses
+  go clean -cache
+fi
 rm -rf /tmp/glide-vendor*
 rm -rf /tmp/go-build*
 rm -rf $HOME/.glide/cache
\ No newline at end of file
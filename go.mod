@@ -7,6 +7,7 @@ require (
 	github.com/RichardKnop/machinery v0.0.0-20180221144734-c5e057032f00
 	github.com/cenkalti/backoff v2.0.0+incompatible
 	github.com/dukex/mixpanel v0.0.0-20170510165255-53bfdf679eec
+	github.com/garyburd/redigo v1.5.0
 	github.com/golang/mock v1.1.1
 	github.com/golangci/getrepoinfo v0.0.0-20180818083854-2a0c71df2c85
 	github.com/golangci/golangci-api v0.0.0-20181118193359-820cf3a69851
@@ -20,5 +21,7 @@ require (
 	github.com/shirou/gopsutil v0.0.0-20180801053943-8048a2e9c577
 	github.com/sirupsen/logrus v1.0.5
 	github.com/stretchr/testify v1.2.1
+	go.etcd.io/bbolt v1.3.5
 	golang.org/x/oauth2 v0.0.0-20180118004544-b28fcf2b08a1
+	gopkg.in/yaml.v2 v2.2.1
 )